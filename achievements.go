@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const (
+	ACHIEVEMENT_KERNEL_COMPILE = iota
+	ACHIEVEMENT_EXEC_STORM
+	ACHIEVEMENT_LOADED_BOX
+	ACHIEVEMENT_OOM_SURVIVOR
+	ACHIEVEMENT_CENTURY
+	ACHIEVEMENT_KINDS
+)
+
+const (
+	// COMPILE_SURVIVAL is how long the snake has to stay alive during a
+	// build to have survived a kernel compile. A build runs while
+	// compilers were started within COMPILE_WINDOW and processes start at
+	// COMPILE_EXEC_RATE or more.
+	COMPILE_SURVIVAL  = time.Minute
+	COMPILE_WINDOW    = 2 * time.Second
+	COMPILE_EXEC_RATE = 50
+	EXEC_STORM_RATE   = 1000
+	LOADED_BOX_LENGTH = 50
+	CENTURY_SCORE     = 100
+)
+
+type achievement struct {
+	// id is the key the achievement is kept under.
+	id          string
+	name        string
+	description string
+}
+
+var achievements = [ACHIEVEMENT_KINDS]achievement{
+	ACHIEVEMENT_KERNEL_COMPILE: {"kernel-compile", "Survived a kernel compile", "stay alive for a minute while compilers start at 50 execve/s or more"},
+	ACHIEVEMENT_EXEC_STORM:     {"exec-storm", "Ate food during 1000 execve/s", "eat while processes start at 1000 execve/s or more"},
+	ACHIEVEMENT_LOADED_BOX:     {"loaded-box", "Length 50 on a loaded box", "grow to 50 while the load average is at least the number of CPUs"},
+	ACHIEVEMENT_OOM_SURVIVOR:   {"oom-survivor", "Outlived the OOM killer", "keep playing after an OOM kill halves the snake"},
+	ACHIEVEMENT_CENTURY:        {"century", "Century", "score 100 in one round"},
+}
+
+// compilers are the programs a build starts over and over.
+var compilers = map[string]bool{
+	"cc": true, "cc1": true, "cc1plus": true, "gcc": true, "clang": true,
+	"as": true, "ld": true, "rustc": true,
+}
+
+// unlockedAchievements maps the ids of the unlocked achievements to when
+// they were unlocked.
+type unlockedAchievements map[string]time.Time
+
+func achievementsPath() string {
+	return filepath.Join(userHomeDir(), ".local", "share", "snake-ebpf", "achievements.json")
+}
+
+func loadAchievements(path string) (unlockedAchievements, error) {
+	unlocked := make(unlockedAchievements)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return unlocked, nil
+	}
+	if err != nil {
+		return unlocked, fmt.Errorf("read achievements: %w", err)
+	}
+	if err := json.Unmarshal(data, &unlocked); err != nil {
+		return make(unlockedAchievements), fmt.Errorf("decode achievements: %w", err)
+	}
+	return unlocked, nil
+}
+
+func saveAchievements(path string, unlocked unlockedAchievements) error {
+	data, err := json.MarshalIndent(unlocked, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode achievements: %w", err)
+	}
+	return writeDataFile(path, append(data, '\n'), "achievements")
+}
+
+// achievementTracker follows a session towards the achievements not
+// unlocked yet.
+type achievementTracker struct {
+	path     string
+	unlocked unlockedAchievements
+	// lastCompiler is when a compiler was last started, and compileSince
+	// when the snake started to stay alive during the current build.
+	lastCompiler time.Time
+	compileSince time.Time
+	score        int
+}
+
+// execStarted notes the compilers among the programs started.
+func (s *session) execStarted(ev ebpfmon.KernelEvent) {
+	if s.achievements == nil {
+		return
+	}
+	name := ev.Comm
+	if ev.Arg != "" {
+		name = filepath.Base(ev.Arg)
+	}
+	if compilers[name] {
+		s.achievements.lastCompiler = s.now
+	}
+}
+
+// checkAchievements unlocks what the round has earned so far. Demo and
+// two-player rounds earn none, like they set no high scores.
+func (s *session) checkAchievements() {
+	a, g := s.achievements, s.game
+	if a == nil || s.demo || g.Player2 != nil {
+		return
+	}
+	if g.GameOver || g.Paused {
+		a.compileSince = time.Time{}
+		a.score = g.Score
+		return
+	}
+	rate := s.ui.Rate
+	building := s.now.Sub(a.lastCompiler) < COMPILE_WINDOW && rate.Execve >= COMPILE_EXEC_RATE
+	switch {
+	case !building:
+		a.compileSince = time.Time{}
+	case a.compileSince.IsZero():
+		a.compileSince = s.now
+	case s.now.Sub(a.compileSince) >= COMPILE_SURVIVAL:
+		s.unlock(ACHIEVEMENT_KERNEL_COMPILE)
+	}
+	if g.Score > a.score && rate.Execve >= EXEC_STORM_RATE {
+		s.unlock(ACHIEVEMENT_EXEC_STORM)
+	}
+	a.score = g.Score
+	// loadedBox reads /proc, so it is only asked until the box is unlocked.
+	if len(g.Snake) >= LOADED_BOX_LENGTH && !a.has(ACHIEVEMENT_LOADED_BOX) && loadedBox() {
+		s.unlock(ACHIEVEMENT_LOADED_BOX)
+	}
+	if len(g.OOMKills) > 0 {
+		s.unlock(ACHIEVEMENT_OOM_SURVIVOR)
+	}
+	if g.Score >= CENTURY_SCORE {
+		s.unlock(ACHIEVEMENT_CENTURY)
+	}
+}
+
+// has reports whether the achievement of kind is unlocked.
+func (a *achievementTracker) has(kind int) bool {
+	_, ok := a.unlocked[achievements[kind].id]
+	return ok
+}
+
+func (s *session) unlock(kind int) {
+	a := s.achievements
+	if a.has(kind) {
+		return
+	}
+	a.unlocked[achievements[kind].id] = s.now
+	s.ui.Toasts.Push("🏆 achievement unlocked: " + achievements[kind].name)
+	if err := saveAchievements(a.path, a.unlocked); err != nil {
+		s.ui.Toasts.Push(err.Error())
+	}
+}
+
+// loadedBox reports whether the 1-minute load average is at least the
+// number of CPUs.
+func loadedBox() bool {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return false
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	return err == nil && load >= float64(runtime.NumCPU())
+}
+
+// runAchievements lists the achievements and which of them are unlocked.
+func runAchievements(args []string) int {
+	fs := newFlagSet("achievements", "")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	unlocked, err := loadAchievements(achievementsPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACHIEVEMENT\tUNLOCKED\tHOW")
+	n := 0
+	for _, a := range achievements {
+		date := "-"
+		if t, ok := unlocked[a.id]; ok {
+			date = t.Local().Format(time.DateOnly)
+			n++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", a.name, date, a.description)
+	}
+	w.Flush()
+	fmt.Printf("\n%d of %d unlocked\n", n, len(achievements))
+	return EXIT_OK
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+type controlCommand struct {
+	action string
+	value  string
+	reply  chan controlReply
+}
+
+type controlReply struct {
+	state apiState
+	game  gameSnapshot
+	err   error
+}
+
+var (
+	errShuttingDown = errors.New("game is shutting down")
+	errGameBusy     = errors.New("game loop busy")
+)
+
+// submitControl hands an action to the game loop and waits for its reply.
+// The error is errShuttingDown or errGameBusy when the loop did not answer;
+// an error of the action itself is in the reply.
+func submitControl(ctx context.Context, commands chan<- controlCommand, action, value string) (controlReply, error) {
+	cmd := controlCommand{action: action, value: value, reply: make(chan controlReply, 1)}
+	select {
+	case commands <- cmd:
+	case <-ctx.Done():
+		return controlReply{}, errShuttingDown
+	case <-time.After(2 * time.Second):
+		return controlReply{}, errGameBusy
+	}
+
+	select {
+	case reply := <-cmd.reply:
+		return reply, nil
+	case <-time.After(2 * time.Second):
+		return controlReply{}, errGameBusy
+	}
+}
+
+type apiState struct {
+	Score      int    `json:"score"`
+	Length     int    `json:"length"`
+	Paused     bool   `json:"paused"`
+	GameOver   bool   `json:"game_over"`
+	Difficulty string `json:"difficulty"`
+	Theme      string `json:"theme"`
+}
+
+type apiServer struct {
+	token    string
+	commands chan<- controlCommand
+}
+
+func generateAPIToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate api token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func startAPIServer(ctx context.Context, addr, token string, commands chan<- controlCommand) (*http.Server, error) {
+	api := &apiServer{token: token, commands: commands}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/state", api.handle("state"))
+	mux.HandleFunc("POST /api/pause", api.handle("pause"))
+	mux.HandleFunc("POST /api/resume", api.handle("resume"))
+	mux.HandleFunc("POST /api/reset", api.handle("reset"))
+	mux.HandleFunc("POST /api/difficulty/{value}", api.handle("difficulty"))
+	mux.HandleFunc("POST /api/theme/{value}", api.handle("theme"))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go server.Serve(listener)
+	return server, nil
+}
+
+func (a *apiServer) authorized(r *http.Request) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(a.token)) == 1
+}
+
+func (a *apiServer) handle(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		reply, err := submitControl(r.Context(), a.commands, action, r.PathValue("value"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if reply.err != nil {
+			http.Error(w, reply.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply.state)
+	}
+}
+
+func (s *session) applyControl(cmd controlCommand) error {
+	switch cmd.action {
+	case "state":
+	case "pause":
+		s.game.Paused = true
+	case "resume":
+		s.game.Paused = false
+	case "reset":
+		s.game.Reset()
+		s.newRound()
+	case "difficulty":
+		d, err := game.LookupDifficulty(cmd.value)
+		if err != nil {
+			return err
+		}
+		s.game.Difficulty = d
+	case "theme":
+		t, ok := tui.LookupTheme(cmd.value)
+		if !ok {
+			return fmt.Errorf("unknown theme %q", cmd.value)
+		}
+		if !s.noColor {
+			s.ui.Theme = t
+		}
+	case "input":
+		// A game key is recorded as input, like one typed.
+		if !s.isGameKey(cmd.value) {
+			return fmt.Errorf("unknown game key %q", cmd.value)
+		}
+		s.handleKey(cmd.value)
+		return nil
+	case "peer-tick":
+		if err := s.peerTicked(cmd.value); err != nil {
+			return err
+		}
+	case "peer-input":
+		if err := s.peerKey(cmd.value); err != nil {
+			return err
+		}
+	case "peer-left":
+		s.ui.Toasts.Push("🔌 player 2 left the game")
+	default:
+		return fmt.Errorf("unknown action %q", cmd.action)
+	}
+	if cmd.action != "state" {
+		s.record(replayFrame{Kind: FRAME_CONTROL, Action: cmd.action, Value: cmd.value})
+	}
+	return nil
+}
+
+func (s *session) apiState() apiState {
+	return apiState{
+		Score:      s.game.Score,
+		Length:     len(s.game.Snake),
+		Paused:     s.game.Paused,
+		GameOver:   s.game.GameOver,
+		Difficulty: s.game.Difficulty.Name,
+		Theme:      s.ui.Theme.Name,
+	}
+}
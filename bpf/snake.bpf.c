@@ -1,149 +1,487 @@
-#include <linux/types.h>
-#include <linux/bpf.h>
+#include "vmlinux.h"
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
 
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} execve_counter SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} file_ops_counter SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} network_counter SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} process_counter SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} context_switch_counter SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_ARRAY);
-    __uint(max_entries, 1);
-    __type(key, __u32);
-    __type(value, __u64);
-} event_rate SEC(".maps");
-
-struct {
-    __uint(type, BPF_MAP_TYPE_HASH);
-    __uint(max_entries, 100);
-    __type(key, __u64);
-    __type(value, __u64);
-} recent_events SEC(".maps");
-
-static void update_event_rate(void)
-{
-    __u64 current_time = bpf_ktime_get_ns() / 1000000000;
-    __u32 key = 0;
-    __u64 *rate = bpf_map_lookup_elem(&event_rate, &key);
-    if (rate) {
-        __u64 *count = bpf_map_lookup_elem(&recent_events, &current_time);
-        if (count) {
-            *rate = *count;
-        } else {
-            *rate = 0;
-        }
-    }
-    
-    __u64 old_time = current_time - 10;
-    bpf_map_delete_elem(&recent_events, &old_time);
-}
+#include "snake.bpf.h"
 
-static void increment_event_bucket(void)
+SEC("tracepoint/syscalls/sys_enter_execve")
+int handle_execve_tp(struct trace_event_raw_sys_enter *ctx)
 {
-    __u64 current_time = bpf_ktime_get_ns() / 1000000000;
-    __u64 *count = bpf_map_lookup_elem(&recent_events, &current_time);
-    if (count) {
-        __sync_fetch_and_add(count, 1);
-    } else {
-        __u64 initial = 1;
-        bpf_map_update_elem(&recent_events, &current_time, &initial, BPF_ANY);
-    }
+    count_execve((const char *)ctx->args[0]);
+    return 0;
 }
 
 SEC("kprobe/sys_enter_execve")
 int handle_execve(struct pt_regs *ctx)
 {
-    __u32 key = 0;
-    __u64 *value = bpf_map_lookup_elem(&execve_counter, &key);
+    count_execve(NULL);
+    return 0;
+}
+
+static void count_file_open(const char *filename)
+{
+    if (!event_allowed()) {
+        return;
+    }
+    __u32 key = COUNTER_FILE_OPS;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
     if (value) {
-        __sync_fetch_and_add(value, 1);
-        increment_event_bucket();
-        update_event_rate();
+        *value += 1;
+        count_event_rate();
+        increment_cgroup_events(EVENT_OPEN);
+        increment_pid_events();
+        emit_event(EVENT_OPEN, filename);
     }
+}
+
+SEC("tracepoint/syscalls/sys_enter_openat")
+int handle_file_open_tp(struct trace_event_raw_sys_enter *ctx)
+{
+    count_file_open((const char *)ctx->args[1]);
     return 0;
 }
 
 SEC("kprobe/do_sys_openat2")
 int handle_file_open(struct pt_regs *ctx)
 {
-    __u32 key = 0;
-    __u64 *value = bpf_map_lookup_elem(&file_ops_counter, &key);
-    if (value) {
-        __sync_fetch_and_add(value, 1);
-        increment_event_bucket();
-    }
+    count_file_open((const char *)PT_REGS_PARM2(ctx));
     return 0;
 }
 
 SEC("kprobe/tcp_v4_connect")
 int handle_network_connect(struct pt_regs *ctx)
 {
-    __u32 key = 0;
-    __u64 *value = bpf_map_lookup_elem(&network_counter, &key);
-    if (value) {
-        __sync_fetch_and_add(value, 1);
-        increment_event_bucket();
-    }
+    count_network_connect();
     return 0;
 }
 
 SEC("kprobe/_do_fork")
 int handle_process_fork(struct pt_regs *ctx)
 {
-    __u32 key = 0;
-    __u64 *value = bpf_map_lookup_elem(&process_counter, &key);
+    count_process_fork();
+    return 0;
+}
+
+static void count_context_switch(void)
+{
+    if (!event_allowed()) {
+        return;
+    }
+    __u32 key = COUNTER_CONTEXT_SWITCH;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
     if (value) {
-        __sync_fetch_and_add(value, 1);
-        increment_event_bucket();
+        if (*value % 100 == 0) {
+            *value += 100;
+        } else {
+            *value += 1;
+        }
     }
+}
+
+SEC("tracepoint/sched/sched_switch")
+int handle_context_switch_tp(void *ctx)
+{
+    count_context_switch();
     return 0;
 }
 
 SEC("kprobe/__schedule")
 int handle_context_switch(struct pt_regs *ctx)
 {
-    __u32 key = 0;
-    __u64 *value = bpf_map_lookup_elem(&context_switch_counter, &key);
-    if (value) {
-        if (*value % 100 == 0) {
-            __sync_fetch_and_add(value, 100);
-        } else {
-            __sync_fetch_and_add(value, 1);
+    count_context_switch();
+    return 0;
+}
+
+SEC("tracepoint/tcp/tcp_retransmit_skb")
+int handle_tcp_retransmit_tp(void *ctx)
+{
+    count_counter(COUNTER_RETRANSMIT);
+    return 0;
+}
+
+SEC("kprobe/tcp_retransmit_skb")
+int handle_tcp_retransmit(struct pt_regs *ctx)
+{
+    count_counter(COUNTER_RETRANSMIT);
+    return 0;
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int handle_skb_drop_tp(void *ctx)
+{
+    count_counter(COUNTER_DROP);
+    return 0;
+}
+
+SEC("kprobe/kfree_skb_reason")
+int handle_skb_drop(struct pt_regs *ctx)
+{
+    count_counter(COUNTER_DROP);
+    return 0;
+}
+
+SEC("tracepoint/exceptions/page_fault_user")
+int handle_page_fault_tp(void *ctx)
+{
+    if (!event_allowed()) {
+        return 0;
+    }
+    count_counter(COUNTER_PAGE_FAULT);
+    return 0;
+}
+
+SEC("kprobe/handle_mm_fault")
+int handle_page_fault(struct pt_regs *ctx)
+{
+    if (!event_allowed()) {
+        return 0;
+    }
+    count_counter(COUNTER_PAGE_FAULT);
+    return 0;
+}
+
+SEC("tracepoint/vmscan/mm_vmscan_direct_reclaim_begin")
+int handle_reclaim_tp(void *ctx)
+{
+    count_counter(COUNTER_RECLAIM);
+    return 0;
+}
+
+SEC("kprobe/try_to_free_pages")
+int handle_reclaim(struct pt_regs *ctx)
+{
+    count_counter(COUNTER_RECLAIM);
+    return 0;
+}
+
+SEC("tracepoint/task/task_newtask")
+int handle_task_new(struct trace_event_raw_task_newtask *ctx)
+{
+    if (!(ctx->clone_flags & CLONE_THREAD)) {
+        count_counter(COUNTER_PROCESS_NEW);
+    }
+    return 0;
+}
+
+SEC("tracepoint/sched/sched_process_exit")
+int handle_process_exit(void *ctx)
+{
+    __u64 pid_tgid = bpf_get_current_pid_tgid();
+    if ((__u32)pid_tgid == pid_tgid >> 32) {
+        count_counter(COUNTER_PROCESS_EXIT);
+    }
+    return 0;
+}
+
+SEC("uprobe")
+int handle_uprobe(struct pt_regs *ctx)
+{
+    if (!event_allowed()) {
+        return 0;
+    }
+    count_counter(COUNTER_UPROBE);
+    return 0;
+}
+
+SEC("usdt")
+int handle_usdt(struct pt_regs *ctx)
+{
+    if (!event_allowed()) {
+        return 0;
+    }
+    count_counter(COUNTER_USDT);
+    return 0;
+}
+
+/* iov_iter before Linux 6.4, where __iov was still called iov. */
+struct iov_iter___pre_6_4 {
+    const struct iovec *iov;
+} __attribute__((preserve_access_index));
+
+/* The data a UDP send takes from user memory: the one buffer of send()
+ * and sendto(), or the first one of sendmsg(). ITER_UBUF only exists
+ * since Linux 6.0, and older kernels always hand over an iovec. */
+static __always_inline const char *udp_payload(struct msghdr *msg)
+{
+    if (bpf_core_enum_value_exists(enum iter_type, ITER_UBUF) &&
+        BPF_CORE_READ(msg, msg_iter.iter_type) == bpf_core_enum_value(enum iter_type, ITER_UBUF)) {
+        return BPF_CORE_READ(msg, msg_iter.ubuf);
+    }
+    if (bpf_core_field_exists(msg->msg_iter.__iov)) {
+        return BPF_CORE_READ(msg, msg_iter.__iov, iov_base);
+    }
+    struct iov_iter___pre_6_4 *iter = (void *)__builtin_preserve_access_index(&msg->msg_iter);
+    return BPF_CORE_READ(iter, iov, iov_base);
+}
+
+SEC("kprobe/udp_sendmsg")
+int handle_dns_query(struct pt_regs *ctx)
+{
+    struct sock *sk = (struct sock *)PT_REGS_PARM1(ctx);
+    struct msghdr *msg = (struct msghdr *)PT_REGS_PARM2(ctx);
+
+    /* sendto() names the server, send() on a connected socket does not. */
+    __u16 port = BPF_CORE_READ(sk, __sk_common.skc_dport);
+    struct sockaddr_in *addr = BPF_CORE_READ(msg, msg_name);
+    if (addr) {
+        port = BPF_CORE_READ(addr, sin_port);
+    }
+    if (port != bpf_htons(DNS_PORT) || !event_allowed()) {
+        return 0;
+    }
+
+    const char *payload = udp_payload(msg);
+    struct dns_query query = {.count = 1};
+    if (!payload || bpf_probe_read_user(query.name, sizeof(query.name), payload + DNS_HEADER_LEN) < 0) {
+        return 0;
+    }
+    __u64 hash = 14695981039346656037ULL;
+    int end = 0;
+    for (int i = 0; i < DNS_NAME_LEN; i++) {
+        char c = query.name[i];
+        if (end) {
+            query.name[i] = 0;
+            continue;
+        }
+        if (c >= 'A' && c <= 'Z') {
+            c += 'a' - 'A';
+            query.name[i] = c;
         }
+        hash = (hash ^ (__u8)c) * 1099511628211ULL;
+        end = c == 0;
+    }
+
+    count_counter(COUNTER_DNS_QUERY);
+    struct dns_query *seen = bpf_map_lookup_elem(&dns_queries, &hash);
+    if (seen) {
+        __sync_fetch_and_add(&seen->count, 1);
+    } else {
+        bpf_map_update_elem(&dns_queries, &hash, &query, BPF_NOEXIST);
+    }
+    return 0;
+}
+
+static __u32 log2_u64(__u64 v)
+{
+    __u32 r = 0;
+    if (v >> 32) { v >>= 32; r += 32; }
+    if (v >> 16) { v >>= 16; r += 16; }
+    if (v >> 8)  { v >>= 8;  r += 8; }
+    if (v >> 4)  { v >>= 4;  r += 4; }
+    if (v >> 2)  { v >>= 2;  r += 2; }
+    if (v >> 1)  { r += 1; }
+    return r;
+}
+
+SEC("tracepoint/block/block_rq_issue")
+int handle_block_issue(struct trace_event_raw_block_rq *ctx)
+{
+    struct io_request req = {.dev = ctx->dev, .sector = ctx->sector};
+    __u64 now = bpf_ktime_get_ns();
+    bpf_map_update_elem(&io_start, &req, &now, BPF_ANY);
+    return 0;
+}
+
+SEC("tracepoint/block/block_rq_complete")
+int handle_block_complete(struct trace_event_raw_block_rq_completion *ctx)
+{
+    struct io_request req = {.dev = ctx->dev, .sector = ctx->sector};
+    __u64 *start = bpf_map_lookup_elem(&io_start, &req);
+    if (!start) {
+        return 0;
+    }
+    __u64 us = (bpf_ktime_get_ns() - *start) / 1000;
+    bpf_map_delete_elem(&io_start, &req);
+
+    __u32 slot = log2_u64(us);
+    if (slot >= IO_LATENCY_SLOTS) {
+        slot = IO_LATENCY_SLOTS - 1;
+    }
+    __u64 *count = bpf_map_lookup_elem(&io_latency, &slot);
+    if (count) {
+        *count += 1;
     }
     return 0;
 }
 
+#define TASK_RUNNING 0
+
+static __always_inline void runq_enqueue(__u32 pid)
+{
+    if (pid == 0) {
+        return;
+    }
+    __u64 now = bpf_ktime_get_ns();
+    bpf_map_update_elem(&runq_start, &pid, &now, BPF_ANY);
+}
+
+SEC("tracepoint/sched/sched_wakeup")
+int handle_runq_wakeup(struct trace_event_raw_sched_wakeup_template *ctx)
+{
+    runq_enqueue(ctx->pid);
+    return 0;
+}
+
+SEC("tracepoint/sched/sched_wakeup_new")
+int handle_runq_wakeup_new(struct trace_event_raw_sched_wakeup_template *ctx)
+{
+    runq_enqueue(ctx->pid);
+    return 0;
+}
+
+/* A task that is switched out while still runnable was preempted and waits
+ * on the run queue again; the one switched in has stopped waiting. */
+SEC("tracepoint/sched/sched_switch")
+int handle_runq_switch(struct trace_event_raw_sched_switch *ctx)
+{
+    if (ctx->prev_state == TASK_RUNNING) {
+        runq_enqueue(ctx->prev_pid);
+    }
+    __u32 pid = ctx->next_pid;
+    __u64 *start = bpf_map_lookup_elem(&runq_start, &pid);
+    if (!start) {
+        return 0;
+    }
+    __u64 us = (bpf_ktime_get_ns() - *start) / 1000;
+    bpf_map_delete_elem(&runq_start, &pid);
+
+    __u32 slot = log2_u64(us);
+    if (slot >= RUNQ_LATENCY_SLOTS) {
+        slot = RUNQ_LATENCY_SLOTS - 1;
+    }
+    __u64 *count = bpf_map_lookup_elem(&runq_latency, &slot);
+    if (count) {
+        *count += 1;
+    }
+    return 0;
+}
+
+SEC("tracepoint/oom/mark_victim")
+int handle_oom_kill_tp(void *ctx)
+{
+    count_notable(NOTABLE_OOM_KILL);
+    return 0;
+}
+
+SEC("kprobe/oom_kill_process")
+int handle_oom_kill(struct pt_regs *ctx)
+{
+    count_notable(NOTABLE_OOM_KILL);
+    return 0;
+}
+
+SEC("kprobe/inet_csk_listen_start")
+int handle_listen(struct pt_regs *ctx)
+{
+    count_notable(NOTABLE_LISTEN);
+    return 0;
+}
+
+SEC("tracepoint/sched/sched_process_exec")
+int handle_sched_exec(void *ctx)
+{
+    char comm[TASK_COMM_LEN] = {};
+    bpf_get_current_comm(&comm, sizeof(comm));
+    __u64 *hits = bpf_map_lookup_elem(&exec_watchlist, &comm);
+    if (hits) {
+        __sync_fetch_and_add(hits, 1);
+    }
+    return 0;
+}
+
+SEC("tracepoint/raw_syscalls/sys_enter")
+int handle_raw_syscall(struct trace_event_raw_sys_enter *ctx)
+{
+    long id = ctx->id;
+    if (id < 0 || id >= SYSCALL_SLOTS || !event_allowed()) {
+        return 0;
+    }
+    __u32 key = id;
+    __u64 *value = bpf_map_lookup_elem(&syscall_counts, &key);
+    if (value) {
+        *value += 1;
+    }
+    return 0;
+}
+
+/* result is 0 (TRACE_SIGNAL_DELIVERED) unless the signal was ignored or
+ * already pending. */
+SEC("tracepoint/signal/signal_generate")
+int handle_signal(struct trace_event_raw_signal_generate *ctx)
+{
+    int sig = ctx->sig;
+    if (sig <= 0 || sig >= SIGNAL_SLOTS || ctx->result != 0 || !event_allowed()) {
+        return 0;
+    }
+    __u32 key = sig;
+    __u64 *value = bpf_map_lookup_elem(&signal_counts, &key);
+    if (value) {
+        *value += 1;
+    }
+    return 0;
+}
+
+#define ETH_P_IP       0x0800
+#define ETH_P_IPV6     0x86DD
+#define IPPROTO_ICMPV6 58
+
+static __u32 xdp_protocol(__u8 protocol)
+{
+    switch (protocol) {
+    case IPPROTO_TCP:
+        return XDP_PROTO_TCP;
+    case IPPROTO_UDP:
+        return XDP_PROTO_UDP;
+    case IPPROTO_ICMP:
+    case IPPROTO_ICMPV6:
+        return XDP_PROTO_ICMP;
+    }
+    return XDP_PROTO_OTHER;
+}
+
+SEC("xdp")
+int handle_xdp(struct xdp_md *ctx)
+{
+    void *data = (void *)(long)ctx->data;
+    void *data_end = (void *)(long)ctx->data_end;
+    struct ethhdr *eth = data;
+    __u32 key = XDP_PROTO_OTHER;
+
+    if ((void *)(eth + 1) <= data_end) {
+        if (eth->h_proto == bpf_htons(ETH_P_IP)) {
+            struct iphdr *ip = (void *)(eth + 1);
+            if ((void *)(ip + 1) <= data_end) {
+                key = xdp_protocol(ip->protocol);
+            }
+        } else if (eth->h_proto == bpf_htons(ETH_P_IPV6)) {
+            struct ipv6hdr *ip6 = (void *)(eth + 1);
+            if ((void *)(ip6 + 1) <= data_end) {
+                key = xdp_protocol(ip6->nexthdr);
+            }
+        }
+    }
+
+    struct xdp_counts *counts = bpf_map_lookup_elem(&xdp_traffic, &key);
+    if (counts) {
+        counts->packets += 1;
+        counts->bytes += data_end - data;
+    }
+    return XDP_PASS;
+}
+
+/* Counts the bytes sent by the cgroup the game runs in. It only watches,
+ * so every packet is let through. */
+SEC("cgroup_skb/egress")
+int handle_egress(struct __sk_buff *skb)
+{
+    __u32 key = COUNTER_EGRESS_BYTES;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
+    if (value) {
+        *value += skb->len;
+    }
+    return 1;
+}
+
 char LICENSE[] SEC("license") = "GPL";
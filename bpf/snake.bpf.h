@@ -0,0 +1,450 @@
+/* Maps and helpers shared by snake.bpf.c, snake_fentry.bpf.c and
+ * snake_multi.bpf.c. The other objects declare the same maps and Go replaces
+ * them with the ones of the main object when it loads them, so all of them
+ * count into the same place. */
+
+#ifndef __SNAKE_BPF_H
+#define __SNAKE_BPF_H
+
+#define COUNTER_EXECVE         0
+#define COUNTER_FILE_OPS       1
+#define COUNTER_NETWORK        2
+#define COUNTER_PROCESS        3
+#define COUNTER_CONTEXT_SWITCH 4
+#define COUNTER_RETRANSMIT     5
+#define COUNTER_DROP           6
+#define COUNTER_PAGE_FAULT     7
+#define COUNTER_RECLAIM        8
+#define COUNTER_PROCESS_NEW    9
+#define COUNTER_PROCESS_EXIT   10
+#define COUNTER_UPROBE         11
+#define COUNTER_USDT           12
+#define COUNTER_EGRESS_BYTES   13
+#define COUNTER_DNS_QUERY      14
+#define COUNTER_KINDS          15
+
+#define CLONE_THREAD 0x00010000
+
+/* The event rate is counted over a sliding second of RATE_SLOTS buckets.
+ * Every bucket remembers the slot of time it counts, so one left over from
+ * an earlier second is known to be stale, by the probes and by Go alike. */
+#define RATE_SLOTS   10
+#define RATE_SLOT_NS (1000000000ULL / RATE_SLOTS)
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, COUNTER_KINDS);
+    __type(key, __u32);
+    __type(value, __u64);
+} counters SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u64);
+} event_rate SEC(".maps");
+
+struct rate_bucket {
+    __u64 slot;
+    __u64 count;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, RATE_SLOTS);
+    __type(key, __u32);
+    __type(value, struct rate_bucket);
+} rate_buckets SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u64);
+    __type(value, __u64);
+} cgroup_events SEC(".maps");
+
+#define TASK_COMM_LEN 16
+
+struct pid_stats {
+    __u64 count;
+    char comm[TASK_COMM_LEN];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, __u32);
+    __type(value, struct pid_stats);
+} pid_events SEC(".maps");
+
+#define EVENT_EXEC    0
+#define EVENT_OPEN    1
+#define EVENT_CONNECT 2
+#define EVENT_FORK    3
+#define EVENT_KINDS   4
+
+/* The argument of the event where there is one: the program of an exec and
+ * the file of an open, read from user memory and cut at EVENT_ARG_LEN. */
+#define EVENT_ARG_LEN 64
+
+struct event {
+    __u64 timestamp;
+    __u32 pid;
+    __u32 type;
+    char comm[TASK_COMM_LEN];
+    char arg[EVENT_ARG_LEN];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_RINGBUF);
+    __uint(max_entries, 256 * 1024);
+} events SEC(".maps");
+
+/* Events per cgroup and kind, aggregated per container in Go. */
+struct cgroup_counts {
+    __u64 count[EVENT_KINDS];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u64);
+    __type(value, struct cgroup_counts);
+} cgroup_counters SEC(".maps");
+
+#define NOTABLE_OOM_KILL 0
+#define NOTABLE_LISTEN   1
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 2);
+    __type(key, __u32);
+    __type(value, __u64);
+} notable_events SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 64);
+    __type(key, char[TASK_COMM_LEN]);
+    __type(value, __u64);
+} exec_watchlist SEC(".maps");
+
+/* One slot per syscall number; numbers are per architecture and are
+ * grouped into categories in Go. */
+#define SYSCALL_SLOTS 512
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, SYSCALL_SLOTS);
+    __type(key, __u32);
+    __type(value, __u64);
+} syscall_counts SEC(".maps");
+
+/* Signals delivered, one slot per signal number. */
+#define SIGNAL_SLOTS 65
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, SIGNAL_SLOTS);
+    __type(key, __u32);
+    __type(value, __u64);
+} signal_counts SEC(".maps");
+
+/* Block request latency as a log2 histogram of microseconds. Requests are
+ * matched between issue and completion by device and sector. */
+#define IO_LATENCY_SLOTS 24
+
+struct io_request {
+    __u32 dev;
+    __u32 pad;
+    __u64 sector;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, struct io_request);
+    __type(value, __u64);
+} io_start SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, IO_LATENCY_SLOTS);
+    __type(key, __u32);
+    __type(value, __u64);
+} io_latency SEC(".maps");
+
+/* Run-queue latency, the time from a task becoming runnable to it running,
+ * in the same log2 histogram of microseconds. */
+#define RUNQ_LATENCY_SLOTS IO_LATENCY_SLOTS
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, __u32);
+    __type(value, __u64);
+} runq_start SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, RUNQ_LATENCY_SLOTS);
+    __type(key, __u32);
+    __type(value, __u64);
+} runq_latency SEC(".maps");
+
+/* Packets and bytes received on the -xdp-iface interface, by protocol. */
+#define XDP_PROTO_TCP   0
+#define XDP_PROTO_UDP   1
+#define XDP_PROTO_ICMP  2
+#define XDP_PROTO_OTHER 3
+#define XDP_PROTOS      4
+
+struct xdp_counts {
+    __u64 packets;
+    __u64 bytes;
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, XDP_PROTOS);
+    __type(key, __u32);
+    __type(value, struct xdp_counts);
+} xdp_traffic SEC(".maps");
+
+/* DNS queries by the FNV-1a hash of the queried name, with the name as it
+ * is sent (length-prefixed labels), lower-cased and cut at DNS_NAME_LEN. */
+#define DNS_PORT       53
+#define DNS_HEADER_LEN 12
+#define DNS_NAME_LEN   64
+
+struct dns_query {
+    __u64 count;
+    char name[DNS_NAME_LEN];
+};
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u64);
+    __type(value, struct dns_query);
+} dns_queries SEC(".maps");
+
+/* Filters written by Go. An event is counted only if it passes every
+ * filter that is on: its UID is in filter_uids, its PID in filter_pids and
+ * it runs in (a descendant of) the cgroup in filter_cgroup. */
+#define FILTER_UID    1
+#define FILTER_PID    2
+#define FILTER_CGROUP 4
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u32);
+} filter_flags SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 64);
+    __type(key, __u32);
+    __type(value, __u8);
+} filter_uids SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, __u32);
+    __type(value, __u8);
+} filter_pids SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_CGROUP_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u32);
+} filter_cgroup SEC(".maps");
+
+static __always_inline int event_allowed(void)
+{
+    __u32 key = 0;
+    __u32 *flags = bpf_map_lookup_elem(&filter_flags, &key);
+    if (!flags || !*flags) {
+        return 1;
+    }
+    if (*flags & FILTER_UID) {
+        __u32 uid = bpf_get_current_uid_gid();
+        if (!bpf_map_lookup_elem(&filter_uids, &uid)) {
+            return 0;
+        }
+    }
+    if (*flags & FILTER_PID) {
+        __u32 pid = bpf_get_current_pid_tgid() >> 32;
+        if (!bpf_map_lookup_elem(&filter_pids, &pid)) {
+            return 0;
+        }
+    }
+    if ((*flags & FILTER_CGROUP) && bpf_current_task_under_cgroup(&filter_cgroup, 0) != 1) {
+        return 0;
+    }
+    return 1;
+}
+
+/* update_event_rate sets event_rate to the events of the second up to
+ * slot now. */
+static __always_inline void update_event_rate(__u64 now)
+{
+    __u64 sum = 0;
+    for (__u32 i = 0; i < RATE_SLOTS; i++) {
+        __u32 key = i;
+        struct rate_bucket *b = bpf_map_lookup_elem(&rate_buckets, &key);
+        if (b && b->slot + RATE_SLOTS > now) {
+            sum += b->count;
+        }
+    }
+    __u32 key = 0;
+    __u64 *rate = bpf_map_lookup_elem(&event_rate, &key);
+    if (rate) {
+        *rate = sum;
+    }
+}
+
+static __always_inline void increment_cgroup_events(__u32 type)
+{
+    __u64 cgroup_id = bpf_get_current_cgroup_id();
+    __u64 *count = bpf_map_lookup_elem(&cgroup_events, &cgroup_id);
+    if (count) {
+        __sync_fetch_and_add(count, 1);
+    } else {
+        __u64 initial = 1;
+        bpf_map_update_elem(&cgroup_events, &cgroup_id, &initial, BPF_NOEXIST);
+    }
+
+    if (type >= EVENT_KINDS) {
+        return;
+    }
+    struct cgroup_counts *counts = bpf_map_lookup_elem(&cgroup_counters, &cgroup_id);
+    if (!counts) {
+        struct cgroup_counts initial = {};
+        bpf_map_update_elem(&cgroup_counters, &cgroup_id, &initial, BPF_NOEXIST);
+        counts = bpf_map_lookup_elem(&cgroup_counters, &cgroup_id);
+        if (!counts) {
+            return;
+        }
+    }
+    __sync_fetch_and_add(&counts->count[type], 1);
+}
+
+static __always_inline void increment_pid_events(void)
+{
+    __u32 pid = bpf_get_current_pid_tgid() >> 32;
+    struct pid_stats *stats = bpf_map_lookup_elem(&pid_events, &pid);
+    if (stats) {
+        __sync_fetch_and_add(&stats->count, 1);
+    } else {
+        struct pid_stats initial = {.count = 1};
+        bpf_get_current_comm(&initial.comm, sizeof(initial.comm));
+        bpf_map_update_elem(&pid_events, &pid, &initial, BPF_NOEXIST);
+    }
+}
+
+static __always_inline void emit_event(__u32 type, const char *arg)
+{
+    struct event *e = bpf_ringbuf_reserve(&events, sizeof(*e), 0);
+    if (!e) {
+        return;
+    }
+    e->timestamp = bpf_ktime_get_ns();
+    e->pid = bpf_get_current_pid_tgid() >> 32;
+    e->type = type;
+    bpf_get_current_comm(&e->comm, sizeof(e->comm));
+    e->arg[0] = 0;
+    if (arg) {
+        bpf_probe_read_user_str(&e->arg, sizeof(e->arg), arg);
+    }
+    bpf_ringbuf_submit(e, 0);
+}
+
+static __always_inline void count_event_rate(void)
+{
+    __u64 slot = bpf_ktime_get_ns() / RATE_SLOT_NS;
+    __u32 key = slot % RATE_SLOTS;
+    struct rate_bucket *b = bpf_map_lookup_elem(&rate_buckets, &key);
+    if (!b) {
+        return;
+    }
+    if (b->slot != slot) {
+        /* The first event of a slot takes over the bucket from a second
+         * ago. CPUs racing here may lose an event or two of it. */
+        b->slot = slot;
+        b->count = 0;
+    }
+    __sync_fetch_and_add(&b->count, 1);
+    update_event_rate(slot);
+}
+
+static __always_inline void count_counter(__u32 key)
+{
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
+    if (value) {
+        *value += 1;
+    }
+}
+
+static __always_inline void count_notable(__u32 key)
+{
+    __u64 *value = bpf_map_lookup_elem(&notable_events, &key);
+    if (value) {
+        __sync_fetch_and_add(value, 1);
+    }
+}
+
+static __always_inline void count_execve(const char *filename)
+{
+    if (!event_allowed()) {
+        return;
+    }
+    __u32 key = COUNTER_EXECVE;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
+    if (value) {
+        *value += 1;
+        count_event_rate();
+        increment_cgroup_events(EVENT_EXEC);
+        increment_pid_events();
+        emit_event(EVENT_EXEC, filename);
+    }
+}
+
+static __always_inline void count_network_connect(void)
+{
+    if (!event_allowed()) {
+        return;
+    }
+    __u32 key = COUNTER_NETWORK;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
+    if (value) {
+        *value += 1;
+        count_event_rate();
+        increment_cgroup_events(EVENT_CONNECT);
+        increment_pid_events();
+        emit_event(EVENT_CONNECT, NULL);
+    }
+}
+
+static __always_inline void count_process_fork(void)
+{
+    if (!event_allowed()) {
+        return;
+    }
+    __u32 key = COUNTER_PROCESS;
+    __u64 *value = bpf_map_lookup_elem(&counters, &key);
+    if (value) {
+        *value += 1;
+        count_event_rate();
+        increment_cgroup_events(EVENT_FORK);
+        increment_pid_events();
+        emit_event(EVENT_FORK, NULL);
+    }
+}
+
+#endif /* __SNAKE_BPF_H */
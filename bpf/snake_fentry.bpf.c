@@ -0,0 +1,70 @@
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+
+#include "snake.bpf.h"
+
+/* fentry versions of the kprobes in snake.bpf.c. The attach points below are
+ * only defaults: Go loads each program once per candidate kernel function. */
+
+SEC("fentry/tcp_v4_connect")
+int BPF_PROG(handle_network_connect_fentry)
+{
+    count_network_connect();
+    return 0;
+}
+
+SEC("fentry/kernel_clone")
+int BPF_PROG(handle_process_fork_fentry)
+{
+    count_process_fork();
+    return 0;
+}
+
+SEC("fentry/tcp_retransmit_skb")
+int BPF_PROG(handle_tcp_retransmit_fentry)
+{
+    count_counter(COUNTER_RETRANSMIT);
+    return 0;
+}
+
+SEC("fentry/kfree_skb_reason")
+int BPF_PROG(handle_skb_drop_fentry)
+{
+    count_counter(COUNTER_DROP);
+    return 0;
+}
+
+SEC("fentry/handle_mm_fault")
+int BPF_PROG(handle_page_fault_fentry)
+{
+    if (!event_allowed()) {
+        return 0;
+    }
+    count_counter(COUNTER_PAGE_FAULT);
+    return 0;
+}
+
+SEC("fentry/try_to_free_pages")
+int BPF_PROG(handle_reclaim_fentry)
+{
+    count_counter(COUNTER_RECLAIM);
+    return 0;
+}
+
+SEC("fentry/oom_kill_process")
+int BPF_PROG(handle_oom_kill_fentry)
+{
+    count_notable(NOTABLE_OOM_KILL);
+    return 0;
+}
+
+SEC("fentry/inet_csk_listen_start")
+int BPF_PROG(handle_listen_fentry)
+{
+    count_notable(NOTABLE_LISTEN);
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,46 @@
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+
+#define SECURITY_EXEC   0
+#define SECURITY_SETUID 1
+#define SECURITY_PTRACE 2
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 3);
+    __type(key, __u32);
+    __type(value, __u64);
+} security_events SEC(".maps");
+
+static void increment_security_event(__u32 key)
+{
+    __u64 *value = bpf_map_lookup_elem(&security_events, &key);
+    if (value) {
+        __sync_fetch_and_add(value, 1);
+    }
+}
+
+SEC("lsm/bprm_check_security")
+int BPF_PROG(handle_bprm_check, struct linux_binprm *bprm, int ret)
+{
+    increment_security_event(SECURITY_EXEC);
+    return ret;
+}
+
+SEC("lsm/task_fix_setuid")
+int BPF_PROG(handle_task_fix_setuid, struct cred *new, const struct cred *old, int flags, int ret)
+{
+    increment_security_event(SECURITY_SETUID);
+    return ret;
+}
+
+SEC("lsm/ptrace_access_check")
+int BPF_PROG(handle_ptrace_access_check, struct task_struct *child, unsigned int mode, int ret)
+{
+    increment_security_event(SECURITY_PTRACE);
+    return ret;
+}
+
+char LICENSE[] SEC("license") = "GPL";
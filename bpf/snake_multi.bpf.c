@@ -0,0 +1,25 @@
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+
+#include "snake.bpf.h"
+
+/* kprobe.multi versions of the kprobes in snake.bpf.c whose candidate
+ * symbols can all be attached at once with a single link. */
+
+SEC("kprobe.multi")
+int handle_execve_multi(struct pt_regs *ctx)
+{
+    count_execve(NULL);
+    return 0;
+}
+
+SEC("kprobe.multi")
+int handle_network_connect_multi(struct pt_regs *ctx)
+{
+    count_network_connect();
+    return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
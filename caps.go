@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const VERIFIER_LOG_LINES = 20
+
+// reportLoadError prints what ebpfmon found out about a failed load: the
+// kernel, the rejected program or map, the end of the verifier log and
+// what may fix it.
+func reportLoadError(err error) {
+	var lerr *ebpfmon.LoadError
+	if !errors.As(err, &lerr) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "  kernel: %s\n", lerr.Kernel)
+	if lerr.Object != "" {
+		fmt.Fprintf(os.Stderr, "  rejected: %s\n", lerr.Object)
+	}
+	if n := len(lerr.Log); n > 0 {
+		log := lerr.Log
+		if n > VERIFIER_LOG_LINES {
+			log = log[n-VERIFIER_LOG_LINES:]
+			fmt.Fprintf(os.Stderr, "  verifier log (last %d of %d lines):\n", VERIFIER_LOG_LINES, n)
+		} else {
+			fmt.Fprintln(os.Stderr, "  verifier log:")
+		}
+		for _, line := range log {
+			fmt.Fprintf(os.Stderr, "    %s\n", line)
+		}
+	}
+	for _, hint := range lerr.Hints {
+		fmt.Fprintf(os.Stderr, "  hint: %s\n", hint)
+	}
+}
+
+func reportMissingCapabilities() {
+	missing, err := ebpfmon.MissingCapabilities()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if len(missing) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Missing capabilities: %s\n", strings.Join(missing, ", "))
+	fmt.Fprintf(os.Stderr, "Run with sudo or grant them: sudo setcap cap_bpf,cap_perfmon,cap_sys_resource+ep %s\n", os.Args[0])
+}
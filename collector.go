@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const COLLECTOR_SOCKET = "/run/snake-ebpf.sock"
+
+// collectorFlags are the play flags that set up probes, which are the
+// collector's business when playing against one.
+var collectorFlags = []string{
+	"btf", "pin", "filter-uid", "filter-pid", "filter-cgroup", "watch",
+	"uprobe", "usdt", "xdp-iface", "egress", "overhead",
+}
+
+const (
+	// COLLECTOR_TOP is how many cgroups and domains a sample lists, of
+	// which clients show as many as they need.
+	COLLECTOR_TOP = 10
+	// COLLECTOR_TIMEOUT is how long a client waits for the collector to
+	// answer before the game carries on without a sample.
+	COLLECTOR_TIMEOUT      = 2 * time.Second
+	COLLECTOR_EVENT_BUFFER = 256
+)
+
+// The collector protocol is JSON, one value per line. A client sends a
+// collectorRequest and reads one reply to it, a sourceStatus for "status"
+// and a collectorSample for "sample". After "events" the connection only
+// carries kernel events, from the collector to the client.
+type collectorRequest struct {
+	Op string `json:"op"`
+	// Container is the container whose events drive the game, if any.
+	Container string `json:"container,omitempty"`
+	// Containers asks for the busiest containers in the sample.
+	Containers bool `json:"containers,omitempty"`
+}
+
+// collectorSample is everything the game reads from the kernel on a tick.
+// Snapshot deltas are since the client's previous sample.
+type collectorSample struct {
+	Snapshot   ebpfmon.Snapshot         `json:"snapshot"`
+	Error      string                   `json:"error,omitempty"`
+	TopCgroups []ebpfmon.CgroupCount    `json:"top_cgroups"`
+	Containers []ebpfmon.ContainerCount `json:"containers,omitempty"`
+	TopDomains []ebpfmon.DomainCount    `json:"top_domains"`
+	Processes  []ebpfmon.ProcessCount   `json:"processes"`
+	Stats      []ebpfmon.ProgramStats   `json:"program_stats,omitempty"`
+}
+
+type collectorError struct {
+	Error string `json:"error"`
+}
+
+// runCollect loads and attaches the probes and serves their counters on a
+// UNIX socket until it is stopped, so that games started without
+// privileges can play against them.
+func runCollect(ctx context.Context, args []string) int {
+	fs := newFlagSet("collect", "")
+	socket := fs.String("socket", COLLECTOR_SOCKET, "UNIX socket the games connect to; anyone who can open it can read the counters")
+	socketGroup := fs.String("socket-group", "", "group whose members may connect to the socket, besides root")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	pinDir := addPinFlag(fs)
+	filters := addFilterFlags(fs)
+	probeOpts := addProbeFlags(fs)
+	logOpts := addLogFlags(fs)
+	pprofAddr := addPprofFlag(fs)
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	closeLog, err := logOpts.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	defer closeLog()
+	stopPprof, ok := startPprof(*pprofAddr)
+	if !ok {
+		return EXIT_FAILURE
+	}
+	defer stopPprof()
+	filter, err := filters.filter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	if err := probeOpts.parse(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	gid := -1
+	if *socketGroup != "" {
+		if gid, err = lookupGroup(*socketGroup); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -socket-group %q: %v\n", *socketGroup, err)
+			return EXIT_USAGE
+		}
+	}
+
+	c, code := loadCollector(ctx, *btfPath, *pinDir, cfg.Probes, filter, probeOpts)
+	if code != EXIT_OK {
+		return code
+	}
+	defer c.close()
+
+	l, err := listenCollector(*socket, gid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *socket, err)
+		return EXIT_FAILURE
+	}
+	defer l.Close()
+	context.AfterFunc(ctx, func() { l.Close() })
+	fmt.Fprintf(os.Stderr, "Collecting on %s\n", *socket)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				logShutdown(ctx, "collect")
+				return EXIT_OK
+			}
+			fmt.Fprintf(os.Stderr, "Failed to accept client: %v\n", err)
+			return EXIT_FAILURE
+		}
+		go c.serve(ctx, conn)
+	}
+}
+
+func lookupGroup(name string) (int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// listenCollector listens on the socket at path, which only its owner and
+// the members of group gid, if it is not -1, may connect to. A socket left
+// behind by a collector that is gone is replaced; one that is in use is
+// not, and neither is anything at path that is not a socket.
+func listenCollector(path string, gid int) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a collector is already running")
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() != os.ModeSocket {
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("restrict socket: %w", err)
+	}
+	if err := os.Lchown(path, -1, gid); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("open socket to group: %w", err)
+	}
+	return l, nil
+}
+
+type collector struct {
+	// mu serializes access to the monitor, whose cgroup and container
+	// caches are shared by all clients.
+	mu      sync.Mutex
+	mon     *ebpfmon.Monitor
+	probes  *ebpfmon.AttachManager
+	status  sourceStatus
+	closers []io.Closer
+	subsMu  sync.Mutex
+	subs    map[chan ebpfmon.KernelEvent]bool
+}
+
+// loadCollector loads and attaches the probes and starts handing out their
+// events, for collect and serve.
+func loadCollector(ctx context.Context, btfPath, pinDir string, disabled []string, filter ebpfmon.Filter, probeOpts *probeOptions) (*collector, int) {
+	mon, probes, code := loadMonitor(btfPath, pinDir, disabled, filter)
+	if code != EXIT_OK {
+		return nil, code
+	}
+	c := &collector{
+		mon:    mon,
+		probes: probes,
+		subs:   make(map[chan ebpfmon.KernelEvent]bool),
+	}
+	for _, name := range probes.Unattached() {
+		fmt.Fprintf(os.Stderr, "Warning: %s not attached: %v\n", name, strings.ReplaceAll(probes.Failure(name).Error(), "\n", "; "))
+	}
+	stats, code := probeOpts.attach(mon, probes)
+	if code != EXIT_OK {
+		c.close()
+		return nil, code
+	}
+	if stats != nil {
+		c.closers = append(c.closers, stats)
+	}
+
+	c.status = newLocalSource(mon, probes, &containerOptions{}, probeOpts).Status()
+	if events, err := mon.NewEventReader(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, games spawn food on a timer\n", err)
+		c.status.Events = false
+	} else {
+		c.closers = append(c.closers, events)
+		go c.fanOut(events.Events())
+	}
+	return c, EXIT_OK
+}
+
+// close detaches the probes, last attached first.
+func (c *collector) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i].Close()
+	}
+	c.probes.Close()
+	c.mon.Close()
+}
+
+// fanOut hands every kernel event to every client streaming them. A client
+// that falls behind misses events rather than holding up the others.
+func (c *collector) fanOut(events <-chan ebpfmon.KernelEvent) {
+	for ev := range events {
+		c.subsMu.Lock()
+		for sub := range c.subs {
+			select {
+			case sub <- ev:
+			default:
+			}
+		}
+		c.subsMu.Unlock()
+	}
+}
+
+func (c *collector) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	slog.Debug("collector client connected")
+	defer slog.Debug("collector client left")
+	c.mu.Lock()
+	reader := c.mon.NewMetricsReader()
+	c.mu.Unlock()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req collectorRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		var err error
+		switch req.Op {
+		case "status":
+			err = enc.Encode(c.status)
+		case "sample":
+			err = enc.Encode(c.sample(reader, req))
+		case "events":
+			c.streamEvents(ctx, enc)
+			return
+		default:
+			err = enc.Encode(collectorError{Error: fmt.Sprintf("unknown op %q", req.Op)})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *collector) sample(reader *ebpfmon.MetricsReader, req collectorRequest) collectorSample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reader.SelectContainer(req.Container)
+	snap, err := reader.ReadSnapshot()
+	sample := collectorSample{
+		Snapshot:   snap,
+		TopCgroups: c.mon.TopCgroups(COLLECTOR_TOP),
+		TopDomains: c.mon.TopDomains(COLLECTOR_TOP),
+		Processes:  c.mon.Processes(),
+	}
+	if err != nil {
+		slog.Debug("counters read failed", "container", req.Container, "err", err)
+		sample.Error = err.Error()
+	}
+	if req.Containers || req.Container != "" {
+		sample.Containers = (&containerOptions{list: true}).top(c.mon)
+	}
+	if c.status.Stats {
+		sample.Stats = c.probes.Stats()
+	}
+	return sample
+}
+
+func (c *collector) subscribe() chan ebpfmon.KernelEvent {
+	sub := make(chan ebpfmon.KernelEvent, COLLECTOR_EVENT_BUFFER)
+	c.subsMu.Lock()
+	c.subs[sub] = true
+	c.subsMu.Unlock()
+	return sub
+}
+
+func (c *collector) unsubscribe(sub chan ebpfmon.KernelEvent) {
+	c.subsMu.Lock()
+	delete(c.subs, sub)
+	c.subsMu.Unlock()
+}
+
+func (c *collector) streamEvents(ctx context.Context, enc *json.Encoder) {
+	sub := c.subscribe()
+	defer c.unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub:
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// collectorClient is a kernelSource that reads from a collector.
+type collectorClient struct {
+	path string
+	// conn is nil after a request failed, until the next one dials again.
+	conn       net.Conn
+	enc        *json.Encoder
+	dec        *json.Decoder
+	containers *containerOptions
+	status     sourceStatus
+	last       collectorSample
+}
+
+func dialCollector(path string, containers *containerOptions) (*collectorClient, error) {
+	c := &collectorClient{path: path, containers: containers}
+	if err := c.dial(); err != nil {
+		return nil, err
+	}
+	if err := c.call(collectorRequest{Op: "status"}, &c.status); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *collectorClient) dial() error {
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.enc = json.NewEncoder(conn)
+	c.dec = json.NewDecoder(conn)
+	return nil
+}
+
+// call sends req and reads the reply to it. After any error, a timeout
+// too, the connection is closed: the decoder fails for good once it has
+// failed, and a late reply would be taken for the answer to the next
+// request. The next call dials a new connection.
+func (c *collectorClient) call(req collectorRequest, reply any) error {
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return fmt.Errorf("reconnect: %w", err)
+		}
+	}
+	c.conn.SetDeadline(time.Now().Add(COLLECTOR_TIMEOUT))
+	err := c.enc.Encode(req)
+	if err != nil {
+		err = fmt.Errorf("send %s request: %w", req.Op, err)
+	} else if err = c.dec.Decode(reply); err != nil {
+		err = fmt.Errorf("read %s reply: %w", req.Op, err)
+	}
+	if err != nil {
+		c.Close()
+	}
+	return err
+}
+
+func (c *collectorClient) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *collectorClient) Status() sourceStatus {
+	return c.status
+}
+
+// ReadSnapshot asks the collector for a sample. When it does not answer,
+// the game carries on with the last counters it got.
+func (c *collectorClient) ReadSnapshot() (ebpfmon.Snapshot, error) {
+	var sample collectorSample
+	req := collectorRequest{Op: "sample", Container: c.containers.selector, Containers: c.containers.enabled()}
+	if err := c.call(req, &sample); err != nil {
+		return ebpfmon.Snapshot{Metrics: c.last.Snapshot.Metrics}, err
+	}
+	c.last = sample
+	if sample.Error != "" {
+		return sample.Snapshot, errors.New(sample.Error)
+	}
+	return sample.Snapshot, nil
+}
+
+func (c *collectorClient) TopCgroups(n int) []ebpfmon.CgroupCount {
+	return c.last.TopCgroups[:min(n, len(c.last.TopCgroups))]
+}
+
+func (c *collectorClient) TopContainers() []ebpfmon.ContainerCount {
+	return c.last.Containers
+}
+
+func (c *collectorClient) TopDomains(n int) []ebpfmon.DomainCount {
+	return c.last.TopDomains[:min(n, len(c.last.TopDomains))]
+}
+
+func (c *collectorClient) Processes() []ebpfmon.ProcessCount {
+	return c.last.Processes
+}
+
+func (c *collectorClient) ProgramStats() []ebpfmon.ProgramStats {
+	return c.last.Stats
+}
+
+// Events streams the collector's kernel events over a connection of their
+// own.
+func (c *collectorClient) Events(ctx context.Context) (<-chan ebpfmon.KernelEvent, error) {
+	if !c.status.Events {
+		return nil, fmt.Errorf("the collector has no event stream")
+	}
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("connect event stream: %w", err)
+	}
+	if err := json.NewEncoder(conn).Encode(collectorRequest{Op: "events"}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("request event stream: %w", err)
+	}
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	events := make(chan ebpfmon.KernelEvent, COLLECTOR_EVENT_BUFFER)
+	go func() {
+		defer close(events)
+		defer conn.Close()
+		dec := json.NewDecoder(conn)
+		for {
+			var ev ebpfmon.KernelEvent
+			if err := dec.Decode(&ev); err != nil {
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// collectorSession is a kernelSource for a game in the collector's own
+// process, like the game of an SSH session. Each has a metrics reader of
+// its own, as the clients on the socket do.
+type collectorSession struct {
+	c      *collector
+	reader *ebpfmon.MetricsReader
+	last   collectorSample
+}
+
+func (c *collector) newSession() *collectorSession {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &collectorSession{c: c, reader: c.mon.NewMetricsReader()}
+}
+
+func (s *collectorSession) Status() sourceStatus {
+	return s.c.status
+}
+
+func (s *collectorSession) ReadSnapshot() (ebpfmon.Snapshot, error) {
+	s.last = s.c.sample(s.reader, collectorRequest{Op: "sample"})
+	if s.last.Error != "" {
+		return s.last.Snapshot, errors.New(s.last.Error)
+	}
+	return s.last.Snapshot, nil
+}
+
+func (s *collectorSession) TopCgroups(n int) []ebpfmon.CgroupCount {
+	return s.last.TopCgroups[:min(n, len(s.last.TopCgroups))]
+}
+
+func (s *collectorSession) TopContainers() []ebpfmon.ContainerCount {
+	return nil
+}
+
+func (s *collectorSession) TopDomains(n int) []ebpfmon.DomainCount {
+	return s.last.TopDomains[:min(n, len(s.last.TopDomains))]
+}
+
+func (s *collectorSession) Processes() []ebpfmon.ProcessCount {
+	return s.last.Processes
+}
+
+func (s *collectorSession) ProgramStats() []ebpfmon.ProgramStats {
+	return s.last.Stats
+}
+
+func (s *collectorSession) Events(ctx context.Context) (<-chan ebpfmon.KernelEvent, error) {
+	if !s.c.status.Events {
+		return nil, fmt.Errorf("the event stream is not available")
+	}
+	sub := s.c.subscribe()
+	context.AfterFunc(ctx, func() { s.c.unsubscribe(sub) })
+	return sub, nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// configFlags are the keys of each config section that set the flag of the
+// same name, with dashes for underscores. A flag given on the command line
+// wins over the config file.
+var configFlags = map[string][]string{
+	"": {
+		"width", "height", "difficulty", "seed", "wrap", "enemy", "two_player", "demo",
+		"rescale", "fps", "no_title", "mouse", "gamepad", "ascii", "no_color", "procs", "watch", "btf", "dbus",
+		"filter_uid", "filter_pid", "filter_cgroup", "containers", "container",
+		"uprobe", "usdt", "xdp_iface", "egress", "overhead", "pin",
+		"collector", "socket", "socket_group", "verbose", "debug", "quiet", "log_file",
+	},
+	"export": {
+		"metrics_addr", "otlp", "influx_url", "statsd_addr", "statsd_prefix", "statsd_tags", "log_metrics", "pprof_addr", "api_addr", "grpc_addr", "spectate_addr", "dashboard_addr", "ssh", "host", "host_key", "authorized_keys", "insecure",
+		"api_token", "snapshot_path", "record", "leaderboard", "player",
+		"interval", "format",
+	},
+}
+
+type config struct {
+	Theme string
+	// Flags maps flag names to the values the config file gives them.
+	Flags map[string]string
+	// Probes are the probes the config file disables.
+	Probes []string
+	Speed  game.SpeedScaling
+	Keys   keymap
+}
+
+func userHomeDir() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		if u, err := user.Lookup(sudoUser); err == nil {
+			return u.HomeDir
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return home
+}
+
+func configPath() string {
+	return filepath.Join(userHomeDir(), ".config", "snake-ebpf", "config.toml")
+}
+
+func addConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", configPath(), "TOML config file with defaults for the flags, probes and speed scaling")
+}
+
+// loadCommandConfig loads the file named by the -config flag of fs and
+// applies it to the flags not given on the command line. A missing file is
+// only an error when -config was given. ok is false when the command should
+// exit with code.
+func loadCommandConfig(fs *flag.FlagSet, path string) (cfg config, code int, ok bool) {
+	cfg, err := loadConfig(path)
+	if errors.Is(err, os.ErrNotExist) && !flagGiven(fs, "config") {
+		err = nil
+	}
+	if err == nil {
+		err = applyConfig(fs, cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config %s: %v\n", path, err)
+		return cfg, EXIT_USAGE, false
+	}
+	return cfg, EXIT_OK, true
+}
+
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// applyConfig sets the flags of fs that the config file gives and the
+// command line does not. Keys for flags of other commands are ignored.
+func applyConfig(fs *flag.FlagSet, cfg config) error {
+	for name, value := range cfg.Flags {
+		if fs.Lookup(name) == nil || flagGiven(fs, name) {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func loadConfig(path string) (config, error) {
+	cfg := config{Theme: tui.Themes[0].Name, Flags: make(map[string]string), Speed: game.DefaultSpeedScaling, Keys: defaultKeymap}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("open config: %w", err)
+	}
+	var doc map[string]any
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return cfg, err
+	}
+	// Keys lists the tables and their keys in the order of the file, so
+	// the first mistake is the one reported.
+	for _, key := range md.Keys() {
+		var section string
+		var value any
+		table, isTable := doc[key[0]].(map[string]any)
+		switch {
+		case len(key) == 1 && isTable && configSections[key[0]]:
+			continue
+		case len(key) == 1:
+			value = doc[key[0]]
+		case len(key) == 2 && isTable && configSections[key[0]]:
+			section, value = key[0], table[key[1]]
+		default:
+			return cfg, fmt.Errorf("%s: unknown key", key)
+		}
+		if err := cfg.set(section, key[len(key)-1], value); err != nil {
+			return cfg, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	for f, scaling := range cfg.Speed {
+		if err := scaling.Validate(); err != nil {
+			return cfg, fmt.Errorf("speed.%s: %w", game.SpeedFactor(f), err)
+		}
+	}
+	keys, err := newKeymap(cfg.Keys.keys)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Keys = keys
+	return cfg, nil
+}
+
+// configSections are the tables a config file may have.
+var configSections = map[string]bool{"export": true, "probes": true, "speed": true, "keymap": true}
+
+func (cfg *config) set(section, key string, value any) error {
+	switch {
+	case section == "" && key == "theme":
+		s, err := configString(value)
+		if err != nil {
+			return err
+		}
+		cfg.Theme = s
+		return nil
+	case section == "probes" && key == "disable":
+		probes, err := configStrings(value)
+		if err != nil {
+			return err
+		}
+		cfg.Probes = probes
+		return nil
+	case section == "speed":
+		return cfg.setSpeed(key, value)
+	case section == "keymap":
+		return cfg.setKeys(key, value)
+	}
+	for _, name := range configFlags[section] {
+		if name == key {
+			s, err := configString(value)
+			if err != nil {
+				return err
+			}
+			cfg.Flags[strings.ReplaceAll(key, "_", "-")] = s
+			return nil
+		}
+	}
+	return errors.New("unknown key")
+}
+
+// setKeys binds a [keymap] action to a key, or to an array of them,
+// instead of its default keys.
+func (cfg *config) setKeys(action string, value any) error {
+	a, ok := lookupKeyAction(action)
+	if !ok {
+		return errors.New("unknown key")
+	}
+	var keys []string
+	var err error
+	if _, ok := value.([]any); ok {
+		keys, err = configStrings(value)
+	} else {
+		var key string
+		key, err = configString(value)
+		keys = []string{key}
+	}
+	if err != nil {
+		return err
+	}
+	cfg.Keys.keys[a] = keys
+	return nil
+}
+
+// speedSettings are the suffixes of the [speed] keys of a factor besides
+// <factor> itself, the step of its model.
+var speedSettings = []string{"_max", "_model", "_weight", "_midpoint"}
+
+// setSpeed sets a [speed] key: <factor> is how much one point or one event
+// per second takes off the tick interval with the linear model, per
+// doubling with the log model, or in total with the sigmoid, which takes
+// about half of it off at <factor>_midpoint. <factor>_weight multiplies what the
+// model takes off and <factor>_max caps it.
+func (cfg *config) setSpeed(key string, value any) error {
+	name, setting := key, ""
+	for _, suffix := range speedSettings {
+		if n, ok := strings.CutSuffix(key, suffix); ok {
+			name, setting = n, suffix
+			break
+		}
+	}
+	f, ok := game.LookupSpeedFactor(name)
+	if !ok {
+		return errors.New("unknown key")
+	}
+	s, err := configString(value)
+	if err != nil {
+		return err
+	}
+	scaling := &cfg.Speed[f]
+	switch setting {
+	case "_model":
+		curve, ok := game.LookupCurve(s)
+		if !ok {
+			return fmt.Errorf("unknown model %q, must be linear, log or sigmoid", s)
+		}
+		scaling.Curve = curve
+	case "_weight", "_midpoint":
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		if v < 0 {
+			return errors.New("must not be negative")
+		}
+		if setting == "_weight" {
+			scaling.Weight = v
+		} else {
+			scaling.Midpoint = v
+		}
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			return errors.New("must not be negative")
+		}
+		if setting == "_max" {
+			scaling.Max = d
+		} else {
+			scaling.Step = d
+		}
+	}
+	return nil
+}
+
+// configString reads a string, or a number or boolean as the text a flag
+// would be given.
+func configString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", errors.New("must be a string, number or boolean")
+}
+
+// configStrings reads an array of strings.
+func configStrings(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// saveConfig stores the theme, keeping the rest of the file as the user
+// wrote it.
+func saveConfig(path string, cfg config) error {
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read config: %w", err)
+	}
+	// A config file that was already there keeps its owner.
+	if os.IsNotExist(err) {
+		created = append(created, path)
+	}
+	theme := fmt.Sprintf("theme = %q", cfg.Theme)
+	lines := strings.SplitAfter(string(data), "\n")
+	end := len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			end = i
+			break
+		}
+		if key, _, ok := strings.Cut(trimmed, "="); ok && strings.Trim(strings.TrimSpace(key), `"'`) == "theme" {
+			lines[i] = theme + "\n"
+			theme = ""
+			break
+		}
+	}
+	if theme != "" {
+		if end > 0 && lines[end-1] != "" && !strings.HasSuffix(lines[end-1], "\n") {
+			lines[end-1] += "\n"
+		}
+		lines = append(lines[:end], append([]string{theme + "\n"}, lines[end:]...)...)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return chownToSudoUser(created...)
+}
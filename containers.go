@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// TOP_CONTAINERS is how many containers are listed below the board.
+const TOP_CONTAINERS = 3
+
+type containerOptions struct {
+	list     bool
+	selector string
+}
+
+func addContainerFlags(fs *flag.FlagSet) *containerOptions {
+	c := &containerOptions{}
+	fs.BoolVar(&c.list, "containers", false, "count events per container and list the busiest ones instead of cgroups")
+	fs.StringVar(&c.selector, "container", "", "container name or ID prefix whose exec, file, network and fork events drive the game (implies -containers)")
+	return c
+}
+
+func (c *containerOptions) enabled() bool {
+	return c.list || c.selector != ""
+}
+
+// apply binds the reader to the selected container, if any.
+func (c *containerOptions) apply(reader *ebpfmon.MetricsReader) {
+	if c.selector != "" {
+		reader.SelectContainer(c.selector)
+	}
+}
+
+// top returns the busiest containers, or nil when container mode is off.
+func (c *containerOptions) top(mon *ebpfmon.Monitor) []ebpfmon.ContainerCount {
+	if !c.enabled() {
+		return nil
+	}
+	containers := mon.Containers()
+	return containers[:min(len(containers), TOP_CONTAINERS)]
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DASHBOARD_BACKLOG is how many of the latest frames a dashboard gets
+	// when it connects, so its charts start with the last minute.
+	DASHBOARD_BACKLOG = int(time.Minute / POLL_INTERVAL)
+	// DASHBOARD_BUFFER is how many frames a dashboard may fall behind
+	// before it misses some.
+	DASHBOARD_BUFFER = 16
+)
+
+//go:embed assets/dashboard.html
+var dashboardHTML []byte
+
+// dashboardServer serves a page that shows the board, the score and charts
+// of the kernel counters over time, big enough for a projector, and
+// streams the frames of spectators to it as server-sent events. Like
+// watching, it needs no token.
+type dashboardServer struct {
+	server *http.Server
+	mu     sync.Mutex
+	subs   map[chan []byte]bool
+	// backlog holds the latest frames, oldest first.
+	backlog [][]byte
+}
+
+func startDashboardServer(addr string) (*dashboardServer, error) {
+	d := &dashboardServer{subs: make(map[chan []byte]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardHTML)
+	})
+	mux.HandleFunc("GET /events", d.serveEvents)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go d.server.Serve(listener)
+	return d, nil
+}
+
+func (d *dashboardServer) close() error {
+	return d.server.Close()
+}
+
+// publish sends the state of this poll to every dashboard. One that falls
+// behind misses frames rather than holding up the game.
+func (d *dashboardServer) publish(s *session) {
+	frame, err := json.Marshal(s.spectateFrame())
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.backlog) == DASHBOARD_BACKLOG {
+		d.backlog = slices.Delete(d.backlog, 0, 1)
+	}
+	d.backlog = append(d.backlog, frame)
+	for sub := range d.subs {
+		select {
+		case sub <- frame:
+		default:
+		}
+	}
+}
+
+func (d *dashboardServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	sub := make(chan []byte, DASHBOARD_BUFFER)
+	d.mu.Lock()
+	backlog := slices.Clone(d.backlog)
+	d.subs[sub] = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.subs, sub)
+		d.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, frame := range backlog {
+		fmt.Fprintf(w, "data: %s\n\n", frame)
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame := <-sub:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", frame); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	DBUS_NAME      = "io.github.gma1k.SnakeEbpf"
+	DBUS_PATH      = "/io/github/gma1k/SnakeEbpf"
+	DBUS_INTERFACE = "io.github.gma1k.SnakeEbpf"
+)
+
+type dbusService struct {
+	conn     *dbus.Conn
+	props    *prop.Properties
+	commands chan<- controlCommand
+}
+
+func startDBusService(commands chan<- controlCommand) (*dbusService, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect session bus: %w", err)
+	}
+
+	s := &dbusService{conn: conn, commands: commands}
+	if err := conn.Export(s, DBUS_PATH, DBUS_INTERFACE); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("export dbus object: %w", err)
+	}
+
+	readOnly := func(v any) *prop.Prop {
+		return &prop.Prop{Value: v, Writable: false, Emit: prop.EmitTrue}
+	}
+	s.props, err = prop.Export(conn, DBUS_PATH, prop.Map{
+		DBUS_INTERFACE: {
+			"Score":     readOnly(int32(0)),
+			"Length":    readOnly(int32(0)),
+			"EventRate": readOnly(uint64(0)),
+			"Paused":    readOnly(false),
+		},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("export dbus properties: %w", err)
+	}
+
+	node := &introspect.Node{
+		Name: DBUS_PATH,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       DBUS_INTERFACE,
+				Methods:    introspect.Methods(s),
+				Properties: s.props.Introspection(DBUS_INTERFACE),
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), DBUS_PATH, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("export dbus introspection: %w", err)
+	}
+
+	reply, err := conn.RequestName(DBUS_NAME, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("request dbus name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("dbus name %s already taken", DBUS_NAME)
+	}
+	return s, nil
+}
+
+func (s *dbusService) Pause() *dbus.Error {
+	return s.send("pause")
+}
+
+func (s *dbusService) Resume() *dbus.Error {
+	return s.send("resume")
+}
+
+func (s *dbusService) send(action string) *dbus.Error {
+	cmd := controlCommand{action: action, reply: make(chan controlReply, 1)}
+	select {
+	case s.commands <- cmd:
+	case <-time.After(2 * time.Second):
+		return dbus.MakeFailedError(fmt.Errorf("game loop busy"))
+	}
+	if reply := <-cmd.reply; reply.err != nil {
+		return dbus.MakeFailedError(reply.err)
+	}
+	return nil
+}
+
+func (s *dbusService) publish(sess *session) {
+	s.set("Score", int32(sess.game.Score))
+	s.set("Length", int32(len(sess.game.Snake)))
+	s.set("EventRate", sess.ui.Metrics.EventRate)
+	s.set("Paused", sess.game.Paused)
+}
+
+func (s *dbusService) set(property string, value any) {
+	if s.props.GetMust(DBUS_INTERFACE, property) != value {
+		s.props.SetMust(DBUS_INTERFACE, property, value)
+	}
+}
+
+func (s *dbusService) close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,518 @@
+package ebpfmon
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+const (
+	ATTACH_TRACEPOINT   = "tracepoint"
+	ATTACH_KPROBE       = "kprobe"
+	ATTACH_FENTRY       = "fentry"
+	ATTACH_KPROBE_MULTI = "kprobe.multi"
+	ATTACH_LSM          = "lsm"
+	ATTACH_UPROBE       = "uprobe"
+	ATTACH_USDT         = "usdt"
+	ATTACH_XDP          = "xdp"
+	ATTACH_XDP_GENERIC  = "xdp-generic"
+	ATTACH_CGROUP_SKB   = "cgroup_skb"
+)
+
+type attachTarget struct {
+	mechanism string
+	group     string
+	name      string
+	prog      *ebpf.Program
+	// load loads the program for symbol name when the target is attached,
+	// for program types that are bound to their attach point at load time.
+	load func(symbol string) (*ebpf.Program, error)
+	// address and semaphore are the file offsets of a USDT probe and its
+	// semaphore, if it has one.
+	address   uint64
+	semaphore uint64
+}
+
+type attachResult struct {
+	program   string
+	mechanism string
+	target    string
+	// prog is where the runtime statistics of the program are read.
+	prog *ebpf.Program
+}
+
+// ErrProbeDisabled is the failure recorded for probes the user turned off.
+var ErrProbeDisabled = errors.New("disabled by configuration")
+
+type AttachManager struct {
+	links      []link.Link
+	attached   []attachResult
+	unattached []string
+	failures   map[string]error
+	// unavailable holds the optional programs the kernel cannot run.
+	unavailable []string
+	// disabled holds the names passed to Attach; matched are those that
+	// named a program.
+	disabled map[string]bool
+	matched  map[string]bool
+	skipped  []string
+}
+
+type ProbeGroupStatus struct {
+	Label     string
+	Program   string
+	Attached  bool
+	Mechanism string
+	Err       error
+}
+
+type ProbeStatus struct {
+	Groups []ProbeGroupStatus
+}
+
+type probeGroup struct {
+	label   string
+	program string
+}
+
+var probeGroups = []probeGroup{
+	{"exec", "handle_execve"},
+	{"file", "handle_file_open"},
+	{"net", "handle_network_connect"},
+	{"fork", "handle_process_fork"},
+	{"sched", "handle_context_switch"},
+	{"sys", "handle_raw_syscall"},
+	{"retx", "handle_tcp_retransmit"},
+	{"drop", "handle_skb_drop"},
+	{"disk", "handle_block_complete"},
+	{"fault", "handle_page_fault"},
+	{"reclaim", "handle_reclaim"},
+	{"exit", "handle_process_exit"},
+	{"dns", "handle_dns_query"},
+	{"signal", "handle_signal"},
+	{"runq", "handle_runq_switch"},
+}
+
+// lsmGroup follows the probe groups in the status. Its hooks only attach
+// on kernels with BPF LSM.
+var lsmGroup = probeGroup{"lsm", "handle_bprm_check"}
+
+func tracepoint(prog *ebpf.Program, group, name string) attachTarget {
+	return attachTarget{mechanism: ATTACH_TRACEPOINT, group: group, name: name, prog: prog}
+}
+
+func kprobes(prog *ebpf.Program, symbols ...string) []attachTarget {
+	targets := make([]attachTarget, 0, len(symbols))
+	for _, symbol := range symbols {
+		targets = append(targets, attachTarget{mechanism: ATTACH_KPROBE, name: symbol, prog: prog})
+	}
+	return targets
+}
+
+// kprobeMulti attaches prog to every one of symbols the kernel has at once.
+// When the kprobe.multi programs could not be loaded, the target fails with
+// loadErr.
+func kprobeMulti(prog *ebpf.Program, loadErr error, symbols ...string) []attachTarget {
+	target := attachTarget{mechanism: ATTACH_KPROBE_MULTI, name: strings.Join(symbols, ","), prog: prog}
+	if loadErr != nil {
+		target.load = func(string) (*ebpf.Program, error) { return nil, loadErr }
+	}
+	return []attachTarget{target}
+}
+
+func (t attachTarget) attach() (link.Link, error) {
+	if t.load != nil {
+		prog, err := t.load(t.name)
+		if err != nil {
+			return nil, err
+		}
+		// The link keeps the program loaded.
+		defer prog.Close()
+		t.prog = prog
+	}
+	if t.prog == nil {
+		return nil, fmt.Errorf("program not loaded")
+	}
+	switch t.mechanism {
+	case ATTACH_TRACEPOINT:
+		return link.Tracepoint(t.group, t.name, t.prog, nil)
+	case ATTACH_KPROBE:
+		return link.Kprobe(t.name, t.prog, nil)
+	case ATTACH_FENTRY:
+		return link.AttachTracing(link.TracingOptions{Program: t.prog})
+	case ATTACH_KPROBE_MULTI:
+		symbols, err := kernelSymbols(strings.Split(t.name, ","))
+		if err != nil {
+			return nil, err
+		}
+		if len(symbols) == 0 {
+			return nil, fmt.Errorf("no such kernel functions")
+		}
+		return link.KprobeMulti(t.prog, link.KprobeMultiOptions{Symbols: symbols})
+	case ATTACH_LSM:
+		return link.AttachLSM(link.LSMOptions{Program: t.prog})
+	case ATTACH_UPROBE:
+		ex, err := link.OpenExecutable(t.group)
+		if err != nil {
+			return nil, err
+		}
+		return ex.Uprobe(t.name, t.prog, nil)
+	case ATTACH_XDP:
+		return attachXDP(t.prog, t.name, link.XDPDriverMode)
+	case ATTACH_XDP_GENERIC:
+		return attachXDP(t.prog, t.name, link.XDPGenericMode)
+	case ATTACH_CGROUP_SKB:
+		return link.AttachCgroup(link.CgroupOptions{Path: t.name, Attach: ebpf.AttachCGroupInetEgress, Program: t.prog})
+	case ATTACH_USDT:
+		ex, err := link.OpenExecutable(t.group)
+		if err != nil {
+			return nil, err
+		}
+		return ex.Uprobe(t.name, t.prog, &link.UprobeOptions{Address: t.address, RefCtrOffset: t.semaphore})
+	}
+	return nil, fmt.Errorf("unknown attach mechanism %q", t.mechanism)
+}
+
+func (t attachTarget) String() string {
+	if t.group != "" {
+		return t.group + ":" + t.name
+	}
+	return t.name
+}
+
+// isDisabled reports whether program was disabled by its own name, by its
+// name without the handle_ prefix or by its group label.
+func (m *AttachManager) isDisabled(program string) bool {
+	names := []string{program, strings.TrimPrefix(program, "handle_")}
+	for _, group := range probeGroups {
+		if group.program == program {
+			names = append(names, group.label)
+		}
+	}
+	disabled := false
+	for _, name := range names {
+		if m.disabled[name] {
+			m.matched[name] = true
+			disabled = true
+		}
+	}
+	return disabled
+}
+
+func (m *AttachManager) attach(program string, targets ...attachTarget) bool {
+	return m.attachPlan(attachPlan{program: program, targets: targets})
+}
+
+// attachEach attaches program at every target instead of the first that
+// works, for probes that live in more than one place.
+func (m *AttachManager) attachEach(program string, targets ...attachTarget) bool {
+	plan := attachPlan{program: program}
+	for _, target := range targets {
+		plan.each = append(plan.each, []attachTarget{target})
+	}
+	return m.attachPlan(plan)
+}
+
+// attachPlan attaches the program of plan at the first of its targets that
+// works or, when none does, at the first place of every list of each that
+// works.
+func (m *AttachManager) attachPlan(plan attachPlan) bool {
+	if m.skip(plan.program) {
+		return false
+	}
+	var errs []error
+	if m.attachFirst(plan.program, plan.targets, &errs) {
+		return true
+	}
+	attached := false
+	for _, targets := range plan.each {
+		if m.attachFirst(plan.program, targets, &errs) {
+			attached = true
+		}
+	}
+	if attached {
+		return true
+	}
+	return m.failAttach(plan.program, errs)
+}
+
+// attachFirst attaches program at the first of targets that works, adding
+// why the others did not to errs.
+func (m *AttachManager) attachFirst(program string, targets []attachTarget, errs *[]error) bool {
+	for _, target := range targets {
+		l, err := target.attach()
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s %s: %w", target.mechanism, target, err))
+			continue
+		}
+		m.links = append(m.links, l)
+		m.attached = append(m.attached, attachResult{
+			program:   program,
+			mechanism: target.mechanism,
+			target:    target.String(),
+			prog:      statsProgram(l, target),
+		})
+		return true
+	}
+	return false
+}
+
+// failAttach records program as not attached for errs.
+func (m *AttachManager) failAttach(program string, errs []error) bool {
+	if len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("no attach targets"))
+	}
+	if m.failures == nil {
+		m.failures = make(map[string]error)
+	}
+	m.unattached = append(m.unattached, program)
+	m.failures[program] = errors.Join(errs...)
+	return false
+}
+
+// skip records program as disabled when it was, and reports whether it was.
+func (m *AttachManager) skip(program string) bool {
+	if !m.isDisabled(program) {
+		return false
+	}
+	if m.failures == nil {
+		m.failures = make(map[string]error)
+	}
+	m.skipped = append(m.skipped, program)
+	m.failures[program] = ErrProbeDisabled
+	return true
+}
+
+// fail records program as not attached because it could not be loaded.
+// Programs the kernel cannot run at all are only unavailable.
+func (m *AttachManager) fail(program string, err error) {
+	if m.skip(program) {
+		return
+	}
+	if m.failures == nil {
+		m.failures = make(map[string]error)
+	}
+	m.failures[program] = err
+	if errors.Is(err, ErrLSMUnavailable) {
+		m.unavailable = append(m.unavailable, program)
+	} else {
+		m.unattached = append(m.unattached, program)
+	}
+}
+
+func (m *AttachManager) Status() ProbeStatus {
+	var s ProbeStatus
+	for _, group := range slices.Concat(probeGroups, []probeGroup{lsmGroup}) {
+		gs := ProbeGroupStatus{Label: group.label, Program: group.program, Err: m.failures[group.program]}
+		var mechanisms []string
+		for _, r := range m.attached {
+			if r.program == group.program && !slices.Contains(mechanisms, r.mechanism) {
+				mechanisms = append(mechanisms, r.mechanism)
+			}
+		}
+		gs.Attached = len(mechanisms) > 0
+		gs.Mechanism = strings.Join(mechanisms, ",")
+		s.Groups = append(s.Groups, gs)
+	}
+	return s
+}
+
+func (s ProbeStatus) Indicator() string {
+	parts := make([]string, 0, len(s.Groups))
+	for _, g := range s.Groups {
+		mark := "✓"
+		if !g.Attached {
+			mark = "✗"
+		}
+		parts = append(parts, g.Label+mark)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m *AttachManager) Links() int {
+	return len(m.links)
+}
+
+func (m *AttachManager) Unattached() []string {
+	return m.unattached
+}
+
+// Unavailable lists the optional programs left unattached because the
+// kernel cannot run them, like the LSM hooks without BPF LSM.
+func (m *AttachManager) Unavailable() []string {
+	return m.unavailable
+}
+
+// Disabled lists the programs left unattached because they were disabled.
+func (m *AttachManager) Disabled() []string {
+	return m.skipped
+}
+
+func (m *AttachManager) Failure(program string) error {
+	return m.failures[program]
+}
+
+// Mechanisms maps every attached program to how and where it attached,
+// each place of a program attached in more than one after a comma.
+func (m *AttachManager) Mechanisms() map[string]string {
+	out := make(map[string]string, len(m.attached))
+	for _, r := range m.attached {
+		if out[r.program] != "" {
+			out[r.program] += ","
+		}
+		out[r.program] += r.mechanism + ":" + r.target
+	}
+	return out
+}
+
+func (m *AttachManager) Close() {
+	for _, l := range m.links {
+		if l != nil {
+			l.Close()
+		}
+	}
+	for _, r := range m.attached {
+		if r.prog != nil {
+			r.prog.Close()
+		}
+	}
+	m.links = nil
+}
+
+// execveSymbols are the execve syscall functions of the architectures the
+// kprobe fallback supports. A kernel has only one of them.
+var execveSymbols = []string{
+	"__x64_sys_execve",
+	"__arm64_sys_execve",
+	"__s390x_sys_execve",
+	"__x86_sys_execve",
+}
+
+// attachPlan is a program and the places it can be attached, in order of
+// preference. A program that has to be in several places at once, like
+// both connect functions, lists the places for each of them in each, to
+// be attached at the first of every list that works when none of targets
+// does.
+type attachPlan struct {
+	program string
+	targets []attachTarget
+	each    [][]attachTarget
+}
+
+// probePlans prefers tracepoints, then fentry and kprobe.multi where the
+// kernel supports them and single kprobes last. kprobe.multi comes first
+// for functions that all have to be hooked, since it takes one link.
+func (m *Monitor) probePlans() []attachPlan {
+	objs := &m.objs
+	return []attachPlan{
+		{program: "handle_execve", targets: slices.Concat([]attachTarget{tracepoint(objs.HandleExecveTp, "syscalls", "sys_enter_execve")},
+			kprobeMulti(m.multi.HandleExecveMulti, m.multiErr, execveSymbols...),
+			kprobes(objs.HandleExecve, execveSymbols...))},
+		{program: "handle_file_open", targets: append([]attachTarget{tracepoint(objs.HandleFileOpenTp, "syscalls", "sys_enter_openat")},
+			kprobes(objs.HandleFileOpen,
+				"do_sys_openat2",
+				"do_sys_open",
+				"__x64_sys_openat",
+			)...)},
+		{program: "handle_context_switch", targets: append([]attachTarget{tracepoint(objs.HandleContextSwitchTp, "sched", "sched_switch")},
+			kprobes(objs.HandleContextSwitch, "__schedule")...)},
+		// One kprobe.multi link hooks both connect functions, otherwise each
+		// is hooked on its own.
+		{program: "handle_network_connect", targets: kprobeMulti(m.multi.HandleNetworkConnectMulti, m.multiErr, "tcp_v4_connect", "tcp_v6_connect"), each: [][]attachTarget{
+			slices.Concat(m.fentries("handle_network_connect", "tcp_v4_connect"), kprobes(objs.HandleNetworkConnect, "tcp_v4_connect")),
+			slices.Concat(m.fentries("handle_network_connect", "tcp_v6_connect"), kprobes(objs.HandleNetworkConnect, "tcp_v6_connect")),
+		}},
+		{program: "handle_process_fork", targets: append(m.fentries("handle_process_fork", "kernel_clone", "_do_fork"),
+			kprobes(objs.HandleProcessFork, "_do_fork", "kernel_clone", "__x64_sys_clone")...)},
+		{program: "handle_oom_kill", targets: slices.Concat([]attachTarget{tracepoint(objs.HandleOomKillTp, "oom", "mark_victim")},
+			m.fentries("handle_oom_kill", "oom_kill_process"),
+			kprobes(objs.HandleOomKill, "oom_kill_process"))},
+		{program: "handle_listen", targets: append(m.fentries("handle_listen", "inet_csk_listen_start"),
+			kprobes(objs.HandleListen, "inet_csk_listen_start")...)},
+		{program: "handle_sched_exec", targets: []attachTarget{tracepoint(objs.HandleSchedExec, "sched", "sched_process_exec")}},
+		{program: "handle_raw_syscall", targets: []attachTarget{tracepoint(objs.HandleRawSyscall, "raw_syscalls", "sys_enter")}},
+		{program: "handle_tcp_retransmit", targets: slices.Concat([]attachTarget{tracepoint(objs.HandleTcpRetransmitTp, "tcp", "tcp_retransmit_skb")},
+			m.fentries("handle_tcp_retransmit", "tcp_retransmit_skb"),
+			kprobes(objs.HandleTcpRetransmit, "tcp_retransmit_skb"))},
+		{program: "handle_skb_drop", targets: slices.Concat([]attachTarget{tracepoint(objs.HandleSkbDropTp, "skb", "kfree_skb")},
+			m.fentries("handle_skb_drop", "kfree_skb_reason", "kfree_skb"),
+			kprobes(objs.HandleSkbDrop, "kfree_skb_reason", "kfree_skb"))},
+		{program: "handle_page_fault", targets: slices.Concat([]attachTarget{tracepoint(objs.HandlePageFaultTp, "exceptions", "page_fault_user")},
+			m.fentries("handle_page_fault", "handle_mm_fault"),
+			kprobes(objs.HandlePageFault, "handle_mm_fault"))},
+		{program: "handle_reclaim", targets: slices.Concat([]attachTarget{tracepoint(objs.HandleReclaimTp, "vmscan", "mm_vmscan_direct_reclaim_begin")},
+			m.fentries("handle_reclaim", "try_to_free_pages"),
+			kprobes(objs.HandleReclaim, "try_to_free_pages"))},
+		{program: "handle_task_new", targets: []attachTarget{tracepoint(objs.HandleTaskNew, "task", "task_newtask")}},
+		{program: "handle_process_exit", targets: []attachTarget{tracepoint(objs.HandleProcessExit, "sched", "sched_process_exit")}},
+		{program: "handle_block_issue", targets: []attachTarget{tracepoint(objs.HandleBlockIssue, "block", "block_rq_issue")}},
+		{program: "handle_block_complete", targets: []attachTarget{tracepoint(objs.HandleBlockComplete, "block", "block_rq_complete")}},
+		{program: "handle_dns_query", targets: kprobes(objs.HandleDnsQuery, "udp_sendmsg")},
+		{program: "handle_signal", targets: []attachTarget{tracepoint(objs.HandleSignal, "signal", "signal_generate")}},
+		{program: "handle_runq_wakeup", targets: []attachTarget{tracepoint(objs.HandleRunqWakeup, "sched", "sched_wakeup")}},
+		{program: "handle_runq_wakeup_new", targets: []attachTarget{tracepoint(objs.HandleRunqWakeupNew, "sched", "sched_wakeup_new")}},
+		{program: "handle_runq_switch", targets: []attachTarget{tracepoint(objs.HandleRunqSwitch, "sched", "sched_switch")}},
+	}
+}
+
+func attachProbes(plans []attachPlan, disabled []string) (*AttachManager, error) {
+	m := &AttachManager{disabled: make(map[string]bool), matched: make(map[string]bool)}
+	for _, name := range disabled {
+		m.disabled[name] = true
+	}
+	for _, plan := range plans {
+		m.attachPlan(plan)
+	}
+
+	if len(m.links) == 0 {
+		return nil, fmt.Errorf("failed to attach any probes")
+	}
+	return m, nil
+}
+
+// CandidateResult is the outcome of attaching a program at one of its
+// candidate places. Err is nil when it attached.
+type CandidateResult struct {
+	Program   string
+	Mechanism string
+	Target    string
+	Err       error
+}
+
+// CheckCandidates attaches every program at every candidate place, one at a
+// time, and detaches it again right away. Unlike Attach, which stops at the
+// first place that works, it shows everything the kernel supports.
+func (m *Monitor) CheckCandidates() []CandidateResult {
+	plans := m.probePlans()
+	if m.lsm != nil {
+		plans = append(plans, lsmPlans(m.lsm)...)
+	}
+	var results []CandidateResult
+	for _, plan := range plans {
+		for _, target := range slices.Concat(append([][]attachTarget{plan.targets}, plan.each...)...) {
+			r := CandidateResult{Program: plan.program, Mechanism: target.mechanism, Target: target.String()}
+			l, err := target.attach()
+			if err == nil {
+				l.Close()
+			}
+			r.Err = err
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// checkDisabled fails for names passed to Attach that matched no program,
+// so a typo does not leave a probe running unnoticed.
+func (m *AttachManager) checkDisabled() error {
+	for name := range m.disabled {
+		if !m.matched[name] {
+			return fmt.Errorf("unknown probe %q", name)
+		}
+	}
+	return nil
+}
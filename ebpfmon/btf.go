@@ -0,0 +1,56 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+)
+
+const KERNEL_BTF_PATH = "/sys/kernel/btf/vmlinux"
+
+// KernelRelease returns the release of the running kernel, like uname -r.
+func KernelRelease() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
+
+func fallbackBTFPaths(release string) []string {
+	return []string{
+		"/boot/vmlinux-" + release,
+		"/lib/modules/" + release + "/vmlinux-" + release,
+		"/usr/lib/debug/boot/vmlinux-" + release,
+		"/usr/lib/debug/lib/modules/" + release + "/vmlinux",
+		"/var/lib/snake-ebpf/btf/" + release + ".btf",
+	}
+}
+
+func collectionOptions(btfPath string) (*ebpf.CollectionOptions, error) {
+	if btfPath == "" {
+		if _, err := os.Stat(KERNEL_BTF_PATH); err == nil {
+			return nil, nil
+		}
+		for _, path := range fallbackBTFPaths(KernelRelease()) {
+			if _, err := os.Stat(path); err == nil {
+				btfPath = path
+				break
+			}
+		}
+		if btfPath == "" {
+			return nil, fmt.Errorf("no kernel BTF found at %s or in fallback locations, pass -btf with a BTF file for kernel %s (e.g. from BTFHub)", KERNEL_BTF_PATH, KernelRelease())
+		}
+	}
+
+	spec, err := btf.LoadSpec(btfPath)
+	if err != nil {
+		return nil, fmt.Errorf("load kernel BTF from %s: %w", btfPath, err)
+	}
+	return &ebpf.CollectionOptions{
+		Programs: ebpf.ProgramOptions{KernelTypes: spec},
+	}, nil
+}
@@ -0,0 +1,49 @@
+package ebpfmon
+
+import "time"
+
+const (
+	BURST_EXECVE_RATE = 50
+	BURST_COOLDOWN    = 20 * time.Second
+
+	// A danger burst is a spike of TCP retransmits and dropped packets.
+	BURST_DANGER_RATE     = 20
+	BURST_DANGER_COOLDOWN = 15 * time.Second
+)
+
+// BurstDetector reports when the rate of a counter within a single poll
+// window crosses a threshold. It fires once per burst and at most once per
+// cooldown.
+type BurstDetector struct {
+	value     func(Delta) uint64
+	threshold float64
+	cooldown  time.Duration
+	bursting  bool
+	last      time.Time
+}
+
+func NewBurstDetector(value func(Delta) uint64, threshold float64, cooldown time.Duration) *BurstDetector {
+	return &BurstDetector{value: value, threshold: threshold, cooldown: cooldown}
+}
+
+func ExecveCount(d Delta) uint64 {
+	return d.Execve
+}
+
+func DangerCount(d Delta) uint64 {
+	return d.Retransmits + d.Drops
+}
+
+func (b *BurstDetector) Detect(snap Snapshot) bool {
+	if snap.Delta.Elapsed <= 0 {
+		return false
+	}
+	rate := float64(b.value(snap.Delta)) / snap.Delta.Elapsed.Seconds()
+	bursting := rate >= b.threshold
+	fire := bursting && !b.bursting && (b.last.IsZero() || snap.Time.Sub(b.last) >= b.cooldown)
+	b.bursting = bursting
+	if fire {
+		b.last = snap.Time
+	}
+	return fire
+}
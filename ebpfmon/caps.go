@@ -0,0 +1,48 @@
+package ebpfmon
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+type capability struct {
+	name string
+	bit  int
+}
+
+var requiredCapabilities = []capability{
+	{"CAP_BPF", unix.CAP_BPF},
+	{"CAP_PERFMON", unix.CAP_PERFMON},
+	{"CAP_SYS_RESOURCE", unix.CAP_SYS_RESOURCE},
+}
+
+func effectiveCapabilities() (uint64, error) {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return 0, fmt.Errorf("read capabilities: %w", err)
+	}
+	return uint64(data[0].Effective) | uint64(data[1].Effective)<<32, nil
+}
+
+func MissingCapabilities() ([]string, error) {
+	caps, err := effectiveCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	has := func(bit int) bool { return caps&(1<<uint(bit)) != 0 }
+
+	var missing []string
+	for _, c := range requiredCapabilities {
+		if has(c.bit) {
+			continue
+		}
+		// Kernels before 5.8 have no CAP_BPF/CAP_PERFMON and check CAP_SYS_ADMIN instead.
+		if (c.bit == unix.CAP_BPF || c.bit == unix.CAP_PERFMON) && has(unix.CAP_SYS_ADMIN) {
+			continue
+		}
+		missing = append(missing, c.name)
+	}
+	return missing, nil
+}
@@ -0,0 +1,165 @@
+package ebpfmon
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+const CGROUP_ROOT = "/sys/fs/cgroup"
+
+const (
+	// CGROUP_REFRESH_INTERVAL is how often the cgroup tree is walked again
+	// for a cgroup that is not known yet. Every walk that does not find
+	// it doubles the interval, up to CGROUP_REFRESH_MAX_INTERVAL.
+	CGROUP_REFRESH_INTERVAL     = 5 * time.Second
+	CGROUP_REFRESH_MAX_INTERVAL = time.Minute
+	// CGROUP_RESOLVE_ATTEMPTS is how many walks a cgroup is missing from
+	// before it is taken for removed, and its counts are deleted.
+	CGROUP_RESOLVE_ATTEMPTS = 3
+)
+
+type CgroupCount struct {
+	ID    uint64
+	Path  string
+	Count uint64
+}
+
+type CgroupResolver struct {
+	paths       map[uint64]string
+	lastRefresh time.Time
+	interval    time.Duration
+	// walks counts the walks of the cgroup tree, and misses how many of
+	// them each unknown cgroup was missing from.
+	walks  int
+	misses map[uint64]cgroupMiss
+}
+
+type cgroupMiss struct {
+	count int
+	walk  int
+}
+
+func NewCgroupResolver() *CgroupResolver {
+	r := &CgroupResolver{
+		paths:    make(map[uint64]string),
+		interval: CGROUP_REFRESH_INTERVAL,
+		misses:   make(map[uint64]cgroupMiss),
+	}
+	r.refresh()
+	return r
+}
+
+func (r *CgroupResolver) refresh() {
+	paths := make(map[uint64]string)
+	filepath.WalkDir(CGROUP_ROOT, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			rel := strings.TrimPrefix(path, CGROUP_ROOT)
+			if rel == "" {
+				rel = "/"
+			}
+			paths[st.Ino] = rel
+		}
+		return nil
+	})
+	r.paths = paths
+	r.lastRefresh = time.Now()
+	r.walks++
+}
+
+func (r *CgroupResolver) Resolve(id uint64) string {
+	if path, ok := r.lookup(id); ok {
+		return path
+	}
+	return "cgroup:" + strconv.FormatUint(id, 10)
+}
+
+// lookup returns the path of cgroup id, walking the tree again when it is
+// not known and the last walk is long enough ago. Cgroups that are gone
+// are not looked for anymore.
+func (r *CgroupResolver) lookup(id uint64) (string, bool) {
+	if path, ok := r.paths[id]; ok {
+		return path, true
+	}
+	if r.gone(id) {
+		return "", false
+	}
+	if time.Since(r.lastRefresh) > r.interval {
+		r.refresh()
+		if path, ok := r.paths[id]; ok {
+			r.interval = CGROUP_REFRESH_INTERVAL
+			delete(r.misses, id)
+			return path, true
+		}
+		r.interval = min(2*r.interval, CGROUP_REFRESH_MAX_INTERVAL)
+	}
+	if miss := r.misses[id]; miss.walk != r.walks {
+		r.misses[id] = cgroupMiss{count: miss.count + 1, walk: r.walks}
+	}
+	return "", false
+}
+
+// gone reports whether cgroup id was missing from the last
+// CGROUP_RESOLVE_ATTEMPTS walks, so it has been removed.
+func (r *CgroupResolver) gone(id uint64) bool {
+	return r.misses[id].count >= CGROUP_RESOLVE_ATTEMPTS
+}
+
+// prune deletes the counts of removed cgroups from m, so they do not take
+// up its entries.
+func (r *CgroupResolver) prune(m *ebpf.Map, ids []uint64) {
+	for _, id := range ids {
+		if m.Delete(id) == nil {
+			delete(r.misses, id)
+		}
+	}
+}
+
+func (r *CgroupResolver) TopCgroups(m *ebpf.Map, n int) []CgroupCount {
+	if m == nil {
+		return nil
+	}
+	var counts []CgroupCount
+	var gone []uint64
+	var id, count uint64
+	countLookup()
+	iter := m.Iterate()
+	for iter.Next(&id, &count) {
+		if _, ok := r.lookup(id); !ok && r.gone(id) {
+			gone = append(gone, id)
+			continue
+		}
+		counts = append(counts, CgroupCount{ID: id, Count: count})
+	}
+	r.prune(m, gone)
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	for i := range counts {
+		counts[i].Path = r.Resolve(counts[i].ID)
+	}
+	return counts
+}
+
+func ShortCgroupName(path string) string {
+	if path == "/" {
+		return path
+	}
+	return filepath.Base(path)
+}
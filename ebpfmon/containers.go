@@ -0,0 +1,180 @@
+package ebpfmon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// DOCKER_ROOT is where Docker keeps the config of each container, which
+// holds its name.
+const DOCKER_ROOT = "/var/lib/docker"
+
+// Container is the container a cgroup belongs to.
+type Container struct {
+	Runtime string
+	ID      string
+	Name    string
+}
+
+// ContainerCount is the events of one container, by kind (EVENT_EXEC and
+// so on), summed over all of its cgroups.
+type ContainerCount struct {
+	Container
+	Counts [EVENT_KINDS]uint64
+}
+
+func (c ContainerCount) Total() uint64 {
+	var total uint64
+	for _, n := range c.Counts {
+		total += n
+	}
+	return total
+}
+
+// Matches reports whether selector is the container's name or a prefix of
+// its ID.
+func (c Container) Matches(selector string) bool {
+	return selector != "" && (c.Name == selector || strings.HasPrefix(c.ID, selector))
+}
+
+// containerRuntimes maps the prefix of a cgroup directory named after a
+// container ID to the runtime that created it.
+var containerRuntimes = []struct {
+	prefix  string
+	runtime string
+}{
+	{"docker-", "docker"},
+	{"cri-containerd-", "containerd"},
+	{"crio-", "cri-o"},
+	{"libpod-", "podman"},
+}
+
+// ContainerOf finds the container of a cgroup path such as
+// /system.slice/docker-<id>.scope or /kubepods/burstable/pod<uid>/<id>.
+// Cgroups nested below a container belong to it as well.
+func ContainerOf(cgroupPath string) (Container, bool) {
+	dirs := strings.Split(strings.Trim(cgroupPath, "/"), "/")
+	for i, dir := range dirs {
+		id := strings.TrimSuffix(dir, ".scope")
+		runtime := ""
+		for _, r := range containerRuntimes {
+			if strings.HasPrefix(id, r.prefix) {
+				id, runtime = strings.TrimPrefix(id, r.prefix), r.runtime
+				break
+			}
+		}
+		if !isContainerID(id) {
+			continue
+		}
+		if runtime == "" {
+			runtime = "containerd"
+			if i > 0 && dirs[i-1] == "docker" {
+				runtime = "docker"
+			}
+		}
+		return Container{Runtime: runtime, ID: id, Name: id[:12]}, true
+	}
+	return Container{}, false
+}
+
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainerResolver names the containers of cgroups, reading Docker's
+// container configs where it can and falling back to the short ID.
+type ContainerResolver struct {
+	cgroups *CgroupResolver
+	names   map[string]string
+}
+
+func NewContainerResolver(cgroups *CgroupResolver) *ContainerResolver {
+	return &ContainerResolver{cgroups: cgroups, names: make(map[string]string)}
+}
+
+func (r *ContainerResolver) resolve(cgroupID uint64) (Container, bool) {
+	c, ok := ContainerOf(r.cgroups.Resolve(cgroupID))
+	if !ok {
+		return c, false
+	}
+	name, cached := r.names[c.ID]
+	if !cached {
+		name = c.Name
+		if c.Runtime == "docker" {
+			if docker, err := dockerName(c.ID); err == nil && docker != "" {
+				name = docker
+			}
+		}
+		r.names[c.ID] = name
+	}
+	c.Name = name
+	return c, true
+}
+
+func dockerName(id string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(DOCKER_ROOT, "containers", id, "config.v2.json"))
+	if err != nil {
+		return "", err
+	}
+	var config struct{ Name string }
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(config.Name, "/"), nil
+}
+
+// Containers sums the per-cgroup counters of m by container, busiest
+// first. Cgroups outside containers are left out.
+func (r *ContainerResolver) Containers(m *ebpf.Map) []ContainerCount {
+	if m == nil {
+		return nil
+	}
+	byID := make(map[string]*ContainerCount)
+	var gone []uint64
+	var id uint64
+	var counts snakeCgroupCounts
+	countLookup()
+	iter := m.Iterate()
+	for iter.Next(&id, &counts) {
+		c, ok := r.resolve(id)
+		if !ok {
+			if r.cgroups.gone(id) {
+				gone = append(gone, id)
+			}
+			continue
+		}
+		total := byID[c.ID]
+		if total == nil {
+			total = &ContainerCount{Container: c}
+			byID[c.ID] = total
+		}
+		for kind, n := range counts.Count {
+			total.Counts[kind] += n
+		}
+	}
+	r.cgroups.prune(m, gone)
+	containers := make([]ContainerCount, 0, len(byID))
+	for _, c := range byID {
+		containers = append(containers, *c)
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		if a, b := containers[i].Total(), containers[j].Total(); a != b {
+			return a > b
+		}
+		return containers[i].Name < containers[j].Name
+	})
+	return containers
+}
@@ -0,0 +1,95 @@
+package ebpfmon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+)
+
+const LOCKDOWN_PATH = "/sys/kernel/security/lockdown"
+
+// ErrEmptyObject is returned for an embedded object that was never
+// generated, which would otherwise fail as a corrupt ELF file.
+var ErrEmptyObject = errors.New("embedded object is empty")
+
+// checkObject fails for the object name embedded as data when it is empty.
+func checkObject(name string, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: %s", ErrEmptyObject, name)
+	}
+	return nil
+}
+
+// LoadError explains why the eBPF objects could not be loaded: what failed,
+// on which kernel, what the verifier said and what may fix it.
+type LoadError struct {
+	Err    error
+	Kernel string
+	// Object is the program or map the kernel rejected, like
+	// "program handle_execve", when the error names one.
+	Object string
+	// Log is the verifier log when the verifier rejected a program.
+	Log   []string
+	Hints []string
+}
+
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+var loadObjectPattern = regexp.MustCompile(`\b(program|map) (\w+):`)
+
+func newLoadError(err error) *LoadError {
+	e := &LoadError{Err: err, Kernel: KernelRelease()}
+	if m := loadObjectPattern.FindStringSubmatch(err.Error()); m != nil {
+		e.Object = m[1] + " " + m[2]
+	}
+	var verr *ebpf.VerifierError
+	if errors.As(err, &verr) {
+		e.Log = verr.Log
+		e.Hints = append(e.Hints, "the verifier rejected the program, see the end of its log above; kernels older than 5.8 may not support everything the programs use")
+	}
+
+	msg := err.Error()
+	switch {
+	case errors.Is(err, ErrEmptyObject):
+		e.Hints = append(e.Hints, "the objects were not generated, run go generate ./... (it needs clang, llvm-strip and bpftool) and rebuild")
+	case strings.Contains(msg, "ELF"):
+		e.Hints = append(e.Hints, "the embedded objects are empty or corrupt, regenerate them with go generate ./... and rebuild")
+	case errors.Is(err, btf.ErrNotFound) || strings.Contains(msg, "BTF"):
+		e.Hints = append(e.Hints, "the kernel BTF is missing or does not match, pass -btf with a BTF file for kernel "+e.Kernel+" (e.g. from BTFHub)")
+	case errors.Is(err, ebpf.ErrNotSupported):
+		e.Hints = append(e.Hints, "kernel "+e.Kernel+" lacks a feature the programs need, a 5.8 or newer kernel is required")
+	}
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		if mode := LockdownMode(); mode != "" && mode != "none" {
+			e.Hints = append(e.Hints, "the kernel is in lockdown mode "+mode+", which restricts eBPF; boot with lockdown=none or turn off Secure Boot to play")
+		}
+	}
+	return e
+}
+
+// LockdownMode returns the active kernel lockdown mode (none, integrity or
+// confidentiality), or "" when the kernel does not report one.
+func LockdownMode() string {
+	data, err := os.ReadFile(LOCKDOWN_PATH)
+	if err != nil {
+		return ""
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			return strings.Trim(field, "[]")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package ebpfmon
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// DNS_NAME_LEN is how much of a queried name dns_queries keeps.
+const DNS_NAME_LEN = 64
+
+// DNS_MAX_LABEL is the longest label a name may have; larger length bytes
+// are compression pointers, which queries do not use.
+const DNS_MAX_LABEL = 63
+
+// DomainCount is how often a domain was queried.
+type DomainCount struct {
+	Name  string
+	Count uint64
+}
+
+// dnsName turns a name as it is sent, length-prefixed labels up to an
+// empty one, into dotted form. A name cut at DNS_NAME_LEN keeps the labels
+// that fit whole.
+func dnsName(raw [DNS_NAME_LEN]int8) string {
+	var labels []string
+	for i := 0; i < len(raw) && raw[i] != 0; {
+		n := int(uint8(raw[i]))
+		i++
+		if n > DNS_MAX_LABEL || i+n > len(raw) {
+			break
+		}
+		label := make([]byte, n)
+		for j := range label {
+			label[j] = byte(raw[i+j])
+		}
+		labels = append(labels, string(label))
+		i += n
+	}
+	return strings.Join(labels, ".")
+}
+
+// readDomains returns the queried domains by the hash the kernel keyed
+// them with.
+func readDomains(m *ebpf.Map) map[uint64]DomainCount {
+	domains := make(map[uint64]DomainCount)
+	var hash uint64
+	var query snakeDnsQuery
+	countLookup()
+	iter := m.Iterate()
+	for iter.Next(&hash, &query) {
+		if name := dnsName(query.Name); name != "" {
+			domains[hash] = DomainCount{Name: name, Count: query.Count}
+		}
+	}
+	return domains
+}
+
+// newDomains returns the domains queried for the first time since the last
+// call, sorted.
+func (r *MetricsReader) newDomains() []string {
+	// Domains queried before the first read, which pinned maps may hold
+	// from earlier runs, are not new.
+	first := r.domains == nil
+	if first {
+		r.domains = make(map[uint64]bool)
+	}
+	var names []string
+	for hash, d := range readDomains(r.mon.objs.DnsQueries) {
+		if first {
+			r.domains[hash] = true
+			continue
+		}
+		if !r.domains[hash] {
+			r.domains[hash] = true
+			names = append(names, d.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// TopDomains returns the n most queried domains, most queried first.
+func (m *Monitor) TopDomains(n int) []DomainCount {
+	var top []DomainCount
+	for _, d := range readDomains(m.objs.DnsQueries) {
+		top = append(top, d)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Name < top[j].Name
+	})
+	return top[:min(n, len(top))]
+}
@@ -0,0 +1,42 @@
+package ebpfmon
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ownCgroup returns the cgroup v2 directory this process runs in.
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return filepath.Join(CGROUP_ROOT, path), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("not in a cgroup v2 hierarchy")
+}
+
+// AttachEgress attaches handle_egress to the cgroup the game runs in, so
+// the bytes sent by everything in the player's session are counted as
+// EgressBytes. It is detached with the other probes.
+func (m *Monitor) AttachEgress(probes *AttachManager) error {
+	path, err := ownCgroup()
+	if err != nil {
+		return fmt.Errorf("find own cgroup: %w", err)
+	}
+	if !probes.attach("handle_egress", attachTarget{mechanism: ATTACH_CGROUP_SKB, name: path, prog: m.objs.HandleEgress}) {
+		return probes.Failure("handle_egress")
+	}
+	return nil
+}
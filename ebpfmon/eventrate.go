@@ -0,0 +1,40 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// The probes count the event rate in EVENT_RATE_SLOTS buckets of
+// EVENT_RATE_SLOT each, like RATE_SLOTS and RATE_SLOT_NS in snake.bpf.h.
+const (
+	EVENT_RATE_SLOTS = 10
+	EVENT_RATE_SLOT  = RATE_WINDOW / EVENT_RATE_SLOTS
+)
+
+// readEventRate sums the buckets of the second up to now. The probes keep
+// the same sum in event_rate, but only when an event comes along; summing
+// here against the clock they use drops the buckets that went stale since,
+// so a quiet system reads 0 however long ago the last poll was.
+func readEventRate(m *ebpf.Map) (uint64, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, fmt.Errorf("read monotonic clock: %w", err)
+	}
+	now := uint64(time.Duration(ts.Nano()) / EVENT_RATE_SLOT)
+
+	countLookup()
+	var sum uint64
+	var key uint32
+	var b snakeRateBucket
+	iter := m.Iterate()
+	for iter.Next(&key, &b) {
+		if b.Slot+EVENT_RATE_SLOTS > now {
+			sum += b.Count
+		}
+	}
+	return sum, iter.Err()
+}
@@ -0,0 +1,128 @@
+package ebpfmon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+	"sync/atomic"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+const (
+	EVENT_EXEC = iota
+	EVENT_OPEN
+	EVENT_CONNECT
+	EVENT_FORK
+	EVENT_KINDS
+)
+
+var eventNames = [EVENT_KINDS]string{
+	EVENT_EXEC:    "execve",
+	EVENT_OPEN:    "openat",
+	EVENT_CONNECT: "connect",
+	EVENT_FORK:    "fork",
+}
+
+const (
+	EVENT_ARG_LEN     = 64
+	EVENT_RECORD_SIZE = 16 + TASK_COMM_LEN + EVENT_ARG_LEN
+)
+
+type KernelEvent struct {
+	Timestamp uint64
+	PID       uint32
+	Kind      uint32
+	// Comm is the command that caused the event.
+	Comm string
+	// Arg is the program of an exec or the file of an open, empty for
+	// other events and where the probe could not read it.
+	Arg string
+}
+
+// String describes the event like a syscall, e.g. "bash → execve(vim)".
+func (ev KernelEvent) String() string {
+	name := "event"
+	if ev.Kind < EVENT_KINDS {
+		name = eventNames[ev.Kind]
+	}
+	arg := ev.Arg
+	if ev.Kind == EVENT_EXEC && arg != "" {
+		arg = path.Base(arg)
+	}
+	return fmt.Sprintf("%s → %s(%s)", ev.Comm, name, arg)
+}
+
+type EventReader struct {
+	reader  *ringbuf.Reader
+	events  chan KernelEvent
+	dropped atomic.Uint64
+}
+
+func newEventReader(m *ebpf.Map) (*EventReader, error) {
+	reader, err := ringbuf.NewReader(m)
+	if err != nil {
+		return nil, fmt.Errorf("open ring buffer: %w", err)
+	}
+	r := &EventReader{reader: reader, events: make(chan KernelEvent, 1024)}
+	go r.loop()
+	return r, nil
+}
+
+func (r *EventReader) loop() {
+	defer close(r.events)
+
+	var record ringbuf.Record
+	var ev KernelEvent
+	for {
+		if err := r.reader.ReadInto(&record); err != nil {
+			if errors.Is(err, ringbuf.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !decodeEvent(record.RawSample, &ev) {
+			continue
+		}
+		select {
+		case r.events <- ev:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+}
+
+func decodeEvent(raw []byte, ev *KernelEvent) bool {
+	if len(raw) < EVENT_RECORD_SIZE {
+		return false
+	}
+	ev.Timestamp = binary.NativeEndian.Uint64(raw[0:8])
+	ev.PID = binary.NativeEndian.Uint32(raw[8:12])
+	ev.Kind = binary.NativeEndian.Uint32(raw[12:16])
+	ev.Comm = cString(raw[16 : 16+TASK_COMM_LEN])
+	ev.Arg = cString(raw[16+TASK_COMM_LEN : EVENT_RECORD_SIZE])
+	return true
+}
+
+// cString returns b up to the first NUL byte.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func (r *EventReader) Events() <-chan KernelEvent {
+	return r.events
+}
+
+func (r *EventReader) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+func (r *EventReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,78 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+)
+
+// fentrySupported reports whether the kernel can attach fentry programs,
+// which need the kernel's own BTF and the tracing program type.
+func fentrySupported() bool {
+	if _, err := os.Stat(KERNEL_BTF_PATH); err != nil {
+		return false
+	}
+	return features.HaveProgramType(ebpf.Tracing) == nil
+}
+
+// loadFentrySpec reads the embedded fentry objects.
+func loadFentrySpec() (*ebpf.CollectionSpec, error) {
+	if err := checkObject("snake_fentry_bpfel.o", _SnakeFentryBytes); err != nil {
+		return nil, err
+	}
+	return loadSnakeFentry()
+}
+
+// fentries returns an fentry target for each symbol, or none when the
+// kernel does not support fentry.
+func (m *Monitor) fentries(program string, symbols ...string) []attachTarget {
+	if m.fentry == nil && m.fentryErr == nil {
+		return nil
+	}
+	targets := make([]attachTarget, 0, len(symbols))
+	for _, symbol := range symbols {
+		targets = append(targets, attachTarget{
+			mechanism: ATTACH_FENTRY,
+			name:      symbol,
+			load:      func(symbol string) (*ebpf.Program, error) { return m.loadFentry(program, symbol) },
+		})
+	}
+	return targets
+}
+
+// loadFentry loads the fentry version of program for symbol. An fentry
+// program is bound to its function when it is loaded, so every candidate
+// loads its own copy. It shares the maps of the main objects.
+func (m *Monitor) loadFentry(program, symbol string) (*ebpf.Program, error) {
+	if m.fentryErr != nil {
+		return nil, m.fentryErr
+	}
+	name := program + "_fentry"
+	prog, ok := m.fentry.Programs[name]
+	if !ok {
+		return nil, fmt.Errorf("no fentry program %s", name)
+	}
+	spec := m.fentry.Copy()
+	spec.Programs = map[string]*ebpf.ProgramSpec{name: prog.Copy()}
+	spec.Programs[name].AttachTo = symbol
+
+	coll, err := ebpf.NewCollectionWithOptions(spec, ebpf.CollectionOptions{MapReplacements: m.maps()})
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+	defer coll.Close()
+	return coll.DetachProgram(name), nil
+}
+
+// maps returns the maps of the main objects by name.
+func (m *Monitor) maps() map[string]*ebpf.Map {
+	maps := make(map[string]*ebpf.Map)
+	v := reflect.ValueOf(m.objs.snakeMaps)
+	for i := range v.NumField() {
+		maps[v.Type().Field(i).Tag.Get("ebpf")] = v.Field(i).Interface().(*ebpf.Map)
+	}
+	return maps
+}
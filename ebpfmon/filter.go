@@ -0,0 +1,77 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FILTER_UID = 1 << iota
+	FILTER_PID
+	FILTER_CGROUP
+)
+
+// Filter limits the counted events to processes of the given users, the
+// given PIDs and the given cgroup with its descendants. Empty fields don't
+// filter. Retransmits, drops, block I/O, reclaims, process starts and exits
+// and the notable events are always counted system-wide.
+type Filter struct {
+	UIDs []uint32
+	PIDs []uint32
+	// Cgroup is a cgroup v2 directory, absolute or relative to CGROUP_ROOT.
+	Cgroup string
+}
+
+func (f Filter) flags() uint32 {
+	var flags uint32
+	if len(f.UIDs) > 0 {
+		flags |= FILTER_UID
+	}
+	if len(f.PIDs) > 0 {
+		flags |= FILTER_PID
+	}
+	if f.Cgroup != "" {
+		flags |= FILTER_CGROUP
+	}
+	return flags
+}
+
+// CgroupPath returns the cgroup directory of the filter.
+func (f Filter) CgroupPath() string {
+	if strings.HasPrefix(f.Cgroup, CGROUP_ROOT+"/") || f.Cgroup == CGROUP_ROOT {
+		return f.Cgroup
+	}
+	return filepath.Join(CGROUP_ROOT, f.Cgroup)
+}
+
+// SetFilter writes the filter to the kernel. Events are only counted once
+// it is in place, so it is best set right after Load.
+func (m *Monitor) SetFilter(f Filter) error {
+	var on uint8 = 1
+	for _, uid := range f.UIDs {
+		if err := m.objs.FilterUids.Put(uid, on); err != nil {
+			return fmt.Errorf("add UID %d to filter: %w", uid, err)
+		}
+	}
+	for _, pid := range f.PIDs {
+		if err := m.objs.FilterPids.Put(pid, on); err != nil {
+			return fmt.Errorf("add PID %d to filter: %w", pid, err)
+		}
+	}
+	if f.Cgroup != "" {
+		dir, err := os.Open(f.CgroupPath())
+		if err != nil {
+			return fmt.Errorf("open cgroup: %w", err)
+		}
+		defer dir.Close()
+		if err := m.objs.FilterCgroup.Put(uint32(0), uint32(dir.Fd())); err != nil {
+			return fmt.Errorf("add cgroup %s to filter: %w", f.Cgroup, err)
+		}
+	}
+	if err := m.objs.FilterFlags.Put(uint32(0), f.flags()); err != nil {
+		return fmt.Errorf("enable filter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package ebpfmon
+
+import "time"
+
+// IO_LATENCY_SLOTS matches the io_latency map. Slot i counts block requests
+// that took from 2^i up to 2^(i+1) microseconds; the last one everything
+// slower.
+const IO_LATENCY_SLOTS = 24
+
+// DISK_LAG_LATENCY is the 99th percentile block I/O latency from which the
+// game slows the snake down.
+const DISK_LAG_LATENCY = 100 * time.Millisecond
+
+// IOLatencyBound is the upper edge of a histogram slot.
+func IOLatencyBound(slot int) time.Duration {
+	return time.Duration(uint64(1)<<(slot+1)) * time.Microsecond
+}
+
+// IOLatencyPercentile estimates quantile q of a latency histogram as the
+// upper edge of the slot it falls in. It is 0 for an empty histogram.
+func IOLatencyPercentile(hist []float64, q float64) time.Duration {
+	total := 0.0
+	for _, n := range hist {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	seen := 0.0
+	for slot, n := range hist {
+		seen += n
+		if seen >= q*total {
+			return IOLatencyBound(slot)
+		}
+	}
+	return IOLatencyBound(len(hist) - 1)
+}
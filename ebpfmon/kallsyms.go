@@ -0,0 +1,46 @@
+package ebpfmon
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const KALLSYMS_PATH = "/proc/kallsyms"
+
+// kernelSymbols returns those of symbols the running kernel has, in order.
+func kernelSymbols(symbols []string) ([]string, error) {
+	f, err := os.Open(KALLSYMS_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", KALLSYMS_PATH, err)
+	}
+	defer f.Close()
+
+	found := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		found[symbol] = false
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// address type name [module]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if _, ok := found[fields[2]]; ok {
+			found[fields[2]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", KALLSYMS_PATH, err)
+	}
+
+	var present []string
+	for _, symbol := range symbols {
+		if found[symbol] {
+			present = append(present, symbol)
+		}
+	}
+	return present, nil
+}
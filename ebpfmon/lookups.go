@@ -0,0 +1,15 @@
+package ebpfmon
+
+import "sync/atomic"
+
+var mapLookups atomic.Uint64
+
+// MapLookups is how many times the package read from a map since the
+// program started. A batch lookup or a walk over a whole map counts once.
+func MapLookups() uint64 {
+	return mapLookups.Load()
+}
+
+func countLookup() {
+	mapLookups.Add(1)
+}
@@ -0,0 +1,331 @@
+package ebpfmon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	COUNTER_EXECVE = iota
+	COUNTER_FILE_OPS
+	COUNTER_NETWORK
+	COUNTER_PROCESS
+	COUNTER_CONTEXT_SWITCH
+	COUNTER_RETRANSMIT
+	COUNTER_DROP
+	COUNTER_PAGE_FAULT
+	COUNTER_RECLAIM
+	COUNTER_PROCESS_NEW
+	COUNTER_PROCESS_EXIT
+	COUNTER_UPROBE
+	COUNTER_USDT
+	COUNTER_EGRESS_BYTES
+	COUNTER_DNS_QUERY
+	COUNTER_KINDS
+)
+
+type Metrics struct {
+	Execve          uint64
+	FileOps         uint64
+	Network         uint64
+	Process         uint64
+	ContextSwitches uint64
+	Retransmits     uint64
+	Drops           uint64
+	PageFaults      uint64
+	Reclaims        uint64
+	Exits           uint64
+	LiveProcesses   uint64
+	UprobeCalls     uint64
+	USDTCalls       uint64
+	EgressBytes     uint64
+	DNSQueries      uint64
+	EventRate       uint64
+	Security        [SECURITY_EVENT_KINDS]uint64
+	Notable         [NOTABLE_EVENT_KINDS]uint64
+	Syscalls        [SYSCALL_CATEGORIES]uint64
+	IOLatency       [IO_LATENCY_SLOTS]uint64
+	Signals         [SIGNAL_KINDS]uint64
+	RunqLatency     [RUNQ_LATENCY_SLOTS]uint64
+	Traffic         Traffic
+	Time            time.Time
+}
+
+type Delta struct {
+	Execve          uint64
+	FileOps         uint64
+	Network         uint64
+	Process         uint64
+	ContextSwitches uint64
+	Retransmits     uint64
+	Drops           uint64
+	PageFaults      uint64
+	Reclaims        uint64
+	UprobeCalls     uint64
+	USDTCalls       uint64
+	EgressBytes     uint64
+	DNSQueries      uint64
+	Security        [SECURITY_EVENT_KINDS]uint64
+	Notable         [NOTABLE_EVENT_KINDS]uint64
+	Syscalls        [SYSCALL_CATEGORIES]uint64
+	IOLatency       [IO_LATENCY_SLOTS]uint64
+	Signals         [SIGNAL_KINDS]uint64
+	RunqLatency     [RUNQ_LATENCY_SLOTS]uint64
+	// NewDomains are the domains queried for the first time.
+	NewDomains []string
+	Elapsed    time.Duration
+}
+
+type Snapshot struct {
+	Metrics
+	Delta Delta
+}
+
+type MetricsReader struct {
+	mon       *Monitor
+	last      Metrics
+	counters  percpuReader
+	syscalls  percpuReader
+	ioLatency percpuReader
+	signals   percpuReader
+	runq      percpuReader
+	liveBase  uint64
+	liveKnown bool
+	container string
+	domains   map[uint64]bool
+}
+
+// SelectContainer makes the exec, file, network and fork counters follow
+// the container named selector, or with an ID starting with it, instead of
+// the whole system. They stay at zero until the container shows up.
+func (r *MetricsReader) SelectContainer(selector string) {
+	r.container = selector
+}
+
+// percpuReader sums the per-CPU values of an array map, with one batch
+// lookup where the kernel supports it.
+type percpuReader struct {
+	cpus    int
+	keys    []uint32
+	values  []uint64
+	noBatch bool
+}
+
+func (r *MetricsReader) ReadSnapshot() (Snapshot, error) {
+	objs := &r.mon.objs
+	cur := Metrics{Time: time.Now()}
+
+	var errs []error
+	var counters [COUNTER_KINDS]uint64
+	countersErr := r.counters.read(objs.Counters, counters[:])
+	if countersErr != nil {
+		errs = append(errs, fmt.Errorf("read counters: %w", countersErr))
+	}
+	cur.Execve = counters[COUNTER_EXECVE]
+	cur.FileOps = counters[COUNTER_FILE_OPS]
+	cur.Network = counters[COUNTER_NETWORK]
+	cur.Process = counters[COUNTER_PROCESS]
+	cur.ContextSwitches = counters[COUNTER_CONTEXT_SWITCH]
+	cur.Retransmits = counters[COUNTER_RETRANSMIT]
+	cur.Drops = counters[COUNTER_DROP]
+	if r.container != "" {
+		var selected [EVENT_KINDS]uint64
+		for _, c := range r.mon.Containers() {
+			if c.Matches(r.container) {
+				selected = c.Counts
+				break
+			}
+		}
+		cur.Execve = selected[EVENT_EXEC]
+		cur.FileOps = selected[EVENT_OPEN]
+		cur.Network = selected[EVENT_CONNECT]
+		cur.Process = selected[EVENT_FORK]
+	}
+	cur.PageFaults = counters[COUNTER_PAGE_FAULT]
+	cur.Reclaims = counters[COUNTER_RECLAIM]
+	cur.Exits = counters[COUNTER_PROCESS_EXIT]
+	cur.UprobeCalls = counters[COUNTER_UPROBE]
+	cur.USDTCalls = counters[COUNTER_USDT]
+	cur.EgressBytes = counters[COUNTER_EGRESS_BYTES]
+	cur.DNSQueries = counters[COUNTER_DNS_QUERY]
+	// The live process count is taken from /proc once and then kept up to
+	// date from the processes started and exited since.
+	started := counters[COUNTER_PROCESS_NEW]
+	if !r.liveKnown && countersErr == nil {
+		if n, err := countProcesses(PROC_PATH); err != nil {
+			errs = append(errs, fmt.Errorf("count processes: %w", err))
+		} else {
+			r.liveBase = n - (started - cur.Exits)
+			r.liveKnown = true
+		}
+	}
+	if r.liveKnown {
+		cur.LiveProcesses = r.liveBase + started - cur.Exits
+	}
+	if rate, err := readEventRate(objs.RateBuckets); err != nil {
+		errs = append(errs, fmt.Errorf("read rate_buckets: %w", err))
+	} else {
+		cur.EventRate = rate
+	}
+	if err := readSecurityEvents(r.mon.securityEvents(), &cur.Security); err != nil {
+		errs = append(errs, fmt.Errorf("read security_events: %w", err))
+	}
+	if err := readNotableEvents(objs.NotableEvents, &cur.Notable); err != nil {
+		errs = append(errs, fmt.Errorf("read notable_events: %w", err))
+	}
+	var syscalls [SYSCALL_SLOTS]uint64
+	if err := r.syscalls.read(objs.SyscallCounts, syscalls[:]); err != nil {
+		errs = append(errs, fmt.Errorf("read syscall_counts: %w", err))
+	}
+	cur.Syscalls = groupSyscalls(syscalls[:])
+	if err := r.ioLatency.read(objs.IoLatency, cur.IOLatency[:]); err != nil {
+		errs = append(errs, fmt.Errorf("read io_latency: %w", err))
+	}
+	var signals [SIGNAL_SLOTS]uint64
+	if err := r.signals.read(objs.SignalCounts, signals[:]); err != nil {
+		errs = append(errs, fmt.Errorf("read signal_counts: %w", err))
+	}
+	cur.Signals = groupSignals(signals[:])
+	if err := r.runq.read(objs.RunqLatency, cur.RunqLatency[:]); err != nil {
+		errs = append(errs, fmt.Errorf("read runq_latency: %w", err))
+	}
+	if err := readTraffic(objs.XdpTraffic, &cur.Traffic); err != nil {
+		errs = append(errs, fmt.Errorf("read xdp_traffic: %w", err))
+	}
+
+	snap := Snapshot{Metrics: cur}
+	if !r.last.Time.IsZero() {
+		prev := r.last
+		snap.Delta = Delta{
+			Execve:          counterDelta(prev.Execve, cur.Execve),
+			FileOps:         counterDelta(prev.FileOps, cur.FileOps),
+			Network:         counterDelta(prev.Network, cur.Network),
+			Process:         counterDelta(prev.Process, cur.Process),
+			ContextSwitches: counterDelta(prev.ContextSwitches, cur.ContextSwitches),
+			Retransmits:     counterDelta(prev.Retransmits, cur.Retransmits),
+			Drops:           counterDelta(prev.Drops, cur.Drops),
+			PageFaults:      counterDelta(prev.PageFaults, cur.PageFaults),
+			Reclaims:        counterDelta(prev.Reclaims, cur.Reclaims),
+			UprobeCalls:     counterDelta(prev.UprobeCalls, cur.UprobeCalls),
+			USDTCalls:       counterDelta(prev.USDTCalls, cur.USDTCalls),
+			EgressBytes:     counterDelta(prev.EgressBytes, cur.EgressBytes),
+			DNSQueries:      counterDelta(prev.DNSQueries, cur.DNSQueries),
+			Elapsed:         cur.Time.Sub(prev.Time),
+		}
+		for i := range cur.Security {
+			snap.Delta.Security[i] = counterDelta(prev.Security[i], cur.Security[i])
+		}
+		for i := range cur.Notable {
+			snap.Delta.Notable[i] = counterDelta(prev.Notable[i], cur.Notable[i])
+		}
+		for i := range cur.Syscalls {
+			snap.Delta.Syscalls[i] = counterDelta(prev.Syscalls[i], cur.Syscalls[i])
+		}
+		for i := range cur.IOLatency {
+			snap.Delta.IOLatency[i] = counterDelta(prev.IOLatency[i], cur.IOLatency[i])
+		}
+		for i := range cur.Signals {
+			snap.Delta.Signals[i] = counterDelta(prev.Signals[i], cur.Signals[i])
+		}
+		for i := range cur.RunqLatency {
+			snap.Delta.RunqLatency[i] = counterDelta(prev.RunqLatency[i], cur.RunqLatency[i])
+		}
+	}
+	snap.Delta.NewDomains = r.newDomains()
+	r.last = cur
+	return snap, errors.Join(errs...)
+}
+
+func (r *percpuReader) read(m *ebpf.Map, out []uint64) error {
+	if !r.noBatch {
+		err := r.batchRead(m, out)
+		if !errors.Is(err, ebpf.ErrNotSupported) {
+			return err
+		}
+		r.noBatch = true
+	}
+	for i := range out {
+		v, err := readPerCPUCounter(m, uint32(i))
+		if err != nil {
+			return err
+		}
+		out[i] = v
+	}
+	return nil
+}
+
+func (r *percpuReader) batchRead(m *ebpf.Map, out []uint64) error {
+	if r.cpus == 0 {
+		cpus, err := ebpf.PossibleCPU()
+		if err != nil {
+			return err
+		}
+		r.cpus = cpus
+		r.keys = make([]uint32, len(out))
+		r.values = make([]uint64, len(out)*cpus)
+	}
+
+	countLookup()
+	var cursor ebpf.MapBatchCursor
+	n, err := m.BatchLookup(&cursor, r.keys, r.values, nil)
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if int(r.keys[i]) >= len(out) {
+			continue
+		}
+		var sum uint64
+		for _, v := range r.values[i*r.cpus : (i+1)*r.cpus] {
+			sum += v
+		}
+		out[r.keys[i]] = sum
+	}
+	return nil
+}
+
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+func readCounter(m *ebpf.Map, key uint32) (uint64, error) {
+	var value uint64
+	countLookup()
+	if err := m.Lookup(&key, &value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+func readPerCPUCounter(m *ebpf.Map, key uint32) (uint64, error) {
+	var values []uint64
+	countLookup()
+	if err := m.Lookup(&key, &values); err != nil {
+		return 0, err
+	}
+	var sum uint64
+	for _, v := range values {
+		sum += v
+	}
+	return sum, nil
+}
+
+func readCounters(m *ebpf.Map, values []uint64) error {
+	if m == nil {
+		return nil
+	}
+	for i := range values {
+		v, err := readCounter(m, uint32(i))
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package ebpfmon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+)
+
+//go:generate make -C ../bpf generate
+
+type Monitor struct {
+	objs       snakeObjects
+	lsm        *snakeLsmObjects
+	lsmErr     error
+	fentry     *ebpf.CollectionSpec
+	fentryErr  error
+	multi      snakeMultiObjects
+	multiErr   error
+	cgroups    *CgroupResolver
+	containers *ContainerResolver
+	reusedPins bool
+}
+
+// Load loads the eBPF objects. With a pinDir the counting maps are pinned
+// there, and maps a previous run pinned there are reused, so their counts
+// carry on.
+func Load(btfPath, pinDir string) (*Monitor, error) {
+	opts, err := collectionOptions(btfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{}
+	if pinDir != "" {
+		if opts, m.reusedPins, err = pinOptions(opts, pinDir); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkObject("snake_bpfel.o", _SnakeBytes); err != nil {
+		return nil, newLoadError(err)
+	}
+	spec, err := loadSnake()
+	if err != nil {
+		return nil, newLoadError(fmt.Errorf("load embedded objects: %w", err))
+	}
+	if err := loadPinned(spec, &m.objs, opts); err != nil {
+		return nil, newLoadError(fmt.Errorf("load embedded objects: %w", err))
+	}
+
+	// Without the LSM objects, why is recorded as the failure of the hooks.
+	if !lsmEnabled() {
+		m.lsmErr = ErrLSMUnavailable
+	} else if m.lsm, m.lsmErr = loadLSM(opts); m.lsmErr != nil {
+		m.lsmErr = fmt.Errorf("load LSM objects: %w", m.lsmErr)
+	}
+
+	multiOpts := ebpf.CollectionOptions{MapReplacements: m.maps()}
+	if opts != nil {
+		multiOpts.Programs = opts.Programs
+	}
+	// Without kprobe.multi support the programs stay nil and only single
+	// kprobes are tried, after a kprobe.multi target that fails with why.
+	if err := checkObject("snake_multi_bpfel.o", _SnakeMultiBytes); err != nil {
+		m.multiErr = err
+	} else if err := loadSnakeMultiObjects(&m.multi, &multiOpts); err != nil {
+		m.multiErr = fmt.Errorf("load kprobe.multi objects: %w", err)
+	}
+
+	// fentry stays nil when the kernel cannot run fentry programs. When
+	// the objects fail to load, that is the failure of every fentry target.
+	if fentrySupported() {
+		if m.fentry, m.fentryErr = loadFentrySpec(); m.fentryErr != nil {
+			m.fentryErr = fmt.Errorf("load fentry objects: %w", m.fentryErr)
+		}
+	}
+
+	return m, nil
+}
+
+// ReusedPins reports whether the maps were pinned by an earlier run.
+func (m *Monitor) ReusedPins() bool {
+	return m.reusedPins
+}
+
+func (m *Monitor) LSM() bool {
+	return m.lsm != nil
+}
+
+// Attach attaches every probe except the disabled ones, named by program
+// (handle_execve), program without its prefix (execve) or group (exec).
+func (m *Monitor) Attach(disabled ...string) (*AttachManager, error) {
+	probes, err := attachProbes(m.probePlans(), disabled)
+	if err != nil {
+		return nil, err
+	}
+	attachLSMHooks(m.lsm, m.lsmErr, probes)
+	if err := probes.checkDisabled(); err != nil {
+		probes.Close()
+		return nil, err
+	}
+	return probes, nil
+}
+
+func (m *Monitor) securityEvents() *ebpf.Map {
+	if m.lsm == nil {
+		return nil
+	}
+	return m.lsm.SecurityEvents
+}
+
+func (m *Monitor) NewMetricsReader() *MetricsReader {
+	return &MetricsReader{mon: m}
+}
+
+func (m *Monitor) TopCgroups(n int) []CgroupCount {
+	if m.cgroups == nil {
+		m.cgroups = NewCgroupResolver()
+	}
+	return m.cgroups.TopCgroups(m.objs.CgroupEvents, n)
+}
+
+// Containers returns the events of every container seen so far, busiest
+// first.
+func (m *Monitor) Containers() []ContainerCount {
+	if m.cgroups == nil {
+		m.cgroups = NewCgroupResolver()
+	}
+	if m.containers == nil {
+		m.containers = NewContainerResolver(m.cgroups)
+	}
+	return m.containers.Containers(m.objs.CgroupCounters)
+}
+
+func (m *Monitor) Processes() []ProcessCount {
+	return readProcesses(m.objs.PidEvents)
+}
+
+// NewEventReader starts reading kernel events from the ring buffer until
+// ctx is cancelled or the reader is closed.
+func (m *Monitor) NewEventReader(ctx context.Context) (*EventReader, error) {
+	r, err := newEventReader(m.objs.Events)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { r.Close() })
+	return r, nil
+}
+
+func (m *Monitor) NewExecWatchlist(binaries []string) (*ExecWatchlist, error) {
+	return newExecWatchlist(m.objs.ExecWatchlist, binaries)
+}
+
+func (m *Monitor) Close() error {
+	if m.lsm != nil {
+		m.lsm.Close()
+	}
+	m.multi.Close()
+	return m.objs.Close()
+}
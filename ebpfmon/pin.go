@@ -0,0 +1,68 @@
+package ebpfmon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+)
+
+// BPFFS_PATH is where the BPF filesystem is mounted, and where -pin
+// directories usually go.
+const BPFFS_PATH = "/sys/fs/bpf"
+
+// unpinnedMaps belong to one run: its event stream, its filter and watchlist,
+// and the start times of what it is timing. Every other map counts, and is
+// pinned so the counts carry over to the next run.
+var unpinnedMaps = map[string]bool{
+	"events":         true,
+	"event_rate":     true,
+	"exec_watchlist": true,
+	"filter_cgroup":  true,
+	"filter_flags":   true,
+	"filter_pids":    true,
+	"filter_uids":    true,
+	"io_start":       true,
+	"rate_buckets":   true,
+	"runq_start":     true,
+}
+
+// pinMaps makes the counting maps of spec pinned by name, so loading it
+// reuses the maps already pinned in the pin directory.
+func pinMaps(spec *ebpf.CollectionSpec) {
+	for name, m := range spec.Maps {
+		if !unpinnedMaps[name] {
+			m.Pinning = ebpf.PinByName
+		}
+	}
+}
+
+// pinOptions returns opts with maps pinned in dir, which is created when it
+// does not exist. reused reports whether maps were already pinned there.
+func pinOptions(opts *ebpf.CollectionOptions, dir string) (*ebpf.CollectionOptions, bool, error) {
+	if opts == nil {
+		opts = &ebpf.CollectionOptions{}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, false, fmt.Errorf("create pin directory: %w", err)
+	}
+	_, err := os.Stat(filepath.Join(dir, "counters"))
+	reused := err == nil
+	opts.Maps.PinPath = dir
+	return opts, reused, nil
+}
+
+// loadPinned loads spec into obj, with its counting maps pinned in
+// opts.Maps.PinPath when that is set.
+func loadPinned(spec *ebpf.CollectionSpec, obj any, opts *ebpf.CollectionOptions) error {
+	if opts != nil && opts.Maps.PinPath != "" {
+		pinMaps(spec)
+	}
+	err := spec.LoadAndAssign(obj, opts)
+	if errors.Is(err, ebpf.ErrMapIncompatible) {
+		return fmt.Errorf("%w: the maps pinned in %s are from another version, remove the directory to start over", err, opts.Maps.PinPath)
+	}
+	return err
+}
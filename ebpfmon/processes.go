@@ -0,0 +1,67 @@
+package ebpfmon
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+type ProcessCount struct {
+	PID   uint32
+	Comm  string
+	Count uint64
+}
+
+func readProcesses(m *ebpf.Map) []ProcessCount {
+	if m == nil {
+		return nil
+	}
+	var procs []ProcessCount
+	var pid uint32
+	var stats snakePidStats
+	countLookup()
+	iter := m.Iterate()
+	for iter.Next(&pid, &stats) {
+		procs = append(procs, ProcessCount{PID: pid, Comm: commString(stats.Comm), Count: stats.Count})
+	}
+	return procs
+}
+
+const PROC_PATH = "/proc"
+
+// countProcesses counts the process directories in procPath.
+func countProcesses(procPath string) (uint64, error) {
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, e := range entries {
+		if _, err := strconv.ParseUint(e.Name(), 10, 32); err == nil && e.IsDir() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TopProcesses(procs []ProcessCount, n int) []ProcessCount {
+	top := append([]ProcessCount(nil), procs...)
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+func commString(comm [TASK_COMM_LEN]int8) string {
+	buf := make([]byte, len(comm))
+	for i, c := range comm {
+		buf[i] = byte(c)
+	}
+	return unix.ByteSliceToString(buf)
+}
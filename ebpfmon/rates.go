@@ -0,0 +1,83 @@
+package ebpfmon
+
+import "time"
+
+const RATE_WINDOW = time.Second
+
+// MEMORY_PRESSURE_RATE is the direct reclaim rate, per second, above which
+// memory is considered under pressure. Direct reclaim means an allocation
+// had to wait for the kernel to free pages, which a healthy system rarely
+// does.
+const MEMORY_PRESSURE_RATE = 1
+
+type Rate struct {
+	Execve          float64
+	FileOps         float64
+	Network         float64
+	Process         float64
+	ContextSwitches float64
+	Retransmits     float64
+	Drops           float64
+	PageFaults      float64
+	Reclaims        float64
+	UprobeCalls     float64
+	USDTCalls       float64
+	RxPackets       float64
+	RxBytes         float64
+	EgressBytes     float64
+	DNSQueries      float64
+	Syscalls        [SYSCALL_CATEGORIES]float64
+	// IOLatency is the block I/O latency histogram, in requests per second.
+	IOLatency [IO_LATENCY_SLOTS]float64
+	// RunqLatency is the run-queue latency histogram, in wakeups per second.
+	RunqLatency [RUNQ_LATENCY_SLOTS]float64
+}
+
+type Rates struct {
+	window  time.Duration
+	samples []Metrics
+}
+
+func NewRates(window time.Duration) *Rates {
+	return &Rates{window: window}
+}
+
+func (r *Rates) Update(m Metrics) Rate {
+	r.samples = append(r.samples, m)
+	for len(r.samples) > 2 && m.Time.Sub(r.samples[1].Time) >= r.window {
+		r.samples = r.samples[1:]
+	}
+
+	oldest := r.samples[0]
+	elapsed := m.Time.Sub(oldest.Time).Seconds()
+	if elapsed <= 0 {
+		return Rate{}
+	}
+	rate := Rate{
+		Execve:          float64(counterDelta(oldest.Execve, m.Execve)) / elapsed,
+		FileOps:         float64(counterDelta(oldest.FileOps, m.FileOps)) / elapsed,
+		Network:         float64(counterDelta(oldest.Network, m.Network)) / elapsed,
+		Process:         float64(counterDelta(oldest.Process, m.Process)) / elapsed,
+		ContextSwitches: float64(counterDelta(oldest.ContextSwitches, m.ContextSwitches)) / elapsed,
+		Retransmits:     float64(counterDelta(oldest.Retransmits, m.Retransmits)) / elapsed,
+		Drops:           float64(counterDelta(oldest.Drops, m.Drops)) / elapsed,
+		PageFaults:      float64(counterDelta(oldest.PageFaults, m.PageFaults)) / elapsed,
+		Reclaims:        float64(counterDelta(oldest.Reclaims, m.Reclaims)) / elapsed,
+		UprobeCalls:     float64(counterDelta(oldest.UprobeCalls, m.UprobeCalls)) / elapsed,
+		USDTCalls:       float64(counterDelta(oldest.USDTCalls, m.USDTCalls)) / elapsed,
+		RxPackets:       float64(counterDelta(oldest.Traffic.TotalPackets(), m.Traffic.TotalPackets())) / elapsed,
+		RxBytes:         float64(counterDelta(oldest.Traffic.TotalBytes(), m.Traffic.TotalBytes())) / elapsed,
+		EgressBytes:     float64(counterDelta(oldest.EgressBytes, m.EgressBytes)) / elapsed,
+		DNSQueries:      float64(counterDelta(oldest.DNSQueries, m.DNSQueries)) / elapsed,
+	}
+	for i := range rate.Syscalls {
+		rate.Syscalls[i] = float64(counterDelta(oldest.Syscalls[i], m.Syscalls[i])) / elapsed
+	}
+	for i := range rate.IOLatency {
+		rate.IOLatency[i] = float64(counterDelta(oldest.IOLatency[i], m.IOLatency[i])) / elapsed
+	}
+	for i := range rate.RunqLatency {
+		rate.RunqLatency[i] = float64(counterDelta(oldest.RunqLatency[i], m.RunqLatency[i])) / elapsed
+	}
+	return rate
+}
@@ -0,0 +1,109 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	NOTABLE_OOM_KILL = iota
+	NOTABLE_LISTEN
+	NOTABLE_EVENT_KINDS
+)
+
+const TASK_COMM_LEN = 16
+
+type Rule struct {
+	Threshold uint64
+	Value     func(m Metrics) uint64
+	Message   func(delta uint64) string
+}
+
+var NotableRules = []Rule{
+	{
+		Threshold: 1,
+		Value:     func(m Metrics) uint64 { return m.Notable[NOTABLE_OOM_KILL] },
+		Message:   func(delta uint64) string { return fmt.Sprintf("💀 OOM kill (%d)", delta) },
+	},
+	{
+		Threshold: 1,
+		Value:     func(m Metrics) uint64 { return m.Notable[NOTABLE_LISTEN] },
+		Message:   func(delta uint64) string { return fmt.Sprintf("👂 new listening socket (%d)", delta) },
+	},
+	{
+		Threshold: 1,
+		Value:     func(m Metrics) uint64 { return m.Security[SECURITY_SETUID] },
+		Message:   func(delta uint64) string { return fmt.Sprintf("⚠ setuid called (%d)", delta) },
+	},
+	{
+		Threshold: 1,
+		Value:     func(m Metrics) uint64 { return m.Security[SECURITY_PTRACE] },
+		Message:   func(delta uint64) string { return fmt.Sprintf("⚠ ptrace attach (%d)", delta) },
+	},
+}
+
+func EvaluateRules(rules []Rule, prev, cur Metrics) []string {
+	var messages []string
+	for _, r := range rules {
+		before, after := r.Value(prev), r.Value(cur)
+		if after > before && after-before >= r.Threshold {
+			messages = append(messages, r.Message(after-before))
+		}
+	}
+	return messages
+}
+
+func readNotableEvents(m *ebpf.Map, events *[NOTABLE_EVENT_KINDS]uint64) error {
+	return readCounters(m, events[:])
+}
+
+type ExecWatchlist struct {
+	m    *ebpf.Map
+	hits map[string]uint64
+}
+
+func newExecWatchlist(m *ebpf.Map, binaries []string) (*ExecWatchlist, error) {
+	w := &ExecWatchlist{m: m, hits: make(map[string]uint64)}
+	if m == nil {
+		return w, nil
+	}
+	for _, name := range binaries {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var zero uint64
+		if err := m.Put(commKey(name), &zero); err != nil {
+			return nil, fmt.Errorf("add %s to exec watchlist: %w", name, err)
+		}
+		w.hits[name] = 0
+	}
+	return w, nil
+}
+
+func commKey(name string) [TASK_COMM_LEN]byte {
+	var key [TASK_COMM_LEN]byte
+	copy(key[:TASK_COMM_LEN-1], name)
+	return key
+}
+
+func (w *ExecWatchlist) Poll() []string {
+	if w.m == nil {
+		return nil
+	}
+	var messages []string
+	for name, prev := range w.hits {
+		var count uint64
+		countLookup()
+		if err := w.m.Lookup(commKey(name), &count); err != nil {
+			continue
+		}
+		if count > prev {
+			messages = append(messages, fmt.Sprintf("👀 exec %s", name))
+			w.hits[name] = count
+		}
+	}
+	return messages
+}
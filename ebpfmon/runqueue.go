@@ -0,0 +1,5 @@
+package ebpfmon
+
+// RUNQ_LATENCY_SLOTS matches the runq_latency map, which has the slots of
+// io_latency, so IOLatencyPercentile reads it too.
+const RUNQ_LATENCY_SLOTS = IO_LATENCY_SLOTS
@@ -0,0 +1,86 @@
+package ebpfmon
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	SECURITY_EXEC = iota
+	SECURITY_SETUID
+	SECURITY_PTRACE
+	SECURITY_EVENT_KINDS
+)
+
+// ErrLSMUnavailable is the failure recorded for the LSM hooks on kernels
+// that do not run BPF LSM programs.
+var ErrLSMUnavailable = errors.New("BPF LSM is not enabled (bpf is not listed in /sys/kernel/security/lsm)")
+
+// lsmHooks are the programs of the LSM objects.
+var lsmHooks = []string{"handle_bprm_check", "handle_task_fix_setuid", "handle_ptrace_access_check"}
+
+func lsmEnabled() bool {
+	data, err := os.ReadFile("/sys/kernel/security/lsm")
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		if name == "bpf" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadLSM loads the LSM objects, with their counters pinned like those of
+// the main objects.
+func loadLSM(opts *ebpf.CollectionOptions) (*snakeLsmObjects, error) {
+	if err := checkObject("snake_lsm_bpfel.o", _SnakeLsmBytes); err != nil {
+		return nil, err
+	}
+	spec, err := loadSnakeLsm()
+	if err != nil {
+		return nil, err
+	}
+	objs := &snakeLsmObjects{}
+	if err := loadPinned(spec, objs, opts); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+func lsmPlans(objs *snakeLsmObjects) []attachPlan {
+	var plans []attachPlan
+	for _, hook := range []struct {
+		name string
+		prog *ebpf.Program
+	}{
+		{"handle_bprm_check", objs.HandleBprmCheck},
+		{"handle_task_fix_setuid", objs.HandleTaskFixSetuid},
+		{"handle_ptrace_access_check", objs.HandlePtraceAccessCheck},
+	} {
+		plans = append(plans, attachPlan{program: hook.name, targets: []attachTarget{{mechanism: ATTACH_LSM, name: hook.name, prog: hook.prog}}})
+	}
+	return plans
+}
+
+// attachLSMHooks attaches the hooks of objs, or records loadErr for each
+// of them when the LSM objects were not loaded.
+func attachLSMHooks(objs *snakeLsmObjects, loadErr error, probes *AttachManager) {
+	if objs == nil {
+		for _, program := range lsmHooks {
+			probes.fail(program, loadErr)
+		}
+		return
+	}
+	for _, plan := range lsmPlans(objs) {
+		probes.attach(plan.program, plan.targets...)
+	}
+}
+
+func readSecurityEvents(m *ebpf.Map, events *[SECURITY_EVENT_KINDS]uint64) error {
+	return readCounters(m, events[:])
+}
@@ -0,0 +1,47 @@
+package ebpfmon
+
+import "syscall"
+
+// SIGNAL_SLOTS is the size of signal_counts, which counts by signal number.
+const SIGNAL_SLOTS = 65
+
+// SignalKind groups the signals counted on signal:signal_generate.
+type SignalKind int
+
+const (
+	SIGNAL_KILL SignalKind = iota
+	SIGNAL_SEGV
+	SIGNAL_TERM
+	SIGNAL_OTHER
+	SIGNAL_KINDS
+)
+
+var signalKindNames = [SIGNAL_KINDS]string{"SIGKILL", "SIGSEGV", "SIGTERM", "other"}
+
+func (k SignalKind) String() string {
+	if k < 0 || k >= SIGNAL_KINDS {
+		return "unknown"
+	}
+	return signalKindNames[k]
+}
+
+func SignalKindOf(sig int) SignalKind {
+	switch syscall.Signal(sig) {
+	case syscall.SIGKILL:
+		return SIGNAL_KILL
+	case syscall.SIGSEGV:
+		return SIGNAL_SEGV
+	case syscall.SIGTERM:
+		return SIGNAL_TERM
+	}
+	return SIGNAL_OTHER
+}
+
+// groupSignals adds up per-signal counts by kind.
+func groupSignals(counts []uint64) [SIGNAL_KINDS]uint64 {
+	var out [SIGNAL_KINDS]uint64
+	for sig, count := range counts {
+		out[SignalKindOf(sig)] += count
+	}
+	return out
+}
@@ -0,0 +1,329 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64 || arm || arm64 || loong64 || mips64le || mipsle || ppc64le || riscv64 || wasm
+
+package ebpfmon
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type snakeCgroupCounts struct {
+	_     structs.HostLayout
+	Count [4]uint64
+}
+
+type snakeDnsQuery struct {
+	_     structs.HostLayout
+	Count uint64
+	Name  [64]int8
+}
+
+type snakeIoRequest struct {
+	_      structs.HostLayout
+	Dev    uint32
+	Pad    uint32
+	Sector uint64
+}
+
+type snakePidStats struct {
+	_     structs.HostLayout
+	Count uint64
+	Comm  [16]int8
+}
+
+type snakeRateBucket struct {
+	_     structs.HostLayout
+	Slot  uint64
+	Count uint64
+}
+
+type snakeXdpCounts struct {
+	_       structs.HostLayout
+	Packets uint64
+	Bytes   uint64
+}
+
+// loadSnake returns the embedded CollectionSpec for snake.
+func loadSnake() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_SnakeBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load snake: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadSnakeObjects loads snake and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*snakeObjects
+//	*snakePrograms
+//	*snakeMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadSnakeObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadSnake()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// snakeSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type snakeSpecs struct {
+	snakeProgramSpecs
+	snakeMapSpecs
+	snakeVariableSpecs
+}
+
+// snakeProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type snakeProgramSpecs struct {
+	HandleBlockComplete   *ebpf.ProgramSpec `ebpf:"handle_block_complete"`
+	HandleBlockIssue      *ebpf.ProgramSpec `ebpf:"handle_block_issue"`
+	HandleContextSwitch   *ebpf.ProgramSpec `ebpf:"handle_context_switch"`
+	HandleContextSwitchTp *ebpf.ProgramSpec `ebpf:"handle_context_switch_tp"`
+	HandleDnsQuery        *ebpf.ProgramSpec `ebpf:"handle_dns_query"`
+	HandleEgress          *ebpf.ProgramSpec `ebpf:"handle_egress"`
+	HandleExecve          *ebpf.ProgramSpec `ebpf:"handle_execve"`
+	HandleExecveTp        *ebpf.ProgramSpec `ebpf:"handle_execve_tp"`
+	HandleFileOpen        *ebpf.ProgramSpec `ebpf:"handle_file_open"`
+	HandleFileOpenTp      *ebpf.ProgramSpec `ebpf:"handle_file_open_tp"`
+	HandleListen          *ebpf.ProgramSpec `ebpf:"handle_listen"`
+	HandleNetworkConnect  *ebpf.ProgramSpec `ebpf:"handle_network_connect"`
+	HandleOomKill         *ebpf.ProgramSpec `ebpf:"handle_oom_kill"`
+	HandleOomKillTp       *ebpf.ProgramSpec `ebpf:"handle_oom_kill_tp"`
+	HandlePageFault       *ebpf.ProgramSpec `ebpf:"handle_page_fault"`
+	HandlePageFaultTp     *ebpf.ProgramSpec `ebpf:"handle_page_fault_tp"`
+	HandleProcessExit     *ebpf.ProgramSpec `ebpf:"handle_process_exit"`
+	HandleProcessFork     *ebpf.ProgramSpec `ebpf:"handle_process_fork"`
+	HandleRawSyscall      *ebpf.ProgramSpec `ebpf:"handle_raw_syscall"`
+	HandleReclaim         *ebpf.ProgramSpec `ebpf:"handle_reclaim"`
+	HandleReclaimTp       *ebpf.ProgramSpec `ebpf:"handle_reclaim_tp"`
+	HandleRunqSwitch      *ebpf.ProgramSpec `ebpf:"handle_runq_switch"`
+	HandleRunqWakeup      *ebpf.ProgramSpec `ebpf:"handle_runq_wakeup"`
+	HandleRunqWakeupNew   *ebpf.ProgramSpec `ebpf:"handle_runq_wakeup_new"`
+	HandleSchedExec       *ebpf.ProgramSpec `ebpf:"handle_sched_exec"`
+	HandleSignal          *ebpf.ProgramSpec `ebpf:"handle_signal"`
+	HandleSkbDrop         *ebpf.ProgramSpec `ebpf:"handle_skb_drop"`
+	HandleSkbDropTp       *ebpf.ProgramSpec `ebpf:"handle_skb_drop_tp"`
+	HandleTaskNew         *ebpf.ProgramSpec `ebpf:"handle_task_new"`
+	HandleTcpRetransmit   *ebpf.ProgramSpec `ebpf:"handle_tcp_retransmit"`
+	HandleTcpRetransmitTp *ebpf.ProgramSpec `ebpf:"handle_tcp_retransmit_tp"`
+	HandleUprobe          *ebpf.ProgramSpec `ebpf:"handle_uprobe"`
+	HandleUsdt            *ebpf.ProgramSpec `ebpf:"handle_usdt"`
+	HandleXdp             *ebpf.ProgramSpec `ebpf:"handle_xdp"`
+}
+
+// snakeMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type snakeMapSpecs struct {
+	CgroupCounters *ebpf.MapSpec `ebpf:"cgroup_counters"`
+	CgroupEvents   *ebpf.MapSpec `ebpf:"cgroup_events"`
+	Counters       *ebpf.MapSpec `ebpf:"counters"`
+	DnsQueries     *ebpf.MapSpec `ebpf:"dns_queries"`
+	EventRate      *ebpf.MapSpec `ebpf:"event_rate"`
+	Events         *ebpf.MapSpec `ebpf:"events"`
+	ExecWatchlist  *ebpf.MapSpec `ebpf:"exec_watchlist"`
+	FilterCgroup   *ebpf.MapSpec `ebpf:"filter_cgroup"`
+	FilterFlags    *ebpf.MapSpec `ebpf:"filter_flags"`
+	FilterPids     *ebpf.MapSpec `ebpf:"filter_pids"`
+	FilterUids     *ebpf.MapSpec `ebpf:"filter_uids"`
+	IoLatency      *ebpf.MapSpec `ebpf:"io_latency"`
+	IoStart        *ebpf.MapSpec `ebpf:"io_start"`
+	NotableEvents  *ebpf.MapSpec `ebpf:"notable_events"`
+	PidEvents      *ebpf.MapSpec `ebpf:"pid_events"`
+	RateBuckets    *ebpf.MapSpec `ebpf:"rate_buckets"`
+	RunqLatency    *ebpf.MapSpec `ebpf:"runq_latency"`
+	RunqStart      *ebpf.MapSpec `ebpf:"runq_start"`
+	SignalCounts   *ebpf.MapSpec `ebpf:"signal_counts"`
+	SyscallCounts  *ebpf.MapSpec `ebpf:"syscall_counts"`
+	XdpTraffic     *ebpf.MapSpec `ebpf:"xdp_traffic"`
+}
+
+// snakeVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type snakeVariableSpecs struct {
+}
+
+// snakeObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadSnakeObjects or ebpf.CollectionSpec.LoadAndAssign.
+type snakeObjects struct {
+	snakePrograms
+	snakeMaps
+	snakeVariables
+}
+
+func (o *snakeObjects) Close() error {
+	return _SnakeClose(
+		&o.snakePrograms,
+		&o.snakeMaps,
+	)
+}
+
+// snakeMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadSnakeObjects or ebpf.CollectionSpec.LoadAndAssign.
+type snakeMaps struct {
+	CgroupCounters *ebpf.Map `ebpf:"cgroup_counters"`
+	CgroupEvents   *ebpf.Map `ebpf:"cgroup_events"`
+	Counters       *ebpf.Map `ebpf:"counters"`
+	DnsQueries     *ebpf.Map `ebpf:"dns_queries"`
+	EventRate      *ebpf.Map `ebpf:"event_rate"`
+	Events         *ebpf.Map `ebpf:"events"`
+	ExecWatchlist  *ebpf.Map `ebpf:"exec_watchlist"`
+	FilterCgroup   *ebpf.Map `ebpf:"filter_cgroup"`
+	FilterFlags    *ebpf.Map `ebpf:"filter_flags"`
+	FilterPids     *ebpf.Map `ebpf:"filter_pids"`
+	FilterUids     *ebpf.Map `ebpf:"filter_uids"`
+	IoLatency      *ebpf.Map `ebpf:"io_latency"`
+	IoStart        *ebpf.Map `ebpf:"io_start"`
+	NotableEvents  *ebpf.Map `ebpf:"notable_events"`
+	PidEvents      *ebpf.Map `ebpf:"pid_events"`
+	RateBuckets    *ebpf.Map `ebpf:"rate_buckets"`
+	RunqLatency    *ebpf.Map `ebpf:"runq_latency"`
+	RunqStart      *ebpf.Map `ebpf:"runq_start"`
+	SignalCounts   *ebpf.Map `ebpf:"signal_counts"`
+	SyscallCounts  *ebpf.Map `ebpf:"syscall_counts"`
+	XdpTraffic     *ebpf.Map `ebpf:"xdp_traffic"`
+}
+
+func (m *snakeMaps) Close() error {
+	return _SnakeClose(
+		m.CgroupCounters,
+		m.CgroupEvents,
+		m.Counters,
+		m.DnsQueries,
+		m.EventRate,
+		m.Events,
+		m.ExecWatchlist,
+		m.FilterCgroup,
+		m.FilterFlags,
+		m.FilterPids,
+		m.FilterUids,
+		m.IoLatency,
+		m.IoStart,
+		m.NotableEvents,
+		m.PidEvents,
+		m.RateBuckets,
+		m.RunqLatency,
+		m.RunqStart,
+		m.SignalCounts,
+		m.SyscallCounts,
+		m.XdpTraffic,
+	)
+}
+
+// snakeVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadSnakeObjects or ebpf.CollectionSpec.LoadAndAssign.
+type snakeVariables struct {
+}
+
+// snakePrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadSnakeObjects or ebpf.CollectionSpec.LoadAndAssign.
+type snakePrograms struct {
+	HandleBlockComplete   *ebpf.Program `ebpf:"handle_block_complete"`
+	HandleBlockIssue      *ebpf.Program `ebpf:"handle_block_issue"`
+	HandleContextSwitch   *ebpf.Program `ebpf:"handle_context_switch"`
+	HandleContextSwitchTp *ebpf.Program `ebpf:"handle_context_switch_tp"`
+	HandleDnsQuery        *ebpf.Program `ebpf:"handle_dns_query"`
+	HandleEgress          *ebpf.Program `ebpf:"handle_egress"`
+	HandleExecve          *ebpf.Program `ebpf:"handle_execve"`
+	HandleExecveTp        *ebpf.Program `ebpf:"handle_execve_tp"`
+	HandleFileOpen        *ebpf.Program `ebpf:"handle_file_open"`
+	HandleFileOpenTp      *ebpf.Program `ebpf:"handle_file_open_tp"`
+	HandleListen          *ebpf.Program `ebpf:"handle_listen"`
+	HandleNetworkConnect  *ebpf.Program `ebpf:"handle_network_connect"`
+	HandleOomKill         *ebpf.Program `ebpf:"handle_oom_kill"`
+	HandleOomKillTp       *ebpf.Program `ebpf:"handle_oom_kill_tp"`
+	HandlePageFault       *ebpf.Program `ebpf:"handle_page_fault"`
+	HandlePageFaultTp     *ebpf.Program `ebpf:"handle_page_fault_tp"`
+	HandleProcessExit     *ebpf.Program `ebpf:"handle_process_exit"`
+	HandleProcessFork     *ebpf.Program `ebpf:"handle_process_fork"`
+	HandleRawSyscall      *ebpf.Program `ebpf:"handle_raw_syscall"`
+	HandleReclaim         *ebpf.Program `ebpf:"handle_reclaim"`
+	HandleReclaimTp       *ebpf.Program `ebpf:"handle_reclaim_tp"`
+	HandleRunqSwitch      *ebpf.Program `ebpf:"handle_runq_switch"`
+	HandleRunqWakeup      *ebpf.Program `ebpf:"handle_runq_wakeup"`
+	HandleRunqWakeupNew   *ebpf.Program `ebpf:"handle_runq_wakeup_new"`
+	HandleSchedExec       *ebpf.Program `ebpf:"handle_sched_exec"`
+	HandleSignal          *ebpf.Program `ebpf:"handle_signal"`
+	HandleSkbDrop         *ebpf.Program `ebpf:"handle_skb_drop"`
+	HandleSkbDropTp       *ebpf.Program `ebpf:"handle_skb_drop_tp"`
+	HandleTaskNew         *ebpf.Program `ebpf:"handle_task_new"`
+	HandleTcpRetransmit   *ebpf.Program `ebpf:"handle_tcp_retransmit"`
+	HandleTcpRetransmitTp *ebpf.Program `ebpf:"handle_tcp_retransmit_tp"`
+	HandleUprobe          *ebpf.Program `ebpf:"handle_uprobe"`
+	HandleUsdt            *ebpf.Program `ebpf:"handle_usdt"`
+	HandleXdp             *ebpf.Program `ebpf:"handle_xdp"`
+}
+
+func (p *snakePrograms) Close() error {
+	return _SnakeClose(
+		p.HandleBlockComplete,
+		p.HandleBlockIssue,
+		p.HandleContextSwitch,
+		p.HandleContextSwitchTp,
+		p.HandleDnsQuery,
+		p.HandleEgress,
+		p.HandleExecve,
+		p.HandleExecveTp,
+		p.HandleFileOpen,
+		p.HandleFileOpenTp,
+		p.HandleListen,
+		p.HandleNetworkConnect,
+		p.HandleOomKill,
+		p.HandleOomKillTp,
+		p.HandlePageFault,
+		p.HandlePageFaultTp,
+		p.HandleProcessExit,
+		p.HandleProcessFork,
+		p.HandleRawSyscall,
+		p.HandleReclaim,
+		p.HandleReclaimTp,
+		p.HandleRunqSwitch,
+		p.HandleRunqWakeup,
+		p.HandleRunqWakeupNew,
+		p.HandleSchedExec,
+		p.HandleSignal,
+		p.HandleSkbDrop,
+		p.HandleSkbDropTp,
+		p.HandleTaskNew,
+		p.HandleTcpRetransmit,
+		p.HandleTcpRetransmitTp,
+		p.HandleUprobe,
+		p.HandleUsdt,
+		p.HandleXdp,
+	)
+}
+
+func _SnakeClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed snake_bpfel.o
+var _SnakeBytes []byte
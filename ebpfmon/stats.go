@@ -0,0 +1,62 @@
+package ebpfmon
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// ProgramStats is what an attached program has cost the kernel since
+// statistics were enabled.
+type ProgramStats struct {
+	Program  string
+	RunCount uint64
+	Runtime  time.Duration
+}
+
+// EnableStats makes the kernel count how often and for how long every BPF
+// program runs, until the returned closer is closed. Counting has a small
+// cost of its own, so it is off by default.
+func EnableStats() (io.Closer, error) {
+	return ebpf.EnableStats(uint32(unix.BPF_STATS_RUN_TIME))
+}
+
+// statsProgram returns a handle on the program l runs to read its
+// statistics from, or nil.
+func statsProgram(l link.Link, target attachTarget) *ebpf.Program {
+	if info, err := l.Info(); err == nil {
+		if prog, err := ebpf.NewProgramFromID(info.Program); err == nil {
+			return prog
+		}
+	}
+	if target.prog != nil {
+		if prog, err := target.prog.Clone(); err == nil {
+			return prog
+		}
+	}
+	return nil
+}
+
+// Stats returns the statistics of every attached program, most expensive
+// first. They stay at zero unless EnableStats was called.
+func (m *AttachManager) Stats() []ProgramStats {
+	seen := make(map[string]bool)
+	var stats []ProgramStats
+	for _, r := range m.attached {
+		if r.prog == nil || seen[r.program] {
+			continue
+		}
+		s, err := r.prog.Stats()
+		if err != nil {
+			continue
+		}
+		seen[r.program] = true
+		stats = append(stats, ProgramStats{Program: r.program, RunCount: s.RunCount, Runtime: s.Runtime})
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Runtime > stats[j].Runtime })
+	return stats
+}
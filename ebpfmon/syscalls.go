@@ -0,0 +1,90 @@
+package ebpfmon
+
+import "golang.org/x/sys/unix"
+
+// SYSCALL_SLOTS matches the size of the syscall_counts map, one slot per
+// syscall number.
+const SYSCALL_SLOTS = 512
+
+// SyscallCategory groups the syscalls counted on raw_syscalls:sys_enter.
+type SyscallCategory int
+
+const (
+	SYSCALL_IO SyscallCategory = iota
+	SYSCALL_NET
+	SYSCALL_PROC
+	SYSCALL_MEM
+	SYSCALL_OTHER
+	SYSCALL_CATEGORIES
+)
+
+var syscallCategoryNames = [SYSCALL_CATEGORIES]string{
+	SYSCALL_IO:    "io",
+	SYSCALL_NET:   "net",
+	SYSCALL_PROC:  "proc",
+	SYSCALL_MEM:   "mem",
+	SYSCALL_OTHER: "other",
+}
+
+func (c SyscallCategory) String() string {
+	return syscallCategoryNames[c]
+}
+
+// syscallCategories maps the syscall numbers of the architecture the game
+// was built for to their category. Anything missing is SYSCALL_OTHER.
+var syscallCategories = func() map[uintptr]SyscallCategory {
+	groups := map[SyscallCategory][]uintptr{
+		SYSCALL_IO: {
+			unix.SYS_READ, unix.SYS_WRITE, unix.SYS_PREAD64, unix.SYS_PWRITE64,
+			unix.SYS_READV, unix.SYS_WRITEV, unix.SYS_OPENAT, unix.SYS_CLOSE,
+			unix.SYS_LSEEK, unix.SYS_FSYNC, unix.SYS_FDATASYNC, unix.SYS_GETDENTS64,
+			unix.SYS_STATX, unix.SYS_IOCTL, unix.SYS_FCNTL, unix.SYS_PPOLL,
+			unix.SYS_EPOLL_PWAIT, unix.SYS_SPLICE, unix.SYS_IO_URING_ENTER,
+			unix.SYS_READLINKAT, unix.SYS_FACCESSAT, unix.SYS_UNLINKAT,
+			unix.SYS_RENAMEAT2, unix.SYS_MKDIRAT,
+		},
+		SYSCALL_NET: {
+			unix.SYS_SOCKET, unix.SYS_CONNECT, unix.SYS_ACCEPT4, unix.SYS_BIND,
+			unix.SYS_LISTEN, unix.SYS_SENDTO, unix.SYS_RECVFROM, unix.SYS_SENDMSG,
+			unix.SYS_RECVMSG, unix.SYS_SENDMMSG, unix.SYS_RECVMMSG, unix.SYS_SHUTDOWN,
+			unix.SYS_GETSOCKOPT, unix.SYS_SETSOCKOPT, unix.SYS_GETSOCKNAME,
+			unix.SYS_GETPEERNAME, unix.SYS_SOCKETPAIR,
+		},
+		SYSCALL_PROC: {
+			unix.SYS_CLONE, unix.SYS_CLONE3, unix.SYS_EXECVE, unix.SYS_EXECVEAT,
+			unix.SYS_EXIT, unix.SYS_EXIT_GROUP, unix.SYS_WAIT4, unix.SYS_WAITID,
+			unix.SYS_KILL, unix.SYS_TGKILL, unix.SYS_GETPID, unix.SYS_GETTID,
+			unix.SYS_SCHED_YIELD, unix.SYS_FUTEX, unix.SYS_NANOSLEEP,
+			unix.SYS_CLOCK_NANOSLEEP, unix.SYS_RT_SIGACTION, unix.SYS_RT_SIGPROCMASK,
+			unix.SYS_RT_SIGRETURN, unix.SYS_PRCTL,
+		},
+		SYSCALL_MEM: {
+			unix.SYS_MUNMAP, unix.SYS_MPROTECT, unix.SYS_BRK, unix.SYS_MREMAP,
+			unix.SYS_MADVISE, unix.SYS_MLOCK, unix.SYS_MUNLOCK, unix.SYS_MSYNC,
+			unix.SYS_MINCORE, unix.SYS_MEMFD_CREATE,
+		},
+	}
+	m := make(map[uintptr]SyscallCategory)
+	for category, numbers := range groups {
+		for _, nr := range numbers {
+			m[nr] = category
+		}
+	}
+	return m
+}()
+
+func SyscallCategoryOf(nr int) SyscallCategory {
+	if c, ok := syscallCategories[uintptr(nr)]; ok {
+		return c
+	}
+	return SYSCALL_OTHER
+}
+
+// groupSyscalls adds up per-syscall counts by category.
+func groupSyscalls(counts []uint64) [SYSCALL_CATEGORIES]uint64 {
+	var out [SYSCALL_CATEGORIES]uint64
+	for nr, count := range counts {
+		out[SyscallCategoryOf(nr)] += count
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+package ebpfmon
+
+// Uprobe is a function in a user program or library whose calls are
+// counted as UprobeCalls.
+type Uprobe struct {
+	Binary string
+	Symbol string
+}
+
+func (u Uprobe) String() string {
+	return u.Binary + ":" + u.Symbol
+}
+
+// AttachUprobe attaches handle_uprobe to u and records it with the other
+// probes.
+func (m *Monitor) AttachUprobe(probes *AttachManager, u Uprobe) error {
+	target := attachTarget{mechanism: ATTACH_UPROBE, group: u.Binary, name: u.Symbol, prog: m.objs.HandleUprobe}
+	if !probes.attach("handle_uprobe", target) {
+		return probes.Failure("handle_uprobe")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package ebpfmon
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+)
+
+const (
+	STAPSDT_NOTE_SECTION = ".note.stapsdt"
+	STAPSDT_BASE_SECTION = ".stapsdt.base"
+	STAPSDT_NOTE_OWNER   = "stapsdt"
+	STAPSDT_NOTE_TYPE    = 3
+)
+
+// USDT is a statically defined probe in a user program or library, e.g.
+// python:function__entry, whose hits are counted as USDTCalls.
+type USDT struct {
+	Provider string
+	Name     string
+	Path     string
+}
+
+func (u USDT) String() string {
+	return u.Provider + ":" + u.Name + "@" + u.Path
+}
+
+// usdtSite is one place a probe is compiled into, as file offsets.
+// semaphore is 0 when the probe has none.
+type usdtSite struct {
+	address   uint64
+	semaphore uint64
+}
+
+// usdtSites finds every site of u in the stapsdt notes of its ELF file.
+func usdtSites(u USDT) ([]usdtSite, error) {
+	f, err := elf.Open(u.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", u.Path, err)
+	}
+	defer f.Close()
+
+	section := f.Section(STAPSDT_NOTE_SECTION)
+	if section == nil {
+		return nil, fmt.Errorf("%s has no USDT probes", u.Path)
+	}
+	data, err := section.Data()
+	if err != nil {
+		return nil, fmt.Errorf("read %s of %s: %w", STAPSDT_NOTE_SECTION, u.Path, err)
+	}
+	// Prelinked files record where .stapsdt.base was when the notes were
+	// written; addresses move with it.
+	var base uint64
+	if s := f.Section(STAPSDT_BASE_SECTION); s != nil {
+		base = s.Addr
+	}
+	addrSize := 8
+	if f.Class == elf.ELFCLASS32 {
+		addrSize = 4
+	}
+
+	var sites []usdtSite
+	for len(data) >= 12 {
+		nameSize := int(f.ByteOrder.Uint32(data[0:]))
+		descSize := int(f.ByteOrder.Uint32(data[4:]))
+		noteType := f.ByteOrder.Uint32(data[8:])
+		descStart := 12 + align4(nameSize)
+		next := descStart + align4(descSize)
+		if next > len(data) || descSize < 3*addrSize {
+			return nil, fmt.Errorf("malformed %s in %s", STAPSDT_NOTE_SECTION, u.Path)
+		}
+		owner := string(bytes.TrimRight(data[12:12+nameSize], "\x00"))
+		desc := data[descStart : descStart+descSize]
+		data = data[next:]
+		if noteType != STAPSDT_NOTE_TYPE || owner != STAPSDT_NOTE_OWNER {
+			continue
+		}
+
+		addr := readAddr(f, desc, addrSize)
+		noteBase := readAddr(f, desc[addrSize:], addrSize)
+		semaphore := readAddr(f, desc[2*addrSize:], addrSize)
+		// provider, name and the argument format follow, NUL-terminated.
+		names := bytes.SplitN(desc[3*addrSize:], []byte{0}, 3)
+		if len(names) < 2 || string(names[0]) != u.Provider || string(names[1]) != u.Name {
+			continue
+		}
+		if base != 0 && noteBase != 0 {
+			addr += base - noteBase
+		}
+		site := usdtSite{}
+		var ok bool
+		if site.address, ok = fileOffset(f, addr); !ok {
+			return nil, fmt.Errorf("USDT probe %s at %#x is outside the loaded segments", u, addr)
+		}
+		if semaphore != 0 {
+			if site.semaphore, ok = fileOffset(f, semaphore); !ok {
+				return nil, fmt.Errorf("semaphore of USDT probe %s at %#x is outside the loaded segments", u, semaphore)
+			}
+		}
+		sites = append(sites, site)
+	}
+	if len(sites) == 0 {
+		return nil, fmt.Errorf("no USDT probe %s:%s in %s", u.Provider, u.Name, u.Path)
+	}
+	return sites, nil
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+func readAddr(f *elf.File, b []byte, size int) uint64 {
+	if size == 4 {
+		return uint64(f.ByteOrder.Uint32(b))
+	}
+	return f.ByteOrder.Uint64(b)
+}
+
+// fileOffset converts a virtual address of f into an offset in the file.
+func fileOffset(f *elf.File, addr uint64) (uint64, bool) {
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && addr >= p.Vaddr && addr < p.Vaddr+p.Filesz {
+			return addr - p.Vaddr + p.Off, true
+		}
+	}
+	return 0, false
+}
+
+// AttachUSDT attaches handle_usdt at every site of u and records it with
+// the other probes.
+func (m *Monitor) AttachUSDT(probes *AttachManager, u USDT) error {
+	sites, err := usdtSites(u)
+	if err != nil {
+		return err
+	}
+	targets := make([]attachTarget, 0, len(sites))
+	for _, site := range sites {
+		targets = append(targets, attachTarget{
+			mechanism: ATTACH_USDT,
+			group:     u.Path,
+			name:      u.Name,
+			prog:      m.objs.HandleUsdt,
+			address:   site.address,
+			semaphore: site.semaphore,
+		})
+	}
+	if !probes.attachEach("handle_usdt", targets...) {
+		return probes.Failure("handle_usdt")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package ebpfmon
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// XDP_PROTO_* are the slots of the xdp_traffic map.
+const (
+	XDP_PROTO_TCP = iota
+	XDP_PROTO_UDP
+	XDP_PROTO_ICMP
+	XDP_PROTO_OTHER
+	XDP_PROTOS
+)
+
+// XDP_BYTES_PER_EVENT is how much received traffic weighs as much as one
+// TCP connect when the network food rate comes from XDP.
+const XDP_BYTES_PER_EVENT = 64 * 1024
+
+var xdpProtoNames = [XDP_PROTOS]string{"tcp", "udp", "icmp", "other"}
+
+func XDPProtoName(proto int) string {
+	return xdpProtoNames[proto]
+}
+
+// Traffic is what XDP saw arrive, by protocol.
+type Traffic struct {
+	Packets [XDP_PROTOS]uint64
+	Bytes   [XDP_PROTOS]uint64
+}
+
+func (t Traffic) TotalPackets() uint64 {
+	var n uint64
+	for _, p := range t.Packets {
+		n += p
+	}
+	return n
+}
+
+func (t Traffic) TotalBytes() uint64 {
+	var n uint64
+	for _, b := range t.Bytes {
+		n += b
+	}
+	return n
+}
+
+func readTraffic(m *ebpf.Map, t *Traffic) error {
+	for proto := range XDP_PROTOS {
+		key := uint32(proto)
+		var values []snakeXdpCounts
+		countLookup()
+		if err := m.Lookup(&key, &values); err != nil {
+			return err
+		}
+		t.Packets[proto], t.Bytes[proto] = 0, 0
+		for _, v := range values {
+			t.Packets[proto] += v.Packets
+			t.Bytes[proto] += v.Bytes
+		}
+	}
+	return nil
+}
+
+// AttachXDP attaches handle_xdp to the interface named iface, in native
+// (driver) mode where the driver supports it and in generic mode otherwise.
+// It is detached with the other probes.
+func (m *Monitor) AttachXDP(probes *AttachManager, iface string) error {
+	if _, err := net.InterfaceByName(iface); err != nil {
+		return err
+	}
+	if !probes.attach("handle_xdp",
+		attachTarget{mechanism: ATTACH_XDP, name: iface, prog: m.objs.HandleXdp},
+		attachTarget{mechanism: ATTACH_XDP_GENERIC, name: iface, prog: m.objs.HandleXdp},
+	) {
+		return probes.Failure("handle_xdp")
+	}
+	return nil
+}
+
+func attachXDP(prog *ebpf.Program, iface string, mode link.XDPAttachFlags) (link.Link, error) {
+	ifc, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("find interface: %w", err)
+	}
+	return link.AttachXDP(link.XDPOptions{Program: prog, Interface: ifc.Index, Flags: mode})
+}
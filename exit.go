@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	EXIT_OK         = 0
+	EXIT_FAILURE    = 1
+	EXIT_USAGE      = 2
+	EXIT_PERMISSION = 3
+	EXIT_LOAD       = 4
+	EXIT_ATTACH     = 5
+	EXIT_PANIC      = 70
+)
+
+func exitCodeFor(err error, fallback int) int {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		return EXIT_PERMISSION
+	}
+	return fallback
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"snake-ebpf/ebpfmon"
+)
+
+type filterOptions struct {
+	uids   string
+	pids   string
+	cgroup string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterOptions {
+	f := &filterOptions{}
+	fs.StringVar(&f.uids, "filter-uid", "", "comma-separated user names or UIDs whose events are counted (all when empty)")
+	fs.StringVar(&f.pids, "filter-pid", "", "comma-separated PIDs whose events are counted (all when empty)")
+	fs.StringVar(&f.cgroup, "filter-cgroup", "", "cgroup path, e.g. /system.slice/docker.service, whose events are counted with its children (all when empty)")
+	return f
+}
+
+// filter parses the flags. The error names the flag that is wrong.
+func (f *filterOptions) filter() (ebpfmon.Filter, error) {
+	uids, err := parseIDs(f.uids, lookupUID)
+	if err != nil {
+		return ebpfmon.Filter{}, fmt.Errorf("-filter-uid: %w", err)
+	}
+	pids, err := parseIDs(f.pids, nil)
+	if err != nil {
+		return ebpfmon.Filter{}, fmt.Errorf("-filter-pid: %w", err)
+	}
+	return ebpfmon.Filter{UIDs: uids, PIDs: pids, Cgroup: f.cgroup}, nil
+}
+
+// parseIDs parses a comma-separated list of numeric IDs. Anything else is
+// passed to lookup when it is not nil.
+func parseIDs(s string, lookup func(string) (uint32, error)) ([]uint32, error) {
+	var ids []uint32
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err == nil {
+			ids = append(ids, uint32(id))
+			continue
+		}
+		if lookup == nil {
+			return nil, fmt.Errorf("invalid ID %q", field)
+		}
+		looked, err := lookup(field)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, looked)
+	}
+	return ids, nil
+}
+
+func lookupUID(name string) (uint32, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("user %s has UID %q: %w", name, u.Uid, err)
+	}
+	return uint32(uid), nil
+}
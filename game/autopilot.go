@@ -0,0 +1,100 @@
+package game
+
+// Autopilot picks the next direction for an unattended game: the first step
+// of a shortest path to the food that avoids the body, or the safe move
+// with the most room left when the food cannot be reached.
+func (g *Game) Autopilot() Position {
+	head := g.Snake[0]
+	blocked := make(map[Position]bool, len(g.Snake))
+	for _, segment := range g.Snake[:len(g.Snake)-1] {
+		blocked[segment] = true
+	}
+	if g.Enemy != nil {
+		for _, segment := range g.Enemy.Body {
+			blocked[segment] = true
+		}
+	}
+	if g.Player2 != nil {
+		for _, segment := range g.Player2.Snake {
+			blocked[segment] = true
+		}
+	}
+	for _, o := range g.Obstacles {
+		blocked[o] = true
+	}
+	if g.Poison != nil {
+		blocked[g.Poison.Pos] = true
+	}
+
+	directions := []Position{Up, Right, Down, Left}
+	first := map[Position]Position{}
+	queue := []Position{}
+	for _, dir := range directions {
+		next, ok := g.neighbor(head, dir)
+		if ok && !blocked[next] {
+			first[next] = dir
+			queue = append(queue, next)
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if g.onFood(p) {
+			return first[p]
+		}
+		for _, dir := range directions {
+			next, ok := g.neighbor(p, dir)
+			if _, seen := first[next]; seen || next == head || blocked[next] || !ok {
+				continue
+			}
+			first[next] = first[p]
+			queue = append(queue, next)
+		}
+	}
+
+	best, bestSpace := g.Direction, -1
+	for _, dir := range directions {
+		next, ok := g.neighbor(head, dir)
+		if !ok || blocked[next] {
+			continue
+		}
+		if space := g.reachable(next, blocked); space > bestSpace {
+			best, bestSpace = dir, space
+		}
+	}
+	return best
+}
+
+func (g *Game) reachable(from Position, blocked map[Position]bool) int {
+	seen := map[Position]bool{from: true, g.Snake[0]: true}
+	stack := []Position{from}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dir := range []Position{Up, Right, Down, Left} {
+			next, ok := g.neighbor(p, dir)
+			if seen[next] || blocked[next] || !ok {
+				continue
+			}
+			seen[next] = true
+			stack = append(stack, next)
+		}
+	}
+	return len(seen) - 1
+}
+
+// neighbor returns the cell next to p in direction dir, wrapping around the
+// borders in wrap mode, and whether the snake can move there at all.
+func (g *Game) neighbor(p, dir Position) (Position, bool) {
+	next := Position{X: p.X + dir.X, Y: p.Y + dir.Y}
+	b := g.Bounds()
+	if g.Wrap {
+		next = b.wrap(next)
+	}
+	return next, b.Contains(next)
+}
+
+func (g *Game) inBounds(p Position) bool {
+	return g.Bounds().Contains(p)
+}
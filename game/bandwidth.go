@@ -0,0 +1,33 @@
+package game
+
+const (
+	// BANDWIDTH_BONUS_RATE is the upload rate, in bytes per second, from
+	// which food is worth double.
+	BANDWIDTH_BONUS_RATE = 100 * 1024
+	// BANDWIDTH_BONUS_STEP is how many times more upload it takes for each
+	// further multiple.
+	BANDWIDTH_BONUS_STEP = 10
+	MAX_BANDWIDTH_BONUS  = 3
+)
+
+// BandwidthBonus is the points multiplier for an upload rate in bytes per
+// second: 1 below BANDWIDTH_BONUS_RATE and one more for every
+// BANDWIDTH_BONUS_STEP times that, up to MAX_BANDWIDTH_BONUS.
+func BandwidthBonus(bytesPerSecond float64) int {
+	bonus := 1
+	for threshold := float64(BANDWIDTH_BONUS_RATE); bytesPerSecond >= threshold && bonus < MAX_BANDWIDTH_BONUS; threshold *= BANDWIDTH_BONUS_STEP {
+		bonus++
+	}
+	return bonus
+}
+
+// SetUploadRate sets the bandwidth bonus from the bytes per second the
+// player's cgroup sends.
+func (g *Game) SetUploadRate(bytesPerSecond float64) {
+	g.uploadBonus = BandwidthBonus(bytesPerSecond)
+}
+
+// UploadBonus is the multiplier food is currently worth, 1 without upload.
+func (g *Game) UploadBonus() int {
+	return max(g.uploadBonus, 1)
+}
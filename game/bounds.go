@@ -0,0 +1,112 @@
+package game
+
+import "time"
+
+const (
+	// MAX_INSET is how many cells memory pressure may take off every edge.
+	MAX_INSET = 3
+	// BOUNDS_MIN_SIZE is the smallest playfield the board shrinks to.
+	BOUNDS_MIN_SIZE = 4
+	// INSET_STEP is how often the playfield shrinks or grows by one cell.
+	INSET_STEP = 5 * time.Second
+)
+
+// Bounds is the part of the board in play, from Min up to but not including
+// Max. Everything else is wall.
+type Bounds struct {
+	Min, Max Position
+}
+
+func (b Bounds) Contains(p Position) bool {
+	return p.X >= b.Min.X && p.X < b.Max.X && p.Y >= b.Min.Y && p.Y < b.Max.Y
+}
+
+// wrap brings a cell that left the playfield back in on the opposite side.
+func (b Bounds) wrap(p Position) Position {
+	w, h := b.Max.X-b.Min.X, b.Max.Y-b.Min.Y
+	p.X = b.Min.X + ((p.X-b.Min.X)%w+w)%w
+	p.Y = b.Min.Y + ((p.Y-b.Min.Y)%h+h)%h
+	return p
+}
+
+func boundsFor(width, height, inset int) Bounds {
+	return Bounds{Min: Position{X: inset, Y: inset}, Max: Position{X: width - inset, Y: height - inset}}
+}
+
+func (g *Game) Bounds() Bounds {
+	return boundsFor(g.Width, g.Height, g.inset)
+}
+
+func insetFits(width, height, inset int) bool {
+	return inset >= 0 && inset <= MAX_INSET && width-2*inset >= BOUNDS_MIN_SIZE && height-2*inset >= BOUNDS_MIN_SIZE
+}
+
+// SetMemoryPressure shrinks the playfield by a cell on every edge while
+// memory pressure is high and grows it back once it is low, by at most one
+// cell per INSET_STEP. It never closes in on the snake. changed reports
+// whether the playfield moved.
+func (g *Game) SetMemoryPressure(high bool) (changed bool) {
+	now := g.Clock()
+	if now.Sub(g.insetChanged) < INSET_STEP {
+		return false
+	}
+	inset := g.inset - 1
+	if high {
+		inset = g.inset + 1
+	}
+	if !insetFits(g.Width, g.Height, inset) {
+		return false
+	}
+	b := boundsFor(g.Width, g.Height, inset)
+	if !g.snakesFit(b) {
+		return false
+	}
+	g.inset = inset
+	g.insetChanged = now
+	g.fitItems(b.Contains)
+	return true
+}
+
+// fitItems drops obstacles, the power-up, poison and extra and bonus food
+// outside the playfield and places the enemy and food again if they no longer fit.
+func (g *Game) fitItems(fits func(Position) bool) {
+	obstacles := g.Obstacles[:0]
+	for _, o := range g.Obstacles {
+		if fits(o) {
+			obstacles = append(obstacles, o)
+		}
+	}
+	g.Obstacles = obstacles
+	if p := g.PowerUp; p != nil && !fits(p.Pos) {
+		g.PowerUp = nil
+	}
+	if p := g.Poison; p != nil && !fits(p.Pos) {
+		g.Poison = nil
+	}
+	if g.Enemy != nil {
+		for _, segment := range g.Enemy.Body {
+			if !fits(segment) {
+				g.spawnEnemy()
+				break
+			}
+		}
+	}
+	if !fits(g.Food) {
+		g.SpawnFood()
+	}
+	food := g.ExtraFood[:0]
+	for _, item := range g.ExtraFood {
+		if fits(item.Pos) {
+			food = append(food, item)
+		}
+	}
+	g.ExtraFood = food
+	bonus := g.BonusFood[:0]
+	for _, item := range g.BonusFood {
+		if fits(item.Pos) {
+			bonus = append(bonus, item)
+		}
+	}
+	g.BonusFood = bonus
+	g.fillFood()
+}
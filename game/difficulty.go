@@ -0,0 +1,45 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+const POLL_INTERVAL = 350 * time.Millisecond
+
+// Difficulty bundles every knob that makes a round harder.
+type Difficulty struct {
+	Name         string
+	BaseInterval time.Duration
+	// ActivityScale multiplies how much kernel activity speeds up the game.
+	ActivityScale float64
+	// Growth is the number of segments the snake grows per food.
+	Growth int
+	// ObstacleEvery places a new obstacle each time this many foods have
+	// been eaten; 0 disables obstacles.
+	ObstacleEvery int
+}
+
+var Difficulties = []Difficulty{
+	{Name: "easy", BaseInterval: 450 * time.Millisecond, ActivityScale: 0.5, Growth: 2},
+	{Name: "normal", BaseInterval: POLL_INTERVAL, ActivityScale: 1, Growth: 3},
+	{Name: "hard", BaseInterval: 250 * time.Millisecond, ActivityScale: 1.5, Growth: 3, ObstacleEvery: 5},
+	{Name: "kernel", BaseInterval: 200 * time.Millisecond, ActivityScale: 2.5, Growth: 4, ObstacleEvery: 3},
+}
+
+func LookupDifficulty(name string) (Difficulty, error) {
+	for _, d := range Difficulties {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return Difficulty{}, fmt.Errorf("unknown difficulty %q", name)
+}
+
+func DifficultyNames() []string {
+	names := make([]string, len(Difficulties))
+	for i, d := range Difficulties {
+		names[i] = d.Name
+	}
+	return names
+}
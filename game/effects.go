@@ -0,0 +1,61 @@
+package game
+
+// Effect is a brief board-wide shock, set off by signals on the system. It
+// lasts a number of ticks rather than a time, so it hits just as hard at
+// any speed.
+type Effect int
+
+const (
+	// EFFECT_REVERSE turns every direction key into its opposite.
+	EFFECT_REVERSE Effect = iota
+	// EFFECT_STALL holds the snake in place.
+	EFFECT_STALL
+	EFFECT_KINDS
+)
+
+// SHOCK_TICKS is how many ticks a shock lasts.
+const SHOCK_TICKS = 2
+
+var effectNames = [EFFECT_KINDS]string{"reversed", "stalled"}
+
+func (e Effect) String() string {
+	if e < 0 || e >= EFFECT_KINDS {
+		return "unknown"
+	}
+	return effectNames[e]
+}
+
+// Shock starts effect for the next ticks ticks, or makes it last that long
+// if it is already running.
+func (g *Game) Shock(effect Effect, ticks int) {
+	g.effectTicks[effect] = max(g.effectTicks[effect], ticks)
+}
+
+// Affected reports whether effect is running.
+func (g *Game) Affected(effect Effect) bool {
+	return g.effectTicks[effect] > 0
+}
+
+// EffectTicks returns how many more ticks effect runs.
+func (g *Game) EffectTicks(effect Effect) int {
+	return g.effectTicks[effect]
+}
+
+// Steer queues a turn of the snake the way the player asked, or the
+// opposite way while EFFECT_REVERSE runs, for the next move that has none
+// queued yet.
+func (g *Game) Steer(dir Position) bool {
+	if g.Affected(EFFECT_REVERSE) {
+		dir = Position{X: -dir.X, Y: -dir.Y}
+	}
+	if g.DirectTurns {
+		return g.Turn(dir)
+	}
+	return g.turns.push(g.Direction, dir)
+}
+
+func (g *Game) countDownEffects() {
+	for e := range g.effectTicks {
+		g.effectTicks[e] = max(g.effectTicks[e]-1, 0)
+	}
+}
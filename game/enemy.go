@@ -0,0 +1,136 @@
+package game
+
+const (
+	ENEMY_MIN_LENGTH = 2
+	ENEMY_MAX_LENGTH = 12
+	ENEMY_MIN_SPEED  = 0.25
+	ENEMY_MAX_SPEED  = 1.0
+)
+
+// Enemy is the "kernel" snake. It chases the player and grows and speeds up
+// with the context-switch rate, so system load becomes a visible adversary.
+type Enemy struct {
+	Body      []Position
+	Direction Position
+	Speed     float64
+	length    int
+	progress  float64
+}
+
+func (g *Game) EnableEnemy() {
+	g.Enemy = &Enemy{}
+	g.spawnEnemy()
+}
+
+func (g *Game) spawnEnemy() {
+	e := g.Enemy
+	e.Body = e.Body[:0]
+	corner := g.Bounds().Min
+	for i := ENEMY_MIN_LENGTH - 1; i >= 0; i-- {
+		e.Body = append(e.Body, Position{X: corner.X + i, Y: corner.Y})
+	}
+	e.Direction = Right
+	e.Speed = ENEMY_MIN_SPEED
+	e.length = ENEMY_MIN_LENGTH
+	e.progress = 0
+}
+
+// SetEnemyLoad scales the enemy's target length and speed with the
+// context-switch rate in switches per second.
+func (g *Game) SetEnemyLoad(contextSwitchRate float64) {
+	if g.Enemy == nil {
+		return
+	}
+	g.Enemy.length = min(ENEMY_MIN_LENGTH+int(contextSwitchRate/1500), ENEMY_MAX_LENGTH)
+	g.Enemy.Speed = min(ENEMY_MIN_SPEED+contextSwitchRate/20000, ENEMY_MAX_SPEED)
+}
+
+func (g *Game) onEnemy(p Position) bool {
+	if g.Enemy == nil {
+		return false
+	}
+	for _, segment := range g.Enemy.Body {
+		if p == segment {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) stepEnemy() bool {
+	e := g.Enemy
+	if e == nil {
+		return false
+	}
+	e.progress += e.Speed
+	moved := false
+	for e.progress >= 1 && !g.GameOver {
+		e.progress--
+		dir, ok := g.enemyDirection()
+		if !ok {
+			continue
+		}
+		e.Direction = dir
+		head := e.Body[0]
+		newHead := Position{X: head.X + dir.X, Y: head.Y + dir.Y}
+		e.Body = append([]Position{newHead}, e.Body...)
+		for len(e.Body) > e.length {
+			e.Body = e.Body[:len(e.Body)-1]
+		}
+		if len(e.Body) < e.length {
+			e.Body = append(e.Body, e.Body[len(e.Body)-1])
+		}
+		if newHead == g.Snake[0] && !g.Protected() {
+			g.GameOver = true
+		}
+		moved = true
+	}
+	return moved
+}
+
+// enemyDirection greedily closes in on the player's head, never reversing
+// and never running into a wall, itself or the player's body.
+func (g *Game) enemyDirection() (Position, bool) {
+	e := g.Enemy
+	head := e.Body[0]
+	target := g.Snake[0]
+	best, bestDist := Position{}, -1
+	for _, dir := range []Position{Up, Right, Down, Left} {
+		if dir.X == -e.Direction.X && dir.Y == -e.Direction.Y {
+			continue
+		}
+		next := Position{X: head.X + dir.X, Y: head.Y + dir.Y}
+		if !g.inBounds(next) || g.enemyBlocked(next) {
+			continue
+		}
+		dist := abs(next.X-target.X) + abs(next.Y-target.Y)
+		if bestDist < 0 || dist < bestDist || dist == bestDist && dir == e.Direction {
+			best, bestDist = dir, dist
+		}
+	}
+	return best, bestDist >= 0
+}
+
+func (g *Game) enemyBlocked(p Position) bool {
+	for _, segment := range g.Enemy.Body[:len(g.Enemy.Body)-1] {
+		if p == segment {
+			return true
+		}
+	}
+	if g.onObstacle(p) || g.onPlayer2(p) {
+		return true
+	}
+	for _, segment := range g.Snake[1:] {
+		if p == segment {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
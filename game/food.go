@@ -0,0 +1,172 @@
+package game
+
+type FoodKind int
+
+const (
+	FOOD_EXEC FoodKind = iota
+	FOOD_FILE
+	FOOD_NETWORK
+	FOOD_FORK
+	// FOOD_USDT only comes from a -usdt probe, never by chance.
+	FOOD_USDT
+	// FOOD_DNS is bonus food for a newly queried domain.
+	FOOD_DNS
+	FOOD_KINDS
+)
+
+var foodNames = [FOOD_KINDS]string{"exec", "file", "network", "fork", "usdt", "dns"}
+
+// FoodPoints rewards the rarer event sources with more points.
+var FoodPoints = [FOOD_KINDS]int{
+	FOOD_EXEC:    2,
+	FOOD_FILE:    1,
+	FOOD_NETWORK: 3,
+	FOOD_FORK:    2,
+	FOOD_USDT:    3,
+	FOOD_DNS:     5,
+}
+
+func (k FoodKind) String() string {
+	if k < 0 || k >= FOOD_KINDS {
+		return "unknown"
+	}
+	return foodNames[k]
+}
+
+// SetFoodRates sets the recent per-second rate of each event source; the
+// kind of the next food is picked with probability proportional to it.
+func (g *Game) SetFoodRates(rates [FOOD_KINDS]float64) {
+	g.foodRates = rates
+}
+
+func (g *Game) pickFoodKind() FoodKind {
+	total := 0.0
+	for _, r := range g.foodRates {
+		total += max(r, 0)
+	}
+	if total == 0 {
+		return FoodKind(g.rng.IntN(int(FOOD_USDT)))
+	}
+	pick := g.rng.Float64() * total
+	last := FOOD_EXEC
+	for k, r := range g.foodRates {
+		if r <= 0 {
+			continue
+		}
+		pick -= r
+		if pick < 0 {
+			return FoodKind(k)
+		}
+		last = FoodKind(k)
+	}
+	// Rounding can leave a little of pick over.
+	return last
+}
+
+const (
+	// MAX_FOOD is the most food items on the board at once.
+	MAX_FOOD = 5
+	// MAX_BONUS_FOOD is the most bonus food on the board at once, on top of
+	// MAX_FOOD.
+	MAX_BONUS_FOOD = 3
+	// FOOD_PROCESSES is the live process count that brings a second food
+	// item; every doubling of it brings one more.
+	FOOD_PROCESSES = 200
+)
+
+// FoodItem is food on the board besides the main Food.
+type FoodItem struct {
+	Pos  Position
+	Kind FoodKind
+}
+
+// FoodCount is how many food items the board holds for a live process
+// count.
+func FoodCount(processes uint64) int {
+	n := 1
+	for p := uint64(FOOD_PROCESSES); processes >= p && n < MAX_FOOD; p *= 2 {
+		n++
+	}
+	return n
+}
+
+// SetFoodCount sets how many food items should be on the board, the main
+// Food included. Extra items are added or taken away right away.
+func (g *Game) SetFoodCount(n int) {
+	g.foodCount = min(max(n, 1), MAX_FOOD)
+	if len(g.ExtraFood) >= g.foodCount {
+		g.ExtraFood = g.ExtraFood[:g.foodCount-1]
+	}
+	g.fillFood()
+}
+
+func (g *Game) fillFood() {
+	for len(g.ExtraFood) < g.foodCount-1 {
+		p, ok := g.randomCell(func(p Position) bool {
+			return !g.occupied(p) && !g.onFood(p) && !g.onPoison(p) && !g.onPowerUp(p)
+		})
+		if !ok {
+			return
+		}
+		g.ExtraFood = append(g.ExtraFood, FoodItem{Pos: p, Kind: g.pickFoodKind()})
+	}
+}
+
+// SpawnBonusFood puts a food of kind on the board besides the regular
+// items, which is not replaced once eaten. It reports false when there are
+// MAX_BONUS_FOOD already or no free cell.
+func (g *Game) SpawnBonusFood(kind FoodKind) bool {
+	if len(g.BonusFood) >= MAX_BONUS_FOOD {
+		return false
+	}
+	p, ok := g.randomCell(func(p Position) bool {
+		return !g.occupied(p) && !g.onFood(p) && !g.onPoison(p) && !g.onPowerUp(p)
+	})
+	if !ok {
+		return false
+	}
+	g.BonusFood = append(g.BonusFood, FoodItem{Pos: p, Kind: kind})
+	return true
+}
+
+// eatFood takes the food under the head off the board. The main Food is
+// placed again right away; extra items are refilled by fillFood once the
+// snake has moved.
+func (g *Game) eatFood(head Position) (FoodKind, bool) {
+	if head == g.Food {
+		kind := g.FoodKind
+		g.SpawnFood()
+		return kind, true
+	}
+	for i, item := range g.ExtraFood {
+		if head == item.Pos {
+			g.ExtraFood = append(g.ExtraFood[:i], g.ExtraFood[i+1:]...)
+			return item.Kind, true
+		}
+	}
+	for i, item := range g.BonusFood {
+		if head == item.Pos {
+			g.BonusFood = append(g.BonusFood[:i], g.BonusFood[i+1:]...)
+			return item.Kind, true
+		}
+	}
+	return 0, false
+}
+
+func (g *Game) onFood(p Position) bool {
+	return p == g.Food || g.onExtraFood(p)
+}
+
+func (g *Game) onExtraFood(p Position) bool {
+	for _, item := range g.ExtraFood {
+		if p == item.Pos {
+			return true
+		}
+	}
+	for _, item := range g.BonusFood {
+		if p == item.Pos {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package game
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+type Position struct {
+	X, Y int
+}
+
+var (
+	Up    = Position{X: 0, Y: -1}
+	Down  = Position{X: 0, Y: 1}
+	Left  = Position{X: -1, Y: 0}
+	Right = Position{X: 1, Y: 0}
+)
+
+type Game struct {
+	Snake         []Position
+	Direction     Position
+	Food          Position
+	FoodKind      FoodKind
+	Score         int
+	GameOver      bool
+	Paused        bool
+	Wrap          bool
+	Width         int
+	Height        int
+	LastFoodSpawn time.Time
+	FoodSpawnDue  bool
+	Enemy         *Enemy
+	Player2       *Player2
+	PowerUp       *PowerUp
+	Poison        *Poison
+	Obstacles     []Position
+	ExtraFood     []FoodItem
+	BonusFood     []FoodItem
+	OOMKills      []OOMKill
+	Difficulty    Difficulty
+	Seed          uint64
+	Clock         func() time.Time
+	rng           *rand.Rand
+	foodsEaten    int
+	foodCount     int
+	foodRates     [FOOD_KINDS]float64
+	uploadBonus   int
+	effectTicks   [EFFECT_KINDS]int
+	activeUntil   [POWERUP_KINDS]time.Time
+	inset         int
+	insetChanged  time.Time
+	turns         turnQueue
+	// DirectTurns makes Steer turn at once, as replays recorded before
+	// turns were queued expect.
+	DirectTurns bool
+	startAt     time.Time
+	graceUntil  time.Time
+	// NoCountdown starts rounds at once and unprotected, as replays
+	// recorded before the countdown expect.
+	NoCountdown bool
+}
+
+func New(width, height int, seed uint64) *Game {
+	g := &Game{Width: width, Height: height, Difficulty: Difficulties[1], Seed: seed, Clock: time.Now, rng: newRNG(seed)}
+	g.Reset()
+	return g
+}
+
+func (g *Game) Level() int {
+	return g.Score / 5
+}
+
+func (g *Game) Turn(dir Position) bool {
+	if dir.X != 0 && g.Direction.X != 0 || dir.Y != 0 && g.Direction.Y != 0 {
+		return false
+	}
+	g.Direction = dir
+	return true
+}
+
+func (g *Game) Step() bool {
+	if g.GameOver || g.Countdown() > 0 {
+		return false
+	}
+
+	stalled := g.Affected(EFFECT_STALL)
+	g.countDownEffects()
+	if stalled {
+		return false
+	}
+	g.Direction = g.turns.pop(g.Direction)
+	if p := g.Player2; p != nil && !p.Remote {
+		p.Direction = p.turns.pop(p.Direction)
+	}
+	if g.Direction.X == 0 && g.Direction.Y == 0 {
+		return false
+	}
+
+	head := g.Snake[0]
+	newHead := Position{
+		X: head.X + g.Direction.X,
+		Y: head.Y + g.Direction.Y,
+	}
+
+	if b := g.Bounds(); !b.Contains(newHead) {
+		if !g.Wrap && !g.Active(POWERUP_WALL_PASS) {
+			return g.crash()
+		}
+		newHead = b.wrap(newHead)
+	}
+
+	for i := 0; i < len(g.Snake)-1; i++ {
+		segment := g.Snake[i]
+		if newHead.X == segment.X && newHead.Y == segment.Y {
+			return g.crash()
+		}
+	}
+
+	if g.onPlayer2(newHead) {
+		if g.Protected() {
+			return false
+		}
+		if next, _ := g.player2Next(); newHead == g.Player2.Snake[0] && next == head {
+			g.crashHeadOn()
+		}
+		g.GameOver = true
+		return true
+	}
+
+	if g.onEnemy(newHead) || g.onObstacle(newHead) {
+		return g.crash()
+	}
+
+	oldSnakeLen := len(g.Snake)
+	oldFood := g.Food
+	kind, ateFood := g.eatFood(newHead)
+	if ateFood {
+		g.Score += g.points(kind)
+		g.foodsEaten++
+	} else {
+		g.Snake = g.Snake[:len(g.Snake)-1]
+	}
+
+	g.Snake = append([]Position{newHead}, g.Snake...)
+
+	if ateFood {
+		for i := 0; i < g.Difficulty.Growth-1; i++ {
+			tail := g.Snake[len(g.Snake)-1]
+			g.Snake = append(g.Snake, tail)
+		}
+		if every := g.Difficulty.ObstacleEvery; every > 0 && g.foodsEaten%every == 0 {
+			g.placeObstacle()
+		}
+		g.fillFood()
+	}
+
+	g.collectPowerUp(newHead)
+	poisoned := g.eatPoison(newHead)
+	enemyMoved := g.stepEnemy()
+	player2Moved := g.stepPlayer2()
+
+	return oldSnakeLen != len(g.Snake) || newHead != head || oldFood != g.Food || enemyMoved || poisoned || player2Moved
+}
+
+func (g *Game) SpawnFood() {
+	g.FoodKind = g.pickFoodKind()
+	if p, ok := g.randomCell(func(p Position) bool { return !g.occupied(p) && !g.onPoison(p) && !g.onExtraFood(p) }); ok {
+		g.Food = p
+	}
+}
+
+func (g *Game) onSnake(p Position) bool {
+	for _, segment := range g.Snake {
+		if p == segment {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) Reset() {
+	g.inset = 0
+	g.insetChanged = time.Time{}
+	startX := g.Width / 2
+	startY := g.Height / 2
+	if g.Player2 != nil {
+		startY = g.Height / 3
+	}
+	g.Snake = []Position{
+		{startX, startY},
+		{startX - 1, startY},
+		{startX - 2, startY},
+	}
+	g.Direction = Right
+	g.turns = nil
+	if g.Enemy != nil {
+		g.spawnEnemy()
+	}
+	if g.Player2 != nil {
+		g.spawnPlayer2()
+	}
+	g.Score = 0
+	g.foodsEaten = 0
+	g.Obstacles = nil
+	g.PowerUp = nil
+	g.Poison = nil
+	g.OOMKills = nil
+	g.ExtraFood = nil
+	g.BonusFood = nil
+	g.activeUntil = [POWERUP_KINDS]time.Time{}
+	g.effectTicks = [EFFECT_KINDS]int{}
+	g.GameOver = false
+	g.Paused = false
+	g.startCountdown()
+	g.SpawnFood()
+	g.LastFoodSpawn = g.Clock()
+	g.FoodSpawnDue = false
+}
+
+// Resize changes the board size mid-game. It refuses to cut off any part of
+// either snake; obstacles outside the new board are dropped, and food, the
+// power-up and the enemy are placed again if they no longer fit. A
+// playfield shrunk by memory pressure grows back as far as the new board
+// needs.
+func (g *Game) Resize(width, height int) bool {
+	inset := g.inset
+	for inset > 0 && !insetFits(width, height, inset) {
+		inset--
+	}
+	b := boundsFor(width, height, inset)
+	if !g.snakesFit(b) {
+		return false
+	}
+	g.Width, g.Height = width, height
+	g.inset = inset
+	g.fitItems(b.Contains)
+	return true
+}
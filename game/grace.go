@@ -0,0 +1,56 @@
+package game
+
+import "time"
+
+const (
+	// COUNTDOWN is how long the snakes wait after a round is dealt before
+	// they start to move.
+	COUNTDOWN = 3 * time.Second
+	// GRACE_PERIOD is how long after the countdown a snake about to crash
+	// stops instead, so a round started while the kernel is busy and the
+	// game at its fastest does not end on the first moves.
+	GRACE_PERIOD = 2 * time.Second
+)
+
+// startCountdown holds the snakes for COUNTDOWN and protects them for
+// GRACE_PERIOD after that.
+func (g *Game) startCountdown() {
+	if g.NoCountdown {
+		g.startAt, g.graceUntil = time.Time{}, time.Time{}
+		return
+	}
+	g.startAt = g.Clock().Add(COUNTDOWN)
+	g.graceUntil = g.startAt.Add(GRACE_PERIOD)
+}
+
+// Countdown returns the seconds left, rounded up, before the snakes start
+// to move, or 0 once they do.
+func (g *Game) Countdown() int {
+	left := g.startAt.Sub(g.Clock())
+	if left <= 0 {
+		return 0
+	}
+	return int((left + time.Second - 1) / time.Second)
+}
+
+// Protected reports whether the countdown or the grace period after it
+// still runs.
+func (g *Game) Protected() bool {
+	return g.Clock().Before(g.graceUntil)
+}
+
+// GraceRemaining returns how long the snakes are still protected after
+// the countdown.
+func (g *Game) GraceRemaining() time.Duration {
+	return min(max(g.graceUntil.Sub(g.Clock()), 0), GRACE_PERIOD)
+}
+
+// crash ends the round, unless the snakes are protected, when the snake
+// about to crash stays where it is instead and waits to be turned.
+func (g *Game) crash() bool {
+	if g.Protected() {
+		return false
+	}
+	g.GameOver = true
+	return true
+}
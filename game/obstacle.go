@@ -0,0 +1,28 @@
+package game
+
+// OBSTACLE_CLEARANCE keeps new obstacles away from the snake's head, so one
+// never appears where the player cannot react.
+const OBSTACLE_CLEARANCE = 3
+
+func (g *Game) placeObstacle() {
+	head := g.Snake[0]
+	p, ok := g.randomCell(func(p Position) bool {
+		return !g.occupied(p) && !g.onFood(p) && abs(p.X-head.X)+abs(p.Y-head.Y) >= OBSTACLE_CLEARANCE
+	})
+	if ok {
+		g.Obstacles = append(g.Obstacles, p)
+	}
+}
+
+func (g *Game) onObstacle(p Position) bool {
+	for _, o := range g.Obstacles {
+		if p == o {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) occupied(p Position) bool {
+	return g.onSnake(p) || g.onPlayer2(p) || g.onEnemy(p) || g.onObstacle(p)
+}
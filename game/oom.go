@@ -0,0 +1,14 @@
+package game
+
+// OOMKill is an OOM kill that hit the snake, kept for the game-over summary.
+type OOMKill struct {
+	Score int
+	Lost  int
+}
+
+// OOMKill halves the snake, always leaving the head.
+func (g *Game) OOMKill() {
+	keep := max(len(g.Snake)/2, 1)
+	g.OOMKills = append(g.OOMKills, OOMKill{Score: g.Score, Lost: len(g.Snake) - keep})
+	g.Snake = g.Snake[:keep]
+}
@@ -0,0 +1,203 @@
+package game
+
+// Player2 is the second snake of two-player mode, steered from the same
+// keyboard. It shares the board, the food and the tick with the first
+// snake and scores on its own. Power-ups and poison are the first player's
+// alone; shocks hit both.
+//
+// A round ends as soon as either snake crashes, and the one still moving
+// wins. Running into the other snake's body is a crash; meeting it head on
+// is a draw.
+type Player2 struct {
+	Snake     []Position
+	Direction Position
+	Score     int
+	Crashed   bool
+	// Remote is set when the snake is played from another machine and
+	// moves on that machine's ticks, by StepPlayer2, instead of with the
+	// first snake.
+	Remote bool
+	headOn bool
+	turns  turnQueue
+}
+
+func (g *Game) EnablePlayer2() {
+	g.Player2 = &Player2{}
+	g.spawnPlayer2()
+}
+
+// spawnPlayer2 starts the second snake on the lower third of the board
+// heading left, opposite the first one.
+func (g *Game) spawnPlayer2() {
+	p := g.Player2
+	x, y := g.Width/2, g.Height*2/3
+	p.Snake = []Position{{x, y}, {x + 1, y}, {x + 2, y}}
+	p.Direction = Left
+	p.turns = nil
+	p.Score = 0
+	p.Crashed = false
+	p.headOn = false
+}
+
+// TurnPlayer2 turns the second snake like Steer turns the first, reversed
+// while EFFECT_REVERSE runs.
+func (g *Game) TurnPlayer2(dir Position) bool {
+	p := g.Player2
+	if p == nil {
+		return false
+	}
+	if g.Affected(EFFECT_REVERSE) {
+		dir = Position{X: -dir.X, Y: -dir.Y}
+	}
+	if !g.DirectTurns {
+		return p.turns.push(p.Direction, dir)
+	}
+	if dir.X != 0 && p.Direction.X != 0 || dir.Y != 0 && p.Direction.Y != 0 {
+		return false
+	}
+	p.Direction = dir
+	return true
+}
+
+// Winner returns the player who won a finished two-player round, 1 or 2,
+// or 0 for a draw.
+func (g *Game) Winner() int {
+	p := g.Player2
+	if p == nil || p.headOn {
+		return 0
+	}
+	if p.Crashed {
+		return 1
+	}
+	return 2
+}
+
+func (g *Game) onPlayer2(p Position) bool {
+	if g.Player2 == nil {
+		return false
+	}
+	for _, segment := range g.Player2.Snake {
+		if p == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// player2Next returns the cell the second snake moves to next, before
+// collisions.
+func (g *Game) player2Next() (Position, bool) {
+	p := g.Player2
+	head := p.Snake[0]
+	next := Position{X: head.X + p.Direction.X, Y: head.Y + p.Direction.Y}
+	if b := g.Bounds(); !b.Contains(next) {
+		if !g.Wrap {
+			return next, false
+		}
+		next = b.wrap(next)
+	}
+	return next, true
+}
+
+// crashHeadOn ends the round in a draw.
+func (g *Game) crashHeadOn() {
+	g.Player2.Crashed = true
+	g.Player2.headOn = true
+	g.GameOver = true
+}
+
+// StepPlayer2 moves a remote second snake by one cell.
+func (g *Game) StepPlayer2() bool {
+	p := g.Player2
+	if p == nil || !p.Remote || g.Paused || g.Countdown() > 0 {
+		return false
+	}
+	p.Direction = p.turns.pop(p.Direction)
+	return g.movePlayer2()
+}
+
+// stepPlayer2 moves the second snake after the first one has moved, unless
+// it moves on ticks of its own.
+func (g *Game) stepPlayer2() bool {
+	if g.Player2 == nil || g.Player2.Remote {
+		return false
+	}
+	return g.movePlayer2()
+}
+
+func (g *Game) movePlayer2() bool {
+	p := g.Player2
+	if g.GameOver {
+		return false
+	}
+	newHead, ok := g.player2Next()
+	if g.Protected() && (!ok || g.player2Crashes(newHead)) {
+		return false
+	}
+	if newHead == g.Snake[0] {
+		g.crashHeadOn()
+		return true
+	}
+	if !ok || g.onSnake(newHead) || g.onEnemy(newHead) || g.onObstacle(newHead) {
+		p.Crashed = true
+		g.GameOver = true
+		return true
+	}
+	for _, segment := range p.Snake[:len(p.Snake)-1] {
+		if newHead == segment {
+			p.Crashed = true
+			g.GameOver = true
+			return true
+		}
+	}
+
+	kind, ateFood := g.eatFood(newHead)
+	if !ateFood {
+		p.Snake = p.Snake[:len(p.Snake)-1]
+	}
+	p.Snake = append([]Position{newHead}, p.Snake...)
+	if ateFood {
+		p.Score += FoodPoints[kind] * g.UploadBonus()
+		g.foodsEaten++
+		for i := 0; i < g.Difficulty.Growth-1; i++ {
+			p.Snake = append(p.Snake, p.Snake[len(p.Snake)-1])
+		}
+		if every := g.Difficulty.ObstacleEvery; every > 0 && g.foodsEaten%every == 0 {
+			g.placeObstacle()
+		}
+		g.fillFood()
+	}
+	return true
+}
+
+// player2Crashes reports whether the second snake crashes with its head
+// moved to next, inside the board.
+func (g *Game) player2Crashes(next Position) bool {
+	if g.onSnake(next) || g.onEnemy(next) || g.onObstacle(next) {
+		return true
+	}
+	p := g.Player2
+	for _, segment := range p.Snake[:len(p.Snake)-1] {
+		if next == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// snakesFit reports whether both players' snakes are inside b.
+func (g *Game) snakesFit(b Bounds) bool {
+	for _, segment := range g.Snake {
+		if !b.Contains(segment) {
+			return false
+		}
+	}
+	if g.Player2 != nil {
+		for _, segment := range g.Player2.Snake {
+			if !b.Contains(segment) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package game
+
+import "time"
+
+const (
+	POISON_LIFETIME = 15 * time.Second
+	// POISON_SHRINK is how many segments eating poison costs; the head is
+	// always left.
+	POISON_SHRINK = 3
+)
+
+// Poison is food spawned by network trouble that shrinks the snake instead
+// of growing it.
+type Poison struct {
+	Pos     Position
+	Expires time.Time
+}
+
+// SpawnPoison places poison on a free cell, replacing any that is still on
+// the board.
+func (g *Game) SpawnPoison() bool {
+	head := g.Snake[0]
+	p, ok := g.randomCell(func(p Position) bool {
+		return !g.occupied(p) && !g.onFood(p) && !g.onPowerUp(p) && abs(p.X-head.X)+abs(p.Y-head.Y) >= OBSTACLE_CLEARANCE
+	})
+	if ok {
+		g.Poison = &Poison{Pos: p, Expires: g.Clock().Add(POISON_LIFETIME)}
+	}
+	return ok
+}
+
+func (g *Game) onPoison(p Position) bool {
+	return g.Poison != nil && g.Poison.Pos == p
+}
+
+func (g *Game) onPowerUp(p Position) bool {
+	return g.PowerUp != nil && g.PowerUp.Pos == p
+}
+
+// eatPoison shrinks the snake when its head is on the poison. ok reports
+// whether it did.
+func (g *Game) eatPoison(head Position) bool {
+	if g.Poison == nil {
+		return false
+	}
+	if g.Clock().After(g.Poison.Expires) {
+		g.Poison = nil
+		return false
+	}
+	if head != g.Poison.Pos {
+		return false
+	}
+	g.Poison = nil
+	g.Snake = g.Snake[:max(len(g.Snake)-POISON_SHRINK, 1)]
+	return true
+}
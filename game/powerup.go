@@ -0,0 +1,76 @@
+package game
+
+import "time"
+
+type PowerUpKind int
+
+const (
+	POWERUP_SLOW_MOTION PowerUpKind = iota
+	POWERUP_WALL_PASS
+	POWERUP_DOUBLE_POINTS
+	POWERUP_KINDS
+)
+
+const (
+	POWERUP_LIFETIME = 10 * time.Second
+	POWERUP_DURATION = 10 * time.Second
+)
+
+var powerUpNames = [POWERUP_KINDS]string{"slow-motion", "wall-pass", "double points"}
+
+func (k PowerUpKind) String() string {
+	if k < 0 || k >= POWERUP_KINDS {
+		return "unknown"
+	}
+	return powerUpNames[k]
+}
+
+// PowerUp is a power-up lying on the board until it is eaten or expires.
+type PowerUp struct {
+	Kind    PowerUpKind
+	Pos     Position
+	Expires time.Time
+}
+
+// SpawnPowerUp places a random power-up on a free cell, replacing any that
+// is still on the board.
+func (g *Game) SpawnPowerUp() (PowerUpKind, bool) {
+	kind := PowerUpKind(g.rng.IntN(int(POWERUP_KINDS)))
+	p, ok := g.randomCell(func(p Position) bool { return !g.occupied(p) && !g.onFood(p) && !g.onPoison(p) })
+	if ok {
+		g.PowerUp = &PowerUp{Kind: kind, Pos: p, Expires: g.Clock().Add(POWERUP_LIFETIME)}
+	}
+	return kind, ok
+}
+
+// Active reports whether a power-up effect is currently running.
+func (g *Game) Active(kind PowerUpKind) bool {
+	return g.Clock().Before(g.activeUntil[kind])
+}
+
+// Remaining returns how long a power-up effect still runs.
+func (g *Game) Remaining(kind PowerUpKind) time.Duration {
+	return max(g.activeUntil[kind].Sub(g.Clock()), 0)
+}
+
+func (g *Game) collectPowerUp(head Position) {
+	if g.PowerUp == nil {
+		return
+	}
+	if g.Clock().After(g.PowerUp.Expires) {
+		g.PowerUp = nil
+		return
+	}
+	if head == g.PowerUp.Pos {
+		g.activeUntil[g.PowerUp.Kind] = g.Clock().Add(POWERUP_DURATION)
+		g.PowerUp = nil
+	}
+}
+
+func (g *Game) points(kind FoodKind) int {
+	points := FoodPoints[kind] * g.UploadBonus()
+	if g.Active(POWERUP_DOUBLE_POINTS) {
+		return 2 * points
+	}
+	return points
+}
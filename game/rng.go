@@ -0,0 +1,44 @@
+package game
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+)
+
+// RandomSeed returns a seed from the operating system's entropy source for
+// runs that do not need to be reproducible.
+func RandomSeed() uint64 {
+	var buf [8]byte
+	crand.Read(buf[:])
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func newRNG(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// randomCell picks a cell of the playfield uniformly among those accepted
+// by free. It tries random cells first and falls back to scanning the
+// playfield when it is nearly full.
+func (g *Game) randomCell(free func(Position) bool) (Position, bool) {
+	b := g.Bounds()
+	for attempt := 0; attempt < 100; attempt++ {
+		p := Position{X: b.Min.X + g.rng.IntN(b.Max.X-b.Min.X), Y: b.Min.Y + g.rng.IntN(b.Max.Y-b.Min.Y)}
+		if free(p) {
+			return p, true
+		}
+	}
+	var cells []Position
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if p := (Position{X: x, Y: y}); free(p) {
+				cells = append(cells, p)
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return Position{}, false
+	}
+	return cells[g.rng.IntN(len(cells))], true
+}
@@ -0,0 +1,266 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const MIN_TICK_INTERVAL = 100 * time.Millisecond
+
+type Activity struct {
+	ExecveRate        float64
+	FileOpsRate       float64
+	ProcessRate       float64
+	EventRate         float64
+	ContextSwitchRate float64
+	UprobeRate        float64
+}
+
+func perSecond(rate float64, step, max time.Duration) time.Duration {
+	d := time.Duration(rate * float64(step))
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// SpeedFactor is one of the things that shorten the tick interval.
+type SpeedFactor int
+
+const (
+	SPEED_SCORE SpeedFactor = iota
+	SPEED_EXECVE
+	SPEED_PROCESS
+	SPEED_EVENT_RATE
+	SPEED_LOAD
+	SPEED_UPROBE
+	SPEED_FACTORS
+)
+
+var speedFactorNames = [SPEED_FACTORS]string{
+	SPEED_SCORE:      "score",
+	SPEED_EXECVE:     "execve",
+	SPEED_PROCESS:    "fork",
+	SPEED_EVENT_RATE: "events",
+	SPEED_LOAD:       "ctxsw",
+	SPEED_UPROBE:     "uprobe",
+}
+
+func (f SpeedFactor) String() string {
+	return speedFactorNames[f]
+}
+
+// SpeedModel maps the value of a factor, points or events per second, to
+// how much it takes off the tick interval.
+type SpeedModel interface {
+	Reduction(value float64) time.Duration
+}
+
+// LinearModel takes Step off per unit.
+type LinearModel struct {
+	Step time.Duration
+}
+
+func (m LinearModel) Reduction(value float64) time.Duration {
+	return time.Duration(value * float64(m.Step))
+}
+
+// LogModel takes Step off every time the value doubles, so the first few
+// events per second count the most.
+type LogModel struct {
+	Step time.Duration
+}
+
+func (m LogModel) Reduction(value float64) time.Duration {
+	return time.Duration(math.Log2(1+max(value, 0)) * float64(m.Step))
+}
+
+// SigmoidModel hardly reacts to a quiet system, takes about half of Span
+// off at Midpoint and levels off towards Span above it.
+type SigmoidModel struct {
+	Span     time.Duration
+	Midpoint float64
+}
+
+func (m SigmoidModel) Reduction(value float64) time.Duration {
+	width := m.Midpoint / 4
+	sigmoid := func(x float64) float64 { return 1 / (1 + math.Exp(-(x-m.Midpoint)/width)) }
+	// Shifted and stretched so that no activity takes nothing off.
+	zero := sigmoid(0)
+	return time.Duration((sigmoid(max(value, 0)) - zero) / (1 - zero) * float64(m.Span))
+}
+
+// Curve is the shape of a SpeedModel.
+type Curve int
+
+const (
+	CURVE_LINEAR Curve = iota
+	CURVE_LOG
+	CURVE_SIGMOID
+	CURVES
+)
+
+var curveNames = [CURVES]string{
+	CURVE_LINEAR:  "linear",
+	CURVE_LOG:     "log",
+	CURVE_SIGMOID: "sigmoid",
+}
+
+func (c Curve) String() string {
+	return curveNames[c]
+}
+
+// LookupCurve finds a curve by the name its String method returns.
+func LookupCurve(name string) (Curve, bool) {
+	for c, n := range curveNames {
+		if n == name {
+			return Curve(c), true
+		}
+	}
+	return 0, false
+}
+
+func (c Curve) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Curve) UnmarshalText(text []byte) error {
+	curve, ok := LookupCurve(string(text))
+	if !ok {
+		return fmt.Errorf("unknown speed curve %q", text)
+	}
+	*c = curve
+	return nil
+}
+
+// Scaling is how one factor speeds the snake up, in the form the config
+// file gives it. Step is what the Curve takes off per unit, per doubling,
+// or in total for a sigmoid, which reaches about half of it at Midpoint. The
+// reduction is multiplied by Weight and capped at Max; a zero Max means no
+// limit.
+type Scaling struct {
+	Curve    Curve         `json:"curve"`
+	Step     time.Duration `json:"step"`
+	Weight   float64       `json:"weight"`
+	Max      time.Duration `json:"max,omitempty"`
+	Midpoint float64       `json:"midpoint,omitempty"`
+}
+
+// Model is the SpeedModel of the curve.
+func (s Scaling) Model() SpeedModel {
+	switch s.Curve {
+	case CURVE_LOG:
+		return LogModel{Step: s.Step}
+	case CURVE_SIGMOID:
+		return SigmoidModel{Span: s.Step, Midpoint: s.Midpoint}
+	}
+	return LinearModel{Step: s.Step}
+}
+
+// Validate reports settings the model cannot work with.
+func (s Scaling) Validate() error {
+	if s.Step < 0 || s.Max < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if s.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	if s.Curve == CURVE_SIGMOID && s.Midpoint <= 0 {
+		return fmt.Errorf("a sigmoid needs a midpoint above 0")
+	}
+	return nil
+}
+
+func (s Scaling) apply(value float64) time.Duration {
+	d := time.Duration(s.Weight * float64(s.Model().Reduction(value)))
+	if s.Max > 0 && d > s.Max {
+		return s.Max
+	}
+	return d
+}
+
+type SpeedScaling [SPEED_FACTORS]Scaling
+
+var DefaultSpeedScaling = SpeedScaling{
+	SPEED_SCORE:      {Step: time.Millisecond, Weight: 1},
+	SPEED_EXECVE:     {Step: time.Millisecond, Weight: 1, Max: 30 * time.Millisecond},
+	SPEED_PROCESS:    {Step: time.Millisecond, Weight: 1, Max: 25 * time.Millisecond},
+	SPEED_EVENT_RATE: {Step: time.Millisecond, Weight: 1, Max: 30 * time.Millisecond},
+	SPEED_LOAD:       {Step: time.Microsecond, Weight: 1, Max: 15 * time.Millisecond},
+	SPEED_UPROBE:     {Step: 100 * time.Microsecond, Weight: 1, Max: 100 * time.Millisecond},
+}
+
+// LookupSpeedFactor finds a factor by the name its String method returns.
+func LookupSpeedFactor(name string) (SpeedFactor, bool) {
+	for f, n := range speedFactorNames {
+		if n == name {
+			return SpeedFactor(f), true
+		}
+	}
+	return 0, false
+}
+
+// SpeedReductions is how much each factor takes off the base interval, with
+// the activity factors already scaled by the difficulty.
+type SpeedReductions [SPEED_FACTORS]time.Duration
+
+// factorValues are the values of every factor, the score and the rates
+// of the activity.
+func factorValues(score int, a Activity) [SPEED_FACTORS]float64 {
+	return [SPEED_FACTORS]float64{
+		SPEED_SCORE:      float64(score),
+		SPEED_EXECVE:     a.ExecveRate,
+		SPEED_PROCESS:    a.ProcessRate,
+		SPEED_EVENT_RATE: a.EventRate,
+		SPEED_LOAD:       a.ContextSwitchRate,
+		SPEED_UPROBE:     a.UprobeRate,
+	}
+}
+
+func NewSpeedReductions(s SpeedScaling, d Difficulty, score int, a Activity) SpeedReductions {
+	var r SpeedReductions
+	for f, value := range factorValues(score, a) {
+		r[f] = s[f].apply(value)
+		if SpeedFactor(f) != SPEED_SCORE {
+			r[f] = time.Duration(float64(r[f]) * d.ActivityScale)
+		}
+	}
+	return r
+}
+
+// Dominant returns the factor that shortens the interval the most. ok is
+// false when nothing does and the game runs at its base speed.
+func (r SpeedReductions) Dominant() (f SpeedFactor, ok bool) {
+	for i := SPEED_SCORE; i < SPEED_FACTORS; i++ {
+		if r[i] > r[f] {
+			f = i
+		}
+	}
+	return f, r[f] > 0
+}
+
+func TickInterval(d Difficulty, score int, a Activity) time.Duration {
+	return NewSpeedReductions(DefaultSpeedScaling, d, score, a).Interval(d)
+}
+
+// Interval is the tick interval left after all reductions.
+func (r SpeedReductions) Interval(d Difficulty) time.Duration {
+	interval := d.BaseInterval
+	for _, reduction := range r {
+		interval -= reduction
+	}
+	if interval < MIN_TICK_INTERVAL {
+		interval = MIN_TICK_INTERVAL
+	}
+	return interval
+}
+
+func FoodSpawnInterval(fileOpsRate float64) time.Duration {
+	fileOpsBonus := perSecond(fileOpsRate/10, 100*time.Millisecond, 3*time.Second)
+	spawnInterval := 15*time.Second - fileOpsBonus
+	if spawnInterval < 5*time.Second {
+		spawnInterval = 5 * time.Second
+	}
+	return spawnInterval
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// STATE_MAX_SIZE is the widest and tallest board a State may describe.
+const STATE_MAX_SIZE = 1000
+
+// State is everything needed to draw a game somewhere else, as the joining
+// side of a networked game does. It leaves out the random number generator
+// and the food rates, so a game set from a State can be drawn but not
+// played on.
+type State struct {
+	Snake       []Position                   `json:"snake"`
+	Direction   Position                     `json:"direction"`
+	Food        Position                     `json:"food"`
+	FoodKind    FoodKind                     `json:"food_kind"`
+	Score       int                          `json:"score"`
+	GameOver    bool                         `json:"game_over"`
+	Paused      bool                         `json:"paused"`
+	Wrap        bool                         `json:"wrap"`
+	Width       int                          `json:"width"`
+	Height      int                          `json:"height"`
+	Inset       int                          `json:"inset"`
+	Difficulty  string                       `json:"difficulty"`
+	Enemy       *Enemy                       `json:"enemy,omitempty"`
+	Player2     *Player2                     `json:"player2,omitempty"`
+	HeadOn      bool                         `json:"head_on,omitempty"`
+	PowerUp     *PowerUp                     `json:"power_up,omitempty"`
+	Poison      *Poison                      `json:"poison,omitempty"`
+	Obstacles   []Position                   `json:"obstacles"`
+	ExtraFood   []FoodItem                   `json:"extra_food"`
+	BonusFood   []FoodItem                   `json:"bonus_food"`
+	OOMKills    []OOMKill                    `json:"oom_kills,omitempty"`
+	UploadBonus int                          `json:"upload_bonus"`
+	Effects     [EFFECT_KINDS]int            `json:"effects"`
+	Remaining   [POWERUP_KINDS]time.Duration `json:"remaining"`
+	Countdown   time.Duration                `json:"countdown,omitempty"`
+	Grace       time.Duration                `json:"grace,omitempty"`
+}
+
+// State returns the game as it is now. The slices are shared with the
+// game, so it must be used before the game moves on.
+func (g *Game) State() State {
+	s := State{
+		Snake:       g.Snake,
+		Direction:   g.Direction,
+		Food:        g.Food,
+		FoodKind:    g.FoodKind,
+		Score:       g.Score,
+		GameOver:    g.GameOver,
+		Paused:      g.Paused,
+		Wrap:        g.Wrap,
+		Width:       g.Width,
+		Height:      g.Height,
+		Inset:       g.inset,
+		Difficulty:  g.Difficulty.Name,
+		Enemy:       g.Enemy,
+		Player2:     g.Player2,
+		HeadOn:      g.Player2 != nil && g.Player2.headOn,
+		PowerUp:     g.PowerUp,
+		Poison:      g.Poison,
+		Obstacles:   g.Obstacles,
+		ExtraFood:   g.ExtraFood,
+		BonusFood:   g.BonusFood,
+		OOMKills:    g.OOMKills,
+		UploadBonus: g.uploadBonus,
+		Effects:     g.effectTicks,
+	}
+	for kind := range s.Remaining {
+		s.Remaining[kind] = g.Remaining(PowerUpKind(kind))
+	}
+	s.Countdown = max(g.startAt.Sub(g.Clock()), 0)
+	s.Grace = max(g.graceUntil.Sub(g.Clock()), 0)
+	return s
+}
+
+// Validate checks that s can be drawn: the board has a size a game could
+// have, and the food, obstacles, power-up and poison lie on it and are of
+// kinds that exist. The snakes may leave the board when they crash.
+func (s State) Validate() error {
+	if s.Width < 1 || s.Width > STATE_MAX_SIZE || s.Height < 1 || s.Height > STATE_MAX_SIZE {
+		return fmt.Errorf("board size %dx%d out of range", s.Width, s.Height)
+	}
+	if !insetFits(s.Width, s.Height, s.Inset) {
+		return fmt.Errorf("inset %d does not fit a %dx%d board", s.Inset, s.Width, s.Height)
+	}
+	onBoard := func(what string, p Position) error {
+		if p.X < 0 || p.X >= s.Width || p.Y < 0 || p.Y >= s.Height {
+			return fmt.Errorf("%s at %d,%d is off the %dx%d board", what, p.X, p.Y, s.Width, s.Height)
+		}
+		return nil
+	}
+	if s.FoodKind < 0 || s.FoodKind >= FOOD_KINDS {
+		return fmt.Errorf("unknown food kind %d", s.FoodKind)
+	}
+	for _, item := range slices.Concat(s.ExtraFood, s.BonusFood) {
+		if err := onBoard("food", item.Pos); err != nil {
+			return err
+		}
+		if item.Kind < 0 || item.Kind >= FOOD_KINDS {
+			return fmt.Errorf("unknown food kind %d", item.Kind)
+		}
+	}
+	for _, o := range s.Obstacles {
+		if err := onBoard("obstacle", o); err != nil {
+			return err
+		}
+	}
+	if p := s.PowerUp; p != nil {
+		if err := onBoard("power-up", p.Pos); err != nil {
+			return err
+		}
+		if p.Kind < 0 || p.Kind >= POWERUP_KINDS {
+			return fmt.Errorf("unknown power-up kind %d", p.Kind)
+		}
+	}
+	if p := s.Poison; p != nil {
+		if err := onBoard("poison", p.Pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetState makes the game look like s, unless s does not pass Validate.
+// Power-ups run for what was left of them by this game's clock.
+func (g *Game) SetState(s State) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	g.Snake = s.Snake
+	g.Direction = s.Direction
+	g.turns = nil
+	g.Food = s.Food
+	g.FoodKind = s.FoodKind
+	g.Score = s.Score
+	g.GameOver = s.GameOver
+	g.Paused = s.Paused
+	g.Wrap = s.Wrap
+	g.Width = s.Width
+	g.Height = s.Height
+	g.inset = s.Inset
+	if d, err := LookupDifficulty(s.Difficulty); err == nil {
+		g.Difficulty = d
+	}
+	g.Enemy = s.Enemy
+	g.Player2 = s.Player2
+	if g.Player2 != nil {
+		g.Player2.headOn = s.HeadOn
+	}
+	g.PowerUp = s.PowerUp
+	g.Poison = s.Poison
+	g.Obstacles = s.Obstacles
+	g.ExtraFood = s.ExtraFood
+	g.BonusFood = s.BonusFood
+	g.OOMKills = s.OOMKills
+	g.uploadBonus = s.UploadBonus
+	g.effectTicks = s.Effects
+	now := g.Clock()
+	for kind, remaining := range s.Remaining {
+		g.activeUntil[kind] = now.Add(remaining)
+	}
+	g.startAt = now.Add(s.Countdown)
+	g.graceUntil = now.Add(s.Grace)
+	return nil
+}
@@ -0,0 +1,34 @@
+package game
+
+// TURN_QUEUE_SIZE is how many turns a snake keeps for its next moves, so a
+// quick up-then-left between two ticks makes both turns, one per move,
+// instead of the second replacing the first.
+const TURN_QUEUE_SIZE = 3
+
+// turnQueue holds the turns a snake has been given but not made yet.
+type turnQueue []Position
+
+// push queues dir after the last queued turn, or after heading when none
+// is queued. A turn that reverses or repeats the direction the snake will
+// have by then is ignored, and so is one that does not fit.
+func (q *turnQueue) push(heading, dir Position) bool {
+	if n := len(*q); n > 0 {
+		heading = (*q)[n-1]
+	}
+	if dir.X != 0 && heading.X != 0 || dir.Y != 0 && heading.Y != 0 || len(*q) == TURN_QUEUE_SIZE {
+		return false
+	}
+	*q = append(*q, dir)
+	return true
+}
+
+// pop returns the direction for the next move: the first queued turn, or
+// heading when there is none.
+func (q *turnQueue) pop(heading Position) Position {
+	if len(*q) == 0 {
+		return heading
+	}
+	dir := (*q)[0]
+	*q = (*q)[1:]
+	return dir
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// GAMEPAD_GLOB finds the joystick devices of the Linux joystick API.
+	GAMEPAD_GLOB = "/dev/input/js*"
+	// GAMEPAD_DEADZONE is how far a stick has to be pushed, out of 32767,
+	// to count as a direction.
+	GAMEPAD_DEADZONE = 16384
+)
+
+// Event types of struct js_event in linux/joystick.h.
+const (
+	JS_EVENT_BUTTON = 0x01
+	JS_EVENT_AXIS   = 0x02
+	JS_EVENT_INIT   = 0x80
+)
+
+// jsEvent is struct js_event.
+type jsEvent struct {
+	Time   uint32
+	Value  int16
+	Type   uint8
+	Number uint8
+}
+
+// gamepadButtons are the actions of the buttons: A or ✕ restarts, B or ○
+// and Start on Xbox (7) and PlayStation (9) pads pause.
+var gamepadButtons = map[uint8]keyAction{
+	0: KEY_RESTART,
+	1: KEY_PAUSE,
+	7: KEY_PAUSE,
+	9: KEY_PAUSE,
+}
+
+// gamepad turns the events of a joystick device into actions: the left
+// stick and the d-pad steer the first snake, and the buttons pause and
+// restart.
+type gamepad struct {
+	f    *os.File
+	axes map[uint8]int
+}
+
+// openGamepad opens the first joystick device.
+func openGamepad() (*gamepad, error) {
+	paths, _ := filepath.Glob(GAMEPAD_GLOB)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no gamepad found at %s", GAMEPAD_GLOB)
+	}
+	f, err := os.Open(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("open gamepad: %w", err)
+	}
+	return &gamepad{f: f, axes: make(map[uint8]int)}, nil
+}
+
+// Name is the device the gamepad was opened from.
+func (p *gamepad) Name() string {
+	return p.f.Name()
+}
+
+// run sends actions until ctx is done or the device goes away, which is
+// reported.
+func (p *gamepad) run(ctx context.Context, actions chan<- keyAction, report func(error)) {
+	stop := context.AfterFunc(ctx, func() { p.f.Close() })
+	defer stop()
+	defer p.f.Close()
+	for {
+		var ev jsEvent
+		if err := binary.Read(p.f, binary.NativeEndian, &ev); err != nil {
+			if ctx.Err() == nil && !errors.Is(err, os.ErrClosed) {
+				report(fmt.Errorf("gamepad: %w", err))
+			}
+			return
+		}
+		a, ok := p.action(ev)
+		if !ok {
+			continue
+		}
+		select {
+		case actions <- a:
+		default:
+		}
+	}
+}
+
+// action is what ev does. The events the driver sends on open to tell the
+// state of every button and axis only set that state.
+func (p *gamepad) action(ev jsEvent) (keyAction, bool) {
+	switch ev.Type &^ JS_EVENT_INIT {
+	case JS_EVENT_BUTTON:
+		a, ok := gamepadButtons[ev.Number]
+		return a, ok && ev.Value == 1 && ev.Type&JS_EVENT_INIT == 0
+	case JS_EVENT_AXIS:
+		dir := 0
+		switch {
+		case ev.Value <= -GAMEPAD_DEADZONE:
+			dir = -1
+		case ev.Value >= GAMEPAD_DEADZONE:
+			dir = 1
+		}
+		prev := p.axes[ev.Number]
+		p.axes[ev.Number] = dir
+		if dir == 0 || dir == prev || ev.Type&JS_EVENT_INIT != 0 {
+			return 0, false
+		}
+		// Axes 0 and 1 are the left stick, 6 and 7 the d-pad of most
+		// pads; up is negative.
+		switch ev.Number {
+		case 0, 6:
+			return [...]keyAction{KEY_LEFT, KEY_RIGHT}[(dir+1)/2], true
+		case 1, 7:
+			return [...]keyAction{KEY_UP, KEY_DOWN}[(dir+1)/2], true
+		}
+	}
+	return 0, false
+}
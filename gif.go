@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// GIF_FRAME_INTERVAL is the shortest time a frame of an exported GIF is
+// shown, so GIFs play at up to 10 frames per second and stay small.
+const GIF_FRAME_INTERVAL = 100 * time.Millisecond
+
+// gifOptions are how an exported GIF looks: the terminal the run is drawn
+// in, fitted to the board when 0, and its theme and glyphs.
+type gifOptions struct {
+	cols, rows int
+	theme      tui.Theme
+	display    displayOptions
+}
+
+// exportGIF plays the replay read from r back offscreen, as fast as it
+// goes, and encodes what the screen showed into an animated GIF at path,
+// with the board, the score and the metrics around it.
+func exportGIF(r io.Reader, path string, opts gifOptions) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	header, err := readReplayHeader(dec)
+	if err != nil {
+		return err
+	}
+	difficulty, err := game.LookupDifficulty(header.Difficulty)
+	if err != nil {
+		return fmt.Errorf("load replay: %w", err)
+	}
+
+	ui := tui.NewOffscreen(header.Width, header.Height, opts.cols, opts.rows)
+	ui.Demo = header.Demo
+	ui.Theme = opts.theme
+	opts.display.apply(ui)
+	s := newReplaySession(header, ui, opts.display.noColor)
+	// Toasts and flashes last as long as they did in the run.
+	ui.Clock, ui.Toasts.Clock = s.clock, s.clock
+	s.startReplay(header, difficulty)
+
+	out, err := tui.NewGIFWriter(path)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	s.render()
+	out.Frame(ui, s.now)
+	drawn := s.now
+	for {
+		var frame replayFrame
+		if err := dec.Decode(&frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fail(fmt.Errorf("read replay: %w", err))
+		}
+		s.now = frame.Time
+		if err := s.replayFrame(frame); err != nil {
+			return fail(fmt.Errorf("replay: %w", err))
+		}
+		if s.now.Sub(drawn) >= GIF_FRAME_INTERVAL {
+			s.render()
+			out.Frame(ui, s.now)
+			drawn = s.now
+		}
+	}
+	s.render()
+	out.Frame(ui, s.now)
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// gifExporter exports the runs kept in memory to GIFs in the background
+// and hands the outcome to the game loop as a toast.
+type gifExporter struct {
+	notices chan<- string
+	busy    atomic.Bool
+	wg      sync.WaitGroup
+}
+
+func newGIFExporter(notices chan<- string) *gifExporter {
+	return &gifExporter{notices: notices}
+}
+
+// export encodes the replay in data to path, unless another export still
+// runs.
+func (e *gifExporter) export(data []byte, path string, opts gifOptions) bool {
+	if !e.busy.CompareAndSwap(false, true) {
+		return false
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.busy.Store(false)
+		msg := "🎞 GIF saved to " + path
+		if err := writeReplayGIF(data, path, opts); err != nil {
+			msg = err.Error()
+		}
+		select {
+		case e.notices <- msg:
+		default:
+		}
+	}()
+	return true
+}
+
+// wait lets an export still running finish before the game exits.
+func (e *gifExporter) wait() {
+	e.wg.Wait()
+}
+
+// writeReplayGIF exports the replay in data to path, in a directory
+// created for it if needed.
+func writeReplayGIF(data []byte, path string, opts gifOptions) error {
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("create replay dir: %w", err)
+	}
+	if err := exportGIF(bytes.NewReader(data), path, opts); err != nil {
+		return err
+	}
+	return chownToSudoUser(append(created, path)...)
+}
+
+// saveGIF exports the run so far, when it is kept in memory, to a GIF in
+// replaysDir named after the time, drawn like the screen shows it now.
+func (s *session) saveGIF() {
+	switch {
+	case s.recorder == nil || s.gifs == nil:
+		s.ui.Toasts.Push("🎞 this run is not kept, record it with -record and export it with snake-ebpf gif")
+	case s.recorder.f != nil:
+		s.ui.Toasts.Push("🎞 export the recording with snake-ebpf gif " + s.recorder.f.Name())
+	case s.recorder.err != nil:
+		s.ui.Toasts.Push(s.recorder.err.Error())
+	default:
+		path := filepath.Join(replaysDir(), s.now.Format("2006-01-02T15-04-05")+".gif")
+		opts := gifOptions{
+			cols:    s.ui.TermWidth,
+			rows:    s.ui.TermHeight,
+			theme:   s.ui.Theme,
+			display: displayOptions{ascii: s.ui.Glyphs == tui.ASCIIGlyphs, noColor: s.noColor},
+		}
+		if !s.gifs.export(bytes.Clone(s.recorder.mem.Bytes()), path, opts) {
+			s.ui.Toasts.Push("🎞 a GIF is still being exported")
+			return
+		}
+		s.ui.Toasts.Push("🎞 exporting the run to a GIF...")
+	}
+}
+
+func runGIF(ctx context.Context, args []string) int {
+	fs := newFlagSet("gif", " FILE")
+	outPath := fs.String("o", "", "GIF file to write (FILE with the extension .gif when empty)")
+	cols := fs.Int("cols", 0, "terminal columns to draw the run in (fitted to the board when 0)")
+	rows := fs.Int("rows", 0, "terminal rows to draw the run in (fitted to the board when 0)")
+	themeName := fs.String("theme", tui.Themes[0].Name, "color theme: "+strings.Join(tui.ThemeNames(), ", "))
+	display := addDisplayFlags(fs)
+	if code, ok := parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	theme, ok := tui.LookupTheme(*themeName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid -theme %q: must be one of %s\n", *themeName, strings.Join(tui.ThemeNames(), ", "))
+		return EXIT_USAGE
+	}
+	if *cols < 0 || *rows < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid -cols %d or -rows %d: must not be negative\n", *cols, *rows)
+		return EXIT_USAGE
+	}
+	if *cols > tui.GIF_MAX_COLS || *rows > tui.GIF_MAX_ROWS {
+		fmt.Fprintf(os.Stderr, "Invalid -cols %d or -rows %d: a GIF holds at most %dx%d cells\n", *cols, *rows, tui.GIF_MAX_COLS, tui.GIF_MAX_ROWS)
+		return EXIT_USAGE
+	}
+
+	path := fs.Arg(0)
+	if *outPath == "" {
+		*outPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".gif"
+	}
+	if *outPath == path {
+		fmt.Fprintf(os.Stderr, "Invalid -o %q: must not be the replay\n", *outPath)
+		return EXIT_USAGE
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open replay: %v\n", err)
+		return EXIT_FAILURE
+	}
+	defer f.Close()
+	// Playing back as fast as it goes takes a moment for long runs, so it
+	// stops early on Ctrl+C.
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+	if err := exportGIF(f, *outPath, gifOptions{cols: *cols, rows: *rows, theme: theme, display: *display}); err != nil {
+		if ctx.Err() != nil {
+			return EXIT_OK
+		}
+		fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+		return EXIT_FAILURE
+	}
+	fmt.Printf("GIF saved to %s\n", *outPath)
+	return EXIT_OK
+}
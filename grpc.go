@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/snakepb"
+)
+
+// GRPC_STREAM_BUFFER is how many ticks a stream may fall behind before it
+// misses some.
+const GRPC_STREAM_BUFFER = 16
+
+// grpcService implements the Snake service of snakepb. The game loop
+// publishes to it after every poll, and input goes to the loop as a control
+// command, like with the REST API.
+type grpcService struct {
+	snakepb.UnimplementedSnakeServer
+	server   *grpc.Server
+	token    string
+	commands chan<- controlCommand
+	ctx      context.Context
+
+	mu      sync.Mutex
+	metrics map[chan *snakepb.Metrics]bool
+	states  map[chan *snakepb.GameState]bool
+}
+
+func startGRPCServer(ctx context.Context, addr, token string, commands chan<- controlCommand) (*grpcService, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	svc := &grpcService{
+		token:    token,
+		commands: commands,
+		ctx:      ctx,
+		metrics:  make(map[chan *snakepb.Metrics]bool),
+		states:   make(map[chan *snakepb.GameState]bool),
+	}
+	svc.server = grpc.NewServer(
+		grpc.UnaryInterceptor(svc.authorizeUnary),
+		grpc.StreamInterceptor(svc.authorizeStream),
+	)
+	snakepb.RegisterSnakeServer(svc.server, svc)
+	go svc.server.Serve(listener)
+	return svc, nil
+}
+
+func (g *grpcService) close() {
+	g.server.Stop()
+}
+
+// authorize checks for the API token as a bearer token in the
+// "authorization" metadata, like the REST API takes it.
+func (g *grpcService) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		given, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(given), []byte(g.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (g *grpcService) authorizeUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := g.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *grpcService) authorizeStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// publish sends the counters and the game state of this poll to the open
+// streams. A stream that falls behind misses polls rather than holding up
+// the game.
+func (g *grpcService) publish(s *session) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.metrics) > 0 {
+		m := metricsProto(s.ui.Metrics)
+		for sub := range g.metrics {
+			select {
+			case sub <- m:
+			default:
+			}
+		}
+	}
+	if len(g.states) > 0 {
+		state := gameStateProto(s.gameSnapshot(s.interval), s.now)
+		for sub := range g.states {
+			select {
+			case sub <- state:
+			default:
+			}
+		}
+	}
+}
+
+func (g *grpcService) MetricsStream(_ *snakepb.MetricsStreamRequest, stream grpc.ServerStreamingServer[snakepb.Metrics]) error {
+	sub := make(chan *snakepb.Metrics, GRPC_STREAM_BUFFER)
+	g.mu.Lock()
+	g.metrics[sub] = true
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.metrics, sub)
+		g.mu.Unlock()
+	}()
+	return streamTicks(g.ctx, stream, sub)
+}
+
+func (g *grpcService) GameStateStream(_ *snakepb.GameStateStreamRequest, stream grpc.ServerStreamingServer[snakepb.GameState]) error {
+	sub := make(chan *snakepb.GameState, GRPC_STREAM_BUFFER)
+	g.mu.Lock()
+	g.states[sub] = true
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.states, sub)
+		g.mu.Unlock()
+	}()
+	return streamTicks(g.ctx, stream, sub)
+}
+
+// streamTicks sends what is published to sub until the client or the game
+// goes away.
+func streamTicks[T any](ctx context.Context, stream grpc.ServerStreamingServer[T], sub <-chan *T) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Unavailable, errShuttingDown.Error())
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case msg := <-sub:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *grpcService) InjectInput(ctx context.Context, req *snakepb.InputRequest) (*snakepb.GameState, error) {
+	reply, err := submitControl(ctx, g.commands, "input", req.GetKey())
+	if errors.Is(err, errShuttingDown) || errors.Is(err, errGameBusy) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	if reply.err != nil {
+		return nil, status.Error(codes.InvalidArgument, reply.err.Error())
+	}
+	return gameStateProto(reply.game, time.Now()), nil
+}
+
+func metricsProto(m ebpfmon.Metrics) *snakepb.Metrics {
+	syscalls := make(map[string]uint64, len(m.Syscalls))
+	for c, count := range m.Syscalls {
+		syscalls[ebpfmon.SyscallCategory(c).String()] = count
+	}
+	signals := make(map[string]uint64, len(m.Signals))
+	for k, count := range m.Signals {
+		signals[ebpfmon.SignalKind(k).String()] = count
+	}
+	return &snakepb.Metrics{
+		TimeUnixNano:    m.Time.UnixNano(),
+		Execve:          m.Execve,
+		FileOps:         m.FileOps,
+		Network:         m.Network,
+		Process:         m.Process,
+		ContextSwitches: m.ContextSwitches,
+		TcpRetransmits:  m.Retransmits,
+		PacketDrops:     m.Drops,
+		PageFaults:      m.PageFaults,
+		DirectReclaims:  m.Reclaims,
+		ProcessExits:    m.Exits,
+		LiveProcesses:   m.LiveProcesses,
+		UprobeCalls:     m.UprobeCalls,
+		UsdtCalls:       m.USDTCalls,
+		EgressBytes:     m.EgressBytes,
+		DnsQueries:      m.DNSQueries,
+		EventRate:       m.EventRate,
+		Syscalls:        syscalls,
+		Signals:         signals,
+		IoLatency:       m.IOLatency[:],
+		RunqLatency:     m.RunqLatency[:],
+	}
+}
+
+func gameStateProto(g gameSnapshot, now time.Time) *snakepb.GameState {
+	return &snakepb.GameState{
+		TimeUnixNano: now.UnixNano(),
+		Score:        int32(g.Score),
+		Snake:        positionsProto(g.Snake),
+		Direction:    positionProto(g.Direction),
+		Food:         positionProto(g.Food),
+		FoodKind:     g.FoodKind,
+		Obstacles:    positionsProto(g.Obstacles),
+		Width:        int32(g.Width),
+		Height:       int32(g.Height),
+		Paused:       g.Paused,
+		GameOver:     g.GameOver,
+		Difficulty:   g.Difficulty,
+		TickInterval: g.Interval,
+		Wrap:         g.Wrap,
+	}
+}
+
+func positionProto(p game.Position) *snakepb.Position {
+	return &snakepb.Position{X: int32(p.X), Y: int32(p.Y)}
+}
+
+func positionsProto(ps []game.Position) []*snakepb.Position {
+	out := make([]*snakepb.Position, len(ps))
+	for i, p := range ps {
+		out[i] = positionProto(p)
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+type headlessSample struct {
+	Time    time.Time       `json:"time"`
+	Metrics metricsSnapshot `json:"metrics"`
+}
+
+func runMonitor(ctx context.Context, args []string) int {
+	fs := newFlagSet("monitor", "")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	metricsAddr := fs.String("metrics-addr", "", "listen address for the Prometheus /metrics endpoint (disabled when empty)")
+	enableOTLP := fs.Bool("otlp", false, "export the counters over OTLP to the OpenTelemetry collector set by the OTEL_EXPORTER_OTLP_* environment variables")
+	interval := fs.Duration("interval", time.Second, "how often the counters are printed")
+	format := fs.String("format", "text", "output format: text, json or influx (InfluxDB line protocol)")
+	influxURL := fs.String("influx-url", "", "InfluxDB write URL, e.g. http://localhost:8086/api/v2/write?org=home&bucket=snake, to write the counters and rates to every interval (disabled when empty)")
+	statsdAddr := fs.String("statsd-addr", "", "statsd agent address, e.g. localhost:8125, to send the counters to over UDP every interval (disabled when empty)")
+	statsdPrefix := fs.String("statsd-prefix", STATSD_PREFIX, "prefix of every statsd metric name")
+	statsdTagList := fs.String("statsd-tags", "", "comma-separated key:value tags added to every statsd metric, in the DogStatsD format")
+	filters := addFilterFlags(fs)
+	containers := addContainerFlags(fs)
+	pinDir := addPinFlag(fs)
+	logOpts := addLogFlags(fs)
+	pprofAddr := addPprofFlag(fs)
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	closeLog, err := logOpts.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	defer closeLog()
+	stopPprof, ok := startPprof(*pprofAddr)
+	if !ok {
+		return EXIT_FAILURE
+	}
+	defer stopPprof()
+	if *format != "text" && *format != "json" && *format != "influx" {
+		fmt.Fprintf(os.Stderr, "Invalid -format %q: must be text, json or influx\n", *format)
+		return EXIT_USAGE
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid -interval %s: must be positive\n", *interval)
+		return EXIT_USAGE
+	}
+	filter, err := filters.filter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+
+	mon, probes, code := loadMonitor(*btfPath, *pinDir, cfg.Probes, filter)
+	if code != EXIT_OK {
+		return code
+	}
+	defer mon.Close()
+	defer probes.Close()
+	for _, name := range probes.Unattached() {
+		fmt.Fprintf(os.Stderr, "Warning: %s not attached: %v\n", name, strings.ReplaceAll(probes.Failure(name).Error(), "\n", "; "))
+	}
+
+	exporter := &metricsExporter{}
+	if *metricsAddr != "" {
+		server, err := startMetricsServer(*metricsAddr, exporter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start metrics endpoint: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer server.Close()
+		fmt.Fprintf(os.Stderr, "Prometheus metrics on http://%s/metrics\n", *metricsAddr)
+	}
+	if *enableOTLP {
+		provider, err := startOTLPExporter(ctx, exporter, func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: otlp: %v\n", err)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start OTLP exporter: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer shutdownOTLP(provider)
+		fmt.Fprintf(os.Stderr, "Exporting metrics over OTLP to %s\n", otlpEndpoint())
+	}
+	var influx *influxExporter
+	if *influxURL != "" {
+		influx, err = startInfluxExporter(ctx, *influxURL, func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+			return EXIT_USAGE
+		}
+	}
+	var statsd *statsdEmitter
+	if *statsdAddr != "" {
+		statsd, err = newStatsdEmitter(*statsdAddr, *statsdPrefix, *statsdTagList, func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+			return EXIT_USAGE
+		}
+		defer statsd.Close()
+	}
+	return runHeadless(ctx, mon, exporter, influx, statsd, *interval, *format, containers)
+}
+
+func runHeadless(ctx context.Context, mon *ebpfmon.Monitor, exporter *metricsExporter, influx *influxExporter, statsd *statsdEmitter, interval time.Duration, format string, containers *containerOptions) int {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reader := mon.NewMetricsReader()
+	containers.apply(reader)
+	rates := ebpfmon.NewRates(ebpfmon.RATE_WINDOW)
+	host := influxHost()
+	var readErr error
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case <-ctx.Done():
+			logShutdown(ctx, "monitor")
+			return EXIT_OK
+		case <-ticker.C:
+			snap, err := reader.ReadSnapshot()
+			if err != nil && readErr == nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			logReadError(err, readErr)
+			readErr = err
+			metrics := snap.Metrics
+			exporter.updateMetrics(metrics)
+			rate := rates.Update(metrics)
+			if influx != nil {
+				influx.add(metrics, rate, nil)
+			}
+			if statsd != nil {
+				statsd.add(metrics, nil)
+			}
+			top := mon.TopCgroups(3)
+			switch format {
+			case "json":
+				err = enc.Encode(headlessSample{Time: metrics.Time, Metrics: newMetricsSnapshot(metrics, top, containers.top(mon))})
+			case "influx":
+				var buf bytes.Buffer
+				appendLineProtocol(&buf, host, metrics, rate, nil)
+				_, err = os.Stdout.Write(buf.Bytes())
+			default:
+				err = writeHeadlessText(os.Stdout, metrics)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write metrics: %v\n", err)
+				return EXIT_FAILURE
+			}
+		}
+	}
+}
+
+func writeHeadlessText(w io.Writer, m ebpfmon.Metrics) error {
+	_, err := fmt.Fprintf(w, "%s execve=%d file_ops=%d network=%d process=%d context_switches=%d tcp_retransmits=%d packet_drops=%d page_faults=%d direct_reclaims=%d process_exits=%d live_processes=%d event_rate=%d",
+		m.Time.Format(time.RFC3339),
+		m.Execve,
+		m.FileOps,
+		m.Network,
+		m.Process,
+		m.ContextSwitches,
+		m.Retransmits,
+		m.Drops,
+		m.PageFaults,
+		m.Reclaims,
+		m.Exits,
+		m.LiveProcesses,
+		m.EventRate,
+	)
+	for c, count := range m.Syscalls {
+		if err == nil {
+			_, err = fmt.Fprintf(w, " syscalls_%s=%d", ebpfmon.SyscallCategory(c), count)
+		}
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w)
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"snake-ebpf/tui"
+)
+
+type highScores struct {
+	Score         int       `json:"score"`
+	Length        int       `json:"length"`
+	PeakEventRate uint64    `json:"peak_event_rate"`
+	Updated       time.Time `json:"updated"`
+}
+
+func highScoresPath() string {
+	return filepath.Join(userHomeDir(), ".local", "share", "snake-ebpf", "highscores.json")
+}
+
+func loadHighScores(path string) (highScores, error) {
+	var h highScores
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("read high scores: %w", err)
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("decode high scores: %w", err)
+	}
+	return h, nil
+}
+
+// update folds a finished round into the records and reports whether the
+// score record was beaten.
+func (h *highScores) update(score, length int, peakEventRate uint64) bool {
+	changed := false
+	newScore := score > h.Score
+	if newScore {
+		h.Score = score
+		changed = true
+	}
+	if length > h.Length {
+		h.Length = length
+		changed = true
+	}
+	if peakEventRate > h.PeakEventRate {
+		h.PeakEventRate = peakEventRate
+		changed = true
+	}
+	if changed {
+		h.Updated = time.Now()
+	}
+	return newScore
+}
+
+func (h highScores) record(newHighScore bool) tui.Record {
+	return tui.Record{Score: h.Score, Length: h.Length, PeakEventRate: h.PeakEventRate, NewHighScore: newHighScore}
+}
+
+func saveHighScores(path string, h highScores) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode high scores: %w", err)
+	}
+	return writeDataFile(path, append(data, '\n'), "high scores")
+}
+
+// writeDataFile replaces the file at path with data in one rename, so a
+// crash never leaves half of it, and hands it and any directories created
+// for it to the invoking user. what names the file in errors.
+func writeDataFile(path string, data []byte, what string) error {
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("create %s dir: %w", what, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("create %s: %w", what, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", what, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", what, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", what, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename %s: %w", what, err)
+	}
+	return chownToSudoUser(append(created, path)...)
+}
+
+// mkdirAllOwned creates dir like os.MkdirAll and hands every directory it
+// had to create to the invoking user, so running under sudo does not leave
+// root-owned directories in their home.
+func mkdirAllOwned(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || d == filepath.Dir(d) {
+			break
+		}
+		missing = append(missing, d)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+func chownToSudoUser(paths ...string) error {
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil {
+		return nil
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		return nil
+	}
+	for _, p := range paths {
+		if err := os.Lchown(p, uid, gid); err != nil {
+			return fmt.Errorf("chown %s: %w", p, err)
+		}
+	}
+	return nil
+}
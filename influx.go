@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const (
+	INFLUX_TIMEOUT = 5 * time.Second
+	// INFLUX_QUEUE is how many polls wait to be written before new ones
+	// are dropped.
+	INFLUX_QUEUE = 16
+)
+
+type influxField struct {
+	key, value string
+}
+
+func intField(key string, v uint64) influxField {
+	return influxField{key, strconv.FormatUint(v, 10) + "i"}
+}
+
+func floatField(key string, v float64) influxField {
+	return influxField{key, strconv.FormatFloat(v, 'g', -1, 64)}
+}
+
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// lineProtocol builds InfluxDB line protocol for one poll, every line
+// tagged with the host.
+type lineProtocol struct {
+	buf  bytes.Buffer
+	host string
+	ts   string
+}
+
+func (l *lineProtocol) line(measurement string, tags []string, fields ...influxField) {
+	l.buf.WriteString(measurement)
+	l.buf.WriteString(",host=")
+	l.buf.WriteString(influxTagEscaper.Replace(l.host))
+	for i := 0; i+1 < len(tags); i += 2 {
+		fmt.Fprintf(&l.buf, ",%s=%s", tags[i], influxTagEscaper.Replace(tags[i+1]))
+	}
+	for i, f := range fields {
+		if i == 0 {
+			l.buf.WriteByte(' ')
+		} else {
+			l.buf.WriteByte(',')
+		}
+		l.buf.WriteString(f.key)
+		l.buf.WriteByte('=')
+		l.buf.WriteString(f.value)
+	}
+	l.buf.WriteByte(' ')
+	l.buf.WriteString(l.ts)
+	l.buf.WriteByte('\n')
+}
+
+// appendLineProtocol writes the counters and rates of one poll, and the
+// game stats when there is a game, as line protocol with nanosecond
+// timestamps.
+func appendLineProtocol(w *bytes.Buffer, host string, m ebpfmon.Metrics, rate ebpfmon.Rate, game *gameStats) {
+	l := &lineProtocol{host: host, ts: strconv.FormatInt(m.Time.UnixNano(), 10)}
+	l.line("snake_ebpf", nil,
+		intField("execve", m.Execve),
+		intField("file_ops", m.FileOps),
+		intField("network", m.Network),
+		intField("process", m.Process),
+		intField("context_switches", m.ContextSwitches),
+		intField("tcp_retransmits", m.Retransmits),
+		intField("packet_drops", m.Drops),
+		intField("page_faults", m.PageFaults),
+		intField("direct_reclaims", m.Reclaims),
+		intField("process_exits", m.Exits),
+		intField("live_processes", m.LiveProcesses),
+		intField("uprobe_calls", m.UprobeCalls),
+		intField("usdt_calls", m.USDTCalls),
+		intField("egress_bytes", m.EgressBytes),
+		intField("dns_queries", m.DNSQueries),
+		intField("event_rate", m.EventRate),
+	)
+	l.line("snake_ebpf_rate", nil,
+		floatField("execve", rate.Execve),
+		floatField("file_ops", rate.FileOps),
+		floatField("network", rate.Network),
+		floatField("process", rate.Process),
+		floatField("context_switches", rate.ContextSwitches),
+		floatField("tcp_retransmits", rate.Retransmits),
+		floatField("packet_drops", rate.Drops),
+		floatField("page_faults", rate.PageFaults),
+		floatField("direct_reclaims", rate.Reclaims),
+		floatField("uprobe_calls", rate.UprobeCalls),
+		floatField("usdt_calls", rate.USDTCalls),
+		floatField("xdp_rx_packets", rate.RxPackets),
+		floatField("xdp_rx_bytes", rate.RxBytes),
+		floatField("egress_bytes", rate.EgressBytes),
+		floatField("dns_queries", rate.DNSQueries),
+	)
+	for c, count := range m.Syscalls {
+		l.line("snake_ebpf_syscalls", []string{"category", ebpfmon.SyscallCategory(c).String()},
+			intField("count", count), floatField("rate", rate.Syscalls[c]))
+	}
+	for k, count := range m.Signals {
+		l.line("snake_ebpf_signals", []string{"signal", ebpfmon.SignalKind(k).String()}, intField("count", count))
+	}
+	if game != nil {
+		l.line("snake_ebpf_game", nil,
+			intField("score", uint64(game.score)),
+			intField("length", uint64(game.length)),
+			floatField("tick_interval_seconds", game.interval.Seconds()),
+		)
+	}
+	w.Write(l.buf.Bytes())
+}
+
+func influxHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
+
+// influxExporter writes every poll to an InfluxDB write endpoint in the
+// background, with the token in INFLUX_TOKEN when set.
+type influxExporter struct {
+	url     string
+	token   string
+	host    string
+	client  *http.Client
+	batches chan []byte
+	report  func(error)
+}
+
+// startInfluxExporter writes to rawURL, the full write URL such as
+// http://localhost:8086/api/v2/write?org=home&bucket=snake, until ctx is
+// done. Write errors go to report.
+func startInfluxExporter(ctx context.Context, rawURL string, report func(error)) (*influxExporter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("influx URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("influx URL %s: must be http or https", rawURL)
+	}
+	e := &influxExporter{
+		url:     rawURL,
+		token:   os.Getenv("INFLUX_TOKEN"),
+		host:    influxHost(),
+		client:  &http.Client{Timeout: INFLUX_TIMEOUT},
+		batches: make(chan []byte, INFLUX_QUEUE),
+		report:  report,
+	}
+	go e.run(ctx)
+	return e, nil
+}
+
+func (e *influxExporter) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-e.batches:
+			if err := e.write(ctx, batch); err != nil && ctx.Err() == nil {
+				e.report(err)
+			}
+		}
+	}
+}
+
+func (e *influxExporter) write(ctx context.Context, batch []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(batch))
+	if err != nil {
+		return fmt.Errorf("influx write: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Token "+e.token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx write: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx write: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// add queues a poll to be written, dropping it when the writes fall too
+// far behind.
+func (e *influxExporter) add(m ebpfmon.Metrics, rate ebpfmon.Rate, game *gameStats) {
+	var buf bytes.Buffer
+	appendLineProtocol(&buf, e.host, m, rate, game)
+	select {
+	case e.batches <- buf.Bytes():
+	default:
+	}
+}
+
+// publish takes in the counters, rates and game of this tick.
+func (e *influxExporter) publish(s *session) {
+	e.add(s.ui.Metrics, s.ui.Rate, &gameStats{score: s.game.Score, length: len(s.game.Snake), interval: s.interval})
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// keyAction is what a key does in the game.
+type keyAction int
+
+const (
+	KEY_UP keyAction = iota
+	KEY_DOWN
+	KEY_LEFT
+	KEY_RIGHT
+	KEY_UP2
+	KEY_DOWN2
+	KEY_LEFT2
+	KEY_RIGHT2
+	KEY_QUIT
+	KEY_PAUSE
+	KEY_RESTART
+	KEY_WRAP
+	KEY_GRAPHS
+	KEY_THEME
+	KEY_HEATMAP
+	KEY_PROCS
+	KEY_METRICS
+	KEY_SPARKLINES
+	KEY_OVERHEAD
+	KEY_HELP
+	KEY_SAVE
+	KEY_GIF
+	KEY_ACTIONS
+)
+
+// keyActionNames are the keys of the [keymap] config section.
+var keyActionNames = [KEY_ACTIONS]string{
+	"up", "down", "left", "right", "up2", "down2", "left2", "right2",
+	"quit", "pause", "restart", "wrap", "graphs", "theme", "heatmap",
+	"procs", "metrics", "sparklines", "overhead", "help", "save", "gif",
+}
+
+func (a keyAction) String() string {
+	return keyActionNames[a]
+}
+
+func lookupKeyAction(name string) (keyAction, bool) {
+	for a, n := range keyActionNames {
+		if n == name {
+			return keyAction(a), true
+		}
+	}
+	return 0, false
+}
+
+// player2 reports whether a steers the second snake. Those keys only count
+// while a second snake is played at this keyboard, and then win over the
+// same key bound to anything else, the way IJKL and the arrow keys steer
+// the second snake in two-player mode.
+func (a keyAction) player2() bool {
+	return a >= KEY_UP2 && a <= KEY_RIGHT2
+}
+
+// direction maps a direction action to the snake it steers, 1 or 2, and
+// the way.
+func (a keyAction) direction() (player int, dir game.Position, ok bool) {
+	if a > KEY_RIGHT2 {
+		return 0, game.Position{}, false
+	}
+	player = 1
+	if a.player2() {
+		player, a = 2, a-KEY_UP2
+	}
+	return player, [...]game.Position{game.Up, game.Down, game.Left, game.Right}[a], true
+}
+
+// recordedKeys are the keys replays record for the actions that change the
+// game, whatever the keymap of the run. Replays read them back with
+// defaultKeymap, which maps them to the same actions in either mode.
+var recordedKeys = map[keyAction]string{
+	KEY_UP: "w", KEY_DOWN: "s", KEY_LEFT: "a", KEY_RIGHT: "d",
+	KEY_UP2: "i", KEY_DOWN2: "k", KEY_LEFT2: "j", KEY_RIGHT2: "l",
+	KEY_PAUSE: "p", KEY_RESTART: "r", KEY_WRAP: "b",
+}
+
+// defaultKeys are the keys of every action unless the [keymap] section of
+// the config file binds it to others.
+var defaultKeys = [KEY_ACTIONS][]string{
+	KEY_UP:         {"w", "k", "up"},
+	KEY_DOWN:       {"s", "j", "down"},
+	KEY_LEFT:       {"a", "h", "left"},
+	KEY_RIGHT:      {"d", "l", "right"},
+	KEY_UP2:        {"i", "up"},
+	KEY_DOWN2:      {"k", "down"},
+	KEY_LEFT2:      {"j", "left"},
+	KEY_RIGHT2:     {"l", "right"},
+	KEY_QUIT:       {"q"},
+	KEY_PAUSE:      {"p"},
+	KEY_RESTART:    {"r"},
+	KEY_WRAP:       {"b"},
+	KEY_GRAPHS:     {"tab"},
+	KEY_THEME:      {"t"},
+	KEY_HEATMAP:    {"m"},
+	KEY_PROCS:      {"n"},
+	KEY_METRICS:    {"i"},
+	KEY_SPARKLINES: {"v"},
+	KEY_OVERHEAD:   {"o"},
+	KEY_HELP:       {"?"},
+	KEY_SAVE:       {"e"},
+	KEY_GIF:        {"g"},
+}
+
+var defaultKeymap = mustKeymap(defaultKeys)
+
+// namedKeys are the keys with a name, as read from the terminal, and how
+// they are shown. Any other key is a single character.
+var namedKeys = map[string]string{
+	"up": "↑", "down": "↓", "left": "←", "right": "→",
+	"tab": "Tab", "space": "Space", "esc": "Esc", "enter": "Enter", "ctrl+c": "Ctrl+C",
+}
+
+// keymap maps the keys read from the terminal to actions.
+type keymap struct {
+	keys [KEY_ACTIONS][]string
+	one  map[string]keyAction
+	// two holds the second snake's keys, looked up first in two-player
+	// mode.
+	two map[string]keyAction
+}
+
+// newKeymap binds every action to its keys. A key may not do two things
+// in the same mode. Ctrl+C always quits.
+func newKeymap(keys [KEY_ACTIONS][]string) (keymap, error) {
+	k := keymap{one: make(map[string]keyAction), two: make(map[string]keyAction)}
+	for a := range KEY_ACTIONS {
+		if len(keys[a]) == 0 {
+			return k, fmt.Errorf("keymap.%s: no keys", a)
+		}
+		m := k.one
+		if a.player2() {
+			m = k.two
+		}
+		for _, key := range keys[a] {
+			key, err := normalizeKey(key)
+			if err != nil {
+				return k, fmt.Errorf("keymap.%s: %w", a, err)
+			}
+			if other, ok := m[key]; ok && other != a {
+				return k, fmt.Errorf("keymap.%s: %q is bound to %s as well", a, key, other)
+			}
+			m[key] = a
+			k.keys[a] = append(k.keys[a], key)
+		}
+	}
+	if a, ok := k.one["ctrl+c"]; ok && a != KEY_QUIT {
+		return k, fmt.Errorf("keymap.%s: \"ctrl+c\" always quits", a)
+	}
+	k.one["ctrl+c"] = KEY_QUIT
+	return k, nil
+}
+
+func mustKeymap(keys [KEY_ACTIONS][]string) keymap {
+	k, err := newKeymap(keys)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// normalizeKey checks that key is a single character or a named key and
+// lowercases it, since letters are read from the terminal in lower case.
+func normalizeKey(key string) (string, error) {
+	key = strings.ToLower(key)
+	if _, ok := namedKeys[key]; ok {
+		return key, nil
+	}
+	if key == " " {
+		return "space", nil
+	}
+	if utf8.RuneCountInString(key) != 1 || key < " " {
+		return "", fmt.Errorf("unknown key %q: must be one character or one of %s", key, strings.Join(namedKeyNames(), ", "))
+	}
+	return key, nil
+}
+
+func namedKeyNames() []string {
+	return []string{"up", "down", "left", "right", "tab", "space", "esc", "enter"}
+}
+
+// lookup returns the action of input, a key as read from the terminal.
+// twoPlayer is set while a second snake is played at this keyboard.
+func (k keymap) lookup(input string, twoPlayer bool) (keyAction, bool) {
+	if input == " " {
+		input = "space"
+	}
+	input = strings.ToLower(input)
+	if twoPlayer {
+		if a, ok := k.two[input]; ok {
+			return a, true
+		}
+	}
+	a, ok := k.one[input]
+	return a, ok
+}
+
+// keyName is how key is shown on the screen.
+func keyName(key string) string {
+	if name, ok := namedKeys[key]; ok {
+		return name
+	}
+	return strings.ToUpper(key)
+}
+
+// hint names the keys that do a in the given mode, like "Q" or "P/Space".
+func (k keymap) hint(a keyAction, twoPlayer bool) string {
+	var names []string
+	for _, key := range k.keys[a] {
+		if b, _ := k.lookup(key, twoPlayer); b == a {
+			names = append(names, keyName(key))
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// moveHint names the groups of keys that steer a snake, like "WASD, HJKL
+// or Arrow keys". first is the up action of the snake; a group is the
+// n-th key of each of its four directions.
+func (k keymap) moveHint(first keyAction, twoPlayer bool) string {
+	var groups []string
+	for n := 0; ; n++ {
+		var group [4]string
+		for d := range group {
+			a := first + keyAction(d)
+			if n >= len(k.keys[a]) {
+				return joinHints(groups)
+			}
+			group[d] = k.keys[a][n]
+			if b, _ := k.lookup(group[d], twoPlayer); b != a {
+				group[d] = ""
+			}
+		}
+		// Up, down, left, right.
+		switch strings.Join(group[:], " ") {
+		case "up down left right":
+			groups = append(groups, "Arrow keys")
+		case "k j h l":
+			groups = append(groups, "HJKL")
+		default:
+			if group[0] != "" && group[1] != "" && group[2] != "" && group[3] != "" {
+				groups = append(groups, keyName(group[0])+keyName(group[2])+keyName(group[1])+keyName(group[3]))
+			}
+		}
+	}
+}
+
+func joinHints(hints []string) string {
+	switch len(hints) {
+	case 0:
+		return ""
+	case 1:
+		return hints[0]
+	}
+	return strings.Join(hints[:len(hints)-1], ", ") + " or " + hints[len(hints)-1]
+}
+
+// keyHelp names the keys under the board and on the game over screen.
+func (k keymap) keyHelp(twoPlayer bool) tui.KeyHelp {
+	var parts []string
+	if twoPlayer {
+		parts = append(parts, k.moveHint(KEY_UP, true)+" move player 1", k.moveHint(KEY_UP2, true)+" player 2")
+	} else {
+		parts = append(parts, "Use "+k.moveHint(KEY_UP, false)+" to move")
+	}
+	for _, a := range []keyAction{KEY_GRAPHS, KEY_HEATMAP, KEY_PROCS, KEY_METRICS, KEY_OVERHEAD, KEY_SPARKLINES, KEY_THEME, KEY_WRAP} {
+		if h := k.hint(a, twoPlayer); h != "" {
+			parts = append(parts, h+" for "+keyHelp[a])
+		}
+	}
+	quit := k.hint(KEY_QUIT, twoPlayer)
+	help := tui.KeyHelp{
+		Keys:     strings.Join(parts, ", "),
+		Quit:     quit + " or Ctrl+C to quit",
+		GameOver: fmt.Sprintf("Press %s to restart, %s to save the replay, %s for a GIF, %s to quit", k.hint(KEY_RESTART, twoPlayer), k.hint(KEY_SAVE, twoPlayer), k.hint(KEY_GIF, twoPlayer), quit),
+	}
+	if h := k.hint(KEY_HELP, twoPlayer); h != "" {
+		help.Quit += ", " + h + " for help"
+	}
+
+	if twoPlayer {
+		help.Controls = append(help.Controls, k.moveHint(KEY_UP, true)+" player 1", k.moveHint(KEY_UP2, true)+" player 2")
+	} else {
+		help.Controls = append(help.Controls, k.moveHint(KEY_UP, false)+" move")
+	}
+	for a := KEY_QUIT; a < KEY_ACTIONS; a++ {
+		if h := k.hint(a, twoPlayer); h != "" {
+			help.Controls = append(help.Controls, h+" "+keyHelp[a])
+		}
+	}
+	return help
+}
+
+var keyHelp = map[keyAction]string{
+	KEY_QUIT: "quit", KEY_PAUSE: "pause", KEY_RESTART: "restart",
+	KEY_GRAPHS: "graphs", KEY_HEATMAP: "heatmap", KEY_PROCS: "processes",
+	KEY_METRICS: "metrics", KEY_OVERHEAD: "BPF overhead", KEY_SPARKLINES: "sparklines",
+	KEY_THEME: "theme", KEY_WRAP: "wrap", KEY_HELP: "help", KEY_SAVE: "save replay",
+	KEY_GIF: "export GIF",
+}
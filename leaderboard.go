@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// LEADERBOARD_SIZE is how many scores a leaderboard keeps and the most
+	// top lists.
+	LEADERBOARD_SIZE    = 100
+	LEADERBOARD_TIMEOUT = 10 * time.Second
+	MAX_PLAYER_NAME     = 32
+	// MAX_LEADERBOARD_BODY bounds the size of a submitted score.
+	MAX_LEADERBOARD_BODY = 4096
+)
+
+// leaderboardEntry is one submitted round.
+type leaderboardEntry struct {
+	Player        string    `json:"player"`
+	Score         int       `json:"score"`
+	Length        int       `json:"length"`
+	Kernel        string    `json:"kernel"`
+	PeakEventRate uint64    `json:"peak_event_rate"`
+	Difficulty    string    `json:"difficulty"`
+	Time          time.Time `json:"time"`
+}
+
+func (e leaderboardEntry) validate() error {
+	switch {
+	case e.Player == "" || utf8.RuneCountInString(e.Player) > MAX_PLAYER_NAME:
+		return fmt.Errorf("player name must be 1 to %d characters", MAX_PLAYER_NAME)
+	case e.Score < 0:
+		return errors.New("negative score")
+	case e.Length < 1:
+		return errors.New("snake length must be at least 1")
+	case len(e.Kernel) > 64 || len(e.Difficulty) > 32:
+		return errors.New("kernel or difficulty too long")
+	}
+	return nil
+}
+
+// leaderboard ranks submitted scores, best first. httpLeaderboard is the
+// client of a leaderboard served over HTTPS, and fileLeaderboard the store
+// behind snake-ebpf leaderboard; a self-hosted backend can put any other
+// store behind leaderboardHandler.
+type leaderboard interface {
+	Submit(ctx context.Context, e leaderboardEntry) error
+	Top(ctx context.Context, limit int) ([]leaderboardEntry, error)
+}
+
+// httpLeaderboard talks to a leaderboard over its HTTP API: POST
+// /api/v1/scores with an entry, and GET /api/v1/scores?limit=N for the top
+// entries.
+type httpLeaderboard struct {
+	base   *url.URL
+	client *http.Client
+}
+
+// newHTTPLeaderboard returns the client of the leaderboard at rawURL, which
+// must be https unless it is on the loopback interface.
+func newHTTPLeaderboard(rawURL string) (*httpLeaderboard, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("leaderboard URL: %w", err)
+	}
+	switch base.Scheme {
+	case "https":
+	case "http":
+		if ip := net.ParseIP(base.Hostname()); base.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("leaderboard URL %s: must be https", rawURL)
+		}
+	default:
+		return nil, fmt.Errorf("leaderboard URL %s: must be https", rawURL)
+	}
+	return &httpLeaderboard{base: base, client: &http.Client{Timeout: LEADERBOARD_TIMEOUT}}, nil
+}
+
+func (l *httpLeaderboard) scoresURL() *url.URL {
+	return l.base.JoinPath("api", "v1", "scores")
+}
+
+func (l *httpLeaderboard) Submit(ctx context.Context, e leaderboardEntry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode score: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.scoresURL().String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("submit score: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("submit score: %w", err)
+	}
+	defer resp.Body.Close()
+	return leaderboardStatus(resp)
+}
+
+func (l *httpLeaderboard) Top(ctx context.Context, limit int) ([]leaderboardEntry, error) {
+	u := l.scoresURL()
+	u.RawQuery = url.Values{"limit": {strconv.Itoa(limit)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch leaderboard: %w", err)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch leaderboard: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := leaderboardStatus(resp); err != nil {
+		return nil, err
+	}
+	var entries []leaderboardEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decode leaderboard: %w", err)
+	}
+	return entries, nil
+}
+
+// leaderboardStatus turns an error response into an error with the
+// server's message.
+func leaderboardStatus(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("leaderboard: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func leaderboardPath() string {
+	return filepath.Join(userHomeDir(), ".local", "share", "snake-ebpf", "leaderboard.json")
+}
+
+// fileLeaderboard keeps the best LEADERBOARD_SIZE scores in a JSON file.
+type fileLeaderboard struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (l *fileLeaderboard) load() ([]leaderboardEntry, error) {
+	var entries []leaderboardEntry
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read leaderboard: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode leaderboard: %w", err)
+	}
+	return entries, nil
+}
+
+func (l *fileLeaderboard) Submit(_ context.Context, e leaderboardEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+	slices.SortStableFunc(entries, func(a, b leaderboardEntry) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), a.Time.Compare(b.Time))
+	})
+	entries = entries[:min(len(entries), LEADERBOARD_SIZE)]
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode leaderboard: %w", err)
+	}
+	return writeDataFile(l.path, append(data, '\n'), "leaderboard")
+}
+
+func (l *fileLeaderboard) Top(_ context.Context, limit int) ([]leaderboardEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return entries[:min(len(entries), limit)], nil
+}
+
+// leaderboardHandler serves the HTTP API of httpLeaderboard on top of lb.
+// Scores are taken as the players submit them, stamped with the time they
+// arrived.
+func leaderboardHandler(lb leaderboard) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/scores", func(w http.ResponseWriter, r *http.Request) {
+		var e leaderboardEntry
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_LEADERBOARD_BODY)).Decode(&e); err != nil {
+			http.Error(w, "invalid score: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := e.validate(); err != nil {
+			http.Error(w, "invalid score: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		e.Time = time.Now().UTC()
+		if err := lb.Submit(r.Context(), e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/v1/scores", func(w http.ResponseWriter, r *http.Request) {
+		limit := 10
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, LEADERBOARD_SIZE)
+		}
+		entries, err := lb.Top(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []leaderboardEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	})
+	return mux
+}
+
+// scoreSubmitter submits the rounds of a game in the background and hands
+// the outcome to the game loop as a toast.
+type scoreSubmitter struct {
+	board   leaderboard
+	player  string
+	notices chan<- string
+	wg      sync.WaitGroup
+}
+
+func newScoreSubmitter(board leaderboard, player string, notices chan<- string) *scoreSubmitter {
+	return &scoreSubmitter{board: board, player: player, notices: notices}
+}
+
+func (s *scoreSubmitter) submit(e leaderboardEntry) {
+	e.Player = s.player
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), LEADERBOARD_TIMEOUT)
+		defer cancel()
+		msg := fmt.Sprintf("🏆 score %d submitted to the leaderboard", e.Score)
+		if err := s.board.Submit(ctx, e); err != nil {
+			msg = err.Error()
+		}
+		select {
+		case s.notices <- msg:
+		default:
+		}
+	}()
+}
+
+// wait lets submissions still running finish before the game exits.
+func (s *scoreSubmitter) wait() {
+	s.wg.Wait()
+}
+
+// defaultPlayerName is the name scores are submitted under without -player:
+// the user who ran the game, through sudo or not.
+func defaultPlayerName() string {
+	for _, name := range []string{os.Getenv("SUDO_USER"), os.Getenv("USER")} {
+		if name != "" && name != "root" {
+			return name
+		}
+	}
+	return "anonymous"
+}
+
+// runTop prints the rankings of a leaderboard.
+func runTop(ctx context.Context, args []string) int {
+	fs := newFlagSet("top", "")
+	boardURL := fs.String("leaderboard", "", "leaderboard URL, e.g. https://snake.example.org")
+	n := fs.Int("n", 10, fmt.Sprintf("number of scores to list, at most %d", LEADERBOARD_SIZE))
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	if _, code, ok := loadCommandConfig(fs, *cfgPath); !ok {
+		return code
+	}
+	if *boardURL == "" {
+		fmt.Fprintln(os.Stderr, "Invalid flags: top needs -leaderboard")
+		return EXIT_USAGE
+	}
+	if *n < 1 || *n > LEADERBOARD_SIZE {
+		fmt.Fprintf(os.Stderr, "Invalid -n %d: must be between 1 and %d\n", *n, LEADERBOARD_SIZE)
+		return EXIT_USAGE
+	}
+	board, err := newHTTPLeaderboard(*boardURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	entries, err := board.Top(ctx, *n)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to fetch leaderboard: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tPLAYER\tSCORE\tLENGTH\tPEAK EVENTS/S\tDIFFICULTY\tKERNEL\tDATE")
+	for i, e := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n", i+1, e.Player, e.Score, e.Length, e.PeakEventRate, e.Difficulty, e.Kernel, e.Time.Local().Format(time.DateOnly))
+	}
+	w.Flush()
+	return EXIT_OK
+}
+
+// runLeaderboard serves a leaderboard for play -leaderboard and top,
+// keeping the scores in a file.
+func runLeaderboard(ctx context.Context, args []string) int {
+	fs := newFlagSet("leaderboard", "")
+	addr := fs.String("listen", "", "listen address for the leaderboard, e.g. :8443")
+	dbPath := fs.String("db", leaderboardPath(), "JSON file the scores are kept in")
+	certFile := fs.String("tls-cert", "", "TLS certificate to serve HTTPS with")
+	keyFile := fs.String("tls-key", "", "TLS private key of -tls-cert")
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	if *addr == "" {
+		fmt.Fprintln(os.Stderr, "Invalid flags: leaderboard needs -listen")
+		return EXIT_USAGE
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		fmt.Fprintln(os.Stderr, "Invalid flags: -tls-cert and -tls-key go together")
+		return EXIT_USAGE
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *addr, err)
+		return EXIT_FAILURE
+	}
+	server := &http.Server{
+		Handler:           leaderboardHandler(&fileLeaderboard{path: *dbPath}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	context.AfterFunc(ctx, func() { server.Close() })
+	if *certFile != "" {
+		fmt.Fprintf(os.Stderr, "Leaderboard on https://%s/, scores kept in %s\n", *addr, *dbPath)
+		err = server.ServeTLS(l, *certFile, *keyFile)
+	} else {
+		fmt.Fprintf(os.Stderr, "Leaderboard on http://%s/, scores kept in %s\n", *addr, *dbPath)
+		fmt.Fprintln(os.Stderr, "Warning: serving plain HTTP, which players only submit to on localhost; put it behind a TLS proxy or use -tls-cert")
+		err = server.Serve(l)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Failed to serve leaderboard: %v\n", err)
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/tui"
+)
+
+// LOG_HOLD_LIMIT bounds the log output held back while the game is on
+// screen; what comes after is dropped.
+const LOG_HOLD_LIMIT = 1 << 20
+
+// logOptions are the flags that set how much is logged and where.
+type logOptions struct {
+	verbose *bool
+	debug   *bool
+	quiet   *bool
+	file    *string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logOptions {
+	return &logOptions{
+		verbose: fs.Bool("verbose", false, "log what the probes attached to and other diagnostics"),
+		debug:   fs.Bool("debug", false, "log everything -verbose does plus every failed map read and terminal detail"),
+		quiet:   fs.Bool("quiet", false, "log errors only"),
+		file:    fs.String("log-file", "", "file to append the log to instead of stderr"),
+	}
+}
+
+func (o *logOptions) level() slog.Level {
+	switch {
+	case *o.quiet:
+		return slog.LevelError
+	case *o.debug:
+		return slog.LevelDebug
+	case *o.verbose:
+		return slog.LevelInfo
+	}
+	return slog.LevelWarn
+}
+
+// setup makes the default slog logger write at the level of the flags.
+// Without -log-file it writes to stderr, held back while the game is on
+// screen and written once the terminal is restored. The returned function
+// closes the log and must run before the program exits.
+func (o *logOptions) setup() (func(), error) {
+	if *o.quiet && (*o.verbose || *o.debug) {
+		return nil, fmt.Errorf("flags: -quiet cannot be combined with -verbose or -debug")
+	}
+	var w io.Writer = stderrLog
+	done := stderrLog.flush
+	if *o.file != "" {
+		f, err := os.OpenFile(*o.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("-log-file: %w", err)
+		}
+		w, done = f, func() { f.Close() }
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: o.level()})))
+	return done, nil
+}
+
+// heldWriter writes to stderr unless the game is drawing on the terminal,
+// in which case it keeps the output until flush or the next write after
+// the terminal was restored.
+type heldWriter struct {
+	mu      sync.Mutex
+	held    bytes.Buffer
+	dropped int
+}
+
+var stderrLog = &heldWriter{}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if tui.TerminalActive() {
+		if h.held.Len()+len(p) > LOG_HOLD_LIMIT {
+			h.dropped++
+		} else {
+			h.held.Write(p)
+		}
+		return len(p), nil
+	}
+	h.flushLocked()
+	return os.Stderr.Write(p)
+}
+
+// flush writes what was held back.
+func (h *heldWriter) flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flushLocked()
+}
+
+func (h *heldWriter) flushLocked() {
+	os.Stderr.Write(h.held.Bytes())
+	h.held.Reset()
+	if h.dropped > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d log records dropped while the game was on screen\n", h.dropped)
+		h.dropped = 0
+	}
+}
+
+// logProbes logs what every probe attached to, or why it did not.
+func logProbes(probes *ebpfmon.AttachManager) {
+	mechanisms := probes.Mechanisms()
+	programs := make([]string, 0, len(mechanisms))
+	for program := range mechanisms {
+		programs = append(programs, program)
+	}
+	sort.Strings(programs)
+	for _, program := range programs {
+		slog.Info("probe attached", "program", program, "via", mechanisms[program])
+	}
+	for _, program := range probes.Disabled() {
+		slog.Info("probe disabled", "program", program)
+	}
+	for _, program := range probes.Unavailable() {
+		slog.Info("probe unavailable", "program", program, "err", probes.Failure(program))
+	}
+	for _, program := range probes.Unattached() {
+		slog.Info("probe not attached", "program", program, "err", probes.Failure(program))
+	}
+}
+
+// logReadError logs a failed read of the counters, given the error of the
+// read before, so a run of failures is reported once and then only at
+// debug level.
+func logReadError(err, prev error) {
+	switch {
+	case err != nil && prev == nil:
+		slog.Info("counters read failed", "err", err)
+	case err != nil:
+		slog.Debug("counters read failed", "err", err)
+	case prev != nil:
+		slog.Info("counters read again")
+	}
+}
+
+// logTerminal logs the terminal the game was set up on.
+func logTerminal() {
+	width, height := tui.TerminalSize()
+	slog.Debug("terminal set up", "width", width, "height", height, "term", os.Getenv("TERM"), "colorterm", os.Getenv("COLORTERM"))
+}
+
+// logShutdown logs why the command stops: ctx is done after a signal.
+func logShutdown(ctx context.Context, command string) {
+	reason := "finished"
+	if ctx.Err() != nil {
+		reason = "signal"
+	}
+	slog.Info("shutting down", "command", command, "reason", reason)
+}
@@ -1,687 +1,999 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"syscall"
 	"time"
-	"unsafe"
 
-	"github.com/cilium/ebpf"
-	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/rlimit"
-	"golang.org/x/sys/unix"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
 )
 
+const DEMO_RESTART_DELAY = 5 * time.Second
+
+// DISK_LAG_SLOWDOWN is added to the tick interval while block I/O is slow.
+const DISK_LAG_SLOWDOWN = 50 * time.Millisecond
+
 const (
-	POLL_INTERVAL = 350 * time.Millisecond
+	// INPUT_LAG_FACTOR is how many times the 99th percentile run-queue
+	// latency direction keys wait before they take effect, so that
+	// scheduling delays of milliseconds can be felt.
+	INPUT_LAG_FACTOR = 50
+	MAX_INPUT_LAG    = 500 * time.Millisecond
 )
 
-type Position struct {
-	X, Y int
+type session struct {
+	game          *game.Game
+	ui            *tui.UI
+	cfg           config
+	cfgPath       string
+	rates         *ebpfmon.Rates
+	bursts        *ebpfmon.BurstDetector
+	dangers       *ebpfmon.BurstDetector
+	interval      time.Duration
+	speed         game.SpeedScaling
+	now           time.Time
+	demo          bool
+	timedFood     bool
+	uprobe        bool
+	xdp           bool
+	inputLag      time.Duration
+	helpPaused    bool
+	activity      game.Activity
+	turns         []laggedTurn
+	peakEventRate uint64
+	stats         roundStats
+	gameOverAt    time.Time
+	scores        highScores
+	scoresPath    string
+	achievements  *achievementTracker
+	recorder      *replayRecorder
+	rescale       bool
+	noColor       bool
+	// peer is set when the second snake is played from another machine
+	// over -host, and peerTick is the last of its ticks played.
+	peer      bool
+	peerTick  int
+	submitter *scoreSubmitter
+	gifs      *gifExporter
 }
 
-type Game struct {
-	snake           []Position
-	direction       Position
-	food            Position
-	score           int
-	gameOver        bool
-	width           int
-	height          int
-	termWidth       int
-	termHeight      int
-	lastFoodSpawn   time.Time
-	ebpfMetrics     eBPFMetrics
+func main() {
+	os.Exit(run())
 }
 
-type eBPFMetrics struct {
-	execveCount        uint64
-	fileOpsCount       uint64
-	networkCount       uint64
-	processCount       uint64
-	contextSwitchCount uint64
-	eventRate          uint64
-	lastUpdate         time.Time
+func run() (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			tui.RestoreTerminal()
+			fmt.Fprintf(os.Stderr, "panic: %v\n%s", r, debug.Stack())
+			code = EXIT_PANIC
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	command, args := "play", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "play":
+		return runPlay(ctx, args)
+	case "monitor":
+		return runMonitor(ctx, args)
+	case "probes":
+		return runProbes(args)
+	case "replay":
+		return runReplayCommand(ctx, args)
+	case "gif":
+		return runGIF(ctx, args)
+	case "collect":
+		return runCollect(ctx, args)
+	case "serve":
+		return runServe(ctx, args)
+	case "join":
+		return runJoin(ctx, args)
+	case "top":
+		return runTop(ctx, args)
+	case "leaderboard":
+		return runLeaderboard(ctx, args)
+	case "achievements":
+		return runAchievements(args)
+	case "help":
+		usage(os.Stdout)
+		return EXIT_OK
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+	usage(os.Stderr)
+	return EXIT_USAGE
 }
 
-func main() {
-	if os.Geteuid() != 0 {
-		fmt.Fprintf(os.Stderr, "Error: This program must be run with sudo\n")
-		fmt.Fprintf(os.Stderr, "Please run: sudo ./snake-ebpf\n")
-		os.Exit(1)
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: snake-ebpf [command] [flags]
+
+Commands:
+  play         play the game (the default)
+  monitor      print the eBPF counters without the game
+  probes       attach the probes and report which ones work
+  replay       play back a run recorded with play -record
+  gif          export a run recorded with play -record as an animated GIF
+  collect      serve the eBPF counters on a UNIX socket to play -collector
+  serve        let remote players play over SSH against this host's counters
+  join         play the second snake of a game hosted with play -host
+  top          list the best scores of a leaderboard
+  leaderboard  host a leaderboard for play -leaderboard and top
+  achievements list the achievements and which of them are unlocked
+
+Run "snake-ebpf <command> -h" for the flags of a command.
+`)
+}
+
+// newFlagSet creates the flags of a command. operands describes its
+// positional arguments in the usage line.
+func newFlagSet(name, operands string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: snake-ebpf %s [flags]%s\n", name, operands)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a command that takes nargs positional
+// arguments. ok is false when the command should exit right away with code,
+// after -h or a usage error.
+func parseFlags(fs *flag.FlagSet, args []string, nargs int) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK, false
+		}
+		return EXIT_USAGE, false
 	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return EXIT_USAGE, false
+	}
+	return EXIT_OK, true
+}
 
+func addDisplayFlags(fs *flag.FlagSet) *displayOptions {
+	d := &displayOptions{}
+	fs.BoolVar(&d.ascii, "ascii", false, "draw with plain ASCII characters, for terminals and fonts without Unicode symbols")
+	fs.BoolVar(&d.noColor, "no-color", tui.NoColor(), "draw without colors (the default when NO_COLOR is set)")
+	return d
+}
+
+func addPinFlag(fs *flag.FlagSet) *string {
+	return fs.String("pin", "", "bpffs directory to pin the counting maps in, e.g. "+ebpfmon.BPFFS_PATH+"/snake; maps pinned there by an earlier run are reused")
+}
+
+// loadMonitor loads the eBPF programs, sets the event filter and attaches
+// the probes. code is not EXIT_OK when that failed and the caller should
+// exit with it.
+func loadMonitor(btfPath, pinDir string, disabled []string, filter ebpfmon.Filter) (*ebpfmon.Monitor, *ebpfmon.AttachManager, int) {
 	if err := rlimit.RemoveMemlock(); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to remove memlock limit: %v\n", err)
-		os.Exit(1)
+		reportMissingCapabilities()
+		return nil, nil, exitCodeFor(err, EXIT_LOAD)
 	}
 
-	collection, err := loadEBPF()
+	mon, err := ebpfmon.Load(btfPath, pinDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load eBPF program: %v\n", err)
-		os.Exit(1)
+		reportLoadError(err)
+		reportMissingCapabilities()
+		return nil, nil, exitCodeFor(err, EXIT_LOAD)
+	}
+	slog.Debug("eBPF objects loaded", "btf", btfPath, "pin", pinDir, "reused_pins", mon.ReusedPins(), "lsm", mon.LSM())
+	if mon.ReusedPins() {
+		fmt.Fprintf(os.Stderr, "Counting on from the maps pinned in %s\n", pinDir)
 	}
-	defer collection.Close()
 
-	links, err := attachAllKprobes(collection)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to attach kprobes: %v\n", err)
-		os.Exit(1)
+	if err := mon.SetFilter(filter); err != nil {
+		mon.Close()
+		fmt.Fprintf(os.Stderr, "Failed to set event filter: %v\n", err)
+		return nil, nil, exitCodeFor(err, EXIT_LOAD)
 	}
-	defer func() {
-		for _, link := range links {
-			if link != nil {
-				link.Close()
-			}
-		}
-	}()
 
-	setupTerminal()
-	defer restoreTerminal()
+	probes, err := mon.Attach(disabled...)
+	if err != nil {
+		mon.Close()
+		fmt.Fprintf(os.Stderr, "Failed to attach probes: %v\n", err)
+		reportMissingCapabilities()
+		return nil, nil, exitCodeFor(err, EXIT_ATTACH)
+	}
+	logProbes(probes)
+	return mon, probes, EXIT_OK
+}
 
-	termWidth, termHeight := getTerminalSize()
-	
-	gameWidth := (termWidth * 3) / 10
-	gameHeight := (termHeight * 3) / 10
-	
-	if gameWidth < 18 {
-		gameWidth = 18
+func runPlay(ctx context.Context, args []string) int {
+	fs := newFlagSet("play", "")
+	watch := fs.String("watch", "nc,ncat,socat,nmap,gdb,strace", "comma-separated binaries that raise a toast when executed")
+	metricsAddr := fs.String("metrics-addr", "", "listen address for the Prometheus /metrics endpoint (disabled when empty)")
+	enableOTLP := fs.Bool("otlp", false, "export the counters and game stats over OTLP to the OpenTelemetry collector set by the OTEL_EXPORTER_OTLP_* environment variables")
+	influxURL := fs.String("influx-url", "", "InfluxDB write URL, e.g. http://localhost:8086/api/v2/write?org=home&bucket=snake, to write the counters, rates and game stats to every tick (disabled when empty)")
+	statsdAddr := fs.String("statsd-addr", "", "statsd agent address, e.g. localhost:8125, to send the counters and game stats to over UDP every tick (disabled when empty)")
+	statsdPrefix := fs.String("statsd-prefix", STATSD_PREFIX, "prefix of every statsd metric name")
+	statsdTagList := fs.String("statsd-tags", "", "comma-separated key:value tags added to every statsd metric, in the DogStatsD format")
+	logMetrics := fs.String("log-metrics", "", "file to append a JSON object with the counters, rates, tick interval, score and length to every tick (disabled when empty)")
+	apiAddr := fs.String("api-addr", "", "listen address for the REST control API (disabled when empty)")
+	spectateAddr := fs.String("spectate-addr", "", "listen address for a browser viewer that lets others watch the game live over a WebSocket (disabled when empty)")
+	dashboardAddr := fs.String("dashboard-addr", "", "listen address for a web dashboard of the board, the score and charts of the kernel counters, e.g. for a projector (disabled when empty)")
+	grpcAddr := fs.String("grpc-addr", "", "listen address for the gRPC API, which streams metrics and game state and takes input (disabled when empty)")
+	snapshotPath := fs.String("snapshot-path", "", "file written with a JSON state snapshot on SIGUSR1 (stderr when empty)")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	enableDBus := fs.Bool("dbus", false, "publish score and metrics on the session D-Bus")
+	pinDir := addPinFlag(fs)
+	showProcs := fs.Bool("procs", false, "show the top processes panel next to the board")
+	width := fs.Int("width", 0, fmt.Sprintf("board width in cells, at least %d (fitted to the terminal when 0)", BOARD_MIN_WIDTH))
+	height := fs.Int("height", 0, fmt.Sprintf("board height in cells, at least %d (fitted to the terminal when 0)", BOARD_MIN_HEIGHT))
+	difficultyName := fs.String("difficulty", "normal", "difficulty, which sets the speed: "+strings.Join(game.DifficultyNames(), ", "))
+	seed := fs.Uint64("seed", 0, "seed for food, obstacle and power-up placement, for reproducible runs (random when 0)")
+	wrap := fs.Bool("wrap", false, "let the snake pass through the walls to the opposite side")
+	enemy := fs.Bool("enemy", false, "add a kernel snake that grows and speeds up with the context-switch rate")
+	twoPlayer := fs.Bool("two-player", false, "add a second snake steered with IJKL or the arrow keys, while WASD steer the first")
+	hostAddr := fs.String("host", "", "listen address, e.g. :7777, to wait on for a player who joins with snake-ebpf join and plays the second snake at their own machine's speed")
+	demo := fs.Bool("demo", false, "let an autopilot play so the game runs unattended as a dashboard")
+	recordPath := fs.String("record", "", "record the run to this replay file, or the screen to an asciicast when it ends in .cast")
+	boardURL := fs.String("leaderboard", "", "HTTPS URL of a leaderboard to submit every finished round to, with the snake's length, the kernel version and the peak event rate (disabled when empty)")
+	player := fs.String("player", defaultPlayerName(), "name to submit scores to the leaderboard under")
+	display := addDisplayFlags(fs)
+	rescale := fs.Bool("rescale", false, "resize the board mid-game when the terminal is resized")
+	enableGamepad := fs.Bool("gamepad", false, "steer with the d-pad or left stick of the first joystick at "+GAMEPAD_GLOB+", which also pauses and restarts with its buttons")
+	noTitle := fs.Bool("no-title", false, "start the game right away instead of showing the title screen")
+	mouse := fs.Bool("mouse", false, "steer with mouse clicks and drags beside the snake's head, in terminals that report the mouse")
+	fps := fs.Int("fps", DEFAULT_FPS, fmt.Sprintf("frames drawn per second, independent of the game speed, %d to %d", MIN_FPS, MAX_FPS))
+	apiToken := fs.String("api-token", os.Getenv("SNAKE_EBPF_API_TOKEN"), "bearer token for the REST control and gRPC APIs (generated when empty)")
+	dryRun := fs.Bool("dry-run", false, "only load the programs, try every attach candidate and print the results, like probes -all")
+	filters := addFilterFlags(fs)
+	containers := addContainerFlags(fs)
+	probeOpts := addProbeFlags(fs)
+	collectorPath := fs.String("collector", "", "UNIX socket of a collector, e.g. "+COLLECTOR_SOCKET+", to play against instead of loading the probes, which needs no privileges")
+	logOpts := addLogFlags(fs)
+	pprofAddr := addPprofFlag(fs)
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	closeLog, err := logOpts.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
 	}
-	if gameWidth > 32 {
-		gameWidth = 32
+	defer closeLog()
+	stopPprof, ok := startPprof(*pprofAddr)
+	if !ok {
+		return EXIT_FAILURE
 	}
-	if gameHeight < 8 {
-		gameHeight = 8
+	defer stopPprof()
+	filter, err := filters.filter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
 	}
-	if gameHeight > 16 {
-		gameHeight = 16
+	if err := probeOpts.parse(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
 	}
-	
-	if termWidth < gameWidth+4 || termHeight < gameHeight+4 {
-		gameWidth = 20
-		gameHeight = 10
+	if *dryRun {
+		return checkProbes(*btfPath, cfg.Probes, true)
 	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 
-	fmt.Println("eBPF program attached! Starting Snake game...")
-	time.Sleep(1 * time.Second)
-
-	startX := gameWidth / 2
-	startY := gameHeight / 2
-	game := &Game{
-		snake: []Position{
-			{startX, startY},
-			{startX - 1, startY},
-			{startX - 2, startY},
-		},
-		direction:  Position{X: 1, Y: 0},
-		gameOver:   false,
-		width:      gameWidth,
-		height:     gameHeight,
-		termWidth:  termWidth,
-		termHeight: termHeight,
-		ebpfMetrics: eBPFMetrics{},
+	difficulty, err := game.LookupDifficulty(*difficultyName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -difficulty: %v\n", err)
+		return EXIT_USAGE
+	}
+	var board leaderboard
+	if *boardURL != "" {
+		if board, err = newHTTPLeaderboard(*boardURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+			return EXIT_USAGE
+		}
+		if *player == "" || len([]rune(*player)) > MAX_PLAYER_NAME {
+			fmt.Fprintf(os.Stderr, "Invalid -player %q: must be 1 to %d characters\n", *player, MAX_PLAYER_NAME)
+			return EXIT_USAGE
+		}
 	}
-	game.spawnFood()
-	game.lastFoodSpawn = time.Now()
-
-	execveMap := collection.Maps["execve_counter"]
-	fileOpsMap := collection.Maps["file_ops_counter"]
-	networkMap := collection.Maps["network_counter"]
-	processMap := collection.Maps["process_counter"]
-	contextSwitchMap := collection.Maps["context_switch_counter"]
-	eventRateMap := collection.Maps["event_rate"]
-
-	if execveMap == nil || fileOpsMap == nil || networkMap == nil ||
-		processMap == nil || contextSwitchMap == nil || eventRateMap == nil {
-		fmt.Fprintf(os.Stderr, "Warning: Some eBPF maps not found, using defaults\n")
+	if *fps < MIN_FPS || *fps > MAX_FPS {
+		fmt.Fprintf(os.Stderr, "Invalid -fps %d: must be %d to %d\n", *fps, MIN_FPS, MAX_FPS)
+		return EXIT_USAGE
+	}
+	if *width != 0 && *width < BOARD_MIN_WIDTH || *height != 0 && *height < BOARD_MIN_HEIGHT {
+		fmt.Fprintf(os.Stderr, "Invalid board size %dx%d: must be at least %dx%d\n", *width, *height, BOARD_MIN_WIDTH, BOARD_MIN_HEIGHT)
+		return EXIT_USAGE
 	}
 
-	game.render()
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	baseInterval := POLL_INTERVAL
-	currentInterval := baseInterval
-	ticker := time.NewTicker(currentInterval)
-	defer ticker.Stop()
-
-	inputChan := make(chan string, 1)
-	go readInput(inputChan)
-
-	for !game.gameOver {
-		select {
-		case <-sigChan:
-			game.gameOver = true
-			break
-		case <-ticker.C:
-			var key uint32 = 0
-			metrics := eBPFMetrics{lastUpdate: time.Now()}
-			
-			if execveMap != nil {
-				execveMap.Lookup(&key, unsafe.Pointer(&metrics.execveCount))
-			}
-			if fileOpsMap != nil {
-				fileOpsMap.Lookup(&key, unsafe.Pointer(&metrics.fileOpsCount))
-			}
-			if networkMap != nil {
-				networkMap.Lookup(&key, unsafe.Pointer(&metrics.networkCount))
-			}
-			if processMap != nil {
-				processMap.Lookup(&key, unsafe.Pointer(&metrics.processCount))
-			}
-			if contextSwitchMap != nil {
-				contextSwitchMap.Lookup(&key, unsafe.Pointer(&metrics.contextSwitchCount))
-			}
-			if eventRateMap != nil {
-				eventRateMap.Lookup(&key, unsafe.Pointer(&metrics.eventRate))
-			}
-			
-			game.ebpfMetrics = metrics
-			
-			if metrics.fileOpsCount > 0 {
-				spawnInterval := 15 * time.Second
-				fileOpsBonus := time.Duration(metrics.fileOpsCount/50) * 100 * time.Millisecond
-				if fileOpsBonus > 3*time.Second {
-					fileOpsBonus = 3 * time.Second
-				}
-				spawnInterval = spawnInterval - fileOpsBonus
-				if spawnInterval < 5*time.Second {
-					spawnInterval = 5 * time.Second
-				}
-				
-				if time.Since(game.lastFoodSpawn) > spawnInterval {
-					game.spawnFood()
-					game.lastFoodSpawn = time.Now()
-				}
-			}
-			
-			changed := game.update()
-			if changed {
-				game.render()
-				
-				scoreSpeedReduction := time.Duration(game.score) * 1 * time.Millisecond
-				
-				execveSpeedReduction := time.Duration(metrics.execveCount) * 500 * time.Microsecond
-				if execveSpeedReduction > 30*time.Millisecond {
-					execveSpeedReduction = 30 * time.Millisecond
-				}
-				
-				processSpeedReduction := time.Duration(metrics.processCount/3) * time.Millisecond
-				if processSpeedReduction > 25*time.Millisecond {
-					processSpeedReduction = 25 * time.Millisecond
-				}
-				
-				rateSpeedReduction := time.Duration(metrics.eventRate) * 1 * time.Millisecond
-				if rateSpeedReduction > 30*time.Millisecond {
-					rateSpeedReduction = 30 * time.Millisecond
-				}
-				
-				loadSpeedReduction := time.Duration(metrics.contextSwitchCount/1500) * time.Millisecond
-				if loadSpeedReduction > 15*time.Millisecond {
-					loadSpeedReduction = 15 * time.Millisecond
-				}
-				
-				newInterval := baseInterval - scoreSpeedReduction - execveSpeedReduction - 
-					processSpeedReduction - rateSpeedReduction - loadSpeedReduction
-				
-				if newInterval < 100*time.Millisecond {
-					newInterval = 100 * time.Millisecond
-				}
-				
-				if newInterval != currentInterval {
-					currentInterval = newInterval
-					ticker.Stop()
-					ticker = time.NewTicker(currentInterval)
-				}
+	var src kernelSource
+	watchlist := &ebpfmon.ExecWatchlist{}
+	var watchErr error
+	if *collectorPath != "" {
+		for _, name := range collectorFlags {
+			if flagGiven(fs, name) {
+				fmt.Fprintf(os.Stderr, "Warning: -%s is ignored with -collector\n", name)
 			}
+		}
+		client, err := dialCollector(*collectorPath, containers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to collector at %s: %v\n", *collectorPath, err)
+			return EXIT_FAILURE
+		}
+		defer client.Close()
+		src = client
+	} else {
+		mon, probes, code := loadMonitor(*btfPath, *pinDir, cfg.Probes, filter)
+		if code != EXIT_OK {
+			return code
+		}
+		defer mon.Close()
+		defer probes.Close()
+		for _, name := range probes.Unattached() {
+			fmt.Fprintf(os.Stderr, "Warning: %s not attached: %v\n", name, strings.ReplaceAll(probes.Failure(name).Error(), "\n", "; "))
+		}
+		stats, code := probeOpts.attach(mon, probes)
+		if code != EXIT_OK {
+			return code
+		}
+		if stats != nil {
+			defer stats.Close()
+		}
+		if w, err := mon.NewExecWatchlist(strings.Split(*watch, ",")); err != nil {
+			watchErr = err
+		} else {
+			watchlist = w
+		}
+		src = newLocalSource(mon, probes, containers, probeOpts)
+	}
+	status := src.Status()
 
-		case input := <-inputChan:
-			dirChanged := false
-			switch input {
-			case "w", "W", "up":
-				if game.direction.Y == 0 {
-					game.direction = Position{X: 0, Y: -1}
-					dirChanged = true
-				}
-			case "s", "S", "down":
-				if game.direction.Y == 0 {
-					game.direction = Position{X: 0, Y: 1}
-					dirChanged = true
-				}
-			case "a", "A", "left":
-				if game.direction.X == 0 {
-					game.direction = Position{X: -1, Y: 0}
-					dirChanged = true
-				}
-			case "d", "D", "right":
-				if game.direction.X == 0 {
-					game.direction = Position{X: 1, Y: 0}
-					dirChanged = true
-				}
-			case "q", "Q":
-				game.gameOver = true
+	exporter := &metricsExporter{}
+	if *metricsAddr != "" {
+		server, err := startMetricsServer(*metricsAddr, exporter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start metrics endpoint: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer server.Close()
+		fmt.Fprintf(os.Stderr, "Prometheus metrics on http://%s/metrics\n", *metricsAddr)
+	}
+	// notices are shown as toasts by the game loop.
+	notices := make(chan string, 4)
+	if *enableOTLP {
+		provider, err := startOTLPExporter(ctx, exporter, func(err error) {
+			select {
+			case notices <- "otlp: " + err.Error():
+			default:
 			}
-			if dirChanged {
-				game.render()
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start OTLP exporter: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer shutdownOTLP(provider)
+		fmt.Fprintf(os.Stderr, "Exporting metrics over OTLP to %s\n", otlpEndpoint())
+	}
+	var influx *influxExporter
+	if *influxURL != "" {
+		influx, err = startInfluxExporter(ctx, *influxURL, func(err error) {
+			select {
+			case notices <- err.Error():
+			default:
 			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+			return EXIT_USAGE
 		}
 	}
-
-	fmt.Println("\nGame Over!")
-	fmt.Printf("Final Score: %d\n", game.score)
-}
-
-func loadEBPF() (*ebpf.Collection, error) {
-	bpfPaths := []string{
-		"bpf/snake.bpf.o",
-		"../bpf/snake.bpf.o",
-		"./bpf/snake.bpf.o",
+	var statsd *statsdEmitter
+	if *statsdAddr != "" {
+		statsd, err = newStatsdEmitter(*statsdAddr, *statsdPrefix, *statsdTagList, func(err error) {
+			select {
+			case notices <- err.Error():
+			default:
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+			return EXIT_USAGE
+		}
+		defer statsd.Close()
 	}
-	
-	var spec *ebpf.CollectionSpec
-	var err error
-	for _, path := range bpfPaths {
-		spec, err = ebpf.LoadCollectionSpec(path)
-		if err == nil {
-			break
+	var metricsFile *metricsLog
+	if *logMetrics != "" {
+		metricsFile, err = openMetricsLog(*logMetrics, func(err error) {
+			select {
+			case notices <- err.Error():
+			default:
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+			return EXIT_FAILURE
 		}
+		defer metricsFile.Close()
 	}
+
+	scoresPath := highScoresPath()
+	scores, err := loadHighScores(scoresPath)
 	if err != nil {
-		return nil, fmt.Errorf("load collection spec (tried paths: %v): %w", bpfPaths, err)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	achievements := &achievementTracker{path: achievementsPath()}
+	if achievements.unlocked, err = loadAchievements(achievements.path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
-	collection, err := ebpf.NewCollection(spec)
-	if err != nil {
-		return nil, fmt.Errorf("new collection: %w", err)
-	}
-
-	var key uint32 = 0
-	var value uint64 = 0
-	
-	mapsToInit := []string{
-		"execve_counter",
-		"file_ops_counter",
-		"network_counter",
-		"process_counter",
-		"context_switch_counter",
-		"event_rate",
-	}
-	
-	for _, mapName := range mapsToInit {
-		if m := collection.Maps[mapName]; m != nil {
-			if err := m.Put(&key, unsafe.Pointer(&value)); err != nil {
-				return nil, fmt.Errorf("initialize %s map: %w", mapName, err)
-			}
-		}
+	if *seed == 0 {
+		*seed = game.RandomSeed()
 	}
 
-	return collection, nil
-}
+	gameWidth, gameHeight := boardSize(tui.TerminalSize())
+	if *width != 0 {
+		gameWidth = *width
+	}
+	if *height != 0 {
+		gameHeight = *height
+	}
 
-func attachAllKprobes(collection *ebpf.Collection) ([]link.Link, error) {
-	var links []link.Link
-	
-	if prog := collection.Programs["handle_execve"]; prog != nil {
-		probeNames := []string{
-			"sys_enter_execve",
-			"__x64_sys_execve",
-			"__arm64_sys_execve",
-			"__s390x_sys_execve",
-			"__x86_sys_execve",
-		}
-		for _, name := range probeNames {
-			if kp, err := link.Kprobe(name, prog, nil); err == nil {
-				links = append(links, kp)
-				break
-			}
+	controlChan := make(chan controlCommand)
+	if (*apiAddr != "" || *grpcAddr != "") && *apiToken == "" {
+		if *apiToken, err = generateAPIToken(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start control API: %v\n", err)
+			return EXIT_FAILURE
 		}
 	}
-	
-	if prog := collection.Programs["handle_file_open"]; prog != nil {
-		probeNames := []string{
-			"do_sys_openat2",
-			"do_sys_open",
-			"__x64_sys_openat",
+	if *apiAddr != "" {
+		server, err := startAPIServer(ctx, *apiAddr, *apiToken, controlChan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start control API: %v\n", err)
+			return EXIT_FAILURE
 		}
-		for _, name := range probeNames {
-			if kp, err := link.Kprobe(name, prog, nil); err == nil {
-				links = append(links, kp)
-				break
-			}
+		defer server.Close()
+		fmt.Printf("Control API listening on %s (token: %s)\n", *apiAddr, *apiToken)
+	}
+	var spectators *spectateServer
+	if *spectateAddr != "" {
+		if spectators, err = startSpectateServer(*spectateAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start spectator server: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer spectators.close()
+		fmt.Printf("Spectators can watch on http://%s/\n", *spectateAddr)
+	}
+	var dashboard *dashboardServer
+	if *dashboardAddr != "" {
+		if dashboard, err = startDashboardServer(*dashboardAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start dashboard: %v\n", err)
+			return EXIT_FAILURE
 		}
+		defer dashboard.close()
+		fmt.Printf("Dashboard on http://%s/\n", *dashboardAddr)
+	}
+	var rpc *grpcService
+	if *grpcAddr != "" {
+		if rpc, err = startGRPCServer(ctx, *grpcAddr, *apiToken, controlChan); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start gRPC API: %v\n", err)
+			return EXIT_FAILURE
+		}
+		defer rpc.close()
+		fmt.Printf("gRPC API listening on %s (token: %s)\n", *grpcAddr, *apiToken)
 	}
-	
-	if prog := collection.Programs["handle_network_connect"]; prog != nil {
-		probeNames := []string{
-			"tcp_v4_connect",
-			"tcp_v6_connect",
+
+	var bus *dbusService
+	if *enableDBus {
+		if bus, err = startDBusService(controlChan); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: D-Bus disabled: %v\n", err)
+		} else {
+			defer bus.close()
 		}
-		for _, name := range probeNames {
-			if kp, err := link.Kprobe(name, prog, nil); err == nil {
-				links = append(links, kp)
-				break
+	}
+
+	var peer *netHost
+	if *hostAddr != "" {
+		if peer, err = acceptPeer(ctx, *hostAddr); err != nil {
+			if ctx.Err() != nil {
+				return EXIT_OK
 			}
+			fmt.Fprintf(os.Stderr, "Failed to host game: %v\n", err)
+			return EXIT_FAILURE
 		}
+		defer peer.conn.Close()
 	}
-	
-	if prog := collection.Programs["handle_process_fork"]; prog != nil {
-		probeNames := []string{
-			"_do_fork",
-			"kernel_clone",
-			"__x64_sys_clone",
+
+	var cast *tui.CastWriter
+	if strings.HasSuffix(*recordPath, ".cast") {
+		var created []string
+		if created, err = mkdirAllOwned(filepath.Dir(*recordPath)); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create cast dir: %v\n", err)
+			return EXIT_FAILURE
 		}
-		for _, name := range probeNames {
-			if kp, err := link.Kprobe(name, prog, nil); err == nil {
-				links = append(links, kp)
-				break
+		if cast, err = tui.NewCastWriter(*recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+			return EXIT_FAILURE
+		}
+		if err := chownToSudoUser(append(created, *recordPath)...); err != nil {
+			cast.Close()
+			fmt.Fprintf(os.Stderr, "Failed to %v\n", err)
+			return EXIT_FAILURE
+		}
+		// Closed after ui, whose frames are still being written until then.
+		defer func() {
+			if err := cast.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
+		}()
+	}
+
+	if err := tui.SetupTerminal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up terminal: %v\n", err)
+		return EXIT_FAILURE
+	}
+	defer tui.RestoreTerminal()
+	logTerminal()
+
+	ui := tui.New(gameWidth, gameHeight)
+	ui.Resize(tui.TerminalSize())
+	ui.ShowProcs = *showProcs
+	ui.ShowOverhead = status.Stats
+	ui.StatsEnabled = status.Stats
+	ui.Demo = *demo
+	ui.Uprobe = status.Uprobe
+	ui.USDT = status.USDT
+	ui.XDP = status.XDP
+	ui.Egress = status.Egress
+	ui.Probes = status.probeStatus()
+	ui.Theme = tui.Themes[tui.ThemeIndex(cfg.Theme)]
+	display.apply(ui)
+	ui.Record = scores.record(false)
+	if cast != nil {
+		ui.RecordCast(cast)
+	}
+	defer ui.Close()
+
+	inputChan := make(chan string, tui.INPUT_BUFFER)
+	go tui.ReadInput(ctx, inputChan)
+
+	// A hosted game has a joiner waiting for it and a demo runs unattended,
+	// so neither waits on the title screen for a key.
+	if !*noTitle && !*demo && peer == nil {
+		source := "eBPF programs attached"
+		if *collectorPath != "" {
+			source = "Connected to the collector on " + *collectorPath
 		}
-	}
-	
-	if prog := collection.Programs["handle_context_switch"]; prog != nil {
-		if kp, err := link.Kprobe("__schedule", prog, nil); err == nil {
-			links = append(links, kp)
+		if difficulty, ok = titleScreen(ctx, ui, inputChan, cfg.Keys, source, difficulty); !ok {
+			return EXIT_OK
 		}
 	}
-	
-	if len(links) == 0 {
-		return nil, fmt.Errorf("failed to attach any kprobes")
-	}
-	
-	return links, nil
-}
-
-func (g *Game) update() bool {
-	if g.gameOver {
-		return false
-	}
 
-	if g.direction.X == 0 && g.direction.Y == 0 {
-		return false
+	s := &session{
+		game:       game.New(gameWidth, gameHeight, *seed),
+		ui:         ui,
+		cfg:        cfg,
+		cfgPath:    *cfgPath,
+		speed:      cfg.Speed,
+		rates:      ebpfmon.NewRates(ebpfmon.RATE_WINDOW),
+		bursts:     ebpfmon.NewBurstDetector(ebpfmon.ExecveCount, ebpfmon.BURST_EXECVE_RATE, ebpfmon.BURST_COOLDOWN),
+		dangers:    ebpfmon.NewBurstDetector(ebpfmon.DangerCount, ebpfmon.BURST_DANGER_RATE, ebpfmon.BURST_DANGER_COOLDOWN),
+		now:        time.Now(),
+		demo:       *demo,
+		uprobe:     status.Uprobe != "",
+		xdp:        status.XDP != "",
+		scores:     scores,
+		scoresPath: scoresPath,
+		rescale:    *rescale,
+		noColor:    display.noColor,
+		peer:       peer != nil,
+	}
+	s.achievements = achievements
+	s.gifs = newGIFExporter(notices)
+	if board != nil {
+		s.submitter = newScoreSubmitter(board, *player, notices)
+	}
+	s.startGame(difficulty, *wrap, *enemy, *twoPlayer || peer != nil)
+	g := s.game
+	for _, name := range status.Probes.Unattached {
+		ui.Toasts.Push(fmt.Sprintf("🔌 %s not attached", name))
+	}
+	if watchErr != nil {
+		ui.Toasts.Push(watchErr.Error())
+	}
+
+	eventChan, err := src.Events(ctx)
+	if err != nil {
+		ui.Toasts.Push(err.Error() + ", food spawns on a timer")
+		s.timedFood = true
 	}
-
-	head := g.snake[0]
-	newHead := Position{
-		X: head.X + g.direction.X,
-		Y: head.Y + g.direction.Y,
+	if *mouse {
+		ui.Toasts.Push(mouseHint(tui.EnableMouse()))
 	}
-
-	if newHead.X < 0 || newHead.X >= g.width ||
-		newHead.Y < 0 || newHead.Y >= g.height {
-		g.gameOver = true
-		return true
+	var padChan chan keyAction
+	if *enableGamepad {
+		pad, err := openGamepad()
+		if err != nil {
+			ui.Toasts.Push("🎮 " + err.Error() + ", steer with the keys")
+		} else {
+			ui.Toasts.Push("🎮 gamepad " + pad.Name())
+			padChan = make(chan keyAction, tui.INPUT_BUFFER)
+			go pad.run(ctx, padChan, func(err error) {
+				select {
+				case notices <- "🎮 " + err.Error():
+				default:
+				}
+			})
+		}
 	}
 
-	for i := 0; i < len(g.snake)-1; i++ {
-		segment := g.snake[i]
-		if newHead.X == segment.X && newHead.Y == segment.Y {
-			g.gameOver = true
-			return true
+	if *recordPath != "" && cast == nil {
+		recorder, err := newReplayRecorder(*recordPath, s.replayHeader(*enemy))
+		if err != nil {
+			ui.Toasts.Push(err.Error())
+		} else {
+			s.recorder = recorder
+			defer func() {
+				if err := recorder.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}()
 		}
+	} else if !*demo {
+		// Kept so the save key of the game-over screen can write it out.
+		s.recorder = newReplayBuffer(s.replayHeader(*enemy))
+	}
+
+	s.render()
+
+	snapshotChan := make(chan os.Signal, 1)
+	signal.Notify(snapshotChan, syscall.SIGUSR1)
+	defer signal.Stop(snapshotChan)
+
+	var publish []func(*session)
+	if bus != nil {
+		publish = append(publish, bus.publish)
+	}
+	if rpc != nil {
+		publish = append(publish, rpc.publish)
+	}
+	if spectators != nil {
+		publish = append(publish, spectators.publish)
+	}
+	if dashboard != nil {
+		publish = append(publish, dashboard.publish)
+	}
+	if influx != nil {
+		publish = append(publish, influx.publish)
+	}
+	if statsd != nil {
+		publish = append(publish, statsd.publish)
+	}
+	if metricsFile != nil {
+		publish = append(publish, metricsFile.publish)
+	}
+	var sync func(*session)
+	if peer != nil {
+		peer.serve(ctx, controlChan)
+		sync = peer.sync
+	}
+	ui.Container = containers.selector
+	s.run(ctx, gameLoop{
+		src:          src,
+		status:       status,
+		watchlist:    watchlist,
+		events:       eventChan,
+		input:        inputChan,
+		gamepad:      padChan,
+		resizes:      tui.Resizes(),
+		size:         tui.TerminalSize,
+		controls:     controlChan,
+		snapshots:    snapshotChan,
+		snapshotPath: *snapshotPath,
+		frame:        time.Second / time.Duration(*fps),
+		publish:      append(publish, exporter.publish),
+		sync:         sync,
+		notices:      notices,
+	})
+
+	logShutdown(ctx, "play")
+	stop()
+	if s.submitter != nil {
+		s.submitter.wait()
+	}
+	s.gifs.wait()
+	ui.Close()
+	tui.RestoreTerminal()
+
+	if !g.GameOver {
+		ui.Summary = s.stats.summary(s.now, s.peakEventRate)
 	}
-
-	oldSnakeLen := len(g.snake)
-	oldFood := g.food
-	ateFood := false
-	if newHead.X == g.food.X && newHead.Y == g.food.Y {
-		g.score++
-		ateFood = true
-		g.spawnFood()
-	} else {
-		g.snake = g.snake[:len(g.snake)-1]
+	fmt.Println("\nGame Over!")
+	for _, line := range ui.SummaryLines(g) {
+		fmt.Println(line)
 	}
+	fmt.Printf("Seed: %d\n", g.Seed)
+	return EXIT_OK
+}
 
-	g.snake = append([]Position{newHead}, g.snake...)
-	
-	if ateFood {
-		for i := 0; i < 2; i++ {
-			tail := g.snake[len(g.snake)-1]
-			g.snake = append(g.snake, tail)
-		}
+// gameLoop is what a game reads from while it runs. Channels left nil are
+// never ready.
+type gameLoop struct {
+	src          kernelSource
+	status       sourceStatus
+	watchlist    *ebpfmon.ExecWatchlist
+	events       <-chan ebpfmon.KernelEvent
+	input        <-chan string
+	gamepad      <-chan keyAction
+	resizes      <-chan struct{}
+	size         func() (int, int)
+	controls     <-chan controlCommand
+	snapshots    <-chan os.Signal
+	snapshotPath string
+	// frame is the time between two frames drawn; DEFAULT_FPS when zero.
+	frame time.Duration
+	// publish is called with the session after every poll is applied.
+	publish []func(*session)
+	// sync, when set, is called after every poll, tick, key and control
+	// command.
+	sync func(*session)
+	// notices are shown as toasts.
+	notices <-chan string
+}
+
+func (l gameLoop) synced(s *session) {
+	if l.sync != nil {
+		l.sync(s)
 	}
-	
-	return oldSnakeLen != len(g.snake) || newHead != head || oldFood != g.food
 }
 
-func (g *Game) spawnFood() {
-	maxAttempts := 100
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		g.food = Position{
-			X: (int(time.Now().UnixNano()) + attempt*17) % g.width,
-			Y: (int(time.Now().UnixNano()/1000) + attempt*23) % g.height,
-		}
-		onSnake := false
-		for _, segment := range g.snake {
-			if g.food.X == segment.X && g.food.Y == segment.Y {
-				onSnake = true
-				break
+const (
+	// POLL_INTERVAL is how often the eBPF counters are read, whatever the
+	// speed of the game.
+	POLL_INTERVAL = 100 * time.Millisecond
+	DEFAULT_FPS   = 30
+	MIN_FPS       = 1
+	MAX_FPS       = 120
+)
+
+// run plays until the player quits or ctx is done. Three clocks drive it:
+// the counters are polled every POLL_INTERVAL, the snakes move every
+// s.interval and the screen is redrawn every frame, when anything changed.
+func (s *session) run(ctx context.Context, l gameLoop) {
+	ui, g := s.ui, s.game
+	src, watchlist := l.src, l.watchlist
+	eventChan, inputChan := l.events, l.input
+
+	frameInterval := l.frame
+	if frameInterval <= 0 {
+		frameInterval = time.Second / DEFAULT_FPS
+	}
+	poll := time.NewTicker(POLL_INTERVAL)
+	defer poll.Stop()
+	frame := time.NewTicker(frameInterval)
+	defer frame.Stop()
+	moveInterval := s.interval
+	lastMove := time.Now()
+	move := time.NewTimer(moveInterval)
+	defer move.Stop()
+
+	var readErr error
+	dirty := true
+	quit := false
+	for !quit {
+		select {
+		case <-ctx.Done():
+			quit = true
+		case <-poll.C:
+			s.now = time.Now()
+			snap, err := src.ReadSnapshot()
+			if err != nil && readErr == nil {
+				ui.Toasts.Push("metrics: " + strings.ReplaceAll(err.Error(), "\n", "; "))
 			}
-		}
-		if !onSnake {
-			return
-		}
-	}
-	for y := 0; y < g.height; y++ {
-		for x := 0; x < g.width; x++ {
-			onSnake := false
-			for _, segment := range g.snake {
-				if x == segment.X && y == segment.Y {
-					onSnake = true
-					break
-				}
+			logReadError(err, readErr)
+			readErr = err
+			metrics := snap.Metrics
+
+			for _, msg := range ebpfmon.EvaluateRules(ebpfmon.NotableRules, ui.Metrics, metrics) {
+				ui.Toasts.Push(msg)
 			}
-			if !onSnake {
-				g.food = Position{X: x, Y: y}
-				return
+			for _, msg := range watchlist.Poll() {
+				ui.Toasts.Push(msg)
 			}
-		}
-	}
-}
 
-func (g *Game) render() {
-	fmt.Print("\033[2J\033[H")
-	
-	gameBlockWidth := g.width*2 + 3
-	gameBlockHeight := g.height + 9
-	
-	padLeft := (g.termWidth - gameBlockWidth) / 2
-	padTop := (g.termHeight - gameBlockHeight) / 2
-	
-	for i := 0; i < padTop; i++ {
-		fmt.Println()
-	}
-	
-	grid := make([][]rune, g.height)
-	for i := range grid {
-		grid[i] = make([]rune, g.width)
-		for j := range grid[i] {
-			grid[i][j] = ' '
-		}
-	}
-
-	for i, segment := range g.snake {
-		if segment.Y >= 0 && segment.Y < g.height && segment.X >= 0 && segment.X < g.width {
-			if i == 0 {
-				grid[segment.Y][segment.X] = '●'
+			ui.Metrics = metrics
+			ui.TopCgroups = src.TopCgroups(3)
+			ui.TopContainers = src.TopContainers()
+			if g.GameOver {
+				ui.TopDomains = src.TopDomains(tui.DNS_SUMMARY_LINES)
+			}
+			ui.History.Record(snap, s.interval, g.Score)
+			procs := src.Processes()
+			ui.UpdateProcesses(procs)
+			if !g.GameOver {
+				s.stats.readProcesses(procs)
+			}
+			if ui.StatsEnabled {
+				ui.UpdateProgramStats(src.ProgramStats(), s.now)
+			}
+			s.poll(snap, ui.ShowGraphs || !ui.Fits(g))
+			s.checkAchievements()
+			for _, publish := range l.publish {
+				publish(s)
+			}
+			dirty = true
+			l.synced(s)
+
+		case <-move.C:
+			s.now = time.Now()
+			lastMove = s.now
+			if s.step(ui.ShowGraphs || !ui.Fits(g)) {
+				s.checkAchievements()
+				dirty = true
+				l.synced(s)
+			}
+			move.Reset(s.interval)
+			moveInterval = s.interval
+
+		case <-frame.C:
+			if !dirty {
+				continue
+			}
+			dirty = false
+			if ui.ShowGraphs {
+				ui.RenderGraphs()
 			} else {
-				grid[segment.Y][segment.X] = '○'
+				s.render()
 			}
-		}
-	}
 
-	if g.food.Y >= 0 && g.food.Y < g.height && g.food.X >= 0 && g.food.X < g.width {
-		grid[g.food.Y][g.food.X] = '*'
-	}
+		case ev, ok := <-eventChan:
+			if !ok {
+				eventChan = nil
+				continue
+			}
+			ui.Ticker.Push(ev)
+			dirty = true
+			if ev.Kind == ebpfmon.EVENT_EXEC {
+				s.execStarted(ev)
+			}
+			if ev.Kind == ebpfmon.EVENT_EXEC && g.FoodSpawnDue && !g.Paused && !g.GameOver && !ui.ShowGraphs {
+				s.now = time.Now()
+				s.spawnDueFood()
+			}
 
-	topBorder := "┌"
-	for i := 0; i < g.width*2+1; i++ {
-		topBorder += "─"
-	}
-	topBorder += "┐"
-	for i := 0; i < padLeft; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(topBorder)
-	
-	for _, row := range grid {
-		for i := 0; i < padLeft; i++ {
-			fmt.Print(" ")
-		}
-		fmt.Print("│ ")
-		for _, cell := range row {
-			switch cell {
-			case '●', '○':
-				fmt.Print("\033[32m" + string(cell) + "\033[0m ")
-			case '*':
-				fmt.Print("\033[31m" + string(cell) + "\033[0m ")
-			default:
-				fmt.Print(string(cell) + " ")
+		case <-l.resizes:
+			s.now = time.Now()
+			s.resize(l.size())
+			dirty = true
+			l.synced(s)
+
+		case msg := <-l.notices:
+			ui.Toasts.Push(msg)
+			dirty = true
+
+		case <-l.snapshots:
+			snap := s.snapshot(s.interval, l.status.Probes)
+			if err := writeSnapshot(l.snapshotPath, snap); err != nil {
+				ui.Toasts.Push(fmt.Sprintf("snapshot failed: %v", err))
+			} else if l.snapshotPath != "" {
+				ui.Toasts.Push("snapshot written to " + l.snapshotPath)
+			}
+			dirty = true
+
+		case cmd := <-l.controls:
+			s.now = time.Now()
+			err := s.applyControl(cmd)
+			cmd.reply <- controlReply{state: s.apiState(), game: s.gameSnapshot(s.interval), err: err}
+			dirty = true
+			l.synced(s)
+
+		case a := <-l.gamepad:
+			if ui.ShowGraphs {
+				continue
+			}
+			s.now = time.Now()
+			if changed, _ := s.handleAction(a); changed {
+				dirty = true
+				l.synced(s)
+			}
+
+		case input, ok := <-inputChan:
+			if !ok {
+				inputChan = nil
+				continue
+			}
+			action, _ := s.cfg.Keys.lookup(input, s.localPlayer2())
+			if action == KEY_GRAPHS {
+				ui.ShowGraphs = !ui.ShowGraphs
+				dirty = true
+				continue
+			}
+			if ui.ShowGraphs && action != KEY_QUIT {
+				continue
+			}
+			s.now = time.Now()
+			changed, stop := s.handleKey(input)
+			quit = stop
+			if changed {
+				dirty = true
+				l.synced(s)
 			}
 		}
-		fmt.Println("│")
-	}
-	
-	bottomBorder := "└"
-	for i := 0; i < g.width*2+1; i++ {
-		bottomBorder += "─"
-	}
-	bottomBorder += "┘"
-	for i := 0; i < padLeft; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(bottomBorder)
-
-	level := g.score / 5
-	
-	infoLine1 := fmt.Sprintf("Level: %d | Score: %d | Length: %d", level, g.score, len(g.snake))
-	infoLine2 := "Use Arrow keys or WASD to move"
-	infoLine3 := "Q or Ctrl+C to quit"
-	infoLine4 := "Powered by eBPF 🐝"
-	
-	infoPadLeft1 := (g.termWidth - len(infoLine1)) / 2
-	infoPadLeft2 := (g.termWidth - len(infoLine2)) / 2
-	infoPadLeft3 := (g.termWidth - len(infoLine3)) / 2
-	
-	oPosition := infoPadLeft3 + 2
-	
-	infoPadLeft4 := oPosition
-	
-	for i := 0; i < infoPadLeft1; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(infoLine1)
-	
-	fmt.Println()
-	
-	for i := 0; i < infoPadLeft2; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(infoLine2)
-	
-	for i := 0; i < infoPadLeft3; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(infoLine3)
-	
-	fmt.Println()
-	fmt.Println()
-	
-	for i := 0; i < infoPadLeft4; i++ {
-		fmt.Print(" ")
-	}
-	fmt.Println(infoLine4)
-	
-	os.Stdout.Sync()
-}
 
-func getTerminalSize() (int, int) {
-	fd := int(os.Stdout.Fd())
-	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
-	if err != nil {
-		return 80, 24
+		// A new speed takes effect from the last move rather than the
+		// next one, so a faster snake does not wait out the old interval.
+		if s.interval != moveInterval {
+			moveInterval = s.interval
+			move.Reset(max(time.Until(lastMove.Add(moveInterval)), 0))
+		}
 	}
-	return int(ws.Col), int(ws.Row)
 }
 
-func setupTerminal() {
-	cmd := exec.Command("stty", "-echo", "-icanon", "min", "1", "time", "0")
-	cmd.Stdin = os.Stdin
-	cmd.Run()
-	
-	fd := int(os.Stdin.Fd())
-	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
-	if err == nil {
-		termios.Lflag &^= unix.ECHO | unix.ICANON
-		termios.Cc[unix.VMIN] = 1
-		termios.Cc[unix.VTIME] = 0
-		unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+const (
+	BOARD_MIN_WIDTH  = 10
+	BOARD_MIN_HEIGHT = 5
+)
+
+// boardSize picks a board that takes up about a third of the terminal.
+func boardSize(termWidth, termHeight int) (int, int) {
+	width := min(max(termWidth*3/10, 18), 32)
+	height := min(max(termHeight*3/10, 8), 16)
+	if termWidth < width+4 || termHeight < height+4 {
+		width, height = 20, 10
 	}
+	return width, height
+}
+
+type displayOptions struct {
+	ascii   bool
+	noColor bool
 }
 
-func restoreTerminal() {
-	cmd := exec.Command("stty", "echo", "icanon")
-	cmd.Stdin = os.Stdin
-	cmd.Run()
-	
-	fd := int(os.Stdin.Fd())
-	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
-	if err == nil {
-		termios.Lflag |= unix.ECHO | unix.ICANON | unix.ISIG
-		unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+func (d displayOptions) apply(ui *tui.UI) {
+	if d.ascii {
+		ui.Glyphs = tui.ASCIIGlyphs
+	}
+	if d.noColor {
+		ui.Theme = tui.Monochrome()
 	}
 }
 
-func readInput(ch chan<- string) {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		char, err := reader.ReadByte()
-		if err != nil {
-			close(ch)
-			return
-		}
-		
-		if char == '\033' || char == 0x1b {
-			peeked, _ := reader.Peek(2)
-			if len(peeked) >= 2 && peeked[0] == '[' {
-				reader.ReadByte()
-				dir, err := reader.ReadByte()
-				if err != nil {
-					continue
-				}
-				var direction string
-				switch dir {
-				case 'A':
-					direction = "up"
-				case 'B':
-					direction = "down"
-				case 'C':
-					direction = "right"
-				case 'D':
-					direction = "left"
-				default:
-					continue
-				}
-				select {
-				case ch <- direction:
-				default:
-				}
-				continue
-			}
-		}
-		
-		input := string(char)
-		if char >= 'A' && char <= 'Z' {
-			input = string(char + 32)
-		}
-		
-		select {
-		case ch <- input:
-		default:
-		}
+func (s *session) cycleTheme() {
+	if s.noColor {
+		s.ui.Toasts.Push("colors are disabled")
+		return
+	}
+	s.ui.Theme = tui.Themes[(tui.ThemeIndex(s.ui.Theme.Name)+1)%len(tui.Themes)]
+	s.cfg.Theme = s.ui.Theme.Name
+	if s.cfgPath == "" {
+		s.ui.Toasts.Push("theme: " + s.ui.Theme.Name)
+		return
 	}
+	if err := saveConfig(s.cfgPath, s.cfg); err != nil {
+		s.ui.Toasts.Push(fmt.Sprintf("theme not saved: %v", err))
+		return
+	}
+	s.ui.Toasts.Push("theme: " + s.ui.Theme.Name)
+}
+
+func (s *session) render() {
+	s.ui.Render(s.game)
 }
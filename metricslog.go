@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// metricsLogEntry is one line of the -log-metrics file.
+type metricsLogEntry struct {
+	Time         time.Time          `json:"time"`
+	Counters     metricsSnapshot    `json:"counters"`
+	Rates        map[string]float64 `json:"rates"`
+	TickInterval float64            `json:"tick_interval_ms"`
+	Score        int                `json:"score"`
+	Length       int                `json:"length"`
+	Seed         uint64             `json:"seed"`
+}
+
+// metricsLog appends a JSON object per tick to a file, a session log that
+// can be analysed later or lined up with a replay of the same seed.
+type metricsLog struct {
+	file    *os.File
+	enc     *json.Encoder
+	failing bool
+	report  func(error)
+}
+
+// openMetricsLog appends to path, creating it when needed. Write errors go
+// to report, once until writing works again.
+func openMetricsLog(path string, report func(error)) (*metricsLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open metrics log: %w", err)
+	}
+	return &metricsLog{file: f, enc: json.NewEncoder(f), report: report}, nil
+}
+
+// publish appends the counters, rates and game of this tick.
+func (l *metricsLog) publish(s *session) {
+	err := l.enc.Encode(metricsLogEntry{
+		Time:         s.now,
+		Counters:     newMetricsSnapshot(s.ui.Metrics, s.ui.TopCgroups, s.ui.TopContainers),
+		Rates:        rateMap(s.ui.Rate),
+		TickInterval: float64(s.interval) / float64(time.Millisecond),
+		Score:        s.game.Score,
+		Length:       len(s.game.Snake),
+		Seed:         s.game.Seed,
+	})
+	if err != nil && !l.failing {
+		l.report(fmt.Errorf("write metrics log: %w", err))
+	}
+	l.failing = err != nil
+}
+
+func (l *metricsLog) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseClick reads the "click X Y" input ReadInput sends for the mouse.
+func parseClick(input string) (x, y int, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) != 3 || fields[0] != "click" {
+		return 0, 0, false
+	}
+	x, errX := strconv.Atoi(fields[1])
+	y, errY := strconv.Atoi(fields[2])
+	return x, y, errX == nil && errY == nil
+}
+
+// clickAction is the direction key a click at column x and row y of the
+// terminal stands for: the way from the first snake's head towards the
+// clicked cell, across the way the snake is heading, since it cannot turn
+// back and already goes ahead. A click in line with the head does nothing.
+func (s *session) clickAction(x, y int) (keyAction, bool) {
+	g := s.game
+	head := g.Snake[0]
+	c := s.ui.BoardCell(x, y)
+	dx, dy := c.X-head.X, c.Y-head.Y
+	if g.Direction.X != 0 || g.Direction.Y == 0 && abs(dy) > abs(dx) {
+		switch {
+		case dy < 0:
+			return KEY_UP, true
+		case dy > 0:
+			return KEY_DOWN, true
+		}
+	}
+	switch {
+	case dx < 0 && g.Direction.X == 0:
+		return KEY_LEFT, true
+	case dx > 0 && g.Direction.X == 0:
+		return KEY_RIGHT, true
+	}
+	return 0, false
+}
+
+func abs(n int) int {
+	return max(n, -n)
+}
+
+// mouseHint is the toast shown when -mouse is given.
+func mouseHint(enabled bool) string {
+	if !enabled {
+		return "🖱 this terminal does not report the mouse, steer with the keys"
+	}
+	return "🖱 click or drag beside the snake's head to steer"
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// NETPLAY_VERSION is the version of the protocol spoken between play -host
+// and join. Both ends must speak the same one.
+const NETPLAY_VERSION = 1
+
+// NETPLAY_TIMEOUT bounds the handshake and every write to the other end.
+const NETPLAY_TIMEOUT = 10 * time.Second
+
+const (
+	// PEER_MAX_AHEAD is how many ticks the joined player may send before
+	// the host has played them; further ticks are held back and sent as
+	// one once the host catches up.
+	PEER_MAX_AHEAD = 3
+	// PEER_MAX_CATCH_UP is how many cells the second snake moves at most
+	// for ticks that arrive together.
+	PEER_MAX_CATCH_UP = 3
+)
+
+// peerMessage is one line of the netplay protocol. The joiner sends hello,
+// then a tick every time its own kernel activity moves the second snake
+// and an input for every key. The host answers with welcome, or error,
+// and then a state after every change, acknowledging the last tick it
+// played.
+type peerMessage struct {
+	Type    string      `json:"type"`
+	Version int         `json:"version,omitempty"`
+	Tick    int         `json:"tick,omitempty"`
+	Key     string      `json:"key,omitempty"`
+	Ack     int         `json:"ack,omitempty"`
+	State   *game.State `json:"state,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// netHost is the host's end of a networked game: the connection of the
+// player who joined.
+type netHost struct {
+	conn   net.Conn
+	dec    *json.Decoder
+	states chan []byte
+}
+
+// acceptPeer waits on addr until a player joins.
+func acceptPeer(ctx context.Context, addr string) (*netHost, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	defer l.Close()
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	fmt.Printf("Waiting for a player to join on %s...\n", addr)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("accept: %w", err)
+		}
+		h, err := greetPeer(conn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s could not join: %v\n", conn.RemoteAddr(), err)
+			conn.Close()
+			continue
+		}
+		fmt.Printf("%s joined as player 2\n", conn.RemoteAddr())
+		return h, nil
+	}
+}
+
+func greetPeer(conn net.Conn) (*netHost, error) {
+	conn.SetDeadline(time.Now().Add(NETPLAY_TIMEOUT))
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	var hello peerMessage
+	if err := dec.Decode(&hello); err != nil {
+		return nil, fmt.Errorf("read hello: %w", err)
+	}
+	if hello.Type != "hello" {
+		return nil, fmt.Errorf("expected hello, got %q", hello.Type)
+	}
+	if hello.Version != NETPLAY_VERSION {
+		err := fmt.Errorf("protocol version %d, the host speaks %d", hello.Version, NETPLAY_VERSION)
+		enc.Encode(peerMessage{Type: "error", Error: err.Error()})
+		return nil, err
+	}
+	if err := enc.Encode(peerMessage{Type: "welcome", Version: NETPLAY_VERSION}); err != nil {
+		return nil, fmt.Errorf("send welcome: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return &netHost{conn: conn, dec: dec, states: make(chan []byte, 1)}, nil
+}
+
+// serve hands the joined player's ticks and keys to the game loop as
+// control commands, so they are recorded like any other, and writes back
+// the states passed to sync. Ticks are played no faster than
+// game.MIN_TICK_INTERVAL, however fast they come.
+func (h *netHost) serve(ctx context.Context, commands chan<- controlCommand) {
+	context.AfterFunc(ctx, func() { h.conn.Close() })
+	go h.writeStates(ctx)
+	go func() {
+		var last time.Time
+		for {
+			var msg peerMessage
+			if err := h.dec.Decode(&msg); err != nil {
+				if ctx.Err() == nil {
+					submitControl(ctx, commands, "peer-left", "")
+				}
+				return
+			}
+			switch msg.Type {
+			case "tick":
+				if wait := game.MIN_TICK_INTERVAL - time.Since(last); wait > 0 {
+					time.Sleep(wait)
+				}
+				last = time.Now()
+				submitControl(ctx, commands, "peer-tick", strconv.Itoa(msg.Tick))
+			case "input":
+				submitControl(ctx, commands, "peer-input", msg.Key)
+			}
+		}
+	}()
+}
+
+func (h *netHost) writeStates(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-h.states:
+			h.conn.SetWriteDeadline(time.Now().Add(NETPLAY_TIMEOUT))
+			if _, err := h.conn.Write(b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sync sends the game as it is now to the joined player. Only the latest
+// state waits when the connection falls behind.
+func (h *netHost) sync(s *session) {
+	state := s.game.State()
+	b, err := json.Marshal(peerMessage{Type: "state", Ack: s.peerTick, State: &state})
+	if err != nil {
+		return
+	}
+	select {
+	case <-h.states:
+	default:
+	}
+	h.states <- append(b, '\n')
+}
+
+// peerTicked moves the second snake for the joined player's ticks up to
+// value. A player who fell behind catches up by at most PEER_MAX_CATCH_UP
+// cells, so the snake does not jump across the board.
+func (s *session) peerTicked(value string) error {
+	tick, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid tick %q", value)
+	}
+	steps := min(tick-s.peerTick, PEER_MAX_CATCH_UP)
+	if steps <= 0 {
+		return nil
+	}
+	s.peerTick = tick
+	g := s.game
+	for range steps {
+		if g.StepPlayer2() && g.GameOver {
+			s.endRound()
+			break
+		}
+	}
+	return nil
+}
+
+// peerKey applies a key of the joined player, who steers the second snake
+// with IJKL whatever keys they press.
+func (s *session) peerKey(key string) error {
+	g := s.game
+	switch key {
+	case "i":
+		g.TurnPlayer2(game.Up)
+	case "k":
+		g.TurnPlayer2(game.Down)
+	case "j":
+		g.TurnPlayer2(game.Left)
+	case "l":
+		g.TurnPlayer2(game.Right)
+	case "p":
+		g.Paused = !g.Paused
+	case "r":
+		if g.GameOver {
+			g.Reset()
+			s.newRound()
+		}
+	default:
+		return fmt.Errorf("unknown peer key %q", key)
+	}
+	return nil
+}
+
+// peerKeys maps the actions of the joined player's keys to the keys they
+// send, those of the second snake in the default keymap.
+var peerKeys = map[keyAction]string{
+	KEY_UP: "i", KEY_DOWN: "k", KEY_LEFT: "j", KEY_RIGHT: "l",
+	KEY_PAUSE: "p", KEY_RESTART: "r",
+}
+
+// netPeer is the joiner's end of a networked game.
+type netPeer struct {
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+func dialHost(ctx context.Context, addr string) (*netPeer, error) {
+	var d net.Dialer
+	ctx, cancel := context.WithTimeout(ctx, NETPLAY_TIMEOUT)
+	defer cancel()
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p := &netPeer{conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(conn)}
+	conn.SetDeadline(time.Now().Add(NETPLAY_TIMEOUT))
+	var welcome peerMessage
+	if err := p.enc.Encode(peerMessage{Type: "hello", Version: NETPLAY_VERSION}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send hello: %w", err)
+	}
+	if err := p.dec.Decode(&welcome); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read welcome: %w", err)
+	}
+	if welcome.Type != "welcome" {
+		conn.Close()
+		if welcome.Error != "" {
+			return nil, errors.New(welcome.Error)
+		}
+		return nil, fmt.Errorf("expected welcome, got %q", welcome.Type)
+	}
+	conn.SetDeadline(time.Time{})
+	return p, nil
+}
+
+func (p *netPeer) send(msg peerMessage) error {
+	p.conn.SetWriteDeadline(time.Now().Add(NETPLAY_TIMEOUT))
+	return p.enc.Encode(msg)
+}
+
+// states reads the host's states until the connection closes.
+func (p *netPeer) states(ctx context.Context) <-chan peerMessage {
+	ch := make(chan peerMessage)
+	go func() {
+		defer close(ch)
+		for {
+			var msg peerMessage
+			if err := p.dec.Decode(&msg); err != nil {
+				return
+			}
+			if msg.State == nil {
+				continue
+			}
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// runJoin joins a game hosted with play -host as player 2. The second snake
+// moves at the speed this machine's kernel activity sets, while the host
+// keeps the board.
+func runJoin(ctx context.Context, args []string) int {
+	fs := newFlagSet("join", " HOST:PORT")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	pinDir := addPinFlag(fs)
+	showProcs := fs.Bool("procs", false, "show the top processes panel next to the board")
+	display := addDisplayFlags(fs)
+	collectorPath := fs.String("collector", "", "UNIX socket of a collector, e.g. "+COLLECTOR_SOCKET+", to take the speed from instead of loading the probes")
+	logOpts := addLogFlags(fs)
+	pprofAddr := addPprofFlag(fs)
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	closeLog, err := logOpts.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	defer closeLog()
+	stopPprof, ok := startPprof(*pprofAddr)
+	if !ok {
+		return EXIT_FAILURE
+	}
+	defer stopPprof()
+	addr := fs.Arg(0)
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var src kernelSource
+	if *collectorPath != "" {
+		for _, name := range collectorFlags {
+			if flagGiven(fs, name) {
+				fmt.Fprintf(os.Stderr, "Warning: -%s is ignored with -collector\n", name)
+			}
+		}
+		client, err := dialCollector(*collectorPath, &containerOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to collector at %s: %v\n", *collectorPath, err)
+			return EXIT_FAILURE
+		}
+		defer client.Close()
+		src = client
+	} else {
+		c, code := loadCollector(ctx, *btfPath, *pinDir, cfg.Probes, ebpfmon.Filter{}, &probeOptions{})
+		if code != EXIT_OK {
+			return code
+		}
+		defer c.close()
+		src = c.newSession()
+	}
+	status := src.Status()
+
+	peer, err := dialHost(ctx, addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to join %s: %v\n", addr, err)
+		return EXIT_FAILURE
+	}
+	defer peer.conn.Close()
+	context.AfterFunc(ctx, func() { peer.conn.Close() })
+	states := peer.states(ctx)
+	fmt.Printf("Joined the game on %s as player 2! Waiting for the board...\n", addr)
+	var first peerMessage
+	select {
+	case msg, ok := <-states:
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Failed to join %s: the host closed the connection\n", addr)
+			return EXIT_FAILURE
+		}
+		first = msg
+		if err := first.State.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to join %s: invalid board from the host: %v\n", addr, err)
+			return EXIT_FAILURE
+		}
+	case <-time.After(NETPLAY_TIMEOUT):
+		fmt.Fprintf(os.Stderr, "Failed to join %s: no board from the host\n", addr)
+		return EXIT_FAILURE
+	case <-ctx.Done():
+		return EXIT_OK
+	}
+
+	if err := tui.SetupTerminal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up terminal: %v\n", err)
+		return EXIT_FAILURE
+	}
+	defer tui.RestoreTerminal()
+	logTerminal()
+
+	// The board is the host's: the game here only mirrors it to be drawn.
+	g := game.New(first.State.Width, first.State.Height, 0)
+	g.SetState(*first.State)
+	ui := tui.New(g.Width, g.Height)
+	ui.Resize(tui.TerminalSize())
+	ui.ShowProcs = *showProcs
+	ui.ShowOverhead = status.Stats
+	ui.StatsEnabled = status.Stats
+	ui.Uprobe = status.Uprobe
+	ui.USDT = status.USDT
+	ui.XDP = status.XDP
+	ui.Egress = status.Egress
+	ui.Probes = status.probeStatus()
+	ui.Theme = tui.Themes[tui.ThemeIndex(cfg.Theme)]
+	display.apply(ui)
+	ui.KeyHelp = cfg.Keys.keyHelp(false)
+	defer ui.Close()
+	ui.Toasts.Push("🌐 you are player 2, steering with " + cfg.Keys.moveHint(KEY_UP, false))
+	ui.Render(g)
+
+	inputChan := make(chan string, tui.INPUT_BUFFER)
+	go tui.ReadInput(ctx, inputChan)
+
+	rates := ebpfmon.NewRates(ebpfmon.RATE_WINDOW)
+	interval := g.Difficulty.BaseInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// tick counts the ticks of the second snake here, sent the last one
+	// sent and ack the last one the host played.
+	tick, sent, ack := 0, 0, first.Ack
+	var readErr, sendErr, stateErr error
+	hostLeft := false
+	for quit := false; !quit; {
+		select {
+		case <-ctx.Done():
+			quit = true
+
+		case <-ticker.C:
+			snap, err := src.ReadSnapshot()
+			if err != nil && readErr == nil {
+				ui.Toasts.Push("metrics: " + strings.ReplaceAll(err.Error(), "\n", "; "))
+			}
+			logReadError(err, readErr)
+			readErr = err
+			rate := rates.Update(snap.Metrics)
+			for _, msg := range ebpfmon.EvaluateRules(ebpfmon.NotableRules, ui.Metrics, snap.Metrics) {
+				ui.Toasts.Push(msg)
+			}
+			ui.Metrics = snap.Metrics
+			ui.Rate = rate
+			ui.TopCgroups = src.TopCgroups(3)
+			ui.UpdateProcesses(src.Processes())
+			if ui.StatsEnabled {
+				ui.UpdateProgramStats(src.ProgramStats(), time.Now())
+			}
+
+			score := 0
+			if g.Player2 != nil {
+				score = g.Player2.Score
+			}
+			ui.History.Record(snap, interval, score)
+			speed := game.NewSpeedReductions(cfg.Speed, g.Difficulty, score, kernelActivity(rate, snap, status.Uprobe != ""))
+			interval = speed.Interval(g.Difficulty)
+			ui.DiskLag = diskLagging(rate)
+			if ui.DiskLag {
+				interval += DISK_LAG_SLOWDOWN
+			}
+			ui.Speed = speed
+			ui.Interval = interval
+			ticker.Reset(interval)
+
+			if !g.Paused && !g.GameOver {
+				tick++
+				if sent-ack < PEER_MAX_AHEAD {
+					sendErr = peer.send(peerMessage{Type: "tick", Tick: tick})
+					sent = tick
+				}
+			}
+			ui.Render(g)
+
+		case msg, ok := <-states:
+			if !ok {
+				hostLeft = true
+				quit = true
+				continue
+			}
+			// A board that cannot be drawn is skipped, and the next
+			// one the host sends replaces it.
+			width, height := g.Width, g.Height
+			if err := g.SetState(*msg.State); err != nil {
+				if stateErr == nil {
+					ui.Toasts.Push("host: " + err.Error())
+				}
+				stateErr = err
+				continue
+			}
+			stateErr = nil
+			if g.Width != width || g.Height != height {
+				ui.ResizeBoard(g.Width, g.Height)
+			}
+			ack = msg.Ack
+			ui.Render(g)
+
+		case <-tui.Resizes():
+			ui.Resize(tui.TerminalSize())
+			ui.Render(g)
+
+		case input := <-inputChan:
+			action, ok := cfg.Keys.lookup(input, false)
+			if !ok {
+				continue
+			}
+			switch action {
+			case KEY_QUIT:
+				quit = true
+			case KEY_HEATMAP:
+				ui.ShowHeatmap = !ui.ShowHeatmap
+				ui.Render(g)
+			case KEY_PROCS:
+				ui.ShowProcs = !ui.ShowProcs
+				ui.Render(g)
+			case KEY_THEME:
+				if !display.noColor {
+					ui.Theme = tui.Themes[(tui.ThemeIndex(ui.Theme.Name)+1)%len(tui.Themes)]
+					ui.Toasts.Push("theme: " + ui.Theme.Name)
+					ui.Render(g)
+				}
+			default:
+				if key, ok := peerKeys[action]; ok {
+					sendErr = peer.send(peerMessage{Type: "input", Key: key})
+				}
+			}
+		}
+		if sendErr != nil {
+			hostLeft = true
+			quit = true
+		}
+	}
+
+	logShutdown(ctx, "join")
+	stop()
+	ui.Close()
+	tui.RestoreTerminal()
+
+	if hostLeft {
+		fmt.Println("\nThe host ended the game.")
+	} else {
+		fmt.Println("\nGame Over!")
+	}
+	if g.Player2 != nil {
+		fmt.Printf("Final Score: %d\n", g.Player2.Score)
+	}
+	return EXIT_OK
+}
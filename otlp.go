@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// OTLP_SHUTDOWN_TIMEOUT bounds the final export on exit.
+const OTLP_SHUTDOWN_TIMEOUT = 5 * time.Second
+
+// startOTLPExporter ships what exporter holds to an OpenTelemetry
+// collector. The endpoint, headers, TLS, protocol and export interval come
+// from the standard OTEL_EXPORTER_OTLP_* and OTEL_METRIC_EXPORT_* variables,
+// and OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES add to the resource.
+// Export errors go to report.
+func startOTLPExporter(ctx context.Context, exporter *metricsExporter, report func(error)) (*sdkmetric.MeterProvider, error) {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(report))
+
+	var exp sdkmetric.Exporter
+	var err error
+	switch protocol := otlpProtocol(); protocol {
+	case "", "http/protobuf":
+		exp, err = otlpmetrichttp.New(ctx)
+	case "grpc":
+		exp, err = otlpmetricgrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, must be grpc or http/protobuf", protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create otlp exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(
+			semconv.ServiceName("snake-ebpf"),
+			semconv.OSTypeLinux,
+			semconv.OSVersion(ebpfmon.KernelRelease()),
+		),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		exp.Shutdown(ctx)
+		return nil, fmt.Errorf("create otlp resource: %w", err)
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp)),
+		sdkmetric.WithResource(res),
+	)
+	if err := registerOTLPMetrics(provider.Meter("snake-ebpf"), exporter); err != nil {
+		provider.Shutdown(ctx)
+		return nil, err
+	}
+	return provider, nil
+}
+
+func registerOTLPMetrics(meter metric.Meter, exporter *metricsExporter) error {
+	var instruments []metric.Observable
+	counters := make([]metric.Int64ObservableCounter, len(exportedCounters))
+	for i, c := range exportedCounters {
+		counter, err := meter.Int64ObservableCounter("snake_ebpf."+c.name, metric.WithUnit(c.unit), metric.WithDescription(c.help))
+		if err != nil {
+			return fmt.Errorf("create otlp metric %s: %w", c.name, err)
+		}
+		counters[i] = counter
+		instruments = append(instruments, counter)
+	}
+	signals, err := meter.Int64ObservableCounter("snake_ebpf.signals", metric.WithUnit("{signal}"), metric.WithDescription("Signals delivered, seen by eBPF."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	syscalls, err := meter.Int64ObservableCounter("snake_ebpf.syscalls", metric.WithUnit("{syscall}"), metric.WithDescription("System calls seen by eBPF, by category."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	rxPackets, err := meter.Int64ObservableCounter("snake_ebpf.xdp.rx_packets", metric.WithUnit("{packet}"), metric.WithDescription("Packets received on the -xdp-iface interface."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	rxBytes, err := meter.Int64ObservableCounter("snake_ebpf.xdp.rx_bytes", metric.WithUnit("By"), metric.WithDescription("Bytes received on the -xdp-iface interface."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	liveProcesses, err := meter.Int64ObservableGauge("snake_ebpf.live_processes", metric.WithUnit("{process}"), metric.WithDescription("Processes running."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	eventRate, err := meter.Int64ObservableGauge("snake_ebpf.event_rate", metric.WithUnit("{event}/s"), metric.WithDescription("Kernel events per second."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	score, err := meter.Int64ObservableGauge("snake_ebpf.game.score", metric.WithDescription("Current game score."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	length, err := meter.Int64ObservableGauge("snake_ebpf.game.snake_length", metric.WithUnit("{cell}"), metric.WithDescription("Current snake length."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	interval, err := meter.Float64ObservableGauge("snake_ebpf.game.tick_interval", metric.WithUnit("s"), metric.WithDescription("Current game tick interval."))
+	if err != nil {
+		return fmt.Errorf("create otlp metric: %w", err)
+	}
+	instruments = append(instruments, signals, syscalls, rxPackets, rxBytes, liveProcesses, eventRate, score, length, interval)
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		m, stats, game := exporter.snapshot()
+		if m.Time.IsZero() {
+			return nil
+		}
+		for i, c := range exportedCounters {
+			o.ObserveInt64(counters[i], int64(c.value(m)))
+		}
+		for k, count := range m.Signals {
+			o.ObserveInt64(signals, int64(count), metric.WithAttributes(attribute.String("signal", ebpfmon.SignalKind(k).String())))
+		}
+		for c, count := range m.Syscalls {
+			o.ObserveInt64(syscalls, int64(count), metric.WithAttributes(attribute.String("category", ebpfmon.SyscallCategory(c).String())))
+		}
+		for proto := range ebpfmon.XDP_PROTOS {
+			protocol := metric.WithAttributes(attribute.String("protocol", ebpfmon.XDPProtoName(proto)))
+			o.ObserveInt64(rxPackets, int64(m.Traffic.Packets[proto]), protocol)
+			o.ObserveInt64(rxBytes, int64(m.Traffic.Bytes[proto]), protocol)
+		}
+		o.ObserveInt64(liveProcesses, int64(m.LiveProcesses))
+		o.ObserveInt64(eventRate, int64(m.EventRate))
+		if game {
+			o.ObserveInt64(score, int64(stats.score))
+			o.ObserveInt64(length, int64(stats.length))
+			o.ObserveFloat64(interval, stats.interval.Seconds())
+		}
+		return nil
+	}, instruments...)
+	if err != nil {
+		return fmt.Errorf("register otlp metrics: %w", err)
+	}
+	return nil
+}
+
+// shutdownOTLP exports what is left before the program exits.
+func shutdownOTLP(provider *sdkmetric.MeterProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), OTLP_SHUTDOWN_TIMEOUT)
+	defer cancel()
+	provider.Shutdown(ctx)
+}
+
+func otlpProtocol() string {
+	if protocol := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"); protocol != "" {
+		return protocol
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+}
+
+// otlpEndpoint describes where the metrics go, for the startup message.
+func otlpEndpoint() string {
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"} {
+		if endpoint := os.Getenv(name); endpoint != "" {
+			return endpoint
+		}
+	}
+	if otlpProtocol() == "grpc" {
+		return "localhost:4317"
+	}
+	return "localhost:4318"
+}
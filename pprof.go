@@ -0,0 +1,69 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/tui"
+)
+
+// startPprofServer serves the runtime profiles under /debug/pprof/ and the
+// expvar counters under /debug/vars. Profiles show what the process does,
+// so the address should not be reachable from elsewhere.
+func startPprofServer(addr string) (*http.Server, error) {
+	publishExpvars()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	// No write timeout: CPU profiles and traces take as long as asked for.
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go server.Serve(listener)
+	return server, nil
+}
+
+// publishExpvars adds the counters of the render and poll loops to the
+// memstats and cmdline expvar always has.
+func publishExpvars() {
+	if expvar.Get("frames_rendered") != nil {
+		return
+	}
+	expvar.Publish("frames_rendered", expvar.Func(func() any { return tui.FramesRendered() }))
+	expvar.Publish("frames_dropped", expvar.Func(func() any { return tui.FramesDropped() }))
+	expvar.Publish("input_dropped", expvar.Func(func() any { return tui.InputDropped() }))
+	expvar.Publish("map_lookups", expvar.Func(func() any { return ebpfmon.MapLookups() }))
+}
+
+func addPprofFlag(fs *flag.FlagSet) *string {
+	return fs.String("pprof-addr", "", "listen address for the pprof profiles and expvar counters, e.g. localhost:6060 (disabled when empty)")
+}
+
+// startPprof starts the -pprof-addr endpoint when addr is set. The
+// returned function stops it; ok is false when it could not listen.
+func startPprof(addr string) (stop func(), ok bool) {
+	if addr == "" {
+		return func() {}, true
+	}
+	server, err := startPprofServer(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start pprof endpoint: %v\n", err)
+		return nil, false
+	}
+	fmt.Fprintf(os.Stderr, "Profiles on http://%s/debug/pprof/\n", addr)
+	return func() { server.Close() }, true
+}
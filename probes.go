@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// probeOptions are the probes a command attaches on top of the default
+// ones, and whether it has the kernel time them.
+type probeOptions struct {
+	uprobeSpec string
+	usdtSpec   string
+	xdpIface   string
+	egress     bool
+	overhead   bool
+	uprobe     ebpfmon.Uprobe
+	usdt       ebpfmon.USDT
+}
+
+func addProbeFlags(fs *flag.FlagSet) *probeOptions {
+	p := &probeOptions{}
+	fs.StringVar(&p.uprobeSpec, "uprobe", "", "BIN:SYMBOL, a function in a program or library, e.g. /usr/lib/libc.so.6:malloc, whose calls set the speed instead of kernel activity")
+	fs.StringVar(&p.usdtSpec, "usdt", "", "provider:probe@path, a USDT probe, e.g. python:function__entry@/usr/lib/libpython3.12.so, whose hits bring their own food")
+	fs.StringVar(&p.xdpIface, "xdp-iface", "", "network interface to count received packets on with XDP; its traffic then sets the network food rate instead of TCP connects")
+	fs.BoolVar(&p.egress, "egress", false, "count the bytes sent from the game's own cgroup; uploading fast multiplies the points food is worth")
+	fs.BoolVar(&p.overhead, "overhead", false, "have the kernel time every BPF program and show what each probe costs in a panel next to the board")
+	return p
+}
+
+// parse parses the probe specs. The error names the flag that is wrong.
+func (p *probeOptions) parse() error {
+	var err error
+	if p.uprobeSpec != "" {
+		if p.uprobe, err = parseUprobe(p.uprobeSpec); err != nil {
+			return err
+		}
+	}
+	if p.usdtSpec != "" {
+		if p.usdt, err = parseUSDT(p.usdtSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attach attaches the optional probes and turns on runtime statistics,
+// which the caller closes when stats is not nil. code is not EXIT_OK when
+// that failed and the caller should exit with it.
+func (p *probeOptions) attach(mon *ebpfmon.Monitor, probes *ebpfmon.AttachManager) (stats io.Closer, code int) {
+	if p.uprobeSpec != "" {
+		if err := mon.AttachUprobe(probes, p.uprobe); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to attach uprobe %s: %v\n", p.uprobe, err)
+			return nil, exitCodeFor(err, EXIT_ATTACH)
+		}
+	}
+	if p.usdtSpec != "" {
+		if err := mon.AttachUSDT(probes, p.usdt); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to attach USDT probe %s: %v\n", p.usdt, err)
+			return nil, exitCodeFor(err, EXIT_ATTACH)
+		}
+	}
+	if p.xdpIface != "" {
+		if err := mon.AttachXDP(probes, p.xdpIface); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to attach XDP program to %s: %v\n", p.xdpIface, err)
+			return nil, exitCodeFor(err, EXIT_ATTACH)
+		}
+	}
+	if p.egress {
+		if err := mon.AttachEgress(probes); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to attach cgroup egress program: %v\n", err)
+			return nil, exitCodeFor(err, EXIT_ATTACH)
+		}
+	}
+	if p.overhead {
+		var err error
+		if stats, err = ebpfmon.EnableStats(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: BPF runtime statistics disabled: %v\n", err)
+			p.overhead = false
+		}
+	}
+	return stats, EXIT_OK
+}
+
+// runProbes attaches every probe once and reports where each program was
+// attached or why it could not be. It exits with EXIT_ATTACH when any probe
+// is missing, so it doubles as a check for a kernel's support.
+func runProbes(args []string) int {
+	fs := newFlagSet("probes", "")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	all := fs.Bool("all", false, "try every attach candidate of each program, not just the first that works")
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	return checkProbes(*btfPath, cfg.Probes, *all)
+}
+
+func checkProbes(btfPath string, disabled []string, all bool) int {
+	mon, probes, code := loadMonitor(btfPath, "", disabled, ebpfmon.Filter{})
+	if code != EXIT_OK {
+		return code
+	}
+	defer mon.Close()
+	defer probes.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if all {
+		printCandidates(w, mon.CheckCandidates())
+	} else {
+		printProbes(w, probes)
+	}
+	w.Flush()
+
+	if len(probes.Unattached()) > 0 {
+		return EXIT_ATTACH
+	}
+	return EXIT_OK
+}
+
+func printProbes(w io.Writer, probes *ebpfmon.AttachManager) {
+	attached := probes.Mechanisms()
+	var programs []string
+	for program := range attached {
+		programs = append(programs, program)
+	}
+	programs = append(programs, probes.Unattached()...)
+	programs = append(programs, probes.Disabled()...)
+	programs = append(programs, probes.Unavailable()...)
+	slices.Sort(programs)
+
+	fmt.Fprintln(w, "PROGRAM\tSTATUS\tATTACHED TO")
+	for _, program := range programs {
+		if target, ok := attached[program]; ok {
+			fmt.Fprintf(w, "%s\tok\t%s\n", program, target)
+		} else if errors.Is(probes.Failure(program), ebpfmon.ErrProbeDisabled) {
+			fmt.Fprintf(w, "%s\tdisabled\t-\n", program)
+		} else if slices.Contains(probes.Unavailable(), program) {
+			fmt.Fprintf(w, "%s\tunavailable\t%s\n", program, probes.Failure(program))
+		} else {
+			fmt.Fprintf(w, "%s\tfailed\t%s\n", program, strings.ReplaceAll(probes.Failure(program).Error(), "\n", "; "))
+		}
+	}
+}
+
+// printCandidates lists every candidate in the order Attach tries them, so
+// the first ok line of a program is the one the game uses, or for connect
+// without kprobe.multi the first ok line of each function.
+func printCandidates(w io.Writer, results []ebpfmon.CandidateResult) {
+	fmt.Fprintln(w, "PROGRAM\tMECHANISM\tTARGET\tSTATUS")
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "failed: " + strings.ReplaceAll(r.Err.Error(), "\n", "; ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Program, r.Mechanism, r.Target, status)
+	}
+}
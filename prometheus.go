@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+type metricsExporter struct {
+	mu       sync.Mutex
+	metrics  ebpfmon.Metrics
+	game     bool
+	score    int
+	length   int
+	interval time.Duration
+}
+
+// exportedCounters are the counters all exporters report. Prometheus names
+// them with the snake_ebpf_ prefix, a _bytes suffix for the "By" unit and
+// _total.
+var exportedCounters = []struct {
+	name, unit, help string
+	value            func(m ebpfmon.Metrics) uint64
+}{
+	{"execve", "{exec}", "Process executions seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Execve }},
+	{"file_ops", "{open}", "File opens seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.FileOps }},
+	{"network", "{connect}", "TCP connects seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Network }},
+	{"process", "{fork}", "Process forks seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Process }},
+	{"context_switches", "{switch}", "Context switches seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.ContextSwitches }},
+	{"tcp_retransmits", "{segment}", "TCP retransmits seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Retransmits }},
+	{"packet_drops", "{packet}", "Dropped packets seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Drops }},
+	{"page_faults", "{fault}", "Page faults seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.PageFaults }},
+	{"direct_reclaims", "{reclaim}", "Direct memory reclaims seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Reclaims }},
+	{"process_exits", "{exit}", "Process exits seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.Exits }},
+	{"uprobe_calls", "{call}", "Calls to the -uprobe function.", func(m ebpfmon.Metrics) uint64 { return m.UprobeCalls }},
+	{"usdt_calls", "{hit}", "Hits of the -usdt probe.", func(m ebpfmon.Metrics) uint64 { return m.USDTCalls }},
+	{"egress", "By", "Bytes sent from the game's cgroup with -egress.", func(m ebpfmon.Metrics) uint64 { return m.EgressBytes }},
+	{"dns_queries", "{query}", "DNS queries sent over UDP seen by eBPF.", func(m ebpfmon.Metrics) uint64 { return m.DNSQueries }},
+}
+
+func startMetricsServer(addr string, exporter *metricsExporter) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", exporter)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go server.Serve(listener)
+	return server, nil
+}
+
+func (e *metricsExporter) updateMetrics(m ebpfmon.Metrics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.metrics = m
+}
+
+func (e *metricsExporter) updateGame(g *game.Game, interval time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.game = true
+	e.score = g.Score
+	e.length = len(g.Snake)
+	e.interval = interval
+}
+
+// publish takes in the counters and the game of this tick.
+func (e *metricsExporter) publish(s *session) {
+	e.updateMetrics(s.ui.Metrics)
+	e.updateGame(s.game, s.interval)
+}
+
+// gameStats is what the exporters report about the game, which is unset
+// without one, as in the monitor command.
+type gameStats struct {
+	score    int
+	length   int
+	interval time.Duration
+}
+
+// snapshot returns the latest counters and game stats.
+func (e *metricsExporter) snapshot() (ebpfmon.Metrics, gameStats, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.metrics, gameStats{score: e.score, length: e.length, interval: e.interval}, e.game
+}
+
+func (e *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, stats, game := e.snapshot()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, c := range exportedCounters {
+		name := "snake_ebpf_" + c.name
+		if c.unit == "By" {
+			name += "_bytes"
+		}
+		writeMetric(w, name+"_total", "counter", c.help, float64(c.value(m)))
+	}
+	writeMetric(w, "snake_ebpf_live_processes", "gauge", "Processes running.", float64(m.LiveProcesses))
+	writeLatency(w, "snake_ebpf_block_io_latency_seconds", "Block I/O request latency seen by eBPF.", m.IOLatency)
+	writeLatency(w, "snake_ebpf_runq_latency_seconds", "Run-queue latency seen by eBPF.", m.RunqLatency)
+	writeTraffic(w, m.Traffic)
+	writeMetric(w, "snake_ebpf_event_rate", "gauge", "Kernel events per second.", float64(m.EventRate))
+	fmt.Fprintf(w, "# HELP snake_ebpf_signals_total Signals delivered, seen by eBPF.\n# TYPE snake_ebpf_signals_total counter\n")
+	for k, count := range m.Signals {
+		fmt.Fprintf(w, "snake_ebpf_signals_total{signal=%q} %d\n", ebpfmon.SignalKind(k), count)
+	}
+	fmt.Fprintf(w, "# HELP snake_ebpf_syscalls_total System calls seen by eBPF, by category.\n# TYPE snake_ebpf_syscalls_total counter\n")
+	for c, count := range m.Syscalls {
+		fmt.Fprintf(w, "snake_ebpf_syscalls_total{category=%q} %d\n", ebpfmon.SyscallCategory(c), count)
+	}
+	if !game {
+		return
+	}
+	writeMetric(w, "snake_ebpf_score", "gauge", "Current game score.", float64(stats.score))
+	writeMetric(w, "snake_ebpf_snake_length", "gauge", "Current snake length.", float64(stats.length))
+	writeMetric(w, "snake_ebpf_tick_interval_seconds", "gauge", "Current game tick interval.", stats.interval.Seconds())
+}
+
+// writeLatency exports the slots of io_latency, or of runq_latency which
+// has the same ones, as a Prometheus histogram.
+// The kernel keeps no sum, so it is left at 0.
+func writeLatency(w io.Writer, name, help string, hist [ebpfmon.IO_LATENCY_SLOTS]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var count uint64
+	for slot, n := range hist {
+		count += n
+		if slot < len(hist)-1 {
+			fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, ebpfmon.IOLatencyBound(slot).Seconds(), count)
+		}
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum 0\n%s_count %d\n", name, count, name, name, count)
+}
+
+// writeTraffic exports what the XDP program counted, by protocol.
+func writeTraffic(w io.Writer, t ebpfmon.Traffic) {
+	fmt.Fprintf(w, "# HELP snake_ebpf_xdp_rx_packets_total Packets received on the -xdp-iface interface.\n# TYPE snake_ebpf_xdp_rx_packets_total counter\n")
+	for proto := range ebpfmon.XDP_PROTOS {
+		fmt.Fprintf(w, "snake_ebpf_xdp_rx_packets_total{protocol=%q} %d\n", ebpfmon.XDPProtoName(proto), t.Packets[proto])
+	}
+	fmt.Fprintf(w, "# HELP snake_ebpf_xdp_rx_bytes_total Bytes received on the -xdp-iface interface.\n# TYPE snake_ebpf_xdp_rx_bytes_total counter\n")
+	for proto := range ebpfmon.XDP_PROTOS {
+		fmt.Fprintf(w, "snake_ebpf_xdp_rx_bytes_total{protocol=%q} %d\n", ebpfmon.XDPProtoName(proto), t.Bytes[proto])
+	}
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// REPLAY_VERSION 2 split the tick frame, which polled the counters and
+// moved the snakes at once, into a poll frame and a tick frame. Version 3
+// queues direction keys for the next moves instead of turning at once, and
+// version 4 counts down before every round. Older replays still play back.
+const REPLAY_VERSION = 4
+
+// REPLAY_BUFFER_LIMIT is how large a run kept in memory, to be saved at
+// game over, may grow. Longer runs can be recorded with -record.
+const REPLAY_BUFFER_LIMIT = 64 << 20
+
+const (
+	FRAME_POLL    = "poll"
+	FRAME_TICK    = "tick"
+	FRAME_INPUT   = "input"
+	FRAME_EXEC    = "exec"
+	FRAME_CONTROL = "control"
+	FRAME_RESIZE  = "resize"
+)
+
+// replayHeader is the first line of a replay file and holds everything
+// needed to rebuild the initial game state.
+type replayHeader struct {
+	Version    int       `json:"version"`
+	Start      time.Time `json:"start"`
+	Seed       uint64    `json:"seed"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	Difficulty string    `json:"difficulty"`
+	Wrap       bool      `json:"wrap"`
+	Enemy      bool      `json:"enemy"`
+	TwoPlayer  bool      `json:"two_player,omitempty"`
+	Peer       bool      `json:"peer,omitempty"`
+	Demo       bool      `json:"demo"`
+	TimedFood  bool      `json:"timed_food"`
+	Uprobe     bool      `json:"uprobe,omitempty"`
+	XDP        bool      `json:"xdp,omitempty"`
+	// Speed is the speed scaling of the config file; replays recorded
+	// before it was kept use the default.
+	Speed *game.SpeedScaling `json:"speed,omitempty"`
+}
+
+// replayFrame is one line per change to the game: a metrics poll, a move
+// of the snakes, a key, an execve event that placed food, a remote control
+// command or a board resize.
+type replayFrame struct {
+	Time     time.Time         `json:"time"`
+	Kind     string            `json:"kind"`
+	Snapshot *ebpfmon.Snapshot `json:"snapshot,omitempty"`
+	Hold     bool              `json:"hold,omitempty"`
+	Input    string            `json:"input,omitempty"`
+	Action   string            `json:"action,omitempty"`
+	Value    string            `json:"value,omitempty"`
+	Width    int               `json:"width,omitempty"`
+	Height   int               `json:"height,omitempty"`
+}
+
+type replayRecorder struct {
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error
+	// mem holds the replay instead of f for a run kept in memory.
+	mem *bytes.Buffer
+}
+
+func newReplayRecorder(path string, header replayHeader) (*replayRecorder, error) {
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("create replay dir: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create replay: %w", err)
+	}
+	if err := chownToSudoUser(append(created, path)...); err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	r := &replayRecorder{f: f, w: w, enc: json.NewEncoder(w)}
+	if err := r.enc.Encode(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write replay: %w", err)
+	}
+	return r, nil
+}
+
+// newReplayBuffer keeps the run in memory until it is saved, or outgrows
+// REPLAY_BUFFER_LIMIT.
+func newReplayBuffer(header replayHeader) *replayRecorder {
+	mem := new(bytes.Buffer)
+	r := &replayRecorder{enc: json.NewEncoder(mem), mem: mem}
+	r.enc.Encode(header)
+	return r
+}
+
+func (r *replayRecorder) write(frame replayFrame) error {
+	if r.err != nil {
+		return nil
+	}
+	if err := r.enc.Encode(frame); err != nil {
+		r.err = fmt.Errorf("write replay: %w", err)
+		return r.err
+	}
+	if r.mem != nil && r.mem.Len() > REPLAY_BUFFER_LIMIT {
+		r.mem = nil
+		r.err = errors.New("replay: the run is too long to keep in memory, record it with -record")
+		return r.err
+	}
+	return nil
+}
+
+// save writes the run kept in memory so far to path.
+func (r *replayRecorder) save(path string) error {
+	if r.err != nil {
+		return r.err
+	}
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("create replay dir: %w", err)
+	}
+	if err := os.WriteFile(path, r.mem.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("save replay: %w", err)
+	}
+	return chownToSudoUser(append(created, path)...)
+}
+
+func (r *replayRecorder) Close() error {
+	err := r.w.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write replay: %w", err)
+	}
+	return r.err
+}
+
+func (s *session) record(frame replayFrame) {
+	if s.recorder == nil {
+		return
+	}
+	frame.Time = s.now
+	if err := s.recorder.write(frame); err != nil {
+		s.ui.Toasts.Push(err.Error())
+	}
+}
+
+// replaysDir is where replays saved at game over go.
+func replaysDir() string {
+	return filepath.Join(userHomeDir(), ".local", "share", "snake-ebpf", "replays")
+}
+
+// saveReplay saves the run so far, when it is kept in memory, to a file
+// of replaysDir named after the time.
+func (s *session) saveReplay() {
+	switch {
+	case s.recorder == nil:
+		s.ui.Toasts.Push("📼 this run is not kept, record it with -record")
+	case s.recorder.f != nil:
+		s.ui.Toasts.Push("📼 the run is recorded to " + s.recorder.f.Name())
+	default:
+		path := filepath.Join(replaysDir(), s.now.Format("2006-01-02T15-04-05")+".jsonl")
+		if err := s.recorder.save(path); err != nil {
+			s.ui.Toasts.Push(err.Error())
+			return
+		}
+		s.ui.Toasts.Push("📼 replay saved to " + path)
+	}
+}
+
+func (s *session) replayHeader(enemy bool) replayHeader {
+	g := s.game
+	return replayHeader{
+		Version:    REPLAY_VERSION,
+		Start:      s.now,
+		Seed:       g.Seed,
+		Width:      g.Width,
+		Height:     g.Height,
+		Difficulty: g.Difficulty.Name,
+		Wrap:       g.Wrap,
+		Enemy:      enemy,
+		TwoPlayer:  g.Player2 != nil,
+		Peer:       s.peer,
+		Demo:       s.demo,
+		TimedFood:  s.timedFood,
+		Uprobe:     s.uprobe,
+		XDP:        s.xdp,
+		Speed:      &s.speed,
+	}
+}
+
+func readReplayHeader(dec *json.Decoder) (replayHeader, error) {
+	var h replayHeader
+	if err := dec.Decode(&h); err != nil {
+		return h, fmt.Errorf("read replay header: %w", err)
+	}
+	if h.Version < 1 || h.Version > REPLAY_VERSION {
+		return h, fmt.Errorf("unsupported replay version %d", h.Version)
+	}
+	if h.Width <= 0 || h.Height <= 0 {
+		return h, fmt.Errorf("invalid replay board %dx%d", h.Width, h.Height)
+	}
+	if h.Speed == nil {
+		h.Speed = &game.DefaultSpeedScaling
+	}
+	for f, scaling := range h.Speed {
+		if err := scaling.Validate(); err != nil {
+			return h, fmt.Errorf("invalid replay speed.%s: %w", game.SpeedFactor(f), err)
+		}
+	}
+	return h, nil
+}
+
+func runReplayCommand(ctx context.Context, args []string) int {
+	fs := newFlagSet("replay", " FILE")
+	display := addDisplayFlags(fs)
+	if code, ok := parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	return runReplay(ctx, fs.Arg(0), *display)
+}
+
+// runReplay plays a recorded run back tick for tick at its original pace.
+// It needs no eBPF programs, so it works without any privileges.
+func runReplay(ctx context.Context, path string, display displayOptions) int {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open replay: %v\n", err)
+		return EXIT_FAILURE
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	header, err := readReplayHeader(dec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load replay: %v\n", err)
+		return EXIT_FAILURE
+	}
+	difficulty, err := game.LookupDifficulty(header.Difficulty)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load replay: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	if err := tui.SetupTerminal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up terminal: %v\n", err)
+		return EXIT_FAILURE
+	}
+	defer tui.RestoreTerminal()
+
+	ui := tui.New(header.Width, header.Height)
+	ui.Resize(tui.TerminalSize())
+	ui.Demo = header.Demo
+	display.apply(ui)
+	defer ui.Close()
+
+	s := newReplaySession(header, ui, display.noColor)
+	s.startReplay(header, difficulty)
+	g := s.game
+	ui.Toasts.Push("▶ replay " + path)
+
+	frames := make(chan replayFrame)
+	decodeErr := make(chan error, 1)
+	go func() {
+		defer tui.RestoreOnPanic()
+		defer close(frames)
+		for {
+			var frame replayFrame
+			if err := dec.Decode(&frame); err != nil {
+				if !errors.Is(err, io.EOF) {
+					decodeErr <- fmt.Errorf("read replay: %w", err)
+				}
+				return
+			}
+			frames <- frame
+		}
+	}()
+
+	inputChan := make(chan string, tui.INPUT_BUFFER)
+	go tui.ReadInput(ctx, inputChan)
+
+	var start, first time.Time
+	for frame := range frames {
+		if start.IsZero() {
+			start, first = time.Now(), frame.Time
+		}
+		wait := time.NewTimer(time.Until(start.Add(frame.Time.Sub(first))))
+		for waiting := true; waiting; {
+			select {
+			case <-wait.C:
+				waiting = false
+			case <-ctx.Done():
+				wait.Stop()
+				return EXIT_OK
+			case <-tui.Resizes():
+				s.resize(tui.TerminalSize())
+				s.render()
+			case input, ok := <-inputChan:
+				if !ok {
+					inputChan = nil
+					continue
+				}
+				if a, _ := defaultKeymap.lookup(input, false); a == KEY_QUIT {
+					wait.Stop()
+					return EXIT_OK
+				}
+			}
+		}
+
+		s.now = frame.Time
+		if err := s.replayFrame(frame); err != nil {
+			ui.Close()
+			tui.RestoreTerminal()
+			fmt.Fprintf(os.Stderr, "Failed to replay: %v\n", err)
+			return EXIT_FAILURE
+		}
+		s.render()
+	}
+	select {
+	case err := <-decodeErr:
+		ui.Close()
+		tui.RestoreTerminal()
+		fmt.Fprintf(os.Stderr, "Failed to replay: %v\n", err)
+		return EXIT_FAILURE
+	default:
+	}
+
+	ui.Close()
+	tui.RestoreTerminal()
+	fmt.Println("\nReplay finished!")
+	fmt.Printf("Final Score: %d\n", g.Score)
+	return EXIT_OK
+}
+
+// newReplaySession returns a session that plays the run of header back on
+// ui.
+func newReplaySession(header replayHeader, ui *tui.UI, noColor bool) *session {
+	return &session{
+		game:      game.New(header.Width, header.Height, header.Seed),
+		ui:        ui,
+		cfg:       config{Keys: defaultKeymap},
+		rates:     ebpfmon.NewRates(ebpfmon.RATE_WINDOW),
+		bursts:    ebpfmon.NewBurstDetector(ebpfmon.ExecveCount, ebpfmon.BURST_EXECVE_RATE, ebpfmon.BURST_COOLDOWN),
+		dangers:   ebpfmon.NewBurstDetector(ebpfmon.DangerCount, ebpfmon.BURST_DANGER_RATE, ebpfmon.BURST_DANGER_COOLDOWN),
+		speed:     *header.Speed,
+		now:       header.Start,
+		demo:      header.Demo,
+		timedFood: header.TimedFood,
+		uprobe:    header.Uprobe,
+		xdp:       header.XDP,
+		noColor:   noColor,
+		peer:      header.Peer,
+	}
+}
+
+// startReplay deals the first round the way the run of header did.
+func (s *session) startReplay(header replayHeader, difficulty game.Difficulty) {
+	s.game.NoCountdown = header.Version < 4
+	s.startGame(difficulty, header.Wrap, header.Enemy, header.TwoPlayer)
+	s.game.DirectTurns = header.Version < 3
+}
+
+func (s *session) replayPoll(snap ebpfmon.Snapshot, hold bool) {
+	s.ui.Metrics = snap.Metrics
+	s.ui.History.Record(snap, s.interval, s.game.Score)
+	s.poll(snap, hold)
+}
+
+func (s *session) replayFrame(frame replayFrame) error {
+	switch frame.Kind {
+	case FRAME_POLL:
+		if frame.Snapshot == nil {
+			return fmt.Errorf("poll at %s without snapshot", frame.Time)
+		}
+		s.replayPoll(*frame.Snapshot, frame.Hold)
+	case FRAME_TICK:
+		// Version 1 ticks polled the counters before moving.
+		if frame.Snapshot != nil {
+			s.replayPoll(*frame.Snapshot, frame.Hold)
+		}
+		s.step(frame.Hold)
+	case FRAME_INPUT:
+		s.gameKey(frame.Input)
+	case FRAME_EXEC:
+		s.spawnDueFood()
+	case FRAME_CONTROL:
+		return s.applyControl(controlCommand{action: frame.Action, value: frame.Value})
+	case FRAME_RESIZE:
+		s.resizeBoard(frame.Width, frame.Height)
+	default:
+		return fmt.Errorf("unknown replay frame %q", frame.Kind)
+	}
+	return nil
+}
@@ -94,16 +94,13 @@ check_build_tools() {
     echo ""
 }
 
-check_kernel_headers() {
-    echo "4. Checking kernel headers..."
-    KERNEL_VERSION=$(uname -r)
-    KERNEL_HEADERS="/usr/src/linux-headers-$KERNEL_VERSION"
-
-    if [ -d "$KERNEL_HEADERS" ]; then
-        check_pass "Kernel headers found: $KERNEL_HEADERS"
+check_kernel_btf() {
+    echo "4. Checking kernel BTF..."
+    if [ -f "/sys/kernel/btf/vmlinux" ]; then
+        check_pass "Kernel BTF found: /sys/kernel/btf/vmlinux"
     else
-        check_fail "Kernel headers not found: $KERNEL_HEADERS"
-        echo "   Install: sudo apt-get install linux-headers-$KERNEL_VERSION"
+        check_warn "Kernel BTF not found: /sys/kernel/btf/vmlinux"
+        echo "   Run with a BTF file for $(uname -r): sudo ./snake-ebpf -btf /path/to/vmlinux.btf"
     fi
     echo ""
 }
@@ -159,16 +156,16 @@ check_project_files() {
 
 check_artifacts() {
     echo "8. Checking compiled artifacts..."
-    if [ -f "bpf/snake.bpf.o" ]; then
-        check_pass "eBPF object file exists: bpf/snake.bpf.o"
-        FILE_TYPE=$(file bpf/snake.bpf.o 2>/dev/null | grep -o "ELF" || echo "")
+    if [ -f "ebpfmon/snake_bpfel.o" ]; then
+        check_pass "eBPF object file exists: ebpfmon/snake_bpfel.o"
+        FILE_TYPE=$(file ebpfmon/snake_bpfel.o 2>/dev/null | grep -o "ELF" || echo "")
         if [ "$FILE_TYPE" = "ELF" ]; then
             check_pass "eBPF object file is valid ELF"
         else
             check_warn "eBPF object file may be invalid"
         fi
     else
-        check_warn "eBPF object file not found: bpf/snake.bpf.o"
+        check_warn "eBPF object file not found: ebpfmon/snake_bpfel.o"
         echo "   Build it: cd bpf && make"
     fi
 
@@ -181,7 +178,7 @@ check_artifacts() {
         fi
     else
         check_warn "Go binary not found: snake-ebpf"
-        echo "   Build it: go build -o snake-ebpf main.go"
+        echo "   Build it: go build -o snake-ebpf ."
     fi
     echo ""
 }
@@ -216,12 +213,12 @@ test_compilation() {
 
     echo ""
     echo "   Testing Go compilation..."
-    if go build -o snake-ebpf-test main.go >/dev/null 2>&1; then
+    if go build -o snake-ebpf-test . >/dev/null 2>&1; then
         check_pass "Go program compiles successfully"
         rm -f snake-ebpf-test
     else
         check_fail "Go program compilation failed"
-        echo "   Run 'go build -o snake-ebpf main.go' to see errors"
+        echo "   Run 'go build -o snake-ebpf .' to see errors"
     fi
     echo ""
 }
@@ -260,7 +257,7 @@ main() {
     check_go
     check_go_modules
     check_build_tools
-    check_kernel_headers
+    check_kernel_btf
     check_libbpf
     check_ebpf_support
     check_project_files
@@ -0,0 +1,686 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v3.21.12
+// source: snake.proto
+
+package snakepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MetricsStreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricsStreamRequest) Reset() {
+	*x = MetricsStreamRequest{}
+	mi := &file_snake_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsStreamRequest) ProtoMessage() {}
+
+func (x *MetricsStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsStreamRequest.ProtoReflect.Descriptor instead.
+func (*MetricsStreamRequest) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{0}
+}
+
+type GameStateStreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameStateStreamRequest) Reset() {
+	*x = GameStateStreamRequest{}
+	mi := &file_snake_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameStateStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameStateStreamRequest) ProtoMessage() {}
+
+func (x *GameStateStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameStateStreamRequest.ProtoReflect.Descriptor instead.
+func (*GameStateStreamRequest) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{1}
+}
+
+// Metrics are the kernel counters since the probes were attached.
+type Metrics struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TimeUnixNano    int64                  `protobuf:"varint,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Execve          uint64                 `protobuf:"varint,2,opt,name=execve,proto3" json:"execve,omitempty"`
+	FileOps         uint64                 `protobuf:"varint,3,opt,name=file_ops,json=fileOps,proto3" json:"file_ops,omitempty"`
+	Network         uint64                 `protobuf:"varint,4,opt,name=network,proto3" json:"network,omitempty"`
+	Process         uint64                 `protobuf:"varint,5,opt,name=process,proto3" json:"process,omitempty"`
+	ContextSwitches uint64                 `protobuf:"varint,6,opt,name=context_switches,json=contextSwitches,proto3" json:"context_switches,omitempty"`
+	TcpRetransmits  uint64                 `protobuf:"varint,7,opt,name=tcp_retransmits,json=tcpRetransmits,proto3" json:"tcp_retransmits,omitempty"`
+	PacketDrops     uint64                 `protobuf:"varint,8,opt,name=packet_drops,json=packetDrops,proto3" json:"packet_drops,omitempty"`
+	PageFaults      uint64                 `protobuf:"varint,9,opt,name=page_faults,json=pageFaults,proto3" json:"page_faults,omitempty"`
+	DirectReclaims  uint64                 `protobuf:"varint,10,opt,name=direct_reclaims,json=directReclaims,proto3" json:"direct_reclaims,omitempty"`
+	ProcessExits    uint64                 `protobuf:"varint,11,opt,name=process_exits,json=processExits,proto3" json:"process_exits,omitempty"`
+	LiveProcesses   uint64                 `protobuf:"varint,12,opt,name=live_processes,json=liveProcesses,proto3" json:"live_processes,omitempty"`
+	UprobeCalls     uint64                 `protobuf:"varint,13,opt,name=uprobe_calls,json=uprobeCalls,proto3" json:"uprobe_calls,omitempty"`
+	UsdtCalls       uint64                 `protobuf:"varint,14,opt,name=usdt_calls,json=usdtCalls,proto3" json:"usdt_calls,omitempty"`
+	EgressBytes     uint64                 `protobuf:"varint,15,opt,name=egress_bytes,json=egressBytes,proto3" json:"egress_bytes,omitempty"`
+	DnsQueries      uint64                 `protobuf:"varint,16,opt,name=dns_queries,json=dnsQueries,proto3" json:"dns_queries,omitempty"`
+	EventRate       uint64                 `protobuf:"varint,17,opt,name=event_rate,json=eventRate,proto3" json:"event_rate,omitempty"`
+	// syscalls and signals are counted by category and by signal name.
+	Syscalls map[string]uint64 `protobuf:"bytes,18,rep,name=syscalls,proto3" json:"syscalls,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Signals  map[string]uint64 `protobuf:"bytes,19,rep,name=signals,proto3" json:"signals,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// io_latency and runq_latency are histograms: slot i counts waits of
+	// 2^i up to 2^(i+1) microseconds, the last one everything slower.
+	IoLatency     []uint64 `protobuf:"varint,20,rep,packed,name=io_latency,json=ioLatency,proto3" json:"io_latency,omitempty"`
+	RunqLatency   []uint64 `protobuf:"varint,21,rep,packed,name=runq_latency,json=runqLatency,proto3" json:"runq_latency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metrics) Reset() {
+	*x = Metrics{}
+	mi := &file_snake_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metrics) ProtoMessage() {}
+
+func (x *Metrics) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metrics.ProtoReflect.Descriptor instead.
+func (*Metrics) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Metrics) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *Metrics) GetExecve() uint64 {
+	if x != nil {
+		return x.Execve
+	}
+	return 0
+}
+
+func (x *Metrics) GetFileOps() uint64 {
+	if x != nil {
+		return x.FileOps
+	}
+	return 0
+}
+
+func (x *Metrics) GetNetwork() uint64 {
+	if x != nil {
+		return x.Network
+	}
+	return 0
+}
+
+func (x *Metrics) GetProcess() uint64 {
+	if x != nil {
+		return x.Process
+	}
+	return 0
+}
+
+func (x *Metrics) GetContextSwitches() uint64 {
+	if x != nil {
+		return x.ContextSwitches
+	}
+	return 0
+}
+
+func (x *Metrics) GetTcpRetransmits() uint64 {
+	if x != nil {
+		return x.TcpRetransmits
+	}
+	return 0
+}
+
+func (x *Metrics) GetPacketDrops() uint64 {
+	if x != nil {
+		return x.PacketDrops
+	}
+	return 0
+}
+
+func (x *Metrics) GetPageFaults() uint64 {
+	if x != nil {
+		return x.PageFaults
+	}
+	return 0
+}
+
+func (x *Metrics) GetDirectReclaims() uint64 {
+	if x != nil {
+		return x.DirectReclaims
+	}
+	return 0
+}
+
+func (x *Metrics) GetProcessExits() uint64 {
+	if x != nil {
+		return x.ProcessExits
+	}
+	return 0
+}
+
+func (x *Metrics) GetLiveProcesses() uint64 {
+	if x != nil {
+		return x.LiveProcesses
+	}
+	return 0
+}
+
+func (x *Metrics) GetUprobeCalls() uint64 {
+	if x != nil {
+		return x.UprobeCalls
+	}
+	return 0
+}
+
+func (x *Metrics) GetUsdtCalls() uint64 {
+	if x != nil {
+		return x.UsdtCalls
+	}
+	return 0
+}
+
+func (x *Metrics) GetEgressBytes() uint64 {
+	if x != nil {
+		return x.EgressBytes
+	}
+	return 0
+}
+
+func (x *Metrics) GetDnsQueries() uint64 {
+	if x != nil {
+		return x.DnsQueries
+	}
+	return 0
+}
+
+func (x *Metrics) GetEventRate() uint64 {
+	if x != nil {
+		return x.EventRate
+	}
+	return 0
+}
+
+func (x *Metrics) GetSyscalls() map[string]uint64 {
+	if x != nil {
+		return x.Syscalls
+	}
+	return nil
+}
+
+func (x *Metrics) GetSignals() map[string]uint64 {
+	if x != nil {
+		return x.Signals
+	}
+	return nil
+}
+
+func (x *Metrics) GetIoLatency() []uint64 {
+	if x != nil {
+		return x.IoLatency
+	}
+	return nil
+}
+
+func (x *Metrics) GetRunqLatency() []uint64 {
+	if x != nil {
+		return x.RunqLatency
+	}
+	return nil
+}
+
+type Position struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_snake_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Position) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Position) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type GameState struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	TimeUnixNano int64                  `protobuf:"varint,1,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Score        int32                  `protobuf:"varint,2,opt,name=score,proto3" json:"score,omitempty"`
+	// snake starts with the head.
+	Snake      []*Position `protobuf:"bytes,3,rep,name=snake,proto3" json:"snake,omitempty"`
+	Direction  *Position   `protobuf:"bytes,4,opt,name=direction,proto3" json:"direction,omitempty"`
+	Food       *Position   `protobuf:"bytes,5,opt,name=food,proto3" json:"food,omitempty"`
+	FoodKind   string      `protobuf:"bytes,6,opt,name=food_kind,json=foodKind,proto3" json:"food_kind,omitempty"`
+	Obstacles  []*Position `protobuf:"bytes,7,rep,name=obstacles,proto3" json:"obstacles,omitempty"`
+	Width      int32       `protobuf:"varint,8,opt,name=width,proto3" json:"width,omitempty"`
+	Height     int32       `protobuf:"varint,9,opt,name=height,proto3" json:"height,omitempty"`
+	Paused     bool        `protobuf:"varint,10,opt,name=paused,proto3" json:"paused,omitempty"`
+	GameOver   bool        `protobuf:"varint,11,opt,name=game_over,json=gameOver,proto3" json:"game_over,omitempty"`
+	Difficulty string      `protobuf:"bytes,12,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	// tick_interval is a Go duration, e.g. "180ms".
+	TickInterval  string `protobuf:"bytes,13,opt,name=tick_interval,json=tickInterval,proto3" json:"tick_interval,omitempty"`
+	Wrap          bool   `protobuf:"varint,14,opt,name=wrap,proto3" json:"wrap,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameState) Reset() {
+	*x = GameState{}
+	mi := &file_snake_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameState) ProtoMessage() {}
+
+func (x *GameState) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameState.ProtoReflect.Descriptor instead.
+func (*GameState) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GameState) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *GameState) GetScore() int32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *GameState) GetSnake() []*Position {
+	if x != nil {
+		return x.Snake
+	}
+	return nil
+}
+
+func (x *GameState) GetDirection() *Position {
+	if x != nil {
+		return x.Direction
+	}
+	return nil
+}
+
+func (x *GameState) GetFood() *Position {
+	if x != nil {
+		return x.Food
+	}
+	return nil
+}
+
+func (x *GameState) GetFoodKind() string {
+	if x != nil {
+		return x.FoodKind
+	}
+	return ""
+}
+
+func (x *GameState) GetObstacles() []*Position {
+	if x != nil {
+		return x.Obstacles
+	}
+	return nil
+}
+
+func (x *GameState) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *GameState) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *GameState) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *GameState) GetGameOver() bool {
+	if x != nil {
+		return x.GameOver
+	}
+	return false
+}
+
+func (x *GameState) GetDifficulty() string {
+	if x != nil {
+		return x.Difficulty
+	}
+	return ""
+}
+
+func (x *GameState) GetTickInterval() string {
+	if x != nil {
+		return x.TickInterval
+	}
+	return ""
+}
+
+func (x *GameState) GetWrap() bool {
+	if x != nil {
+		return x.Wrap
+	}
+	return false
+}
+
+type InputRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// key is a key as typed in the game: "up", "down", "left", "right" or
+	// one of w, a, s, d, p (pause), b (wrap) and r (restart).
+	Key           string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InputRequest) Reset() {
+	*x = InputRequest{}
+	mi := &file_snake_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputRequest) ProtoMessage() {}
+
+func (x *InputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputRequest.ProtoReflect.Descriptor instead.
+func (*InputRequest) Descriptor() ([]byte, []int) {
+	return file_snake_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InputRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+var File_snake_proto protoreflect.FileDescriptor
+
+const file_snake_proto_rawDesc = "" +
+	"\n" +
+	"\vsnake.proto\x12\bsnake.v1\"\x16\n" +
+	"\x14MetricsStreamRequest\"\x18\n" +
+	"\x16GameStateStreamRequest\"\xfa\x06\n" +
+	"\aMetrics\x12$\n" +
+	"\x0etime_unix_nano\x18\x01 \x01(\x03R\ftimeUnixNano\x12\x16\n" +
+	"\x06execve\x18\x02 \x01(\x04R\x06execve\x12\x19\n" +
+	"\bfile_ops\x18\x03 \x01(\x04R\afileOps\x12\x18\n" +
+	"\anetwork\x18\x04 \x01(\x04R\anetwork\x12\x18\n" +
+	"\aprocess\x18\x05 \x01(\x04R\aprocess\x12)\n" +
+	"\x10context_switches\x18\x06 \x01(\x04R\x0fcontextSwitches\x12'\n" +
+	"\x0ftcp_retransmits\x18\a \x01(\x04R\x0etcpRetransmits\x12!\n" +
+	"\fpacket_drops\x18\b \x01(\x04R\vpacketDrops\x12\x1f\n" +
+	"\vpage_faults\x18\t \x01(\x04R\n" +
+	"pageFaults\x12'\n" +
+	"\x0fdirect_reclaims\x18\n" +
+	" \x01(\x04R\x0edirectReclaims\x12#\n" +
+	"\rprocess_exits\x18\v \x01(\x04R\fprocessExits\x12%\n" +
+	"\x0elive_processes\x18\f \x01(\x04R\rliveProcesses\x12!\n" +
+	"\fuprobe_calls\x18\r \x01(\x04R\vuprobeCalls\x12\x1d\n" +
+	"\n" +
+	"usdt_calls\x18\x0e \x01(\x04R\tusdtCalls\x12!\n" +
+	"\fegress_bytes\x18\x0f \x01(\x04R\vegressBytes\x12\x1f\n" +
+	"\vdns_queries\x18\x10 \x01(\x04R\n" +
+	"dnsQueries\x12\x1d\n" +
+	"\n" +
+	"event_rate\x18\x11 \x01(\x04R\teventRate\x12;\n" +
+	"\bsyscalls\x18\x12 \x03(\v2\x1f.snake.v1.Metrics.SyscallsEntryR\bsyscalls\x128\n" +
+	"\asignals\x18\x13 \x03(\v2\x1e.snake.v1.Metrics.SignalsEntryR\asignals\x12\x1d\n" +
+	"\n" +
+	"io_latency\x18\x14 \x03(\x04R\tioLatency\x12!\n" +
+	"\frunq_latency\x18\x15 \x03(\x04R\vrunqLatency\x1a;\n" +
+	"\rSyscallsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1a:\n" +
+	"\fSignalsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"&\n" +
+	"\bPosition\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\"\xd6\x03\n" +
+	"\tGameState\x12$\n" +
+	"\x0etime_unix_nano\x18\x01 \x01(\x03R\ftimeUnixNano\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x05R\x05score\x12(\n" +
+	"\x05snake\x18\x03 \x03(\v2\x12.snake.v1.PositionR\x05snake\x120\n" +
+	"\tdirection\x18\x04 \x01(\v2\x12.snake.v1.PositionR\tdirection\x12&\n" +
+	"\x04food\x18\x05 \x01(\v2\x12.snake.v1.PositionR\x04food\x12\x1b\n" +
+	"\tfood_kind\x18\x06 \x01(\tR\bfoodKind\x120\n" +
+	"\tobstacles\x18\a \x03(\v2\x12.snake.v1.PositionR\tobstacles\x12\x14\n" +
+	"\x05width\x18\b \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\t \x01(\x05R\x06height\x12\x16\n" +
+	"\x06paused\x18\n" +
+	" \x01(\bR\x06paused\x12\x1b\n" +
+	"\tgame_over\x18\v \x01(\bR\bgameOver\x12\x1e\n" +
+	"\n" +
+	"difficulty\x18\f \x01(\tR\n" +
+	"difficulty\x12#\n" +
+	"\rtick_interval\x18\r \x01(\tR\ftickInterval\x12\x12\n" +
+	"\x04wrap\x18\x0e \x01(\bR\x04wrap\" \n" +
+	"\fInputRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key2\xd5\x01\n" +
+	"\x05Snake\x12D\n" +
+	"\rMetricsStream\x12\x1e.snake.v1.MetricsStreamRequest\x1a\x11.snake.v1.Metrics0\x01\x12J\n" +
+	"\x0fGameStateStream\x12 .snake.v1.GameStateStreamRequest\x1a\x13.snake.v1.GameState0\x01\x12:\n" +
+	"\vInjectInput\x12\x16.snake.v1.InputRequest\x1a\x13.snake.v1.GameStateB\x14Z\x12snake-ebpf/snakepbb\x06proto3"
+
+var (
+	file_snake_proto_rawDescOnce sync.Once
+	file_snake_proto_rawDescData []byte
+)
+
+func file_snake_proto_rawDescGZIP() []byte {
+	file_snake_proto_rawDescOnce.Do(func() {
+		file_snake_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_snake_proto_rawDesc), len(file_snake_proto_rawDesc)))
+	})
+	return file_snake_proto_rawDescData
+}
+
+var file_snake_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_snake_proto_goTypes = []any{
+	(*MetricsStreamRequest)(nil),   // 0: snake.v1.MetricsStreamRequest
+	(*GameStateStreamRequest)(nil), // 1: snake.v1.GameStateStreamRequest
+	(*Metrics)(nil),                // 2: snake.v1.Metrics
+	(*Position)(nil),               // 3: snake.v1.Position
+	(*GameState)(nil),              // 4: snake.v1.GameState
+	(*InputRequest)(nil),           // 5: snake.v1.InputRequest
+	nil,                            // 6: snake.v1.Metrics.SyscallsEntry
+	nil,                            // 7: snake.v1.Metrics.SignalsEntry
+}
+var file_snake_proto_depIdxs = []int32{
+	6, // 0: snake.v1.Metrics.syscalls:type_name -> snake.v1.Metrics.SyscallsEntry
+	7, // 1: snake.v1.Metrics.signals:type_name -> snake.v1.Metrics.SignalsEntry
+	3, // 2: snake.v1.GameState.snake:type_name -> snake.v1.Position
+	3, // 3: snake.v1.GameState.direction:type_name -> snake.v1.Position
+	3, // 4: snake.v1.GameState.food:type_name -> snake.v1.Position
+	3, // 5: snake.v1.GameState.obstacles:type_name -> snake.v1.Position
+	0, // 6: snake.v1.Snake.MetricsStream:input_type -> snake.v1.MetricsStreamRequest
+	1, // 7: snake.v1.Snake.GameStateStream:input_type -> snake.v1.GameStateStreamRequest
+	5, // 8: snake.v1.Snake.InjectInput:input_type -> snake.v1.InputRequest
+	2, // 9: snake.v1.Snake.MetricsStream:output_type -> snake.v1.Metrics
+	4, // 10: snake.v1.Snake.GameStateStream:output_type -> snake.v1.GameState
+	4, // 11: snake.v1.Snake.InjectInput:output_type -> snake.v1.GameState
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_snake_proto_init() }
+func file_snake_proto_init() {
+	if File_snake_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_snake_proto_rawDesc), len(file_snake_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_snake_proto_goTypes,
+		DependencyIndexes: file_snake_proto_depIdxs,
+		MessageInfos:      file_snake_proto_msgTypes,
+	}.Build()
+	File_snake_proto = out.File
+	file_snake_proto_goTypes = nil
+	file_snake_proto_depIdxs = nil
+}
@@ -0,0 +1,216 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: snake.proto
+
+package snakepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Snake_MetricsStream_FullMethodName   = "/snake.v1.Snake/MetricsStream"
+	Snake_GameStateStream_FullMethodName = "/snake.v1.Snake/GameStateStream"
+	Snake_InjectInput_FullMethodName     = "/snake.v1.Snake/InjectInput"
+)
+
+// SnakeClient is the client API for Snake service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Snake streams the kernel counters and the game state of a running game,
+// and takes input for it.
+type SnakeClient interface {
+	// MetricsStream sends the kernel counters after every poll (100ms).
+	MetricsStream(ctx context.Context, in *MetricsStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Metrics], error)
+	// GameStateStream sends the game state after every poll (100ms).
+	GameStateStream(ctx context.Context, in *GameStateStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GameState], error)
+	// InjectInput presses a game key and returns the game state after it.
+	InjectInput(ctx context.Context, in *InputRequest, opts ...grpc.CallOption) (*GameState, error)
+}
+
+type snakeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSnakeClient(cc grpc.ClientConnInterface) SnakeClient {
+	return &snakeClient{cc}
+}
+
+func (c *snakeClient) MetricsStream(ctx context.Context, in *MetricsStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Metrics], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Snake_ServiceDesc.Streams[0], Snake_MetricsStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MetricsStreamRequest, Metrics]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snake_MetricsStreamClient = grpc.ServerStreamingClient[Metrics]
+
+func (c *snakeClient) GameStateStream(ctx context.Context, in *GameStateStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GameState], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Snake_ServiceDesc.Streams[1], Snake_GameStateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GameStateStreamRequest, GameState]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snake_GameStateStreamClient = grpc.ServerStreamingClient[GameState]
+
+func (c *snakeClient) InjectInput(ctx context.Context, in *InputRequest, opts ...grpc.CallOption) (*GameState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, Snake_InjectInput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SnakeServer is the server API for Snake service.
+// All implementations must embed UnimplementedSnakeServer
+// for forward compatibility.
+//
+// Snake streams the kernel counters and the game state of a running game,
+// and takes input for it.
+type SnakeServer interface {
+	// MetricsStream sends the kernel counters after every poll (100ms).
+	MetricsStream(*MetricsStreamRequest, grpc.ServerStreamingServer[Metrics]) error
+	// GameStateStream sends the game state after every poll (100ms).
+	GameStateStream(*GameStateStreamRequest, grpc.ServerStreamingServer[GameState]) error
+	// InjectInput presses a game key and returns the game state after it.
+	InjectInput(context.Context, *InputRequest) (*GameState, error)
+	mustEmbedUnimplementedSnakeServer()
+}
+
+// UnimplementedSnakeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSnakeServer struct{}
+
+func (UnimplementedSnakeServer) MetricsStream(*MetricsStreamRequest, grpc.ServerStreamingServer[Metrics]) error {
+	return status.Errorf(codes.Unimplemented, "method MetricsStream not implemented")
+}
+func (UnimplementedSnakeServer) GameStateStream(*GameStateStreamRequest, grpc.ServerStreamingServer[GameState]) error {
+	return status.Errorf(codes.Unimplemented, "method GameStateStream not implemented")
+}
+func (UnimplementedSnakeServer) InjectInput(context.Context, *InputRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InjectInput not implemented")
+}
+func (UnimplementedSnakeServer) mustEmbedUnimplementedSnakeServer() {}
+func (UnimplementedSnakeServer) testEmbeddedByValue()               {}
+
+// UnsafeSnakeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SnakeServer will
+// result in compilation errors.
+type UnsafeSnakeServer interface {
+	mustEmbedUnimplementedSnakeServer()
+}
+
+func RegisterSnakeServer(s grpc.ServiceRegistrar, srv SnakeServer) {
+	// If the following call pancis, it indicates UnimplementedSnakeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Snake_ServiceDesc, srv)
+}
+
+func _Snake_MetricsStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MetricsStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnakeServer).MetricsStream(m, &grpc.GenericServerStream[MetricsStreamRequest, Metrics]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snake_MetricsStreamServer = grpc.ServerStreamingServer[Metrics]
+
+func _Snake_GameStateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GameStateStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnakeServer).GameStateStream(m, &grpc.GenericServerStream[GameStateStreamRequest, GameState]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snake_GameStateStreamServer = grpc.ServerStreamingServer[GameState]
+
+func _Snake_InjectInput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnakeServer).InjectInput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Snake_InjectInput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnakeServer).InjectInput(ctx, req.(*InputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Snake_ServiceDesc is the grpc.ServiceDesc for Snake service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Snake_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "snake.v1.Snake",
+	HandlerType: (*SnakeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InjectInput",
+			Handler:    _Snake_InjectInput_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MetricsStream",
+			Handler:       _Snake_MetricsStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GameStateStream",
+			Handler:       _Snake_GameStateStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "snake.proto",
+}
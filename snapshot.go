@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+type stateSnapshot struct {
+	Time   time.Time       `json:"time"`
+	Game   gameSnapshot    `json:"game"`
+	Metric metricsSnapshot `json:"metrics"`
+	Probes probeSnapshot   `json:"probes"`
+	Config configSnapshot  `json:"config"`
+}
+
+type gameSnapshot struct {
+	Score      int             `json:"score"`
+	Snake      []game.Position `json:"snake"`
+	Direction  game.Position   `json:"direction"`
+	Food       game.Position   `json:"food"`
+	Obstacles  []game.Position `json:"obstacles"`
+	Poison     *game.Position  `json:"poison,omitempty"`
+	FoodKind   string          `json:"food_kind"`
+	ExtraFood  []foodSnapshot  `json:"extra_food"`
+	BonusFood  []foodSnapshot  `json:"bonus_food"`
+	Width      int             `json:"width"`
+	Height     int             `json:"height"`
+	Playfield  game.Bounds     `json:"playfield"`
+	Paused     bool            `json:"paused"`
+	Wrap       bool            `json:"wrap"`
+	GameOver   bool            `json:"game_over"`
+	Difficulty string          `json:"difficulty"`
+	Seed       uint64          `json:"seed"`
+	Interval   string          `json:"tick_interval"`
+	// Player2 is the second snake in two-player mode.
+	Player2 *player2Snapshot `json:"player2,omitempty"`
+}
+
+type player2Snapshot struct {
+	Score     int             `json:"score"`
+	Snake     []game.Position `json:"snake"`
+	Direction game.Position   `json:"direction"`
+	Crashed   bool            `json:"crashed"`
+}
+
+type foodSnapshot struct {
+	Pos  game.Position `json:"pos"`
+	Kind string        `json:"kind"`
+}
+
+type rxCounts struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+type containerSnapshot struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	Runtime string `json:"runtime"`
+	Execve  uint64 `json:"execve"`
+	FileOps uint64 `json:"file_ops"`
+	Network uint64 `json:"network"`
+	Process uint64 `json:"process"`
+}
+
+type metricsSnapshot struct {
+	Execve         uint64              `json:"execve"`
+	FileOps        uint64              `json:"file_ops"`
+	Network        uint64              `json:"network"`
+	Process        uint64              `json:"process"`
+	ContextSwitch  uint64              `json:"context_switches"`
+	Retransmits    uint64              `json:"tcp_retransmits"`
+	Drops          uint64              `json:"packet_drops"`
+	PageFaults     uint64              `json:"page_faults"`
+	Reclaims       uint64              `json:"direct_reclaims"`
+	Exits          uint64              `json:"process_exits"`
+	LiveProcesses  uint64              `json:"live_processes"`
+	UprobeCalls    uint64              `json:"uprobe_calls"`
+	USDTCalls      uint64              `json:"usdt_calls"`
+	EgressBytes    uint64              `json:"egress_bytes"`
+	DNSQueries     uint64              `json:"dns_queries"`
+	EventRate      uint64              `json:"event_rate"`
+	SecurityEvents []uint64            `json:"security_events"`
+	NotableEvents  []uint64            `json:"notable_events"`
+	Syscalls       map[string]uint64   `json:"syscalls"`
+	IOLatency      []uint64            `json:"io_latency"`
+	RunqLatency    []uint64            `json:"runq_latency"`
+	Signals        map[string]uint64   `json:"signals"`
+	Traffic        map[string]rxCounts `json:"xdp_traffic"`
+	TopCgroups     map[string]uint64   `json:"top_cgroups"`
+	TopContainers  []containerSnapshot `json:"top_containers,omitempty"`
+}
+
+type probeSnapshot struct {
+	Links      int               `json:"links"`
+	Unattached []string          `json:"unattached"`
+	Mechanisms map[string]string `json:"mechanisms"`
+	LSM        bool              `json:"lsm"`
+}
+
+type configSnapshot struct {
+	Path  string `json:"path"`
+	Theme string `json:"theme"`
+}
+
+func (s *session) snapshot(interval time.Duration, probes probeSnapshot) stateSnapshot {
+	return stateSnapshot{
+		Time:   time.Now(),
+		Game:   s.gameSnapshot(interval),
+		Metric: newMetricsSnapshot(s.ui.Metrics, s.ui.TopCgroups, s.ui.TopContainers),
+		Probes: probes,
+		Config: configSnapshot{Path: s.cfgPath, Theme: s.ui.Theme.Name},
+	}
+}
+
+func (s *session) gameSnapshot(interval time.Duration) gameSnapshot {
+	g := s.game
+	return gameSnapshot{
+		Score:      g.Score,
+		Snake:      append([]game.Position(nil), g.Snake...),
+		Direction:  g.Direction,
+		Food:       g.Food,
+		Obstacles:  append([]game.Position(nil), g.Obstacles...),
+		Poison:     poisonPosition(g),
+		FoodKind:   g.FoodKind.String(),
+		ExtraFood:  foodItems(g.ExtraFood),
+		BonusFood:  foodItems(g.BonusFood),
+		Width:      g.Width,
+		Height:     g.Height,
+		Playfield:  g.Bounds(),
+		Paused:     g.Paused,
+		Wrap:       g.Wrap,
+		GameOver:   g.GameOver,
+		Difficulty: g.Difficulty.Name,
+		Seed:       g.Seed,
+		Interval:   interval.String(),
+		Player2:    player2State(g),
+	}
+}
+
+func player2State(g *game.Game) *player2Snapshot {
+	p := g.Player2
+	if p == nil {
+		return nil
+	}
+	return &player2Snapshot{
+		Score:     p.Score,
+		Snake:     append([]game.Position(nil), p.Snake...),
+		Direction: p.Direction,
+		Crashed:   p.Crashed,
+	}
+}
+
+// rateMap names the per-second rates like the counters they come from.
+func rateMap(rate ebpfmon.Rate) map[string]float64 {
+	rates := map[string]float64{
+		"execve":           rate.Execve,
+		"file_ops":         rate.FileOps,
+		"network":          rate.Network,
+		"process":          rate.Process,
+		"context_switches": rate.ContextSwitches,
+		"tcp_retransmits":  rate.Retransmits,
+		"packet_drops":     rate.Drops,
+		"page_faults":      rate.PageFaults,
+		"direct_reclaims":  rate.Reclaims,
+		"uprobe_calls":     rate.UprobeCalls,
+		"usdt_calls":       rate.USDTCalls,
+		"xdp_rx_packets":   rate.RxPackets,
+		"xdp_rx_bytes":     rate.RxBytes,
+		"egress_bytes":     rate.EgressBytes,
+		"dns_queries":      rate.DNSQueries,
+	}
+	for c, r := range rate.Syscalls {
+		rates["syscalls_"+ebpfmon.SyscallCategory(c).String()] = r
+	}
+	return rates
+}
+
+func newMetricsSnapshot(m ebpfmon.Metrics, topCgroups []ebpfmon.CgroupCount, topContainers []ebpfmon.ContainerCount) metricsSnapshot {
+	cgroups := make(map[string]uint64, len(topCgroups))
+	for _, cg := range topCgroups {
+		cgroups[cg.Path] = cg.Count
+	}
+	syscalls := make(map[string]uint64, len(m.Syscalls))
+	for c, count := range m.Syscalls {
+		syscalls[ebpfmon.SyscallCategory(c).String()] = count
+	}
+	signals := make(map[string]uint64, len(m.Signals))
+	for k, count := range m.Signals {
+		signals[ebpfmon.SignalKind(k).String()] = count
+	}
+	rx := make(map[string]rxCounts, ebpfmon.XDP_PROTOS)
+	for proto := range ebpfmon.XDP_PROTOS {
+		rx[ebpfmon.XDPProtoName(proto)] = rxCounts{Packets: m.Traffic.Packets[proto], Bytes: m.Traffic.Bytes[proto]}
+	}
+	return metricsSnapshot{
+		Execve:         m.Execve,
+		FileOps:        m.FileOps,
+		Network:        m.Network,
+		Process:        m.Process,
+		ContextSwitch:  m.ContextSwitches,
+		Retransmits:    m.Retransmits,
+		Drops:          m.Drops,
+		PageFaults:     m.PageFaults,
+		Reclaims:       m.Reclaims,
+		Exits:          m.Exits,
+		LiveProcesses:  m.LiveProcesses,
+		UprobeCalls:    m.UprobeCalls,
+		USDTCalls:      m.USDTCalls,
+		EgressBytes:    m.EgressBytes,
+		DNSQueries:     m.DNSQueries,
+		EventRate:      m.EventRate,
+		SecurityEvents: m.Security[:],
+		NotableEvents:  m.Notable[:],
+		Syscalls:       syscalls,
+		IOLatency:      m.IOLatency[:],
+		RunqLatency:    m.RunqLatency[:],
+		Signals:        signals,
+		Traffic:        rx,
+		TopCgroups:     cgroups,
+		TopContainers:  containerSnapshots(topContainers),
+	}
+}
+
+func writeSnapshot(path string, snap stateSnapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "" {
+		_, err := os.Stderr.Write(data)
+		return err
+	}
+
+	return writeDataFile(path, data, "snapshot")
+}
+
+func poisonPosition(g *game.Game) *game.Position {
+	if g.Poison == nil {
+		return nil
+	}
+	p := g.Poison.Pos
+	return &p
+}
+
+func foodItems(items []game.FoodItem) []foodSnapshot {
+	food := make([]foodSnapshot, len(items))
+	for i, item := range items {
+		food[i] = foodSnapshot{Pos: item.Pos, Kind: item.Kind.String()}
+	}
+	return food
+}
+
+func containerSnapshots(containers []ebpfmon.ContainerCount) []containerSnapshot {
+	var out []containerSnapshot
+	for _, c := range containers {
+		out = append(out, containerSnapshot{
+			Name:    c.Name,
+			ID:      c.ID,
+			Runtime: c.Runtime,
+			Execve:  c.Counts[ebpfmon.EVENT_EXEC],
+			FileOps: c.Counts[ebpfmon.EVENT_OPEN],
+			Network: c.Counts[ebpfmon.EVENT_CONNECT],
+			Process: c.Counts[ebpfmon.EVENT_FORK],
+		})
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// kernelSource is where the game reads kernel activity from: the probes it
+// loaded itself, or a collector it is connected to.
+type kernelSource interface {
+	Status() sourceStatus
+	ReadSnapshot() (ebpfmon.Snapshot, error)
+	TopCgroups(n int) []ebpfmon.CgroupCount
+	// TopContainers returns the busiest containers, or nil when container
+	// mode is off.
+	TopContainers() []ebpfmon.ContainerCount
+	TopDomains(n int) []ebpfmon.DomainCount
+	Processes() []ebpfmon.ProcessCount
+	ProgramStats() []ebpfmon.ProgramStats
+	// Events streams kernel events until ctx is done.
+	Events(ctx context.Context) (<-chan ebpfmon.KernelEvent, error)
+}
+
+// sourceStatus describes the probes behind a kernelSource.
+type sourceStatus struct {
+	Probes probeSnapshot `json:"probes"`
+	Groups []probeGroup  `json:"groups"`
+	Uprobe string        `json:"uprobe,omitempty"`
+	USDT   string        `json:"usdt,omitempty"`
+	XDP    string        `json:"xdp_iface,omitempty"`
+	Egress bool          `json:"egress"`
+	Stats  bool          `json:"program_stats"`
+	Events bool          `json:"events"`
+}
+
+// probeGroup is an ebpfmon.ProbeGroupStatus that can be sent as JSON.
+type probeGroup struct {
+	Label     string `json:"label"`
+	Program   string `json:"program"`
+	Attached  bool   `json:"attached"`
+	Mechanism string `json:"mechanism,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func probeGroups(status ebpfmon.ProbeStatus) []probeGroup {
+	groups := make([]probeGroup, len(status.Groups))
+	for i, g := range status.Groups {
+		groups[i] = probeGroup{Label: g.Label, Program: g.Program, Attached: g.Attached, Mechanism: g.Mechanism}
+		if g.Err != nil {
+			groups[i].Error = g.Err.Error()
+		}
+	}
+	return groups
+}
+
+func (s sourceStatus) probeStatus() ebpfmon.ProbeStatus {
+	var status ebpfmon.ProbeStatus
+	for _, g := range s.Groups {
+		gs := ebpfmon.ProbeGroupStatus{Label: g.Label, Program: g.Program, Attached: g.Attached, Mechanism: g.Mechanism}
+		if g.Error != "" {
+			gs.Err = errors.New(g.Error)
+		}
+		status.Groups = append(status.Groups, gs)
+	}
+	return status
+}
+
+// localSource reads the probes this process loaded.
+type localSource struct {
+	mon        *ebpfmon.Monitor
+	probes     *ebpfmon.AttachManager
+	reader     *ebpfmon.MetricsReader
+	containers *containerOptions
+	opts       *probeOptions
+}
+
+func newLocalSource(mon *ebpfmon.Monitor, probes *ebpfmon.AttachManager, containers *containerOptions, opts *probeOptions) *localSource {
+	reader := mon.NewMetricsReader()
+	containers.apply(reader)
+	return &localSource{mon: mon, probes: probes, reader: reader, containers: containers, opts: opts}
+}
+
+func (s *localSource) Status() sourceStatus {
+	return sourceStatus{
+		Probes: probeSnapshot{
+			Links:      s.probes.Links(),
+			Unattached: s.probes.Unattached(),
+			Mechanisms: s.probes.Mechanisms(),
+			LSM:        s.mon.LSM(),
+		},
+		Groups: probeGroups(s.probes.Status()),
+		Uprobe: s.opts.uprobe.Symbol,
+		USDT:   s.opts.usdt.Name,
+		XDP:    s.opts.xdpIface,
+		Egress: s.opts.egress,
+		Stats:  s.opts.overhead,
+		Events: true,
+	}
+}
+
+func (s *localSource) ReadSnapshot() (ebpfmon.Snapshot, error) {
+	return s.reader.ReadSnapshot()
+}
+
+func (s *localSource) TopCgroups(n int) []ebpfmon.CgroupCount {
+	return s.mon.TopCgroups(n)
+}
+
+func (s *localSource) TopContainers() []ebpfmon.ContainerCount {
+	return s.containers.top(s.mon)
+}
+
+func (s *localSource) TopDomains(n int) []ebpfmon.DomainCount {
+	return s.mon.TopDomains(n)
+}
+
+func (s *localSource) Processes() []ebpfmon.ProcessCount {
+	return s.mon.Processes()
+}
+
+func (s *localSource) ProgramStats() []ebpfmon.ProgramStats {
+	return s.probes.Stats()
+}
+
+func (s *localSource) Events(ctx context.Context) (<-chan ebpfmon.KernelEvent, error) {
+	r, err := s.mon.NewEventReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Events(), nil
+}
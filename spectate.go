@@ -0,0 +1,120 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// SPECTATE_BUFFER is how many frames a spectator may fall behind before it
+// misses some.
+const SPECTATE_BUFFER = 16
+
+//go:embed assets/spectate.html
+var spectateHTML []byte
+
+// spectateFrame is what spectators get after every poll (100ms): the board
+// and the kernel activity behind it.
+type spectateFrame struct {
+	Time    time.Time          `json:"time"`
+	Game    gameSnapshot       `json:"game"`
+	Metrics metricsSnapshot    `json:"metrics"`
+	Rates   map[string]float64 `json:"rates"`
+}
+
+// spectateServer serves a viewer page and streams frames to it over a
+// WebSocket. Watching needs no token; there is nothing to control.
+type spectateServer struct {
+	server *http.Server
+	mu     sync.Mutex
+	subs   map[chan []byte]bool
+}
+
+func startSpectateServer(addr string) (*spectateServer, error) {
+	sp := &spectateServer{subs: make(map[chan []byte]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(spectateHTML)
+	})
+	mux.Handle("GET /ws", websocket.Server{Handler: sp.serveWS})
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	sp.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go sp.server.Serve(listener)
+	return sp, nil
+}
+
+func (sp *spectateServer) close() error {
+	return sp.server.Close()
+}
+
+// publish sends the state after this poll to every spectator. One that
+// falls behind misses frames rather than holding up the game.
+func (sp *spectateServer) publish(s *session) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if len(sp.subs) == 0 {
+		return
+	}
+	frame, err := json.Marshal(s.spectateFrame())
+	if err != nil {
+		return
+	}
+	for sub := range sp.subs {
+		select {
+		case sub <- frame:
+		default:
+		}
+	}
+}
+
+func (s *session) spectateFrame() spectateFrame {
+	return spectateFrame{
+		Time:    s.now,
+		Game:    s.gameSnapshot(s.interval),
+		Metrics: newMetricsSnapshot(s.ui.Metrics, s.ui.TopCgroups, s.ui.TopContainers),
+		Rates:   rateMap(s.ui.Rate),
+	}
+}
+
+func (sp *spectateServer) serveWS(ws *websocket.Conn) {
+	defer ws.Close()
+	sub := make(chan []byte, SPECTATE_BUFFER)
+	sp.mu.Lock()
+	sp.subs[sub] = true
+	sp.mu.Unlock()
+	defer func() {
+		sp.mu.Lock()
+		delete(sp.subs, sub)
+		sp.mu.Unlock()
+	}()
+
+	// Spectators send nothing; reading only notices when they leave.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+	for {
+		select {
+		case <-gone:
+			return
+		case frame := <-sub:
+			if err := websocket.Message.Send(ws, string(frame)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// SSH_FALLBACK_TERM is the terminal type assumed for clients whose own
+// type has no terminfo entry here.
+const SSH_FALLBACK_TERM = "xterm"
+
+func sshHostKeyPath() string {
+	return filepath.Join(userHomeDir(), ".local", "share", "snake-ebpf", "ssh_host_ed25519_key")
+}
+
+// runServe loads and attaches the probes once and lets remote players play
+// over SSH, every session a game of its own against the same counters.
+func runServe(ctx context.Context, args []string) int {
+	fs := newFlagSet("serve", "")
+	sshAddr := fs.String("ssh", "", "listen address for the SSH server, e.g. :2222; every session plays a game of its own")
+	hostKeyPath := fs.String("host-key", sshHostKeyPath(), "SSH host key, generated when missing")
+	authorizedKeys := fs.String("authorized-keys", "", "authorized_keys file with the keys allowed to play; needed unless -ssh is a loopback address or -insecure is given")
+	insecure := fs.Bool("insecure", false, "let anyone who can reach -ssh play without -authorized-keys")
+	btfPath := fs.String("btf", "", "kernel BTF file for CO-RE relocations on kernels without "+ebpfmon.KERNEL_BTF_PATH)
+	pinDir := addPinFlag(fs)
+	difficultyName := fs.String("difficulty", "normal", "difficulty, which sets the speed: "+strings.Join(game.DifficultyNames(), ", "))
+	wrap := fs.Bool("wrap", false, "let the snake pass through the walls to the opposite side")
+	enemy := fs.Bool("enemy", false, "add a kernel snake that grows and speeds up with the context-switch rate")
+	showProcs := fs.Bool("procs", false, "show the top processes panel next to the board")
+	display := addDisplayFlags(fs)
+	filters := addFilterFlags(fs)
+	probeOpts := addProbeFlags(fs)
+	logOpts := addLogFlags(fs)
+	pprofAddr := addPprofFlag(fs)
+	cfgPath := addConfigFlag(fs)
+	if code, ok := parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	cfg, code, ok := loadCommandConfig(fs, *cfgPath)
+	if !ok {
+		return code
+	}
+	closeLog, err := logOpts.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	defer closeLog()
+	stopPprof, ok := startPprof(*pprofAddr)
+	if !ok {
+		return EXIT_FAILURE
+	}
+	defer stopPprof()
+	if *sshAddr == "" {
+		fmt.Fprintln(os.Stderr, "Invalid flags: serve needs -ssh")
+		return EXIT_USAGE
+	}
+	if *authorizedKeys == "" && !*insecure && !loopbackAddr(*sshAddr) {
+		fmt.Fprintf(os.Stderr, "Invalid flags: serving on %s needs -authorized-keys, or -insecure to let anyone who can reach it play\n", *sshAddr)
+		return EXIT_USAGE
+	}
+	filter, err := filters.filter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	if err := probeOpts.parse(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %v\n", err)
+		return EXIT_USAGE
+	}
+	difficulty, err := game.LookupDifficulty(*difficultyName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -difficulty: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	hostKey, err := loadHostKey(*hostKeyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load SSH host key: %v\n", err)
+		return EXIT_FAILURE
+	}
+	var players []ssh.PublicKey
+	if *authorizedKeys != "" {
+		if players, err = loadAuthorizedKeys(*authorizedKeys); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load authorized keys: %v\n", err)
+			return EXIT_FAILURE
+		}
+	}
+
+	c, code := loadCollector(ctx, *btfPath, *pinDir, cfg.Probes, filter, probeOpts)
+	if code != EXIT_OK {
+		return code
+	}
+	defer c.close()
+
+	sv := &sshServer{
+		ctx:        ctx,
+		collector:  c,
+		cfg:        cfg,
+		difficulty: difficulty,
+		wrap:       *wrap,
+		enemy:      *enemy,
+		showProcs:  *showProcs,
+		display:    *display,
+	}
+	server := &ssh.Server{Handler: sv.handle}
+	server.AddHostKey(hostKey)
+	if players != nil {
+		server.PublicKeyHandler = func(_ ssh.Context, key ssh.PublicKey) bool {
+			for _, player := range players {
+				if ssh.KeysEqual(key, player) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	l, err := net.Listen("tcp", *sshAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *sshAddr, err)
+		return EXIT_FAILURE
+	}
+	context.AfterFunc(ctx, func() { server.Close() })
+	fmt.Fprintf(os.Stderr, "Serving the game over SSH on %s (host key %s)\n", *sshAddr, gossh.FingerprintSHA256(hostKey.PublicKey()))
+	if players == nil {
+		fmt.Fprintln(os.Stderr, "Warning: anyone who can connect may play and see the host's processes, use -authorized-keys to restrict")
+	}
+
+	if err := server.Serve(l); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Failed to serve SSH: %v\n", err)
+		return EXIT_FAILURE
+	}
+	logShutdown(ctx, "serve")
+	return EXIT_OK
+}
+
+// sshServer starts a game for every SSH session.
+type sshServer struct {
+	ctx        context.Context
+	collector  *collector
+	cfg        config
+	difficulty game.Difficulty
+	wrap       bool
+	enemy      bool
+	showProcs  bool
+	display    displayOptions
+}
+
+func (sv *sshServer) handle(sess ssh.Session) {
+	pty, winch, ok := sess.Pty()
+	if !ok {
+		fmt.Fprintln(sess.Stderr(), "snake-ebpf needs a terminal, connect with ssh -t")
+		sess.Exit(EXIT_USAGE)
+		return
+	}
+	player := fmt.Sprintf("%s@%s", sess.User(), sess.RemoteAddr())
+	ctx, stop := context.WithCancel(sess.Context())
+	defer stop()
+	context.AfterFunc(sv.ctx, stop)
+
+	tty := newSSHTty(sess, pty.Window, winch)
+	term, err := tui.NewTerminal(tty, pty.Term)
+	if err != nil {
+		term, err = tui.NewTerminal(tty, SSH_FALLBACK_TERM)
+	}
+	if err != nil {
+		fmt.Fprintf(sess.Stderr(), "Failed to set up terminal: %v\r\n", err)
+		sess.Exit(EXIT_FAILURE)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s joined\n", player)
+
+	src := sv.collector.newSession()
+	status := src.Status()
+	gameWidth, gameHeight := boardSize(term.Size())
+	ui := tui.NewOn(term, gameWidth, gameHeight)
+	ui.Resize(term.Size())
+	ui.ShowProcs = sv.showProcs
+	ui.ShowOverhead = status.Stats
+	ui.StatsEnabled = status.Stats
+	ui.Uprobe = status.Uprobe
+	ui.USDT = status.USDT
+	ui.XDP = status.XDP
+	ui.Egress = status.Egress
+	ui.Probes = status.probeStatus()
+	ui.Theme = tui.Themes[tui.ThemeIndex(sv.cfg.Theme)]
+	sv.display.apply(ui)
+
+	// Players share nothing but the counters: no high scores, and a theme
+	// change is not saved to the host's config.
+	s := &session{
+		game:    game.New(gameWidth, gameHeight, game.RandomSeed()),
+		ui:      ui,
+		cfg:     sv.cfg,
+		speed:   sv.cfg.Speed,
+		rates:   ebpfmon.NewRates(ebpfmon.RATE_WINDOW),
+		bursts:  ebpfmon.NewBurstDetector(ebpfmon.ExecveCount, ebpfmon.BURST_EXECVE_RATE, ebpfmon.BURST_COOLDOWN),
+		dangers: ebpfmon.NewBurstDetector(ebpfmon.DangerCount, ebpfmon.BURST_DANGER_RATE, ebpfmon.BURST_DANGER_COOLDOWN),
+		now:     time.Now(),
+		uprobe:  status.Uprobe != "",
+		xdp:     status.XDP != "",
+		noColor: sv.display.noColor,
+	}
+	s.startGame(sv.difficulty, sv.wrap, sv.enemy, false)
+	for _, name := range status.Probes.Unattached {
+		ui.Toasts.Push(fmt.Sprintf("🔌 %s not attached", name))
+	}
+	eventChan, err := src.Events(ctx)
+	if err != nil {
+		ui.Toasts.Push(err.Error() + ", food spawns on a timer")
+		s.timedFood = true
+	}
+	s.render()
+
+	inputChan := make(chan string, tui.INPUT_BUFFER)
+	go term.ReadInput(ctx, inputChan)
+	s.run(ctx, gameLoop{
+		src:       src,
+		status:    status,
+		watchlist: &ebpfmon.ExecWatchlist{},
+		events:    eventChan,
+		input:     inputChan,
+		resizes:   term.Resizes(),
+		size:      term.Size,
+	})
+
+	stop()
+	ui.Close()
+	term.Close()
+	fmt.Fprintf(os.Stderr, "%s left with a score of %d\n", player, s.game.Score)
+	fmt.Fprintf(sess, "\r\nGame Over!\r\nFinal Score: %d\r\nSeed: %d\r\n", s.game.Score, s.game.Seed)
+	sess.Exit(EXIT_OK)
+}
+
+// sshTty is the tcell.Tty of an SSH session. There is no terminal device
+// behind it: the client already put its terminal in raw mode, and size
+// changes arrive as window-change requests.
+type sshTty struct {
+	sess      ssh.Session
+	input     chan []byte
+	pending   []byte
+	drain     chan struct{}
+	drainOnce sync.Once
+
+	mu      sync.Mutex
+	size    ssh.Window
+	resized func()
+}
+
+func newSSHTty(sess ssh.Session, size ssh.Window, winch <-chan ssh.Window) *sshTty {
+	t := &sshTty{sess: sess, input: make(chan []byte), drain: make(chan struct{}), size: size}
+	go t.readInput()
+	go t.watchSize(winch)
+	return t
+}
+
+func (t *sshTty) readInput() {
+	defer close(t.input)
+	for {
+		buf := make([]byte, 256)
+		n, err := t.sess.Read(buf)
+		if n > 0 {
+			select {
+			case t.input <- buf[:n]:
+			case <-t.drain:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *sshTty) watchSize(winch <-chan ssh.Window) {
+	for size := range winch {
+		t.mu.Lock()
+		t.size = size
+		resized := t.resized
+		t.mu.Unlock()
+		if resized != nil {
+			resized()
+		}
+	}
+}
+
+func (t *sshTty) Start() error {
+	return nil
+}
+
+func (t *sshTty) Stop() error {
+	return nil
+}
+
+// Drain makes Read return, which tcell waits for before it lets go of the
+// terminal.
+func (t *sshTty) Drain() error {
+	t.drainOnce.Do(func() { close(t.drain) })
+	return nil
+}
+
+func (t *sshTty) NotifyResize(cb func()) {
+	t.mu.Lock()
+	t.resized = cb
+	t.mu.Unlock()
+}
+
+func (t *sshTty) WindowSize() (tcell.WindowSize, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return tcell.WindowSize{Width: t.size.Width, Height: t.size.Height}, nil
+}
+
+func (t *sshTty) Read(p []byte) (int, error) {
+	if len(t.pending) == 0 {
+		select {
+		case data, ok := <-t.input:
+			if !ok {
+				return 0, io.EOF
+			}
+			t.pending = data
+		case <-t.drain:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+func (t *sshTty) Write(p []byte) (int, error) {
+	return t.sess.Write(p)
+}
+
+// Close leaves the session open for the final score.
+func (t *sshTty) Close() error {
+	return nil
+}
+
+// loadHostKey reads the SSH host key at path, generating one on the first
+// run.
+func loadHostKey(path string) (gossh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = generateHostKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, err := gossh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+func generateHostKey(path string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate host key: %w", err)
+	}
+	block, err := gossh.MarshalPrivateKey(key, "snake-ebpf")
+	if err != nil {
+		return nil, fmt.Errorf("encode host key: %w", err)
+	}
+	data := pem.EncodeToMemory(block)
+	created, err := mkdirAllOwned(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("create host key dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("write host key: %w", err)
+	}
+	return data, chownToSudoUser(append(created, path)...)
+}
+
+// loopbackAddr reports whether the listen address addr only accepts
+// connections from this host.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// loadAuthorizedKeys reads the public keys of an authorized_keys file.
+// Options in front of a key are ignored.
+func loadAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var keys []ssh.PublicKey
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in %s", path)
+	}
+	return keys, nil
+}
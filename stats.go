@@ -0,0 +1,73 @@
+package main
+
+import (
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/tui"
+)
+
+// eventCategories are the kernel events the game-over screen counts.
+var eventCategories = [...]struct {
+	label string
+	count func(ebpfmon.Delta) uint64
+}{
+	{"execve", func(d ebpfmon.Delta) uint64 { return d.Execve }},
+	{"open", func(d ebpfmon.Delta) uint64 { return d.FileOps }},
+	{"connect", func(d ebpfmon.Delta) uint64 { return d.Network }},
+	{"fork", func(d ebpfmon.Delta) uint64 { return d.Process }},
+	{"ctxsw", func(d ebpfmon.Delta) uint64 { return d.ContextSwitches }},
+}
+
+// roundStats is what the game-over screen sums up about a round besides
+// the score.
+type roundStats struct {
+	start   time.Time
+	fastest time.Duration
+	events  [len(eventCategories)]uint64
+	// procs are the event counts per process last read during the round,
+	// and procBase the counts of the first read, which the round's counts
+	// start from.
+	procs    []ebpfmon.ProcessCount
+	procBase map[uint32]uint64
+}
+
+func newRoundStats(start time.Time, interval time.Duration) roundStats {
+	return roundStats{start: start, fastest: interval}
+}
+
+func (r *roundStats) add(d ebpfmon.Delta) {
+	for i, c := range eventCategories {
+		r.events[i] += c.count(d)
+	}
+}
+
+func (r *roundStats) readProcesses(procs []ebpfmon.ProcessCount) {
+	if r.procBase == nil {
+		r.procBase = make(map[uint32]uint64, len(procs))
+		for _, p := range procs {
+			r.procBase[p.PID] = p.Count
+		}
+	}
+	r.procs = procs
+}
+
+// summary sums up the round as of now.
+func (r *roundStats) summary(now time.Time, peakEventRate uint64) *tui.Summary {
+	s := &tui.Summary{Duration: now.Sub(r.start), Fastest: r.fastest, PeakEventRate: peakEventRate}
+	for i, c := range eventCategories {
+		s.Events = append(s.Events, tui.EventCount{Label: c.label, Count: r.events[i]})
+	}
+	procs := make([]ebpfmon.ProcessCount, 0, len(r.procs))
+	for _, p := range r.procs {
+		// A PID reused since the first read counts from zero.
+		if base := r.procBase[p.PID]; base <= p.Count {
+			p.Count -= base
+		}
+		if p.Count > 0 {
+			procs = append(procs, p)
+		}
+	}
+	s.TopProcs = ebpfmon.TopProcesses(procs, tui.PROCESS_SUMMARY_LINES)
+	return s
+}
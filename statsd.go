@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const (
+	STATSD_PREFIX = "snake_ebpf."
+	// STATSD_MAX_PACKET keeps datagrams under the usual Ethernet MTU so
+	// they are not fragmented on the way to the agent.
+	STATSD_MAX_PACKET = 1432
+)
+
+// statsdEmitter sends every poll to a statsd agent over UDP: the counters
+// as increments since the previous poll and the rest as gauges.
+type statsdEmitter struct {
+	conn    net.Conn
+	prefix  string
+	tags    string
+	last    ebpfmon.Metrics
+	started bool
+	failing bool
+	report  func(error)
+}
+
+// newStatsdEmitter sends to addr with every name starting with prefix.
+// tags is a comma-separated list of key:value pairs added to every metric
+// in the DogStatsD format; plain statsd agents need it empty. Send errors
+// go to report, once until sending works again.
+func newStatsdEmitter(addr, prefix, tags string, report func(error)) (*statsdEmitter, error) {
+	suffix, err := statsdTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(prefix, ":|@# \n") {
+		return nil, fmt.Errorf("statsd prefix %q: must not contain ':', '|', '@', '#' or spaces", prefix)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd address: %w", err)
+	}
+	return &statsdEmitter{conn: conn, prefix: prefix, tags: suffix, report: report}, nil
+}
+
+func statsdTags(tags string) (string, error) {
+	if tags == "" {
+		return "", nil
+	}
+	parts := strings.Split(tags, ",")
+	for _, tag := range parts {
+		if tag == "" || strings.ContainsAny(tag, "|@#, \n") {
+			return "", fmt.Errorf("statsd tag %q: must be key:value without '|', '@', '#' or spaces", tag)
+		}
+	}
+	return "|#" + strings.Join(parts, ","), nil
+}
+
+// statsdPacket collects metric lines and sends them in as few datagrams
+// as fit.
+type statsdPacket struct {
+	e   *statsdEmitter
+	buf bytes.Buffer
+	err error
+}
+
+func (p *statsdPacket) add(name, value, kind string) {
+	line := p.e.prefix + name + ":" + value + "|" + kind + p.e.tags
+	if p.buf.Len() > 0 && p.buf.Len()+1+len(line) > STATSD_MAX_PACKET {
+		p.flush()
+	}
+	if p.buf.Len() > 0 {
+		p.buf.WriteByte('\n')
+	}
+	p.buf.WriteString(line)
+}
+
+func (p *statsdPacket) count(name string, cur, prev uint64) {
+	// A counter going backwards means the collector restarted; the
+	// increment is lost rather than sent as a huge one.
+	if cur > prev {
+		p.add(name, strconv.FormatUint(cur-prev, 10), "c")
+	}
+}
+
+func (p *statsdPacket) flush() {
+	if p.buf.Len() == 0 {
+		return
+	}
+	if _, err := p.e.conn.Write(p.buf.Bytes()); err != nil && p.err == nil {
+		p.err = err
+	}
+	p.buf.Reset()
+}
+
+// add sends one poll. The first one only sets the baseline for the
+// counters.
+func (e *statsdEmitter) add(m ebpfmon.Metrics, game *gameStats) {
+	p := &statsdPacket{e: e}
+	if e.started {
+		for _, c := range exportedCounters {
+			p.count(c.name, c.value(m), c.value(e.last))
+		}
+		for c := range m.Syscalls {
+			p.count("syscalls."+ebpfmon.SyscallCategory(c).String(), m.Syscalls[c], e.last.Syscalls[c])
+		}
+		for k := range m.Signals {
+			p.count("signals."+strings.ToLower(ebpfmon.SignalKind(k).String()), m.Signals[k], e.last.Signals[k])
+		}
+		for proto := range ebpfmon.XDP_PROTOS {
+			name := ebpfmon.XDPProtoName(proto)
+			p.count("xdp.rx_packets."+name, m.Traffic.Packets[proto], e.last.Traffic.Packets[proto])
+			p.count("xdp.rx_bytes."+name, m.Traffic.Bytes[proto], e.last.Traffic.Bytes[proto])
+		}
+	}
+	e.last, e.started = m, true
+	p.add("live_processes", strconv.FormatUint(m.LiveProcesses, 10), "g")
+	p.add("event_rate", strconv.FormatUint(m.EventRate, 10), "g")
+	if game != nil {
+		p.add("game.score", strconv.Itoa(game.score), "g")
+		p.add("game.snake_length", strconv.Itoa(game.length), "g")
+		p.add("game.tick_interval_ms", strconv.FormatInt(game.interval.Milliseconds(), 10), "g")
+	}
+	p.flush()
+
+	if p.err != nil && !e.failing {
+		e.report(fmt.Errorf("statsd send: %w", p.err))
+	}
+	e.failing = p.err != nil
+}
+
+// publish sends the counters and game of this tick.
+func (e *statsdEmitter) publish(s *session) {
+	e.add(s.ui.Metrics, &gameStats{score: s.game.Score, length: len(s.game.Snake), interval: s.interval})
+}
+
+func (e *statsdEmitter) Close() error {
+	return e.conn.Close()
+}
@@ -24,8 +24,8 @@ check_binary() {
 }
 
 check_bpf_object() {
-    if [ ! -f "./bpf/snake.bpf.o" ]; then
-        print_error "bpf/snake.bpf.o not found"
+    if [ ! -s "./ebpfmon/snake_bpfel.o" ]; then
+        print_error "ebpfmon/snake_bpfel.o not found or empty"
         return 1
     fi
     return 0
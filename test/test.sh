@@ -22,16 +22,16 @@ print_warning() {
 check_binary() {
     if [ ! -f "./snake-ebpf" ]; then
         print_error "snake-ebpf binary not found"
-        echo "   Build it first: go build -o snake-ebpf main.go"
+        echo "   Build it first: go build -o snake-ebpf ."
         return 1
     fi
     return 0
 }
 
 check_bpf_object() {
-    if [ ! -f "./bpf/snake.bpf.o" ]; then
-        print_error "bpf/snake.bpf.o not found"
-        echo "   Build it first: cd bpf && make"
+    if [ ! -s "./ebpfmon/snake_bpfel.o" ]; then
+        print_error "ebpfmon/snake_bpfel.o not found or empty"
+        echo "   Generate it first: go generate ./..."
         return 1
     fi
     return 0
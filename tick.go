@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+// poll takes in one poll of the eBPF counters: the rates, the speed and
+// what the kernel does to the board. hold is set while the board is not
+// visible, so nothing happens behind the player's back. Everything that
+// changes the game goes through here or through step, gameAction,
+// spawnDueFood and applyControl, which is what makes replays exact.
+func (s *session) poll(snap ebpfmon.Snapshot, hold bool) {
+	g := s.game
+	s.record(replayFrame{Kind: FRAME_POLL, Snapshot: &snap, Hold: hold})
+
+	rate := s.rates.Update(snap.Metrics)
+	s.ui.Rate = rate
+	s.activity = kernelActivity(rate, snap, s.uprobe)
+	s.inputLag = inputLag(ebpfmon.IOLatencyPercentile(rate.RunqLatency[:], 0.99))
+	s.ui.InputLag = s.inputLag
+	if !g.GameOver {
+		s.peakEventRate = max(s.peakEventRate, snap.EventRate)
+		s.stats.add(snap.Delta)
+	}
+	if hold {
+		return
+	}
+	if s.demo && g.GameOver && s.now.Sub(s.gameOverAt) > DEMO_RESTART_DELAY {
+		g.Reset()
+		s.newRound()
+	}
+	if g.Paused || g.GameOver {
+		return
+	}
+
+	if snap.FileOps > 0 && s.now.Sub(g.LastFoodSpawn) > game.FoodSpawnInterval(rate.FileOps) {
+		if s.timedFood {
+			g.SpawnFood()
+			g.LastFoodSpawn = s.now
+		} else {
+			g.FoodSpawnDue = true
+		}
+	}
+
+	network := rate.Network
+	if s.xdp {
+		network = rate.RxBytes / ebpfmon.XDP_BYTES_PER_EVENT
+	}
+	g.SetFoodRates([game.FOOD_KINDS]float64{
+		game.FOOD_EXEC:    rate.Execve,
+		game.FOOD_FILE:    rate.FileOps,
+		game.FOOD_NETWORK: network,
+		game.FOOD_FORK:    rate.Process,
+		game.FOOD_USDT:    rate.USDTCalls,
+	})
+	for _, domain := range snap.Delta.NewDomains {
+		if g.SpawnBonusFood(game.FOOD_DNS) {
+			s.ui.Toasts.Push("🌐 DNS query for " + domain + ": bonus food")
+		}
+	}
+	g.SetEnemyLoad(rate.ContextSwitches)
+	g.SetUploadRate(rate.EgressBytes)
+	g.SetFoodCount(game.FoodCount(snap.LiveProcesses))
+	if s.bursts.Detect(snap) {
+		if kind, ok := g.SpawnPowerUp(); ok {
+			s.ui.Toasts.Push(fmt.Sprintf("⚡ execve burst: %s power-up", kind))
+		}
+	}
+	if snap.Delta.Notable[ebpfmon.NOTABLE_OOM_KILL] > 0 {
+		g.OOMKill()
+		s.ui.Flash()
+	}
+	if snap.Delta.Signals[ebpfmon.SIGNAL_KILL] > 0 {
+		if !g.Affected(game.EFFECT_REVERSE) {
+			s.ui.Toasts.Push("💀 SIGKILL: controls reversed")
+		}
+		g.Shock(game.EFFECT_REVERSE, game.SHOCK_TICKS)
+	}
+	if snap.Delta.Signals[ebpfmon.SIGNAL_SEGV] > 0 {
+		if !g.Affected(game.EFFECT_STALL) {
+			s.ui.Toasts.Push("💥 SIGSEGV: the snake stalls")
+		}
+		g.Shock(game.EFFECT_STALL, game.SHOCK_TICKS)
+	}
+	if s.dangers.Detect(snap) && g.SpawnPoison() {
+		s.ui.Toasts.Push("☠ TCP retransmits and drops: poison on the board")
+	}
+	inset := g.Bounds().Min.X
+	if g.SetMemoryPressure(rate.Reclaims >= ebpfmon.MEMORY_PRESSURE_RATE) {
+		if g.Bounds().Min.X > inset {
+			s.ui.Toasts.Push("🧠 memory pressure: the board closes in")
+		} else {
+			s.ui.Toasts.Push("🧠 memory freed: the board grows back")
+		}
+	}
+	s.updateSpeed()
+}
+
+// step moves the snakes one cell, the game tick that runs every interval
+// whatever the polls and the screen are doing. It reports whether anything
+// moved.
+func (s *session) step(hold bool) bool {
+	g := s.game
+	s.record(replayFrame{Kind: FRAME_TICK, Hold: hold})
+	if hold || g.Paused || g.GameOver {
+		return false
+	}
+	if s.demo {
+		g.Turn(g.Autopilot())
+	}
+	s.applyTurns()
+	if !g.Step() {
+		return false
+	}
+	if g.GameOver {
+		s.endRound()
+	}
+	s.updateSpeed()
+	return true
+}
+
+// updateSpeed sets the tick interval from the score and the activity of
+// the last poll.
+func (s *session) updateSpeed() {
+	g, rate := s.game, s.ui.Rate
+	speed := game.NewSpeedReductions(s.speed, g.Difficulty, g.Score, s.activity)
+	s.interval = speed.Interval(g.Difficulty)
+	if g.Active(game.POWERUP_SLOW_MOTION) {
+		s.interval *= 2
+	}
+	diskLag := diskLagging(rate)
+	if diskLag {
+		if !s.ui.DiskLag {
+			s.ui.Toasts.Push("💾 disk lag: the snake slows down")
+		}
+		s.interval += DISK_LAG_SLOWDOWN
+	}
+	s.ui.DiskLag = diskLag
+	s.ui.Speed = speed
+	s.ui.Interval = s.interval
+	if !g.GameOver {
+		s.stats.fastest = min(s.stats.fastest, s.interval)
+	}
+}
+
+// kernelActivity is what the snake speeds up with. With -uprobe that is
+// the traced function alone.
+func kernelActivity(rate ebpfmon.Rate, snap ebpfmon.Snapshot, uprobe bool) game.Activity {
+	if uprobe {
+		return game.Activity{UprobeRate: rate.UprobeCalls}
+	}
+	return game.Activity{
+		ExecveRate:        rate.Execve,
+		FileOpsRate:       rate.FileOps,
+		ProcessRate:       rate.Process,
+		EventRate:         float64(snap.EventRate),
+		ContextSwitchRate: rate.ContextSwitches,
+	}
+}
+
+// diskLagging reports whether block I/O is slow enough to slow the snake
+// down by DISK_LAG_SLOWDOWN.
+func diskLagging(rate ebpfmon.Rate) bool {
+	return ebpfmon.IOLatencyPercentile(rate.IOLatency[:], 0.99) >= ebpfmon.DISK_LAG_LATENCY
+}
+
+// spawnDueFood places food that became due on the timer once the next
+// execve event arrives.
+func (s *session) spawnDueFood() {
+	s.record(replayFrame{Kind: FRAME_EXEC})
+	g := s.game
+	g.SpawnFood()
+	g.LastFoodSpawn = s.now
+	g.FoodSpawnDue = false
+}
+
+// gameKey applies a recorded key, which replays read with defaultKeymap.
+func (s *session) gameKey(input string) (changed, ok bool) {
+	a, ok := defaultKeymap.lookup(input, s.localPlayer2())
+	if !ok {
+		return false, false
+	}
+	return s.gameAction(a)
+}
+
+// gameAction applies the actions that change the game itself, as opposed
+// to how it is displayed, and records them. ok is false for any other
+// action.
+func (s *session) gameAction(a keyAction) (changed, ok bool) {
+	g := s.game
+	switch a {
+	case KEY_PAUSE:
+		g.Paused = !g.Paused
+		changed = true
+	case KEY_WRAP:
+		g.Wrap = !g.Wrap
+		changed = true
+	case KEY_RESTART:
+		if !g.GameOver {
+			return false, false
+		}
+		g.Reset()
+		s.newRound()
+		changed = true
+	default:
+		player, dir, ok := a.direction()
+		if !ok {
+			return false, false
+		}
+		changed = s.steer(player, dir)
+	}
+	s.record(replayFrame{Kind: FRAME_INPUT, Input: recordedKeys[a]})
+	return changed, true
+}
+
+// localPlayer2 reports whether a second snake is steered from this
+// keyboard. A second snake played from another machine takes no keys from
+// here.
+func (s *session) localPlayer2() bool {
+	p := s.game.Player2
+	return p != nil && !p.Remote
+}
+
+// isGameKey reports whether input is bound to an action that changes the
+// game.
+func (s *session) isGameKey(input string) bool {
+	a, ok := s.cfg.Keys.lookup(input, s.localPlayer2())
+	if !ok {
+		return false
+	}
+	_, _, steers := a.direction()
+	return steers || a == KEY_PAUSE || a == KEY_WRAP || a == KEY_RESTART
+}
+
+func (s *session) handleKey(input string) (changed, quit bool) {
+	if s.ui.ShowHelp {
+		return s.closeHelp(), false
+	}
+	a, ok := s.cfg.Keys.lookup(input, s.localPlayer2())
+	if x, y, click := parseClick(input); click {
+		a, ok = s.clickAction(x, y)
+	}
+	if !ok {
+		return false, false
+	}
+	return s.handleAction(a)
+}
+
+// handleAction does what a key, click or gamepad button stands for.
+func (s *session) handleAction(a keyAction) (changed, quit bool) {
+	g := s.game
+	if s.ui.ShowHelp {
+		return s.closeHelp(), false
+	}
+	if a == KEY_QUIT {
+		return false, true
+	}
+	if g.GameOver {
+		switch a {
+		case KEY_RESTART:
+			changed, _ = s.gameAction(a)
+		case KEY_SAVE:
+			s.saveReplay()
+			changed = true
+		case KEY_GIF:
+			s.saveGIF()
+			changed = true
+		}
+		return changed, false
+	}
+	if player, _, ok := a.direction(); ok && player == 1 && s.demo {
+		return false, false
+	}
+	if changed, ok := s.gameAction(a); ok {
+		return changed, false
+	}
+	switch a {
+	case KEY_THEME:
+		s.cycleTheme()
+		changed = true
+	case KEY_HEATMAP:
+		s.ui.ShowHeatmap = !s.ui.ShowHeatmap
+		changed = true
+	case KEY_PROCS:
+		s.ui.ShowProcs = !s.ui.ShowProcs
+		changed = true
+	case KEY_METRICS:
+		s.ui.ShowMetrics = !s.ui.ShowMetrics
+		changed = true
+	case KEY_SPARKLINES:
+		s.ui.ShowSparklines = !s.ui.ShowSparklines
+		changed = true
+	case KEY_OVERHEAD:
+		s.ui.ShowOverhead = !s.ui.ShowOverhead
+		changed = true
+	case KEY_HELP:
+		s.openHelp()
+		changed = true
+	}
+	return changed, false
+}
+
+// openHelp shows the help overlay and pauses the game behind it, the way
+// the pause key does, so replays pause there too.
+func (s *session) openHelp() {
+	s.ui.ShowHelp = true
+	if !s.game.Paused {
+		s.gameAction(KEY_PAUSE)
+		s.helpPaused = true
+	}
+}
+
+// closeHelp hides the help overlay and resumes a game it paused.
+func (s *session) closeHelp() bool {
+	s.ui.ShowHelp = false
+	if s.helpPaused && s.game.Paused && !s.game.GameOver {
+		s.gameAction(KEY_PAUSE)
+	}
+	s.helpPaused = false
+	return true
+}
+
+// startGame applies the command line settings and deals the first round.
+// Live runs and replays must go through the same steps so the random number
+// generator is consumed identically.
+func (s *session) startGame(difficulty game.Difficulty, wrap, enemy, twoPlayer bool) {
+	g := s.game
+	g.Clock = s.clock
+	g.Difficulty = difficulty
+	g.Wrap = wrap
+	if enemy {
+		g.EnableEnemy()
+	}
+	if twoPlayer {
+		g.EnablePlayer2()
+		g.Player2.Remote = s.peer
+	}
+	g.Reset()
+	s.newRound()
+	s.ui.KeyHelp = s.cfg.Keys.keyHelp(s.localPlayer2())
+}
+
+// resize follows a terminal resize. With -rescale the board is resized to
+// match as well, which changes the game and is therefore recorded.
+func (s *session) resize(termWidth, termHeight int) {
+	s.ui.Resize(termWidth, termHeight)
+	if s.rescale {
+		s.resizeBoard(boardSize(termWidth, termHeight))
+	}
+}
+
+func (s *session) resizeBoard(width, height int) {
+	g := s.game
+	if width == g.Width && height == g.Height {
+		return
+	}
+	s.record(replayFrame{Kind: FRAME_RESIZE, Width: width, Height: height})
+	if !g.Resize(width, height) {
+		s.ui.Toasts.Push(fmt.Sprintf("board kept at %dx%d: the snake does not fit in %dx%d", g.Width, g.Height, width, height))
+		return
+	}
+	s.ui.ResizeBoard(width, height)
+}
+
+// laggedTurn is a direction key held back by the input lag until due.
+type laggedTurn struct {
+	player int
+	dir    game.Position
+	due    time.Time
+}
+
+// inputLag is how long direction keys wait for a 99th percentile run-queue
+// latency of p99.
+func inputLag(p99 time.Duration) time.Duration {
+	return min(p99*INPUT_LAG_FACTOR, MAX_INPUT_LAG)
+}
+
+// steer turns a player's snake, or queues the turn while there is input
+// lag, which holds back both players alike.
+func (s *session) steer(player int, dir game.Position) bool {
+	if s.inputLag == 0 && len(s.turns) == 0 {
+		return s.turn(player, dir)
+	}
+	s.turns = append(s.turns, laggedTurn{player: player, dir: dir, due: s.now.Add(s.inputLag)})
+	return false
+}
+
+// applyTurns makes the queued turns that are due, in the order the keys
+// were pressed.
+func (s *session) applyTurns() {
+	for len(s.turns) > 0 && !s.now.Before(s.turns[0].due) {
+		s.turn(s.turns[0].player, s.turns[0].dir)
+		s.turns = s.turns[1:]
+	}
+}
+
+func (s *session) turn(player int, dir game.Position) bool {
+	if player == 2 {
+		return s.game.TurnPlayer2(dir)
+	}
+	return s.game.Steer(dir)
+}
+
+func (s *session) newRound() {
+	s.turns = nil
+	s.interval = s.game.Difficulty.BaseInterval
+	s.ui.Speed = game.SpeedReductions{}
+	s.ui.Interval = s.interval
+	s.peakEventRate = 0
+	s.stats = newRoundStats(s.now, s.interval)
+}
+
+func (s *session) endRound() {
+	g := s.game
+	s.gameOverAt = s.now
+	s.ui.Summary = s.stats.summary(s.now, s.peakEventRate)
+	if s.demo || g.Player2 != nil {
+		return
+	}
+	if s.submitter != nil {
+		s.submitter.submit(leaderboardEntry{
+			Score:         g.Score,
+			Length:        len(g.Snake),
+			Kernel:        ebpfmon.KernelRelease(),
+			PeakEventRate: s.peakEventRate,
+			Difficulty:    g.Difficulty.Name,
+			Time:          s.now,
+		})
+	}
+	if s.scoresPath == "" {
+		return
+	}
+	newHighScore := s.scores.update(g.Score, len(g.Snake), s.peakEventRate)
+	s.ui.Record = s.scores.record(newHighScore)
+	if err := saveHighScores(s.scoresPath, s.scores); err != nil {
+		s.ui.Toasts.Push("highscores: " + err.Error())
+	}
+}
+
+func (s *session) clock() time.Time {
+	return s.now
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+	"snake-ebpf/tui"
+)
+
+// titleScreen shows the title screen until the player starts the game,
+// which it does with the difficulty picked there, or quits, when ok is
+// false. Enter, Space and the restart key start; the left and right keys
+// pick the difficulty.
+func titleScreen(ctx context.Context, ui *tui.UI, input <-chan string, keys keymap, source string, difficulty game.Difficulty) (game.Difficulty, bool) {
+	title := tui.Title{
+		Source:       source,
+		Kernel:       ebpfmon.KernelRelease(),
+		Difficulties: game.Difficulties,
+		Keys: fmt.Sprintf("%s and %s pick the difficulty, Enter or %s starts, %s quits",
+			keys.hint(KEY_LEFT, false), keys.hint(KEY_RIGHT, false), keys.hint(KEY_RESTART, false), keys.hint(KEY_QUIT, false)),
+	}
+	for i, d := range game.Difficulties {
+		if d.Name == difficulty.Name {
+			title.Selected = i
+		}
+	}
+	resizes := tui.Resizes()
+	for {
+		ui.RenderTitle(title)
+		select {
+		case <-ctx.Done():
+			return difficulty, false
+		case <-resizes:
+			ui.Resize(tui.TerminalSize())
+		case key, ok := <-input:
+			if !ok {
+				return difficulty, false
+			}
+			a, ok := keys.lookup(key, false)
+			switch {
+			case ok && a == KEY_QUIT:
+				return difficulty, false
+			case ok && (a == KEY_LEFT || a == KEY_UP):
+				title.Selected = (title.Selected + len(title.Difficulties) - 1) % len(title.Difficulties)
+			case ok && (a == KEY_RIGHT || a == KEY_DOWN):
+				title.Selected = (title.Selected + 1) % len(title.Difficulties)
+			case ok && a == KEY_RESTART, key == "enter", key == " ":
+				return title.Difficulties[title.Selected], true
+			}
+		}
+	}
+}
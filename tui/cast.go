@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// CastWriter records the frames drawn to an asciicast v2 file, which
+// asciinema plays back and web pages embed. Every frame is written as the
+// cells that changed since the one before, at the time it was drawn.
+type CastWriter struct {
+	f     *os.File
+	w     *bufio.Writer
+	start time.Time
+	prev  *screen
+	err   error
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title"`
+	Env       map[string]string `json:"env"`
+}
+
+// NewCastWriter creates the cast file at path. The header, which holds
+// the terminal size, is written with the first frame.
+func NewCastWriter(path string) (*CastWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create cast: %w", err)
+	}
+	return &CastWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// RecordCast makes the UI write every frame to c as well. It must be
+// called before the first frame.
+func (u *UI) RecordCast(c *CastWriter) {
+	u.out.cast = c
+}
+
+func (c *CastWriter) frame(s *screen, now time.Time) {
+	if c.err != nil {
+		return
+	}
+	var b strings.Builder
+	switch {
+	case c.prev == nil:
+		c.start = now
+		c.write(castHeader{
+			Version:   2,
+			Width:     s.width,
+			Height:    s.height,
+			Timestamp: now.Unix(),
+			Title:     "snake-ebpf",
+			Env:       map[string]string{"TERM": "xterm-256color"},
+		})
+		b.WriteString("\033[?25l\033[0m\033[2J")
+	case c.prev.width != s.width || c.prev.height != s.height:
+		c.write([]any{c.elapsed(now), "r", fmt.Sprintf("%dx%d", s.width, s.height)})
+		b.WriteString("\033[0m\033[2J")
+		c.prev = nil
+	}
+
+	// The style the terminal is left in by the last event is not known.
+	var style tcell.Style
+	known := false
+	for y, row := range s.rows {
+		next := -1
+		for x, cl := range row {
+			if cl.text == "" || c.prev != nil && c.prev.rows[y][x] == cl {
+				continue
+			}
+			if x != next {
+				fmt.Fprintf(&b, "\033[%d;%dH", y+1, x+1)
+			}
+			if !known || cl.style != style {
+				b.WriteString(sgr(cl.style))
+				style, known = cl.style, true
+			}
+			b.WriteString(cl.text)
+			next = x + runewidth.StringWidth(cl.text)
+		}
+	}
+	c.prev = s
+	if b.Len() > 0 {
+		c.write([]any{c.elapsed(now), "o", b.String()})
+	}
+}
+
+// sgr returns the escape sequence that resets the terminal to style.
+func sgr(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	params := []string{"0"}
+	for _, a := range []struct {
+		attr  tcell.AttrMask
+		param string
+	}{
+		{tcell.AttrBold, "1"},
+		{tcell.AttrDim, "2"},
+		{tcell.AttrUnderline, "4"},
+		{tcell.AttrReverse, "7"},
+	} {
+		if attrs&a.attr != 0 {
+			params = append(params, a.param)
+		}
+	}
+	params = append(params, sgrColor(fg, 30)...)
+	params = append(params, sgrColor(bg, 40)...)
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// sgrColor returns the parameters that set c, for the foreground with base
+// 30 and for the background with base 40.
+func sgrColor(c tcell.Color, base int) []string {
+	switch {
+	case !c.Valid():
+		return nil
+	case c.IsRGB():
+		r, g, b := c.RGB()
+		return []string{strconv.Itoa(base + 8), "2", strconv.Itoa(int(r)), strconv.Itoa(int(g)), strconv.Itoa(int(b))}
+	}
+	switch n := int(c - tcell.ColorValid); {
+	case n < 8:
+		return []string{strconv.Itoa(base + n)}
+	case n < 16:
+		return []string{strconv.Itoa(base + 60 + n - 8)}
+	default:
+		return []string{strconv.Itoa(base + 8), "5", strconv.Itoa(n)}
+	}
+}
+
+func (c *CastWriter) elapsed(now time.Time) float64 {
+	return now.Sub(c.start).Seconds()
+}
+
+// write adds one line of JSON to the cast.
+func (c *CastWriter) write(v any) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		_, err = c.w.Write(append(data, '\n'))
+	}
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("write cast: %w", err)
+	}
+}
+
+// Close finishes the cast and reports the first error writing it.
+func (c *CastWriter) Close() error {
+	err := c.w.Flush()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	if c.err != nil {
+		return c.err
+	}
+	if err != nil {
+		return fmt.Errorf("write cast: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package tui
+
+import "strings"
+
+// countdownDigits are the digits of the countdown, in the lettering of
+// the title screen.
+var countdownDigits = [...][]string{
+	1: {
+		` _ `,
+		`/ |`,
+		`| |`,
+		`| |`,
+		`|_|`,
+	},
+	2: {
+		` ____  `,
+		`|___ \ `,
+		`  __) |`,
+		` / __/ `,
+		`|_____|`,
+	},
+	3: {
+		` _____ `,
+		`|___ / `,
+		`  |_ \ `,
+		` ___) |`,
+		`|____/ `,
+	},
+}
+
+// countdownLayer shows the seconds left before the snakes start to move.
+func (u *UI) countdownLayer(seconds int) Layer {
+	const caption = "Get ready"
+	var lines []string
+	for _, line := range countdownDigits[min(seconds, len(countdownDigits)-1)] {
+		lines = append(lines, strings.Repeat(" ", (len(caption)-len(line))/2)+line)
+	}
+	return u.boxLayer(append(lines, "", caption))
+}
@@ -0,0 +1,27 @@
+package tui
+
+import "sync/atomic"
+
+// Counters of every UI in the process, for the diagnostics endpoint.
+var (
+	framesRendered atomic.Uint64
+	framesDropped  atomic.Uint64
+	inputDropped   atomic.Uint64
+)
+
+// FramesRendered is how many frames were drawn on a screen.
+func FramesRendered() uint64 {
+	return framesRendered.Load()
+}
+
+// FramesDropped is how many frames were replaced by a newer one before
+// the screen could draw them.
+func FramesDropped() uint64 {
+	return framesDropped.Load()
+}
+
+// InputDropped is how many key presses were dropped because the game had
+// not taken the one before yet.
+func InputDropped() uint64 {
+	return inputDropped.Load()
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	FLASH_DURATION = time.Second
+	// FLASH_COLOR is the color of the border while the board flashes.
+	FLASH_COLOR = 9
+	// OOM_SUMMARY_LINES is how many OOM kills the game-over screen lists,
+	// the most recent ones.
+	OOM_SUMMARY_LINES = 3
+	// DNS_SUMMARY_LINES is how many of the most queried domains the
+	// game-over screen lists.
+	DNS_SUMMARY_LINES = 3
+	// PROCESS_SUMMARY_LINES is how many of the processes behind most of a
+	// round's events the game-over screen lists.
+	PROCESS_SUMMARY_LINES = 5
+)
+
+// Flash draws the board in red and shakes it for FLASH_DURATION.
+func (u *UI) Flash() {
+	u.flashUntil = u.now().Add(FLASH_DURATION)
+}
+
+// flashFrame returns the border style of the next frame and how many
+// columns the board moves left, alternating while it shakes.
+func (u *UI) flashFrame() (border tcell.Style, shake int) {
+	if u.now().After(u.flashUntil) {
+		return u.Theme.border, 0
+	}
+	u.flashFrames++
+	return fg(FLASH_COLOR).Bold(true), u.flashFrames % 2
+}
@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	SLOW_FRAME_THRESHOLD = 40 * time.Millisecond
+	FRAME_EWMA_WEIGHT    = 0.2
+)
+
+type frame struct {
+	screen *screen
+	layers []Layer
+}
+
+type frameWriter struct {
+	out       tcell.Screen
+	frames    chan frame
+	done      sync.WaitGroup
+	closeOnce sync.Once
+	avgWrite  atomic.Int64
+	dropped   atomic.Uint64
+	skipCount int
+	// cast also records the frames when set.
+	cast *CastWriter
+	// last is the latest frame of an offscreen writer, which composes
+	// every frame at once instead of drawing it on a terminal.
+	last *screen
+}
+
+func newFrameWriter(out tcell.Screen) *frameWriter {
+	f := &frameWriter{out: out, frames: make(chan frame, 1)}
+	f.done.Add(1)
+	go f.loop()
+	return f
+}
+
+func newOffscreenFrameWriter() *frameWriter {
+	return &frameWriter{}
+}
+
+// compose draws the layers of fr over its screen.
+func (fr frame) compose() *screen {
+	for _, l := range fr.layers {
+		fr.screen.overlay(l)
+	}
+	return fr.screen
+}
+
+func (f *frameWriter) loop() {
+	defer f.done.Done()
+	defer RestoreOnPanic()
+	for fr := range f.frames {
+		if f.out == nil {
+			continue
+		}
+		start := time.Now()
+		s := fr.compose()
+		if f.cast != nil {
+			f.cast.frame(s, start)
+		}
+		s.draw(f.out)
+		f.out.Show()
+		elapsed := time.Since(start)
+		framesRendered.Add(1)
+
+		avg := time.Duration(f.avgWrite.Load())
+		avg = time.Duration(float64(avg)*(1-FRAME_EWMA_WEIGHT) + float64(elapsed)*FRAME_EWMA_WEIGHT)
+		f.avgWrite.Store(int64(avg))
+	}
+}
+
+func (f *frameWriter) submit(s *screen, layers ...Layer) {
+	fr := frame{screen: s, layers: layers}
+	if f.frames == nil {
+		f.last = fr.compose()
+		return
+	}
+	select {
+	case f.frames <- fr:
+		return
+	default:
+	}
+	select {
+	case <-f.frames:
+		f.dropped.Add(1)
+		framesDropped.Add(1)
+	default:
+	}
+	f.frames <- fr
+}
+
+func (f *frameWriter) slow() bool {
+	return time.Duration(f.avgWrite.Load()) > SLOW_FRAME_THRESHOLD
+}
+
+func (f *frameWriter) skipFrame() bool {
+	if !f.slow() {
+		f.skipCount = 0
+		return false
+	}
+	f.skipCount++
+	return f.skipCount%2 == 0
+}
+
+func (f *frameWriter) close() {
+	f.closeOnce.Do(func() {
+		if f.frames == nil {
+			return
+		}
+		close(f.frames)
+		f.done.Wait()
+	})
+}
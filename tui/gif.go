@@ -0,0 +1,384 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// GIF_CELL_WIDTH and GIF_CELL_HEIGHT are the size of a terminal cell in
+	// font pixels, each of which is GIF_SCALE pixels of the picture wide.
+	GIF_CELL_WIDTH  = 7
+	GIF_CELL_HEIGHT = 13
+	GIF_SCALE       = 2
+	// GIF_MAX_SIZE is the most pixels a GIF can be wide or high, so
+	// GIF_MAX_COLS and GIF_MAX_ROWS are the largest terminal it can show.
+	GIF_MAX_SIZE = math.MaxUint16
+	GIF_MAX_COLS = GIF_MAX_SIZE / (GIF_CELL_WIDTH * GIF_SCALE)
+	GIF_MAX_ROWS = GIF_MAX_SIZE / (GIF_CELL_HEIGHT * GIF_SCALE)
+	// GIF_FINAL_DELAY is how long the last frame stays up before the GIF
+	// starts over.
+	GIF_FINAL_DELAY = 3 * time.Second
+	// GIF_FOREGROUND and GIF_BACKGROUND are the palette colors of text
+	// and background without a color of their own.
+	GIF_FOREGROUND = 7
+	GIF_BACKGROUND = 0
+	// OFFSCREEN_MIN_WIDTH is how wide NewOffscreen makes the screen at
+	// least, so the lines under the board fit.
+	OFFSCREEN_MIN_WIDTH = 80
+)
+
+// gifPalette is the 256-color palette of xterm, which the themes pick
+// their colors from.
+var gifPalette = func() color.Palette {
+	p := make(color.Palette, 256)
+	for i := range p {
+		r, g, b := tcell.PaletteColor(i).RGB()
+		p[i] = color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+	}
+	return p
+}()
+
+// GIFWriter turns the frames of an offscreen UI into an animated GIF,
+// encoded with image/gif when it is closed. Every frame after the first
+// only covers the cells that changed, so the frames kept until then stay
+// small.
+type GIFWriter struct {
+	f    *os.File
+	anim gif.GIF
+	// err is why the frames cannot be encoded, if they cannot.
+	err error
+	// start is when the first frame was drawn, and shown the centiseconds
+	// of the frames added so far.
+	start time.Time
+	shown int
+	// prev is the last frame added and next the frame shown until the
+	// one after it.
+	prev, next *screen
+	colors     map[tcell.Style]gifColors
+	blends     map[[2]uint8]uint8
+}
+
+// gifColors are the palette colors of a cell style.
+type gifColors struct {
+	fg, bg    uint8
+	underline bool
+}
+
+// NewGIFWriter creates the GIF at path. The picture is as large as the
+// first frame.
+func NewGIFWriter(path string) (*GIFWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create GIF: %w", err)
+	}
+	return &GIFWriter{
+		f:      f,
+		colors: make(map[tcell.Style]gifColors),
+		blends: make(map[[2]uint8]uint8),
+	}, nil
+}
+
+// Frame adds the frame u drew last, drawn at now. A frame that looks like
+// the one before only makes that one stay up longer.
+func (g *GIFWriter) Frame(u *UI, now time.Time) {
+	s := u.out.last
+	switch {
+	case s == nil || g.err != nil:
+		return
+	case g.next == nil:
+		if s.width > GIF_MAX_COLS || s.height > GIF_MAX_ROWS {
+			g.err = fmt.Errorf("write GIF: %dx%d cells do not fit in %d pixels, at most %dx%d do", s.width, s.height, GIF_MAX_SIZE, GIF_MAX_COLS, GIF_MAX_ROWS)
+			return
+		}
+		g.start = now
+		g.anim.Config = image.Config{
+			ColorModel: gifPalette,
+			Width:      s.width * GIF_CELL_WIDTH * GIF_SCALE,
+			Height:     s.height * GIF_CELL_HEIGHT * GIF_SCALE,
+		}
+		g.anim.BackgroundIndex = GIF_BACKGROUND
+	case g.next.equal(s):
+		return
+	default:
+		g.addFrame(g.next, now.Sub(g.start))
+	}
+	g.next = s
+}
+
+// Close adds the last frame, which stays up for GIF_FINAL_DELAY, and
+// encodes the GIF.
+func (g *GIFWriter) Close() error {
+	err := g.err
+	switch {
+	case err != nil:
+	case g.next == nil:
+		err = errors.New("write GIF: no frames drawn")
+	default:
+		g.addFrame(g.next, time.Duration(g.shown)*10*time.Millisecond+GIF_FINAL_DELAY)
+		w := bufio.NewWriter(g.f)
+		if err = gif.EncodeAll(w, &g.anim); err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			err = fmt.Errorf("write GIF: %w", err)
+		}
+	}
+	if cerr := g.f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("write GIF: %w", cerr)
+	}
+	return err
+}
+
+// addFrame adds the cells of s that changed since the last frame added,
+// to be shown until end after the first frame.
+func (g *GIFWriter) addFrame(s *screen, end time.Duration) {
+	left, top, right, bottom := g.changed(s)
+	g.prev = s
+
+	endCS := int(end / (10 * time.Millisecond))
+	delay := min(max(endCS-g.shown, 0), math.MaxUint16)
+	g.shown += delay
+
+	const cellWidth, cellHeight = GIF_CELL_WIDTH * GIF_SCALE, GIF_CELL_HEIGHT * GIF_SCALE
+	img := image.NewPaletted(image.Rect(left*cellWidth, top*cellHeight, right*cellWidth, bottom*cellHeight), gifPalette)
+	for y := top; y < bottom; y++ {
+		for x := left; x < right; x++ {
+			c := s.rows[y][x]
+			colors := g.cellColors(c.style)
+			mask := [GIF_CELL_HEIGHT]uint8{}
+			if r, _ := utf8.DecodeRuneInString(c.text); c.text != "" {
+				mask = glyphMask(r)
+			}
+			if colors.underline {
+				mask[GIF_CELL_HEIGHT-1] = 1<<GIF_CELL_WIDTH - 1
+			}
+			for py := range cellHeight {
+				row := ((y-top)*cellHeight+py)*img.Stride + (x-left)*cellWidth
+				for px := range cellWidth {
+					index := colors.bg
+					if mask[py/GIF_SCALE]&(1<<(px/GIF_SCALE)) != 0 {
+						index = colors.fg
+					}
+					img.Pix[row+px] = index
+				}
+			}
+		}
+	}
+	// Every frame is drawn over the ones before.
+	g.anim.Image = append(g.anim.Image, img)
+	g.anim.Delay = append(g.anim.Delay, delay)
+	g.anim.Disposal = append(g.anim.Disposal, gif.DisposalNone)
+}
+
+// changed returns the cells of s that differ from the last frame added,
+// or all of them for the first one. A frame like the last is added as its
+// first cell.
+func (g *GIFWriter) changed(s *screen) (left, top, right, bottom int) {
+	if g.prev == nil || g.prev.width != s.width || g.prev.height != s.height {
+		return 0, 0, s.width, s.height
+	}
+	left, top = s.width, s.height
+	for y, row := range s.rows {
+		for x, c := range row {
+			if g.prev.rows[y][x] == c {
+				continue
+			}
+			// Either half of a wide character takes the other along.
+			left, right = min(left, max(x-1, 0)), max(right, min(x+2, s.width))
+			top, bottom = min(top, y), max(bottom, y+1)
+		}
+	}
+	if right == 0 {
+		return 0, 0, 1, 1
+	}
+	return left, top, right, bottom
+}
+
+func (g *GIFWriter) cellColors(style tcell.Style) gifColors {
+	if c, ok := g.colors[style]; ok {
+		return c
+	}
+	fg, bg, attrs := style.Decompose()
+	c := gifColors{
+		fg:        paletteIndex(fg, GIF_FOREGROUND),
+		bg:        paletteIndex(bg, GIF_BACKGROUND),
+		underline: attrs&tcell.AttrUnderline != 0,
+	}
+	// Terminals show bold text in the bright colors.
+	if attrs&tcell.AttrBold != 0 && c.fg < 8 {
+		c.fg += 8
+	}
+	if attrs&tcell.AttrReverse != 0 {
+		c.fg, c.bg = c.bg, c.fg
+	}
+	if attrs&tcell.AttrDim != 0 {
+		c.fg = g.blend(c.fg, c.bg)
+	}
+	g.colors[style] = c
+	return c
+}
+
+// blend returns the palette color closest to halfway between fg and bg.
+func (g *GIFWriter) blend(fg, bg uint8) uint8 {
+	key := [2]uint8{fg, bg}
+	if c, ok := g.blends[key]; ok {
+		return c
+	}
+	f, b := gifPalette[fg].(color.RGBA), gifPalette[bg].(color.RGBA)
+	mid := color.RGBA{uint8((int(f.R) + int(b.R)) / 2), uint8((int(f.G) + int(b.G)) / 2), uint8((int(f.B) + int(b.B)) / 2), 0xff}
+	c := uint8(gifPalette.Index(mid))
+	g.blends[key] = c
+	return c
+}
+
+// paletteIndex returns the palette color of c, or def for the default.
+func paletteIndex(c tcell.Color, def uint8) uint8 {
+	switch {
+	case !c.Valid():
+		return def
+	case c.IsRGB():
+		r, g, b := c.RGB()
+		return uint8(gifPalette.Index(color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}))
+	case c-tcell.ColorValid < 256:
+		return uint8(c - tcell.ColorValid)
+	}
+	return def
+}
+
+// boxLines are the sides of a cell the box-drawing characters reach:
+// up, down, left and right.
+var boxLines = map[rune][4]bool{
+	'─': {false, false, true, true}, '━': {false, false, true, true}, '═': {false, false, true, true},
+	'│': {true, true, false, false}, '┃': {true, true, false, false}, '║': {true, true, false, false},
+	'┌': {false, true, false, true}, '╭': {false, true, false, true}, '╔': {false, true, false, true},
+	'┐': {false, true, true, false}, '╮': {false, true, true, false}, '╗': {false, true, true, false},
+	'└': {true, false, false, true}, '╰': {true, false, false, true}, '╚': {true, false, false, true},
+	'┘': {true, false, true, false}, '╯': {true, false, true, false}, '╝': {true, false, true, false},
+	'├': {true, true, false, true}, '┤': {true, true, true, false},
+	'┬': {false, true, true, true}, '┴': {true, false, true, true}, '┼': {true, true, true, true},
+}
+
+// glyphMask draws r in a cell, one bit per font pixel from the left in
+// every row. Characters the font lacks are drawn as shapes, and those
+// that are none of them, like emoji, as a dot.
+func glyphMask(r rune) [GIF_CELL_HEIGHT]uint8 {
+	var m [GIF_CELL_HEIGHT]uint8
+	const full = 1<<GIF_CELL_WIDTH - 1
+	fill := func(from, to int, bits uint8) {
+		for y := from; y < to; y++ {
+			m[y] |= bits
+		}
+	}
+	// shape sets the pixels whose center is more than inner and at most
+	// outer away from the middle of the cell, as measured by dist.
+	shape := func(dist func(dx, dy float64) float64, inner, outer float64) {
+		for y := range GIF_CELL_HEIGHT {
+			for x := range GIF_CELL_WIDTH {
+				d := dist(float64(x)+0.5-GIF_CELL_WIDTH/2.0, float64(y)+0.5-GIF_CELL_HEIGHT/2.0)
+				if d > inner && d <= outer {
+					m[y] |= 1 << x
+				}
+			}
+		}
+	}
+	circle := math.Hypot
+	diamond := func(dx, dy float64) float64 { return math.Abs(dx) + math.Abs(dy) }
+	square := func(dx, dy float64) float64 { return max(math.Abs(dx), math.Abs(dy)) }
+	cross := func(dx, dy float64) float64 {
+		if math.Abs(math.Abs(dx)-math.Abs(dy)) >= 0.8 {
+			return math.Inf(1)
+		}
+		return circle(dx, dy)
+	}
+
+	if r >= ' ' && r < utf8.RuneSelf {
+		face := basicfont.Face7x13
+		dr, mask, mp, _, _ := face.Glyph(fixed.P(0, face.Ascent), r)
+		for y := range min(dr.Dy(), GIF_CELL_HEIGHT) {
+			for x := range min(dr.Dx(), GIF_CELL_WIDTH) {
+				if _, _, _, a := mask.At(mp.X+x, mp.Y+y).RGBA(); a != 0 {
+					m[dr.Min.Y+y] |= 1 << (dr.Min.X + x)
+				}
+			}
+		}
+		return m
+	}
+	if lines, ok := boxLines[r]; ok {
+		const mid, center = GIF_CELL_HEIGHT / 2, GIF_CELL_WIDTH / 2
+		if lines[0] {
+			fill(0, mid+1, 1<<center)
+		}
+		if lines[1] {
+			fill(mid, GIF_CELL_HEIGHT, 1<<center)
+		}
+		if lines[2] {
+			m[mid] |= 1<<(center+1) - 1
+		}
+		if lines[3] {
+			m[mid] |= full &^ (1<<center - 1)
+		}
+		return m
+	}
+	switch {
+	case r == '█':
+		fill(0, GIF_CELL_HEIGHT, full)
+	case r == '▀':
+		fill(0, GIF_CELL_HEIGHT/2, full)
+	case r > '▀' && r < '█':
+		// The lower eighths, from ▁ to ▇.
+		fill(GIF_CELL_HEIGHT-int(r-'▀')*GIF_CELL_HEIGHT/8, GIF_CELL_HEIGHT, full)
+	case r == '▌':
+		fill(0, GIF_CELL_HEIGHT, 1<<(GIF_CELL_WIDTH/2)-1)
+	case r == '▐':
+		fill(0, GIF_CELL_HEIGHT, full&^(1<<(GIF_CELL_WIDTH/2)-1))
+	case r == '░' || r == '▒' || r == '▓':
+		// Shades of one in four, two in four and three in four pixels.
+		for y := range GIF_CELL_HEIGHT {
+			for x := range GIF_CELL_WIDTH {
+				if n := (x + 2*y) % 4; r == '░' && n == 0 || r == '▒' && n%2 == 0 || r == '▓' && n != 0 {
+					m[y] |= 1 << x
+				}
+			}
+		}
+	case r == '●' || r == '◉':
+		shape(circle, -1, 2.6)
+	case r == '○':
+		shape(circle, 1.6, 2.6)
+	case r == '◎':
+		shape(circle, 1.6, 2.6)
+		shape(circle, -1, 0.8)
+	case r == '◆':
+		shape(diamond, -1, 3)
+	case r == '◇':
+		shape(diamond, 2, 3)
+	case r == '■':
+		shape(square, -1, 2.5)
+	case r == '✕' || r == '✗':
+		shape(cross, -1, 3)
+	default:
+		// The ASCII glyph set has a stand-in for some symbols.
+		if c, _ := utf8.DecodeRuneInString(ASCIIGlyphs.Text(string(r))); c != utf8.RuneError && c < utf8.RuneSelf {
+			return glyphMask(c)
+		}
+		radius := 1.6
+		if runewidth.RuneWidth(r) > 1 {
+			radius = 2.6
+		}
+		shape(circle, -1, radius)
+	}
+	return m
+}
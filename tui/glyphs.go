@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"strings"
+
+	"snake-ebpf/game"
+)
+
+// Glyph is one of the things the renderer draws that looks different in
+// each glyph set.
+type Glyph int
+
+const (
+	GLYPH_EMPTY Glyph = iota
+	GLYPH_SNAKE_HEAD
+	GLYPH_SNAKE_BODY
+	GLYPH_ENEMY_HEAD
+	GLYPH_ENEMY_BODY
+	GLYPH_PLAYER2_HEAD
+	GLYPH_PLAYER2_BODY
+	GLYPH_OBSTACLE
+	GLYPH_HORIZONTAL
+	GLYPH_VERTICAL
+	GLYPH_TOP_LEFT
+	GLYPH_TOP_RIGHT
+	GLYPH_BOTTOM_LEFT
+	GLYPH_BOTTOM_RIGHT
+	GLYPH_ARROW
+	GLYPH_WALL
+	GLYPH_POISON
+	GLYPH_FOOD
+	GLYPH_POWERUP = GLYPH_FOOD + Glyph(game.FOOD_KINDS)
+	GLYPH_KINDS   = GLYPH_POWERUP + Glyph(game.POWERUP_KINDS)
+)
+
+func foodGlyph(kind game.FoodKind) Glyph {
+	return GLYPH_FOOD + Glyph(kind)
+}
+
+func powerUpGlyph(kind game.PowerUpKind) Glyph {
+	return GLYPH_POWERUP + Glyph(kind)
+}
+
+// GlyphSet supplies the characters the renderer draws with. Every glyph is
+// exactly one column wide.
+type GlyphSet interface {
+	Glyph(g Glyph) string
+	// Bars are the levels of sparklines and charts, from empty to full.
+	Bars() []rune
+	// Text adapts free text such as toasts to the set.
+	Text(s string) string
+}
+
+type glyphTable struct {
+	glyphs [GLYPH_KINDS]string
+	bars   []rune
+	ascii  bool
+	text   map[rune]string
+}
+
+func (t *glyphTable) Glyph(g Glyph) string {
+	return t.glyphs[g]
+}
+
+func (t *glyphTable) Bars() []rune {
+	return t.bars
+}
+
+// Text replaces the symbols the game uses in messages by ASCII and drops
+// anything else, like emoji, that an ASCII terminal cannot show.
+func (t *glyphTable) Text(s string) string {
+	if !t.ascii {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			b.WriteRune(r)
+		case t.text[r] != "":
+			b.WriteString(t.text[r])
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var UnicodeGlyphs GlyphSet = &glyphTable{
+	glyphs: [GLYPH_KINDS]string{
+		GLYPH_EMPTY:        " ",
+		GLYPH_SNAKE_HEAD:   "●",
+		GLYPH_SNAKE_BODY:   "○",
+		GLYPH_ENEMY_HEAD:   "◆",
+		GLYPH_ENEMY_BODY:   "◇",
+		GLYPH_PLAYER2_HEAD: "◉",
+		GLYPH_PLAYER2_BODY: "◎",
+		GLYPH_OBSTACLE:     "■",
+		GLYPH_HORIZONTAL:   "─",
+		GLYPH_VERTICAL:     "│",
+		GLYPH_TOP_LEFT:     "┌",
+		GLYPH_TOP_RIGHT:    "┐",
+		GLYPH_BOTTOM_LEFT:  "└",
+		GLYPH_BOTTOM_RIGHT: "┘",
+		GLYPH_ARROW:        "←",
+		GLYPH_WALL:         "░",
+		GLYPH_POISON:       "✕",
+
+		GLYPH_FOOD + Glyph(game.FOOD_EXEC):    "*",
+		GLYPH_FOOD + Glyph(game.FOOD_FILE):    "+",
+		GLYPH_FOOD + Glyph(game.FOOD_NETWORK): "@",
+		GLYPH_FOOD + Glyph(game.FOOD_FORK):    "%",
+		GLYPH_FOOD + Glyph(game.FOOD_USDT):    "$",
+		GLYPH_FOOD + Glyph(game.FOOD_DNS):     "?",
+
+		GLYPH_POWERUP + Glyph(game.POWERUP_SLOW_MOTION):   "S",
+		GLYPH_POWERUP + Glyph(game.POWERUP_WALL_PASS):     "W",
+		GLYPH_POWERUP + Glyph(game.POWERUP_DOUBLE_POINTS): "D",
+	},
+	bars: []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'},
+}
+
+var ASCIIGlyphs GlyphSet = &glyphTable{
+	glyphs: [GLYPH_KINDS]string{
+		GLYPH_EMPTY:        " ",
+		GLYPH_SNAKE_HEAD:   "O",
+		GLYPH_SNAKE_BODY:   "o",
+		GLYPH_ENEMY_HEAD:   "X",
+		GLYPH_ENEMY_BODY:   "x",
+		GLYPH_PLAYER2_HEAD: "Q",
+		GLYPH_PLAYER2_BODY: "q",
+		GLYPH_OBSTACLE:     "#",
+		GLYPH_HORIZONTAL:   "-",
+		GLYPH_VERTICAL:     "|",
+		GLYPH_TOP_LEFT:     "+",
+		GLYPH_TOP_RIGHT:    "+",
+		GLYPH_BOTTOM_LEFT:  "+",
+		GLYPH_BOTTOM_RIGHT: "+",
+		GLYPH_ARROW:        "<",
+		GLYPH_WALL:         ":",
+		GLYPH_POISON:       "!",
+
+		GLYPH_FOOD + Glyph(game.FOOD_EXEC):    "*",
+		GLYPH_FOOD + Glyph(game.FOOD_FILE):    "+",
+		GLYPH_FOOD + Glyph(game.FOOD_NETWORK): "@",
+		GLYPH_FOOD + Glyph(game.FOOD_FORK):    "%",
+		GLYPH_FOOD + Glyph(game.FOOD_USDT):    "$",
+		GLYPH_FOOD + Glyph(game.FOOD_DNS):     "?",
+
+		GLYPH_POWERUP + Glyph(game.POWERUP_SLOW_MOTION):   "S",
+		GLYPH_POWERUP + Glyph(game.POWERUP_WALL_PASS):     "W",
+		GLYPH_POWERUP + Glyph(game.POWERUP_DOUBLE_POINTS): "D",
+	},
+	bars:  []rune{' ', '.', ',', '-', '=', '+', '*', '#', '@'},
+	ascii: true,
+	text: map[rune]string{
+		'✓': "+",
+		'✗': "-",
+		'─': "-",
+		'│': "|",
+		'←': "<",
+		'☠': "!",
+		'→': "->",
+		'·': "|",
+	},
+}
@@ -0,0 +1,269 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const SPARKLINE_SAMPLES = 60
+
+// HISTORY_SAMPLES caps the samples the session graphs are drawn from. Once
+// they are full, neighbouring samples are merged, so each one covers twice
+// as many polls and the whole session still fits.
+const HISTORY_SAMPLES = 1024
+
+type historySample struct {
+	at        time.Time
+	eventRate float64
+	execRate  float64
+	fileRate  float64
+	netRate   float64
+	forkRate  float64
+	ctxRate   float64
+	interval  time.Duration
+	score     int
+}
+
+// add returns the sum of s and o, at the time of o.
+func (s historySample) add(o historySample) historySample {
+	return historySample{
+		at:        o.at,
+		eventRate: s.eventRate + o.eventRate,
+		execRate:  s.execRate + o.execRate,
+		fileRate:  s.fileRate + o.fileRate,
+		netRate:   s.netRate + o.netRate,
+		forkRate:  s.forkRate + o.forkRate,
+		ctxRate:   s.ctxRate + o.ctxRate,
+		interval:  s.interval + o.interval,
+		score:     s.score + o.score,
+	}
+}
+
+// average returns s, the sum of n samples, divided by n.
+func (s historySample) average(n int) historySample {
+	f := float64(n)
+	return historySample{
+		at:        s.at,
+		eventRate: s.eventRate / f,
+		execRate:  s.execRate / f,
+		fileRate:  s.fileRate / f,
+		netRate:   s.netRate / f,
+		forkRate:  s.forkRate / f,
+		ctxRate:   s.ctxRate / f,
+		interval:  s.interval / time.Duration(n),
+		score:     s.score / n,
+	}
+}
+
+type History struct {
+	start time.Time
+	// samples each average span polls. pending sums the polls of the next
+	// sample until there are span of them.
+	samples  []historySample
+	span     int
+	pending  historySample
+	pendingN int
+	recent   [SPARKLINE_SAMPLES]historySample
+	next     int
+	count    int
+}
+
+func (h *History) Record(snap ebpfmon.Snapshot, interval time.Duration, score int) {
+	if h.start.IsZero() {
+		h.start = snap.Time
+	}
+	sample := historySample{
+		at:        snap.Time,
+		eventRate: float64(snap.EventRate),
+		interval:  interval,
+		score:     score,
+	}
+	if elapsed := snap.Delta.Elapsed.Seconds(); elapsed > 0 {
+		sample.execRate = float64(snap.Delta.Execve) / elapsed
+		sample.fileRate = float64(snap.Delta.FileOps) / elapsed
+		sample.netRate = float64(snap.Delta.Network) / elapsed
+		sample.forkRate = float64(snap.Delta.Process) / elapsed
+		sample.ctxRate = float64(snap.Delta.ContextSwitches) / elapsed
+	}
+	h.keep(sample)
+	h.recent[h.next] = sample
+	h.next = (h.next + 1) % SPARKLINE_SAMPLES
+	h.count = min(h.count+1, SPARKLINE_SAMPLES)
+}
+
+// keep adds sample to the session samples, merging them when they are
+// full.
+func (h *History) keep(sample historySample) {
+	h.span = max(h.span, 1)
+	h.pending = h.pending.add(sample)
+	h.pendingN++
+	if h.pendingN < h.span {
+		return
+	}
+	h.samples = append(h.samples, h.pending.average(h.pendingN))
+	h.pending, h.pendingN = historySample{}, 0
+	if len(h.samples) == HISTORY_SAMPLES {
+		for i := range HISTORY_SAMPLES / 2 {
+			h.samples[i] = h.samples[2*i].add(h.samples[2*i+1]).average(2)
+		}
+		h.samples = h.samples[:HISTORY_SAMPLES/2]
+		h.span *= 2
+	}
+}
+
+// recentSeries returns up to the last width samples of the ring buffer,
+// oldest first.
+func (h *History) recentSeries(width int, value func(historySample) float64) []float64 {
+	n := min(width, h.count)
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = value(h.recent[(h.next-n+i+SPARKLINE_SAMPLES)%SPARKLINE_SAMPLES])
+	}
+	return out
+}
+
+// sparkline draws values as one row of bars scaled to their maximum,
+// right-aligned in width columns.
+func sparkline(values []float64, width int, bars []rune) string {
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+	var line strings.Builder
+	line.WriteString(strings.Repeat(" ", width-len(values)))
+	for _, v := range values {
+		level := 1
+		if maxValue > 0 {
+			level += int(v / maxValue * float64(len(bars)-2))
+		}
+		line.WriteRune(bars[level])
+	}
+	return line.String()
+}
+
+func (h *History) series(width int, value func(historySample) float64) []float64 {
+	if len(h.samples) == 0 || width <= 0 {
+		return nil
+	}
+	buckets := width
+	if len(h.samples) < buckets {
+		buckets = len(h.samples)
+	}
+	out := make([]float64, buckets)
+	for b := 0; b < buckets; b++ {
+		from := b * len(h.samples) / buckets
+		to := (b + 1) * len(h.samples) / buckets
+		sum := 0.0
+		for _, s := range h.samples[from:to] {
+			sum += value(s)
+		}
+		out[b] = sum / float64(to-from)
+	}
+	return out
+}
+
+var sparklineSeries = []struct {
+	label string
+	value func(historySample) float64
+}{
+	{"execve", func(s historySample) float64 { return s.execRate }},
+	{"open", func(s historySample) float64 { return s.fileRate }},
+	{"connect", func(s historySample) float64 { return s.netRate }},
+	{"fork", func(s historySample) float64 { return s.forkRate }},
+	{"ctxsw", func(s historySample) float64 { return s.ctxRate }},
+}
+
+// sparklines draws one line per event category, width columns wide, from
+// the most recent samples.
+func (u *UI) sparklines(width int) []string {
+	columns := min(width-18, SPARKLINE_SAMPLES)
+	var lines []string
+	for _, series := range sparklineSeries {
+		values := u.History.recentSeries(columns, series.value)
+		last := 0.0
+		if len(values) > 0 {
+			last = values[len(values)-1]
+		}
+		lines = append(lines, fmt.Sprintf("%-7s %s %7.0f/s", series.label, sparkline(values, columns, u.Glyphs.Bars()), last))
+	}
+	return lines
+}
+
+func renderChart(title string, values []float64, height int, unit string, glyphs GlyphSet) []string {
+	bars := glyphs.Bars()
+	maxValue := 0.0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+	lines := []string{fmt.Sprintf("%s (max %.1f%s)", title, maxValue, unit)}
+	for row := height - 1; row >= 0; row-- {
+		var line strings.Builder
+		line.WriteString(glyphs.Glyph(GLYPH_VERTICAL))
+		for _, v := range values {
+			level := 0.0
+			if maxValue > 0 {
+				level = v / maxValue * float64(height)
+			}
+			cell := level - float64(row)
+			switch {
+			case cell >= 1:
+				line.WriteRune(bars[len(bars)-1])
+			case cell > 0:
+				line.WriteRune(bars[int(cell*float64(len(bars)-1))])
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	lines = append(lines, glyphs.Glyph(GLYPH_BOTTOM_LEFT)+strings.Repeat(glyphs.Glyph(GLYPH_HORIZONTAL), len(values)))
+	return lines
+}
+
+func (u *UI) RenderGraphs() {
+	s := newScreen(u.TermWidth, u.TermHeight)
+
+	width := u.TermWidth - 4
+	if width < 10 {
+		width = 10
+	}
+	charts := []struct {
+		title string
+		unit  string
+		value func(historySample) float64
+	}{
+		{"Event rate", "/s", func(s historySample) float64 { return s.eventRate }},
+		{"execve", "/s", func(s historySample) float64 { return s.execRate }},
+		{"File opens", "/s", func(s historySample) float64 { return s.fileRate }},
+		{"Connects", "/s", func(s historySample) float64 { return s.netRate }},
+		{"Forks", "/s", func(s historySample) float64 { return s.forkRate }},
+		{"Tick interval", "ms", func(s historySample) float64 { return float64(s.interval.Milliseconds()) }},
+		{"Score", "", func(s historySample) float64 { return float64(s.score) }},
+	}
+
+	chartHeight := (u.TermHeight-3)/len(charts) - 2
+	if chartHeight < 1 {
+		chartHeight = 1
+	}
+
+	session := time.Duration(0)
+	if !u.History.start.IsZero() {
+		session = time.Since(u.History.start).Truncate(time.Second)
+	}
+	s.text(2, 0, fmt.Sprintf("Session metrics (%s) - press Tab to return to the game", session), plain)
+	y := 2
+	for _, chart := range charts {
+		for _, line := range renderChart(chart.title, u.History.series(width, chart.value), chartHeight, chart.unit, u.Glyphs) {
+			s.text(2, y, line, plain)
+			y++
+		}
+	}
+	u.Toasts.render(s, u.Glyphs, u.Theme.toast)
+
+	u.out.submit(s)
+}
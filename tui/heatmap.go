@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"hash/fnv"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const (
+	HEATMAP_DECAY  = 0.6
+	HEATMAP_LEVELS = 5
+)
+
+type heatmap struct {
+	width  int
+	height int
+	cells  []float64
+	prev   map[uint32]uint64
+}
+
+func newHeatmap(width, height int) *heatmap {
+	return &heatmap{
+		width:  width,
+		height: height,
+		cells:  make([]float64, width*height),
+		prev:   make(map[uint32]uint64),
+	}
+}
+
+func (h *heatmap) update(procs []ebpfmon.ProcessCount) {
+	for i := range h.cells {
+		h.cells[i] *= HEATMAP_DECAY
+	}
+
+	seen := make(map[uint32]uint64, len(h.prev))
+	for _, p := range procs {
+		pid, count := p.PID, p.Count
+		seen[pid] = count
+		prev, ok := h.prev[pid]
+		if !ok || count < prev {
+			prev = 0
+		}
+		if count > prev {
+			h.cells[h.cellFor(pid)] += float64(count - prev)
+		}
+	}
+	h.prev = seen
+}
+
+func (h *heatmap) cellFor(pid uint32) int {
+	hash := fnv.New32a()
+	hash.Write([]byte{byte(pid), byte(pid >> 8), byte(pid >> 16), byte(pid >> 24)})
+	return int(hash.Sum32() % uint32(len(h.cells)))
+}
+
+// level returns how busy a cell is, from 0 (idle) to HEATMAP_LEVELS.
+func (h *heatmap) level(x, y int) int {
+	heat := h.cells[y*h.width+x]
+	level := 0
+	for threshold := 1.0; heat >= threshold && level < HEATMAP_LEVELS; threshold *= 4 {
+		level++
+	}
+	return level
+}
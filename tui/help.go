@@ -0,0 +1,48 @@
+package tui
+
+import "fmt"
+
+// HELP_WIDTH is the widest line of the help overlay.
+const HELP_WIDTH = 60
+
+// kernelEffects explains how the kernel metrics change the game.
+var kernelEffects = []string{
+	"execve, fork, event and context switch rates speed the snake up",
+	"food appears on an execve once due; file opens bring it sooner",
+	"each food comes from an event source, picked by its rate",
+	"execve bursts bring power-ups, drops and retransmits poison",
+	"OOM kills halve the snake, memory reclaim shrinks the board",
+	"200 or more live processes put extra food on the board",
+	"slow disks slow the snake, run queue delays lag the keys",
+	"SIGKILL reverses the keys and SIGSEGV stalls the snake",
+}
+
+// helpLayer is the overlay the help key shows: the controls, which probes
+// are attached and what the kernel does to the game.
+func (u *UI) helpLayer() Layer {
+	lines := []string{"HELP (any key to close)", "", "Controls"}
+	lines = append(lines, wrapItems(u.KeyHelp.Controls, "   ", HELP_WIDTH)...)
+
+	lines = append(lines, "")
+	lines = append(lines, u.probeLines(HELP_WIDTH)...)
+	lines = append(lines, "", "What the kernel does")
+	lines = append(lines, kernelEffects...)
+	return u.boxLayer(lines)
+}
+
+// probeLines count the attached probe groups and mark each of them, in
+// lines of at most width columns.
+func (u *UI) probeLines(width int) []string {
+	attached, labels := 0, make([]string, 0, len(u.Probes.Groups))
+	for _, g := range u.Probes.Groups {
+		mark := "✓"
+		if g.Attached {
+			attached++
+		} else {
+			mark = "✗"
+		}
+		labels = append(labels, g.Label+u.Glyphs.Text(mark))
+	}
+	lines := []string{fmt.Sprintf("Probes (%d of %d attached)", attached, len(u.Probes.Groups))}
+	return append(lines, wrapItems(labels, " ", width)...)
+}
@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Layer is a box drawn over a finished frame, centered on the terminal.
+// The frame shows through it dimmed wherever the box has no text, so the
+// game stays in sight behind it.
+type Layer struct {
+	Lines []string
+	style tcell.Style
+}
+
+// boxLayer frames lines with a border.
+func (u *UI) boxLayer(lines []string) Layer {
+	glyph := u.Glyphs.Glyph
+	width := 0
+	for _, line := range lines {
+		width = max(width, runewidth.StringWidth(line))
+	}
+	horizontal := strings.Repeat(glyph(GLYPH_HORIZONTAL), width+2)
+	box := make([]string, 0, len(lines)+2)
+	box = append(box, glyph(GLYPH_TOP_LEFT)+horizontal+glyph(GLYPH_TOP_RIGHT))
+	for _, line := range lines {
+		pad := strings.Repeat(" ", width-runewidth.StringWidth(line))
+		box = append(box, glyph(GLYPH_VERTICAL)+" "+line+pad+" "+glyph(GLYPH_VERTICAL))
+	}
+	box = append(box, glyph(GLYPH_BOTTOM_LEFT)+horizontal+glyph(GLYPH_BOTTOM_RIGHT))
+	return Layer{Lines: box, style: u.Theme.text}
+}
+
+// wrapItems lays items out in lines of at most width columns, separated by
+// sep.
+func wrapItems(items []string, sep string, width int) []string {
+	var lines []string
+	line := ""
+	for _, item := range items {
+		switch {
+		case line == "":
+			line = item
+		case runewidth.StringWidth(line+sep+item) <= width:
+			line += sep + item
+		default:
+			lines = append(lines, line)
+			line = item
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
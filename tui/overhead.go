@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const OVERHEAD_PANEL_WIDTH = 30
+
+// programOverhead is what one program cost the kernel between the last two
+// samples of its statistics.
+type programOverhead struct {
+	name   string
+	runs   uint64
+	perRun time.Duration
+	// cpu is the share of one CPU the program took, in percent.
+	cpu float64
+}
+
+// UpdateProgramStats turns the running totals of the attached programs into
+// their cost since the previous call.
+func (u *UI) UpdateProgramStats(stats []ebpfmon.ProgramStats, now time.Time) {
+	elapsed := now.Sub(u.statsTime)
+	first := u.statsTime.IsZero()
+	prev := u.stats
+	u.stats = make(map[string]ebpfmon.ProgramStats, len(stats))
+	u.statsTime = now
+	u.overhead = u.overhead[:0]
+	for _, s := range stats {
+		u.stats[s.Program] = s
+		p := prev[s.Program]
+		o := programOverhead{
+			name: strings.TrimPrefix(s.Program, "handle_"),
+			runs: s.RunCount - p.RunCount,
+		}
+		runtime := s.Runtime - p.Runtime
+		if o.runs > 0 {
+			o.perRun = runtime / time.Duration(o.runs)
+		}
+		if !first && elapsed > 0 {
+			o.cpu = 100 * runtime.Seconds() / elapsed.Seconds()
+		}
+		u.overhead = append(u.overhead, o)
+	}
+}
+
+// overheadPanel shows what each attached program costs per run and in CPU
+// time, most expensive first.
+func (u *UI) overheadPanel(rows int) []string {
+	w := OVERHEAD_PANEL_WIDTH - 2
+	lines := []string{
+		fmt.Sprintf("%-*s", w, "BPF overhead"),
+		strings.Repeat(u.Glyphs.Glyph(GLYPH_HORIZONTAL), w),
+	}
+	if !u.StatsEnabled {
+		lines = append(lines, fmt.Sprintf("%-*s", w, "start with -overhead"))
+	} else {
+		lines = append(lines, fmt.Sprintf("%-12s %8s %6s", "program", "ns/run", "cpu"))
+		var total float64
+		for _, o := range u.overhead {
+			total += o.cpu
+		}
+		lines = append(lines, fmt.Sprintf("%-12s %8s %5.2f%%", "total", "", total))
+		for _, o := range u.overhead {
+			perRun := "-"
+			if o.runs > 0 {
+				perRun = fmt.Sprint(o.perRun.Nanoseconds())
+			}
+			lines = append(lines, fmt.Sprintf("%-12.12s %8s %5.2f%%", o.name, perRun, o.cpu))
+		}
+	}
+	for len(lines) < rows {
+		lines = append(lines, fmt.Sprintf("%-*s", w, ""))
+	}
+	return lines[:rows]
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+)
+
+const (
+	PROC_PANEL_WIDTH    = 30
+	PROC_PANEL_ROWS     = 10
+	METRICS_PANEL_WIDTH = 24
+)
+
+func (u *UI) procPanel(rows int) []string {
+	lines := []string{
+		fmt.Sprintf("%-*s", PROC_PANEL_WIDTH-2, "Top processes"),
+		strings.Repeat(u.Glyphs.Glyph(GLYPH_HORIZONTAL), PROC_PANEL_WIDTH-2),
+	}
+	for _, p := range u.TopProcs {
+		lines = append(lines, fmt.Sprintf("%7d %-12.12s %7d", p.PID, p.Comm, p.Count))
+	}
+	for len(lines) < rows {
+		lines = append(lines, "")
+	}
+	return lines[:rows]
+}
+
+// metricsPanel shows the rates that drive the game and what they do to its
+// speed.
+func (u *UI) metricsPanel(rows int) []string {
+	w := METRICS_PANEL_WIDTH - 2
+	driver := "base"
+	if f, ok := u.Speed.Dominant(); ok {
+		driver = f.String()
+	}
+	lines := []string{
+		fmt.Sprintf("%-*s", w, "Kernel activity"),
+		strings.Repeat(u.Glyphs.Glyph(GLYPH_HORIZONTAL), w),
+		fmt.Sprintf("%-10s%*.1f", "execve/s", w-10, u.Rate.Execve),
+		fmt.Sprintf("%-10s%*.1f", "open/s", w-10, u.Rate.FileOps),
+		fmt.Sprintf("%-10s%*.1f", "connect/s", w-10, u.Rate.Network),
+		fmt.Sprintf("%-10s%*.1f", "fork/s", w-10, u.Rate.Process),
+		fmt.Sprintf("%-10s%*.0f", "ctxsw/s", w-10, u.Rate.ContextSwitches),
+		fmt.Sprintf("%-10s%*.1f", "retx/s", w-10, u.Rate.Retransmits),
+		fmt.Sprintf("%-10s%*.1f", "drop/s", w-10, u.Rate.Drops),
+		fmt.Sprintf("%-10s%*.1f", "fault/s", w-10, u.Rate.PageFaults),
+		fmt.Sprintf("%-10s%*.1f", "reclaim/s", w-10, u.Rate.Reclaims),
+		fmt.Sprintf("%-10s%*.1f", "dns/s", w-10, u.Rate.DNSQueries),
+		fmt.Sprintf("%-*.*s", w, w, fmt.Sprintf("tick %dms %s %s", u.Interval.Milliseconds(), u.Glyphs.Glyph(GLYPH_ARROW), driver)),
+	}
+	if u.Uprobe != "" {
+		lines = append(lines, fmt.Sprintf("%-10s%*.1f", "uprobe/s", w-10, u.Rate.UprobeCalls))
+	}
+	if u.USDT != "" {
+		lines = append(lines, fmt.Sprintf("%-10s%*.1f", "usdt/s", w-10, u.Rate.USDTCalls))
+	}
+	if u.XDP != "" {
+		lines = append(lines,
+			fmt.Sprintf("%-10s%*.0f", "rx pkt/s", w-10, u.Rate.RxPackets),
+			fmt.Sprintf("%-10s%*.1f", "rx KiB/s", w-10, u.Rate.RxBytes/1024),
+		)
+	}
+	if u.Egress {
+		lines = append(lines, fmt.Sprintf("%-10s%*.1f", "tx KiB/s", w-10, u.Rate.EgressBytes/1024))
+	}
+	for c := ebpfmon.SYSCALL_IO; c < ebpfmon.SYSCALL_OTHER; c++ {
+		lines = append(lines, fmt.Sprintf("%-10s%*.0f", "sys "+c.String()+"/s", w-10, u.Rate.Syscalls[c]))
+	}
+	lines = append(lines, u.ioLatencyLines(w)...)
+	for len(lines) < rows {
+		lines = append(lines, fmt.Sprintf("%-*s", w, ""))
+	}
+	return lines[:rows]
+}
+
+// ioLatencyLines shows the block I/O latency histogram of the last second,
+// from 4us on the left to 8s and more on the right, and its 99th percentile.
+func (u *UI) ioLatencyLines(w int) []string {
+	hist := u.Rate.IOLatency[:]
+	slots := hist[max(len(hist)-w, 0):]
+	return []string{
+		fmt.Sprintf("%-10s%*s", "disk p99", w-10, latencyString(ebpfmon.IOLatencyPercentile(hist, 0.99))),
+		fmt.Sprintf("%-*s", w, sparkline(slots, w, u.Glyphs.Bars())),
+	}
+}
+
+// latencyString shows a latency percentile, to the millisecond from 1ms on,
+// or "-" when there was nothing to measure.
+func latencyString(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond).String()
+	} else if d > 0 {
+		return d.String()
+	}
+	return "-"
+}
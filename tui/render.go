@@ -0,0 +1,280 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+func (u *UI) Render(g *game.Game) {
+	if u.out.skipFrame() {
+		return
+	}
+	if !u.Fits(g) {
+		u.renderTooSmall(g)
+		return
+	}
+
+	s := newScreen(u.TermWidth, u.TermHeight)
+
+	gameBlockWidth := g.Width*2 + 3
+	gameBlockHeight := g.Height + 9
+
+	panel := make([]string, g.Height)
+	if u.ShowMetrics && u.TermWidth >= gameBlockWidth+METRICS_PANEL_WIDTH {
+		for y, line := range u.metricsPanel(g.Height) {
+			panel[y] += "  " + line
+		}
+		gameBlockWidth += METRICS_PANEL_WIDTH
+	}
+	if u.ShowProcs && u.TermWidth >= gameBlockWidth+PROC_PANEL_WIDTH {
+		for y, line := range u.procPanel(g.Height) {
+			panel[y] += "  " + line
+		}
+		gameBlockWidth += PROC_PANEL_WIDTH
+	}
+	if u.ShowOverhead && u.TermWidth >= gameBlockWidth+OVERHEAD_PANEL_WIDTH {
+		for y, line := range u.overheadPanel(g.Height) {
+			panel[y] += "  " + line
+		}
+		gameBlockWidth += OVERHEAD_PANEL_WIDTH
+	}
+
+	showSparklines := u.ShowSparklines && u.TermHeight >= gameBlockHeight+len(sparklineSeries)
+	if showSparklines {
+		gameBlockHeight += len(sparklineSeries)
+	}
+
+	padLeft := (u.TermWidth - gameBlockWidth) / 2
+	padTop := (u.TermHeight - gameBlockHeight) / 2
+	y := padTop
+
+	glyph := u.Glyphs.Glyph
+	grid := make([][]Glyph, g.Height)
+	bounds := g.Bounds()
+	for y := range grid {
+		grid[y] = make([]Glyph, g.Width)
+		for x := range grid[y] {
+			if !bounds.Contains(game.Position{X: x, Y: y}) {
+				grid[y][x] = GLYPH_WALL
+			}
+		}
+	}
+
+	if g.Enemy != nil {
+		for i, segment := range g.Enemy.Body {
+			if segment.Y >= 0 && segment.Y < g.Height && segment.X >= 0 && segment.X < g.Width {
+				if i == 0 {
+					grid[segment.Y][segment.X] = GLYPH_ENEMY_HEAD
+				} else {
+					grid[segment.Y][segment.X] = GLYPH_ENEMY_BODY
+				}
+			}
+		}
+	}
+
+	for i, segment := range g.Snake {
+		if segment.Y >= 0 && segment.Y < g.Height && segment.X >= 0 && segment.X < g.Width {
+			if i == 0 {
+				grid[segment.Y][segment.X] = GLYPH_SNAKE_HEAD
+			} else {
+				grid[segment.Y][segment.X] = GLYPH_SNAKE_BODY
+			}
+		}
+	}
+
+	if p := g.Player2; p != nil {
+		for i, segment := range p.Snake {
+			if segment.Y >= 0 && segment.Y < g.Height && segment.X >= 0 && segment.X < g.Width {
+				if i == 0 {
+					grid[segment.Y][segment.X] = GLYPH_PLAYER2_HEAD
+				} else {
+					grid[segment.Y][segment.X] = GLYPH_PLAYER2_BODY
+				}
+			}
+		}
+	}
+
+	if g.Food.Y >= 0 && g.Food.Y < g.Height && g.Food.X >= 0 && g.Food.X < g.Width {
+		grid[g.Food.Y][g.Food.X] = foodGlyph(g.FoodKind)
+	}
+	for _, item := range g.ExtraFood {
+		grid[item.Pos.Y][item.Pos.X] = foodGlyph(item.Kind)
+	}
+	for _, item := range g.BonusFood {
+		grid[item.Pos.Y][item.Pos.X] = foodGlyph(item.Kind)
+	}
+
+	for _, o := range g.Obstacles {
+		grid[o.Y][o.X] = GLYPH_OBSTACLE
+	}
+
+	if p := g.PowerUp; p != nil {
+		grid[p.Pos.Y][p.Pos.X] = powerUpGlyph(p.Kind)
+	}
+
+	if p := g.Poison; p != nil {
+		grid[p.Pos.Y][p.Pos.X] = GLYPH_POISON
+	}
+
+	border, shake := u.flashFrame()
+	padLeft -= min(shake, padLeft)
+	u.boardLeft, u.boardTop = padLeft+2, padTop+1
+
+	topBorder := glyph(GLYPH_TOP_LEFT) + strings.Repeat(glyph(GLYPH_HORIZONTAL), g.Width*2+1) + glyph(GLYPH_TOP_RIGHT)
+	s.text(padLeft, y, topBorder, border)
+	y++
+
+	for gy, row := range grid {
+		x := s.text(padLeft, y, glyph(GLYPH_VERTICAL), border) + 1
+		for gx, cell := range row {
+			var style tcell.Style
+			text := glyph(cell) + " "
+			switch {
+			case cell == GLYPH_SNAKE_HEAD || cell == GLYPH_SNAKE_BODY:
+				style = u.Theme.snake
+			case cell == GLYPH_PLAYER2_HEAD || cell == GLYPH_PLAYER2_BODY:
+				style = u.Theme.player2
+			case cell == GLYPH_ENEMY_HEAD || cell == GLYPH_ENEMY_BODY:
+				style = u.Theme.enemy
+			case cell == GLYPH_OBSTACLE:
+				style = border
+			case cell == GLYPH_WALL:
+				style = border
+				text = glyph(cell) + glyph(cell)
+			case cell == GLYPH_POISON:
+				style = u.Theme.poison
+			case cell >= GLYPH_POWERUP:
+				style = u.Theme.powerUp
+			case cell >= GLYPH_FOOD:
+				style = u.Theme.food[cell-GLYPH_FOOD]
+			}
+			if u.ShowHeatmap && !u.out.slow() {
+				if level := u.heat.level(gx, gy); level > 0 {
+					style = style.Background(u.Theme.heat[level-1])
+				}
+			}
+			x = s.text(x, y, text, style)
+		}
+		x = s.text(x, y, glyph(GLYPH_VERTICAL), border)
+		s.text(x, y, panel[gy], u.Theme.text)
+		y++
+	}
+
+	bottomBorder := glyph(GLYPH_BOTTOM_LEFT) + strings.Repeat(glyph(GLYPH_HORIZONTAL), g.Width*2+1) + glyph(GLYPH_BOTTOM_RIGHT)
+	s.text(padLeft, y, bottomBorder, border)
+	y++
+	if showSparklines {
+		for _, line := range u.sparklines(g.Width*2 + 3) {
+			s.text(padLeft, y, line, u.Theme.text)
+			y++
+		}
+	}
+
+	infoLine1 := fmt.Sprintf("Level: %d | Score: %d | Length: %d | %s", g.Level(), g.Score, len(g.Snake), g.Difficulty.Name)
+	if p := g.Player2; p != nil {
+		infoLine1 = fmt.Sprintf("P1: %d (%d) | P2: %d (%d) | %s", g.Score, len(g.Snake), p.Score, len(p.Snake), g.Difficulty.Name)
+	}
+	if g.Paused {
+		infoLine1 += " | PAUSED"
+	}
+	if g.Wrap {
+		infoLine1 += " | WRAP"
+	}
+	if u.Demo {
+		infoLine1 += " | DEMO"
+	}
+	if grace := g.GraceRemaining(); grace > 0 && g.Countdown() == 0 {
+		infoLine1 += fmt.Sprintf(" | SAFE %ds", int((grace+time.Second-1)/time.Second))
+	}
+	if u.DiskLag {
+		infoLine1 += " | DISK LAG"
+	}
+	if u.Container != "" {
+		infoLine1 += " | Container: " + u.Container
+	}
+	if u.Uprobe != "" {
+		infoLine1 += " | Uprobe: " + u.Uprobe
+	}
+	if u.USDT != "" {
+		infoLine1 += " | USDT: " + u.USDT
+	}
+	if u.Egress {
+		infoLine1 += fmt.Sprintf(" | TX: %.1f KiB/s", u.Rate.EgressBytes/1024)
+		if bonus := g.UploadBonus(); bonus > 1 {
+			infoLine1 += fmt.Sprintf(" x%d", bonus)
+		}
+	}
+	if u.Metrics.LiveProcesses > 0 {
+		infoLine1 += fmt.Sprintf(" | Procs: %d", u.Metrics.LiveProcesses)
+	}
+	if p99 := ebpfmon.IOLatencyPercentile(u.Rate.RunqLatency[:], 0.99); p99 > 0 {
+		p50 := ebpfmon.IOLatencyPercentile(u.Rate.RunqLatency[:], 0.5)
+		infoLine1 += fmt.Sprintf(" | RunQ p50 %s p99 %s", latencyString(p50), latencyString(p99))
+		if u.InputLag >= time.Millisecond {
+			infoLine1 += " lag " + latencyString(u.InputLag)
+		}
+	}
+	for kind := game.PowerUpKind(0); kind < game.POWERUP_KINDS; kind++ {
+		if g.Active(kind) {
+			infoLine1 += fmt.Sprintf(" | %s %ds", kind, int(g.Remaining(kind).Seconds()+0.5))
+		}
+	}
+	for effect := game.Effect(0); effect < game.EFFECT_KINDS; effect++ {
+		if g.Affected(effect) {
+			infoLine1 += " | " + strings.ToUpper(effect.String())
+		}
+	}
+	if u.out.slow() {
+		infoLine1 += " | slow terminal"
+	}
+	if secEvents := u.Metrics.Security; secEvents != [ebpfmon.SECURITY_EVENT_KINDS]uint64{} {
+		infoLine1 += fmt.Sprintf(" | Security: %d", secEvents[ebpfmon.SECURITY_SETUID]+secEvents[ebpfmon.SECURITY_PTRACE])
+	}
+	infoLine2 := u.KeyHelp.Keys
+	infoLine3 := u.KeyHelp.Quit
+	infoLine4 := u.Glyphs.Text("Powered by eBPF 🐝")
+
+	// The last line starts two columns right of the quit line.
+	infoPadLeft4 := max((u.TermWidth-runewidth.StringWidth(infoLine3))/2, 0) + 2
+
+	s.centered(y, infoLine1, u.Theme.text)
+	s.centered(y+1, u.Glyphs.Text(u.Probes.Indicator()), u.Theme.text)
+	s.centered(y+2, infoLine2, u.Theme.text)
+	s.centered(y+3, infoLine3, u.Theme.text)
+	y += 4
+
+	var names []string
+	for _, c := range u.TopContainers {
+		names = append(names, fmt.Sprintf("%s (%d)", c.Name, c.Total()))
+	}
+	if len(names) == 0 {
+		for _, cg := range u.TopCgroups {
+			names = append(names, fmt.Sprintf("%s (%d)", ebpfmon.ShortCgroupName(cg.Path), cg.Count))
+		}
+	}
+	if len(names) > 0 {
+		s.centered(y, "Top: "+strings.Join(names, ", "), u.Theme.text)
+	}
+	s.centered(y+1, u.Ticker.line(u.TermWidth-2, u.Glyphs), u.Theme.text)
+	s.text(infoPadLeft4, y+2, infoLine4, u.Theme.text)
+
+	u.Toasts.render(s, u.Glyphs, u.Theme.toast)
+
+	var layers []Layer
+	if g.GameOver {
+		layers = append(layers, u.gameOverLayer(g))
+	} else if n := g.Countdown(); n > 0 {
+		layers = append(layers, u.countdownLayer(n))
+	}
+	if u.ShowHelp {
+		layers = append(layers, u.helpLayer())
+	}
+	u.out.submit(s, layers...)
+}
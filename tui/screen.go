@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// cell is one terminal column. The right half of a double-width character
+// is a cell with empty text.
+type cell struct {
+	text  string
+	style tcell.Style
+}
+
+var blankCell = cell{text: " "}
+
+// screen is a frame as the cells of the terminal. The renderers draw on
+// it, and it is copied onto the tcell screen once it is finished.
+type screen struct {
+	rows          [][]cell
+	width, height int
+}
+
+func newScreen(width, height int) *screen {
+	s := &screen{width: width, height: height, rows: make([][]cell, height)}
+	for i := range s.rows {
+		s.rows[i] = make([]cell, width)
+		for j := range s.rows[i] {
+			s.rows[i][j] = blankCell
+		}
+	}
+	return s
+}
+
+// equal reports whether s and o show the same.
+func (s *screen) equal(o *screen) bool {
+	if s.width != o.width || s.height != o.height {
+		return false
+	}
+	for y, row := range s.rows {
+		if !slices.Equal(row, o.rows[y]) {
+			return false
+		}
+	}
+	return true
+}
+
+// text draws t from column x of row y in style and returns the column
+// after it. What lies outside the screen is clipped, and a combining
+// character joins the character before it.
+func (s *screen) text(x, y int, t string, style tcell.Style) int {
+	for _, r := range t {
+		switch w := runewidth.RuneWidth(r); {
+		case r < 0x20:
+		case w == 0:
+			if y >= 0 && y < s.height && x > 0 && x <= s.width {
+				lead := x - 1
+				for lead > 0 && s.rows[y][lead].text == "" {
+					lead--
+				}
+				s.rows[y][lead].text += string(r)
+			}
+		default:
+			if y >= 0 && y < s.height && x >= 0 && x+w <= s.width {
+				s.rows[y][x] = cell{text: string(r), style: style}
+				for i := 1; i < w; i++ {
+					s.rows[y][x+i] = cell{style: style}
+				}
+			}
+			x += w
+		}
+	}
+	return x
+}
+
+// centered draws t in the middle of row y, or from its left edge when it
+// is wider than the screen.
+func (s *screen) centered(y int, t string, style tcell.Style) {
+	s.text(max((s.width-runewidth.StringWidth(t))/2, 0), y, t, style)
+}
+
+// overlay draws l centered on the screen. The cells under the box are
+// dimmed and only the characters of its lines other than spaces cover
+// them.
+func (s *screen) overlay(l Layer) {
+	width := 0
+	for _, line := range l.Lines {
+		width = max(width, runewidth.StringWidth(line))
+	}
+	top, left := (s.height-len(l.Lines))/2, (s.width-width)/2
+	for i, line := range l.Lines {
+		row := top + i
+		if row < 0 || row >= s.height {
+			continue
+		}
+		cells := s.rows[row]
+		for x := max(left, 0); x < min(left+width, s.width); x++ {
+			cells[x].style = cells[x].style.Dim(true)
+		}
+		col := left
+		for _, r := range line {
+			w := runewidth.RuneWidth(r)
+			if r != ' ' && col >= 0 && col+w <= s.width {
+				// Do not leave half of a wide character behind.
+				if cells[col].text == "" && col > 0 {
+					cells[col-1] = cell{text: " ", style: cells[col-1].style}
+				}
+				if next := col + w; next < s.width && cells[next].text == "" {
+					cells[next] = cell{text: " ", style: cells[next].style}
+				}
+				cells[col] = cell{text: string(r), style: l.style}
+				for j := 1; j < w; j++ {
+					cells[col+j] = cell{style: l.style}
+				}
+			}
+			col += w
+		}
+	}
+}
+
+// draw copies the cells onto the tcell screen, which sends only the cells
+// that changed since the last Show to the terminal.
+func (s *screen) draw(out tcell.Screen) {
+	for y, row := range s.rows {
+		for x, c := range row {
+			if c.text == "" {
+				continue
+			}
+			runes := []rune(c.text)
+			out.SetContent(x, y, runes[0], runes[1:], c.style)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+// Summary sums up a finished round for the game-over screen.
+type Summary struct {
+	Duration time.Duration
+	// Fastest is the shortest time between two moves of the round.
+	Fastest       time.Duration
+	Events        []EventCount
+	PeakEventRate uint64
+	// TopProcs are the processes behind most of the round's events.
+	TopProcs []ebpfmon.ProcessCount
+}
+
+// EventCount is how many kernel events of a category a round saw.
+type EventCount struct {
+	Label string
+	Count uint64
+}
+
+// gameOverLayer is the game-over screen drawn over the board: the result,
+// the round's statistics and what to press next.
+func (u *UI) gameOverLayer(g *game.Game) Layer {
+	// The box adds two borders, the title, the prompt and a blank line
+	// after each.
+	lines := append([]string{"GAME OVER", ""}, u.summaryLines(g, u.TermHeight-6)...)
+	return u.boxLayer(append(lines, "", u.KeyHelp.GameOver))
+}
+
+// SummaryLines sum up the round of g: the result, the statistics of
+// u.Summary when set, and the OOM kills and domains of the round.
+func (u *UI) SummaryLines(g *game.Game) []string {
+	return u.summaryLines(g, math.MaxInt)
+}
+
+// summaryLines are SummaryLines in at most height lines, which the
+// processes, OOM kills and domains are left out of if they do not fit.
+func (u *UI) summaryLines(g *game.Game, height int) []string {
+	var lines []string
+	if p := g.Player2; p != nil {
+		result := "Draw!"
+		if winner := g.Winner(); winner != 0 {
+			result = fmt.Sprintf("Player %d wins!", winner)
+		}
+		lines = append(lines, result,
+			fmt.Sprintf("P1: %d  Length: %d", g.Score, len(g.Snake)),
+			fmt.Sprintf("P2: %d  Length: %d", p.Score, len(p.Snake)))
+	} else {
+		record := fmt.Sprintf("Best: %d  Length: %d", u.Record.Score, u.Record.Length)
+		if u.Record.NewHighScore {
+			record = "New high score!"
+		}
+		lines = append(lines, fmt.Sprintf("Score: %d  Length: %d  %s", g.Score, len(g.Snake), g.Difficulty.Name), record)
+	}
+
+	fits := func(more []string) bool {
+		return len(lines)+len(more) <= height
+	}
+	if s := u.Summary; s != nil {
+		lines = append(lines, fmt.Sprintf("Time: %v  Fastest move: every %v", s.Duration.Round(time.Second), s.Fastest))
+		events := make([]string, len(s.Events))
+		for i, e := range s.Events {
+			events[i] = fmt.Sprintf("%s %d", e.Label, e.Count)
+		}
+		lines = append(lines, "", fmt.Sprintf("Kernel events (peak %d/s)", s.PeakEventRate))
+		lines = append(lines, wrapItems(events, "  ", HELP_WIDTH)...)
+
+		if len(s.TopProcs) > 0 {
+			procs := []string{"", "Noisiest processes"}
+			for _, p := range s.TopProcs {
+				procs = append(procs, fmt.Sprintf("%8d  %-16s pid %d", p.Count, p.Comm, p.PID))
+			}
+			if fits(procs) {
+				lines = append(lines, procs...)
+			}
+		}
+	}
+	var kills []string
+	for _, kill := range g.OOMKills[max(len(g.OOMKills)-OOM_SUMMARY_LINES, 0):] {
+		kills = append(kills, fmt.Sprintf("OOM kill at score %d: -%d", kill.Score, kill.Lost))
+	}
+	if fits(kills) {
+		lines = append(lines, kills...)
+	}
+	var domains []string
+	for _, d := range u.TopDomains {
+		domains = append(domains, fmt.Sprintf("DNS %d× %.*s", d.Count, HELP_WIDTH-16, d.Name))
+	}
+	if fits(domains) {
+		lines = append(lines, domains...)
+	}
+	return lines
+}
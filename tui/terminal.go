@@ -0,0 +1,235 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+	"golang.org/x/sys/unix"
+)
+
+// INPUT_BUFFER is how many key presses ReadInput holds while the game is
+// busy, so keys typed in a quick burst between two ticks all arrive. Keys
+// are only dropped past it.
+const INPUT_BUFFER = 16
+
+var (
+	term     tcell.Screen
+	termLock sync.Mutex
+	resizes  = make(chan struct{}, 1)
+)
+
+func TerminalSize() (int, int) {
+	termLock.Lock()
+	s := term
+	termLock.Unlock()
+	if s != nil {
+		return s.Size()
+	}
+	fd := int(os.Stdout.Fd())
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// SetupTerminal switches the terminal to raw mode and the alternate screen
+// and hides the cursor, so the shell's screen and scrollback survive the
+// game. Everything drawn afterwards goes through the tcell screen, and
+// RestoreTerminal must be called on every way out, panics included.
+func SetupTerminal() error {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("open terminal: %w", err)
+	}
+	if err := s.Init(); err != nil {
+		return fmt.Errorf("init terminal: %w", err)
+	}
+	s.HideCursor()
+	s.Clear()
+	termLock.Lock()
+	term = s
+	termLock.Unlock()
+	return nil
+}
+
+// EnableMouse turns on xterm mouse reporting for clicks and drags, which
+// ReadInput then sends. It reports false when the terminal does not seem
+// to support a mouse, and the game is played with the keys alone.
+func EnableMouse() bool {
+	termLock.Lock()
+	s := term
+	termLock.Unlock()
+	if s == nil || !s.HasMouse() {
+		return false
+	}
+	s.EnableMouse(tcell.MouseButtonEvents | tcell.MouseDragEvents)
+	return true
+}
+
+// TerminalActive reports whether the game is drawing on the terminal, so
+// nothing else should write to it.
+func TerminalActive() bool {
+	termLock.Lock()
+	defer termLock.Unlock()
+	return term != nil
+}
+
+// RestoreTerminal leaves the alternate screen and restores the terminal
+// modes. It is safe to call more than once.
+func RestoreTerminal() {
+	termLock.Lock()
+	defer termLock.Unlock()
+	if term == nil {
+		return
+	}
+	term.Fini()
+	term = nil
+}
+
+// RestoreOnPanic restores the terminal when the calling goroutine panics and
+// then lets the panic carry on, so the stack trace is printed on the normal
+// screen instead of a terminal left in raw mode. Every goroutine that runs
+// while the game is on screen defers it first.
+func RestoreOnPanic() {
+	if r := recover(); r != nil {
+		RestoreTerminal()
+		panic(r)
+	}
+}
+
+// Resizes delivers a notification whenever the terminal changed size.
+func Resizes() <-chan struct{} {
+	return resizes
+}
+
+// ReadInput sends key presses to ch until ctx is cancelled or the terminal
+// is restored, and then closes ch. A key is a lowercase character or one
+// of up, down, left, right, tab, esc, enter and ctrl+c. With the mouse
+// enabled, pressing or dragging with the left button sends "click X Y"
+// with the terminal column and row.
+func ReadInput(ctx context.Context, ch chan<- string) {
+	defer RestoreOnPanic()
+	termLock.Lock()
+	s := term
+	termLock.Unlock()
+	if s == nil {
+		close(ch)
+		return
+	}
+	readInput(ctx, s, resizes, ch)
+}
+
+func readInput(ctx context.Context, s tcell.Screen, resizes chan<- struct{}, ch chan<- string) {
+	defer close(ch)
+	stop := context.AfterFunc(ctx, func() { s.PostEvent(tcell.NewEventInterrupt(nil)) })
+	defer stop()
+	for {
+		var input string
+		switch ev := s.PollEvent().(type) {
+		case nil:
+			return
+		case *tcell.EventInterrupt:
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		case *tcell.EventResize:
+			s.Sync()
+			select {
+			case resizes <- struct{}{}:
+			default:
+			}
+			continue
+		case *tcell.EventMouse:
+			if ev.Buttons()&tcell.Button1 == 0 {
+				continue
+			}
+			x, y := ev.Position()
+			input = fmt.Sprintf("click %d %d", x, y)
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyUp:
+				input = "up"
+			case tcell.KeyDown:
+				input = "down"
+			case tcell.KeyLeft:
+				input = "left"
+			case tcell.KeyRight:
+				input = "right"
+			case tcell.KeyTab:
+				input = "tab"
+			case tcell.KeyEscape:
+				input = "esc"
+			case tcell.KeyEnter:
+				input = "enter"
+			case tcell.KeyCtrlC:
+				input = "ctrl+c"
+			case tcell.KeyRune:
+				input = string(unicode.ToLower(ev.Rune()))
+			default:
+				continue
+			}
+		default:
+			continue
+		}
+
+		select {
+		case ch <- input:
+		default:
+			inputDropped.Add(1)
+		}
+	}
+}
+
+// Terminal is a screen of a UI's own, such as the terminal of an SSH
+// session, rather than the one of the process that SetupTerminal takes
+// over. A panic does not restore it; closing it does.
+type Terminal struct {
+	screen  tcell.Screen
+	resizes chan struct{}
+}
+
+// NewTerminal sets up the terminal on the other end of tty, whose type is
+// name, like SetupTerminal does for the process's own.
+func NewTerminal(tty tcell.Tty, name string) (*Terminal, error) {
+	ti, err := terminfo.LookupTerminfo(name)
+	if err != nil {
+		return nil, fmt.Errorf("look up terminal %q: %w", name, err)
+	}
+	s, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		return nil, fmt.Errorf("open terminal: %w", err)
+	}
+	if err := s.Init(); err != nil {
+		return nil, fmt.Errorf("init terminal: %w", err)
+	}
+	s.HideCursor()
+	s.Clear()
+	return &Terminal{screen: s, resizes: make(chan struct{}, 1)}, nil
+}
+
+func (t *Terminal) Size() (int, int) {
+	return t.screen.Size()
+}
+
+// Resizes delivers a notification whenever the terminal changed size.
+func (t *Terminal) Resizes() <-chan struct{} {
+	return t.resizes
+}
+
+// ReadInput sends key presses to ch until ctx is cancelled or the terminal
+// is closed, and then closes ch.
+func (t *Terminal) ReadInput(ctx context.Context, ch chan<- string) {
+	readInput(ctx, t.screen, t.resizes, ch)
+}
+
+// Close restores the terminal modes, like RestoreTerminal.
+func (t *Terminal) Close() {
+	t.screen.Fini()
+}
@@ -0,0 +1,148 @@
+package tui
+
+import (
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+
+	"snake-ebpf/game"
+)
+
+// Theme holds every style the renderer uses. The colors are from the
+// 256-color palette, which tcell maps onto what the terminal supports.
+type Theme struct {
+	Name    string
+	snake   tcell.Style
+	player2 tcell.Style
+	food    [game.FOOD_KINDS]tcell.Style
+	enemy   tcell.Style
+	powerUp tcell.Style
+	poison  tcell.Style
+	border  tcell.Style
+	text    tcell.Style
+	toast   tcell.Style
+	heat    [HEATMAP_LEVELS]tcell.Color
+}
+
+var (
+	plain = tcell.StyleDefault
+	bold  = plain.Bold(true)
+)
+
+// fg is the style of text in color c of the palette.
+func fg(c int) tcell.Style {
+	return plain.Foreground(tcell.PaletteColor(c))
+}
+
+// on is the style of text in color c on background bg.
+func on(c, bg int) tcell.Style {
+	return fg(c).Background(tcell.PaletteColor(bg))
+}
+
+var Themes = []Theme{
+	{
+		Name:    "classic",
+		snake:   fg(2),
+		player2: fg(12),
+		food:    [game.FOOD_KINDS]tcell.Style{fg(1), fg(3), fg(6), fg(4), fg(10), fg(14).Bold(true)},
+		enemy:   fg(5),
+		powerUp: bold,
+		poison:  fg(5).Bold(true),
+		toast:   on(0, 3),
+		heat:    heatPalette(235, 52, 88, 124, 160),
+	},
+	{
+		Name:    "matrix",
+		snake:   fg(10),
+		player2: fg(15),
+		food:    [game.FOOD_KINDS]tcell.Style{fg(15), fg(10), fg(14), fg(11), fg(15).Bold(true), fg(10).Bold(true)},
+		enemy:   fg(9),
+		powerUp: fg(2).Bold(true),
+		poison:  fg(9).Bold(true),
+		border:  fg(2),
+		text:    fg(2),
+		toast:   on(0, 2),
+		heat:    heatPalette(234, 22, 28, 34, 40),
+	},
+	{
+		Name:    "amber",
+		snake:   fg(214),
+		player2: fg(229),
+		food:    [game.FOOD_KINDS]tcell.Style{fg(196), fg(226), fg(208), fg(222), fg(230), fg(220).Bold(true)},
+		enemy:   fg(130),
+		powerUp: fg(214).Bold(true),
+		poison:  fg(196).Bold(true),
+		border:  fg(172),
+		text:    fg(214),
+		toast:   on(0, 214),
+		heat:    heatPalette(235, 52, 94, 130, 166),
+	},
+	{
+		Name:    "solarized",
+		snake:   fg(64),
+		player2: fg(33),
+		food:    [game.FOOD_KINDS]tcell.Style{fg(160), fg(136), fg(37), fg(33), fg(61), fg(166)},
+		enemy:   fg(125),
+		powerUp: fg(166).Bold(true),
+		poison:  fg(125).Bold(true),
+		border:  fg(240),
+		text:    fg(244),
+		toast:   on(230, 33),
+		heat:    heatPalette(235, 23, 24, 31, 37),
+	},
+	{
+		Name:    "monochrome",
+		snake:   bold,
+		player2: plain.Underline(true),
+		powerUp: bold,
+		poison:  plain.Reverse(true),
+		toast:   plain.Reverse(true),
+	},
+}
+
+// heatPalette builds the heatmap backgrounds from 256-color palette indexes.
+func heatPalette(colors ...int) [HEATMAP_LEVELS]tcell.Color {
+	var heat [HEATMAP_LEVELS]tcell.Color
+	for i, c := range colors {
+		heat[i] = tcell.PaletteColor(c)
+	}
+	return heat
+}
+
+// NoColor reports whether the user asked for output without colors with
+// the NO_COLOR environment variable (https://no-color.org).
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// Monochrome is the theme used when colors are disabled.
+func Monochrome() Theme {
+	t, _ := LookupTheme("monochrome")
+	return t
+}
+
+func ThemeIndex(name string) int {
+	for i, t := range Themes {
+		if t.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+func LookupTheme(name string) (Theme, bool) {
+	for _, t := range Themes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
+
+func ThemeNames() []string {
+	names := make([]string, len(Themes))
+	for i, t := range Themes {
+		names[i] = t.Name
+	}
+	return names
+}
@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// TICKER_EVENTS is how many of the latest kernel events the activity ticker
+// keeps, more than fit on any line.
+const TICKER_EVENTS = 32
+
+// Ticker is the scrolling line of the latest kernel events under the board.
+type Ticker struct {
+	events [TICKER_EVENTS]ebpfmon.KernelEvent
+	next   int
+	count  int
+}
+
+func (t *Ticker) Push(ev ebpfmon.KernelEvent) {
+	t.events[t.next] = ev
+	t.next = (t.next + 1) % TICKER_EVENTS
+	t.count = min(t.count+1, TICKER_EVENTS)
+}
+
+// line returns the latest events that fit in width columns, oldest first,
+// so new events scroll in from the right.
+func (t *Ticker) line(width int, glyphs GlyphSet) string {
+	separator := "  " + glyphs.Text("·") + "  "
+	var items []string
+	used := 0
+	for i := 1; i <= t.count; i++ {
+		ev := t.events[(t.next-i+TICKER_EVENTS)%TICKER_EVENTS]
+		item := glyphs.Text(printable(ev.String()))
+		n := len([]rune(item))
+		if len(items) > 0 {
+			n += len([]rune(separator))
+		}
+		if used+n > width {
+			break
+		}
+		used += n
+		items = append(items, item)
+	}
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return strings.Join(items, separator)
+}
+
+// printable drops control characters, so a file name cannot send escape
+// sequences to the terminal.
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, strings.ToValidUTF8(s, ""))
+}
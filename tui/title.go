@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"snake-ebpf/game"
+)
+
+// TITLE_WIDTH is the widest line of the title screen below the logo.
+const TITLE_WIDTH = 60
+
+var titleArt = []string{
+	` ____   _   _     _     _  __ _____ `,
+	`/ ___| | \ | |   / \   | |/ /| ____|`,
+	`\___ \ |  \| |  / _ \  | ' / |  _|  `,
+	` ___) || |\  | / ___ \ | . \ | |___ `,
+	`|____/ |_| \_|/_/   \_\|_|\_\|_____|`,
+}
+
+// Title is what the title screen shows besides the probes and the high
+// score.
+type Title struct {
+	// Source tells where the counters come from, like "eBPF programs
+	// attached".
+	Source       string
+	Kernel       string
+	Difficulties []game.Difficulty
+	// Selected is the index of the difficulty the game starts with.
+	Selected int
+	// Keys names the keys of the title screen.
+	Keys string
+}
+
+// RenderTitle draws the title screen shown before the first round. The
+// logo is left out on terminals too short for everything.
+func (u *UI) RenderTitle(t Title) {
+	var lines []string
+	lines = append(lines, "", u.Glyphs.Text("Powered by eBPF 🐝"), "", t.Source)
+	if t.Kernel != "" {
+		lines[len(lines)-1] += " on Linux " + t.Kernel
+	}
+	lines = append(lines, u.probeLines(TITLE_WIDTH)...)
+
+	lines = append(lines, "")
+	if r := u.Record; r.Score > 0 {
+		lines = append(lines, fmt.Sprintf("High score %d  Longest snake %d  Peak %d events/s", r.Score, r.Length, r.PeakEventRate))
+	} else {
+		lines = append(lines, "No high score yet")
+	}
+
+	names := make([]string, len(t.Difficulties))
+	for i, d := range t.Difficulties {
+		names[i] = " " + d.Name + " "
+		if i == t.Selected {
+			names[i] = "[" + d.Name + "]"
+		}
+	}
+	d := t.Difficulties[t.Selected]
+	obstacles := "no obstacles"
+	if d.ObstacleEvery > 0 {
+		obstacles = fmt.Sprintf("an obstacle every %d foods", d.ObstacleEvery)
+	}
+	lines = append(lines, "", "Difficulty  "+strings.Join(names, " "),
+		fmt.Sprintf("%v base tick, %gx activity speed-up, grows %d, %s", d.BaseInterval, d.ActivityScale, d.Growth, obstacles),
+		"", u.Glyphs.Text(t.Keys))
+
+	art := titleArt
+	if u.TermHeight < len(art)+len(lines) {
+		art = nil
+	}
+
+	s := newScreen(u.TermWidth, u.TermHeight)
+	y := max((u.TermHeight-len(art)-len(lines))/2, 0)
+	for _, line := range art {
+		s.text(max((u.TermWidth-len(titleArt[0]))/2, 0), y, line, u.Theme.snake)
+		y++
+	}
+	for _, line := range lines {
+		s.centered(y, line, u.Theme.text)
+		y++
+	}
+	u.Toasts.render(s, u.Glyphs, u.Theme.toast)
+	u.out.submit(s)
+}
@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	TOAST_DURATION    = 4 * time.Second
+	TOAST_MAX_VISIBLE = 4
+)
+
+type toast struct {
+	message string
+	expires time.Time
+}
+
+type ToastQueue struct {
+	items []toast
+	// Clock returns the time toasts expire by; time.Now when nil.
+	Clock func() time.Time
+}
+
+func (q *ToastQueue) now() time.Time {
+	if q.Clock == nil {
+		return time.Now()
+	}
+	return q.Clock()
+}
+
+func (q *ToastQueue) Push(message string) {
+	q.items = append(q.items, toast{message: message, expires: q.now().Add(TOAST_DURATION)})
+}
+
+func (q *ToastQueue) active() []toast {
+	now := q.now()
+	kept := q.items[:0]
+	for _, t := range q.items {
+		if now.Before(t.expires) {
+			kept = append(kept, t)
+		}
+	}
+	q.items = kept
+	if len(kept) > TOAST_MAX_VISIBLE {
+		return kept[len(kept)-TOAST_MAX_VISIBLE:]
+	}
+	return kept
+}
+
+// render draws the toasts in the top right corner of s, one per row from
+// the second.
+func (q *ToastQueue) render(s *screen, glyphs GlyphSet, style tcell.Style) {
+	for i, t := range q.active() {
+		text := " " + glyphs.Text(t.message) + " "
+		s.text(max(s.width-runewidth.StringWidth(text)-2, 0), i+1, text, style)
+	}
+}
@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"snake-ebpf/ebpfmon"
+	"snake-ebpf/game"
+)
+
+type UI struct {
+	TermWidth      int
+	TermHeight     int
+	Theme          Theme
+	Glyphs         GlyphSet
+	Toasts         ToastQueue
+	Ticker         Ticker
+	History        History
+	ShowGraphs     bool
+	ShowHeatmap    bool
+	ShowProcs      bool
+	ShowMetrics    bool
+	ShowSparklines bool
+	ShowOverhead   bool
+	ShowHelp       bool
+	StatsEnabled   bool
+	Demo           bool
+	DiskLag        bool
+	InputLag       time.Duration
+	Metrics        ebpfmon.Metrics
+	Rate           ebpfmon.Rate
+	Interval       time.Duration
+	Speed          game.SpeedReductions
+	TopCgroups     []ebpfmon.CgroupCount
+	TopContainers  []ebpfmon.ContainerCount
+	// TopDomains are the most queried domains, for the game-over screen.
+	TopDomains []ebpfmon.DomainCount
+	// Clock returns the time flashes end by; time.Now when nil.
+	Clock func() time.Time
+	// Container is the container that drives the game, if any.
+	Container   string
+	Uprobe      string
+	USDT        string
+	XDP         string
+	Egress      bool
+	TopProcs    []ebpfmon.ProcessCount
+	Probes      ebpfmon.ProbeStatus
+	Record      Record
+	KeyHelp     KeyHelp
+	Summary     *Summary
+	heat        *heatmap
+	flashUntil  time.Time
+	flashFrames int
+	boardLeft   int
+	boardTop    int
+	stats       map[string]ebpfmon.ProgramStats
+	statsTime   time.Time
+	overhead    []programOverhead
+	out         *frameWriter
+}
+
+// KeyHelp names the keys of the game, as bound by the keymap.
+type KeyHelp struct {
+	// Keys is the line of hints under the board, Quit the line below it
+	// and GameOver the last line of the game over screen. Controls are
+	// the keys and what they do, for the help overlay.
+	Keys     string
+	Quit     string
+	GameOver string
+	Controls []string
+}
+
+type Record struct {
+	Score         int
+	Length        int
+	PeakEventRate uint64
+	NewHighScore  bool
+}
+
+func New(boardWidth, boardHeight int) *UI {
+	return newUI(term, boardWidth, boardHeight)
+}
+
+// NewOn returns a UI that draws on t instead of the process's terminal.
+func NewOn(t *Terminal, boardWidth, boardHeight int) *UI {
+	return newUI(t.screen, boardWidth, boardHeight)
+}
+
+func newUI(screen tcell.Screen, boardWidth, boardHeight int) *UI {
+	return &UI{
+		Theme:          Themes[0],
+		Glyphs:         UnicodeGlyphs,
+		ShowHeatmap:    true,
+		ShowMetrics:    true,
+		ShowSparklines: true,
+		heat:           newHeatmap(boardWidth, boardHeight),
+		out:            newFrameWriter(screen),
+	}
+}
+
+// NewOffscreen returns a UI that draws on no terminal of width×height, for
+// a GIFWriter to turn its frames into pictures. A width or height of 0
+// fits the board with the metrics panel and the sparklines.
+func NewOffscreen(boardWidth, boardHeight, width, height int) *UI {
+	u := newUI(nil, boardWidth, boardHeight)
+	u.out = newOffscreenFrameWriter()
+	minWidth, minHeight := minTerminalSize(&game.Game{Width: boardWidth, Height: boardHeight})
+	if width <= 0 {
+		width = max(minWidth+METRICS_PANEL_WIDTH+2, OFFSCREEN_MIN_WIDTH)
+	}
+	if height <= 0 {
+		height = minHeight + len(sparklineSeries)
+	}
+	u.Resize(width, height)
+	return u
+}
+
+func (u *UI) now() time.Time {
+	if u.Clock == nil {
+		return time.Now()
+	}
+	return u.Clock()
+}
+
+func (u *UI) UpdateProcesses(procs []ebpfmon.ProcessCount) {
+	u.heat.update(procs)
+	u.TopProcs = ebpfmon.TopProcesses(procs, PROC_PANEL_ROWS)
+}
+
+func (u *UI) Close() {
+	u.out.close()
+}
+
+func minTerminalSize(g *game.Game) (int, int) {
+	return g.Width*2 + 3, g.Height + 9
+}
+
+func (u *UI) Fits(g *game.Game) bool {
+	minWidth, minHeight := minTerminalSize(g)
+	return u.TermWidth >= minWidth && u.TermHeight >= minHeight
+}
+
+// BoardCell returns the board cell drawn at column x and row y of the
+// terminal, counted from 0, by where the last frame put the board. It may
+// lie outside the board.
+func (u *UI) BoardCell(x, y int) game.Position {
+	dx := x - u.boardLeft
+	if dx < 0 {
+		dx-- // round the left border down, not towards the board
+	}
+	return game.Position{X: dx / 2, Y: y - u.boardTop}
+}
+
+func (u *UI) Resize(termWidth, termHeight int) {
+	u.TermWidth = termWidth
+	u.TermHeight = termHeight
+}
+
+// ResizeBoard follows a change of the game board size.
+func (u *UI) ResizeBoard(boardWidth, boardHeight int) {
+	u.heat = newHeatmap(boardWidth, boardHeight)
+}
+
+func (u *UI) renderTooSmall(g *game.Game) {
+	minWidth, minHeight := minTerminalSize(g)
+	lines := []string{
+		"Terminal too small",
+		fmt.Sprintf("resize to at least %dx%d", minWidth, minHeight),
+		fmt.Sprintf("(currently %dx%d)", u.TermWidth, u.TermHeight),
+	}
+
+	s := newScreen(u.TermWidth, u.TermHeight)
+	top := max((u.TermHeight-len(lines))/2, 0)
+	for i, line := range lines {
+		s.centered(top+i, line, plain)
+	}
+	u.out.submit(s)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// parseUprobe parses the -uprobe flag, BIN:SYMBOL. A BIN without a slash is
+// looked up in PATH.
+func parseUprobe(s string) (ebpfmon.Uprobe, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return ebpfmon.Uprobe{}, fmt.Errorf("-uprobe %q: want BIN:SYMBOL", s)
+	}
+	u := ebpfmon.Uprobe{Binary: s[:i], Symbol: s[i+1:]}
+	if !strings.Contains(u.Binary, "/") {
+		path, err := exec.LookPath(u.Binary)
+		if err != nil {
+			return ebpfmon.Uprobe{}, fmt.Errorf("-uprobe: %w", err)
+		}
+		u.Binary = path
+	}
+	return u, nil
+}
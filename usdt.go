@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"snake-ebpf/ebpfmon"
+)
+
+// parseUSDT parses the -usdt flag, provider:probe@path. A path without a
+// slash is looked up in PATH.
+func parseUSDT(s string) (ebpfmon.USDT, error) {
+	probe, path, _ := strings.Cut(s, "@")
+	provider, name, _ := strings.Cut(probe, ":")
+	if provider == "" || name == "" || path == "" {
+		return ebpfmon.USDT{}, fmt.Errorf("-usdt %q: want provider:probe@path", s)
+	}
+	u := ebpfmon.USDT{Provider: provider, Name: name, Path: path}
+	if !strings.Contains(path, "/") {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return ebpfmon.USDT{}, fmt.Errorf("-usdt: %w", err)
+		}
+		u.Path = found
+	}
+	return u, nil
+}